@@ -10,8 +10,10 @@ import (
 	"syscall"
 	"time"
 
+	"recursiveDine/internal/cache"
 	"recursiveDine/internal/config"
 	"recursiveDine/internal/controllers"
+	"recursiveDine/internal/media"
 	"recursiveDine/internal/middleware"
 	"recursiveDine/internal/repositories"
 	"recursiveDine/internal/services"
@@ -62,34 +64,89 @@ func main() {
 	tableRepo := repositories.NewTableRepository(db)
 	menuRepo := repositories.NewMenuRepository(db)
 	orderRepo := repositories.NewOrderRepository(db)
+	orderEventRepo := repositories.NewOrderEventRepository(db)
+	cancellationRepo := repositories.NewOrderCancellationRepository(db)
+	courseRepo := repositories.NewOrderCourseRepository(db)
+	deliveryRepo := repositories.NewDeliveryRepository(db)
+	mappingRepo := repositories.NewExternalItemMappingRepository(db)
+	menuVersionRepo := repositories.NewMenuVersionRepository(db)
 	paymentRepo := repositories.NewPaymentRepository(db)
+	prepTimeRepo := repositories.NewPrepTimeRepository(db)
+	printerRepo := repositories.NewPrinterRepository(db)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	transactor := repositories.NewTransactor(db)
+
+	menuCache := services.NewMenuCache(initMenuCacheStore(cfg), time.Duration(cfg.MenuCacheTTLSeconds)*time.Second)
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, cfg)
 	userService := services.NewUserService(userRepo)
 	tableService := services.NewTableService(tableRepo)
-	menuService := services.NewMenuService(menuRepo)
-	orderService := services.NewOrderService(orderRepo, menuRepo)
-	paymentService := services.NewPaymentService(paymentRepo, orderRepo, cfg)
+	menuService := services.NewMenuService(menuRepo, menuCache, transactor, cfg)
+	menuImportService := services.NewMenuImportService(menuRepo, mappingRepo, menuCache, transactor)
+	menuVersionService := services.NewMenuVersionService(menuVersionRepo, menuRepo, mappingRepo, menuCache, transactor)
+	menuImageService := services.NewMenuImageService(menuRepo, menuCache, media.NewLocalStorage(cfg.MediaDir, cfg.MediaBaseURL), cfg)
 	kitchenService := services.NewKitchenService(orderRepo)
+	prepTimeService := services.NewPrepTimeService(prepTimeRepo, orderRepo, orderEventRepo, kitchenService, cfg)
+	orderService := services.NewOrderService(orderRepo, menuRepo, orderEventRepo, deliveryRepo, kitchenService, prepTimeService, transactor, cfg)
+	paymentService := services.NewPaymentService(paymentRepo, orderRepo, kitchenService, transactor, cfg)
+	cancellationService := services.NewCancellationService(cancellationRepo, orderRepo, paymentService, kitchenService, transactor)
+	courseService := services.NewCourseService(courseRepo, orderRepo, kitchenService, transactor, cfg)
+	scheduleService := services.NewScheduleService(orderRepo, kitchenService, transactor, cfg)
+	deliveryService := services.NewDeliveryService(deliveryRepo, orderRepo, userRepo, kitchenService, transactor)
+	integrationService := services.NewIntegrationService(mappingRepo, orderRepo, menuRepo, userRepo, orderService, paymentService, cfg)
+	orderTrackingService := services.NewOrderTrackingService(orderRepo, kitchenService)
+	receiptService := services.NewReceiptService(orderRepo, cfg)
+	printService := services.NewPrintService(printerRepo, orderRepo, orderEventRepo, receiptService, kitchenService, cfg)
 	seedService := services.NewSeedService(db, userRepo, tableRepo, menuRepo)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg)
+
+	// Learn prep times and keep order ETAs in step with the kitchen queue
+	prepTimeService.Start()
+
+	// Deliver queued tickets to printers, print kitchen tickets for orders
+	// that reach the kitchen and void the tickets of cancelled ones
+	printService.Start()
+
+	// Fire the next course of dine-in orders once it is due
+	courseService.Start()
+
+	// Release scheduled takeaway orders into the kitchen queue
+	scheduleService.Start()
+
+	// Purge expired Idempotency-Key responses
+	idempotencyService.Start()
+
+	// Apply scheduled menu price changes once they are due
+	menuVersionService.Start()
 
 	// Initialize controllers
 	authController := controllers.NewAuthController(authService)
 	tableController := controllers.NewTableController(tableService)
 	menuController := controllers.NewMenuController(menuService)
+	menuImageController := controllers.NewMenuImageController(menuImageService)
+	menuImportController := controllers.NewMenuImportController(menuImportService)
+	menuVersionController := controllers.NewMenuVersionController(menuVersionService)
 	orderController := controllers.NewOrderController(orderService, authService)
 	paymentController := controllers.NewPaymentController(paymentService)
-	kitchenController := controllers.NewKitchenController(kitchenService)
+	kitchenController := controllers.NewKitchenController(kitchenService, prepTimeService)
+	orderTrackingController := controllers.NewOrderTrackingController(orderTrackingService)
+	receiptController := controllers.NewReceiptController(receiptService)
+	printerController := controllers.NewPrinterController(printService)
 	seedController := controllers.NewSeedController(seedService)
 
 	// Initialize CRUD controllers
 	userController := controllers.NewUserController(userService)
 	orderManagementController := controllers.NewOrderManagementController(orderService)
 	paymentManagementController := controllers.NewPaymentManagementController(paymentService)
+	cancellationController := controllers.NewCancellationController(cancellationService, orderService)
+	courseController := controllers.NewCourseController(courseService, orderService)
+	scheduleController := controllers.NewScheduleController(scheduleService)
+	deliveryController := controllers.NewDeliveryController(deliveryService)
+	integrationController := controllers.NewIntegrationController(integrationService)
 
 	// Setup router
-	router := setupRouter(cfg, authController, tableController, menuController, orderController, paymentController, kitchenController, userController, orderManagementController, paymentManagementController, seedController)
+	router := setupRouter(cfg, authController, tableController, menuController, menuImageController, menuImportController, menuVersionController, orderController, paymentController, kitchenController, orderTrackingController, receiptController, printerController, userController, orderManagementController, paymentManagementController, cancellationController, courseController, scheduleController, deliveryController, integrationController, seedController, idempotencyService)
 
 	// Start server
 	srv := &http.Server{
@@ -182,7 +239,27 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-func setupRouter(cfg *config.Config, authController *controllers.AuthController, tableController *controllers.TableController, menuController *controllers.MenuController, orderController *controllers.OrderController, paymentController *controllers.PaymentController, kitchenController *controllers.KitchenController, userController *controllers.UserController, orderManagementController *controllers.OrderManagementController, paymentManagementController *controllers.PaymentManagementController, seedController *controllers.SeedController) *gin.Engine {
+// initMenuCacheStore returns the store for the menu cache, or nil when the
+// cache is off. When Redis cannot be reached the menu is cached in memory.
+func initMenuCacheStore(cfg *config.Config) cache.Store {
+	switch cfg.MenuCache {
+	case "off":
+		utils.LogInfo("Menu cache disabled", nil)
+		return nil
+	case "redis":
+		store := cache.NewRedisStore(fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort), cfg.RedisPassword, cfg.RedisDB)
+		if err := store.Ping(); err != nil {
+			utils.LogError("Failed to connect to Redis, caching the menu in memory", err, nil)
+			return cache.NewMemoryStore()
+		}
+		utils.LogInfo("Menu cache uses Redis", nil)
+		return store
+	default:
+		return cache.NewMemoryStore()
+	}
+}
+
+func setupRouter(cfg *config.Config, authController *controllers.AuthController, tableController *controllers.TableController, menuController *controllers.MenuController, menuImageController *controllers.MenuImageController, menuImportController *controllers.MenuImportController, menuVersionController *controllers.MenuVersionController, orderController *controllers.OrderController, paymentController *controllers.PaymentController, kitchenController *controllers.KitchenController, orderTrackingController *controllers.OrderTrackingController, receiptController *controllers.ReceiptController, printerController *controllers.PrinterController, userController *controllers.UserController, orderManagementController *controllers.OrderManagementController, paymentManagementController *controllers.PaymentManagementController, cancellationController *controllers.CancellationController, courseController *controllers.CourseController, scheduleController *controllers.ScheduleController, deliveryController *controllers.DeliveryController, integrationController *controllers.IntegrationController, seedController *controllers.SeedController, idempotencyService *services.IdempotencyService) *gin.Engine {
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -206,6 +283,9 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Uploaded images, served with long-lived cache headers
+	router.GET("/media/*key", menuImageController.ServeMedia)
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
@@ -230,13 +310,28 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 			menu.GET("", menuController.GetMenu)
 			menu.GET("/categories", menuController.GetCategories)
 			menu.GET("/items/search", menuController.SearchItems)
+			menu.GET("/tags", menuController.GetMenuTags)
 		}
 
+		// Public pickup board for the lobby screen
+		pickup := api.Group("/pickup")
+		{
+			pickup.GET("/board", orderTrackingController.GetPickupBoard)
+			pickup.GET("/board/events", orderTrackingController.HandleBoardStream)
+			pickup.GET("/slots", scheduleController.GetPickupSlots)
+		}
+
+		// Public delivery fee lookup
+		api.GET("/delivery/quote", deliveryController.QuoteDelivery)
+
+		// Order webhooks of delivery platforms, authenticated by signature
+		api.POST("/integrations/:platform/orders", integrationController.ReceiveOrder)
+
 		// Order routes
 		orders := api.Group("/orders")
 		orders.Use(middleware.AuthMiddleware(cfg))
 		{
-			orders.POST("", orderController.CreateOrder)
+			orders.POST("", middleware.Idempotency(idempotencyService), orderController.CreateOrder)
 			orders.GET("/:id", orderController.GetOrder)
 			orders.GET("", orderController.GetOrders)
 			orders.GET("/type", orderController.GetOrdersByType)
@@ -245,11 +340,21 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 			orders.PATCH("/:id/status", middleware.RoleMiddleware("staff", "admin"), orderController.UpdateOrderStatus)
 		}
 
+		// Saved delivery addresses of the current customer
+		addresses := api.Group("/addresses")
+		addresses.Use(middleware.AuthMiddleware(cfg))
+		{
+			addresses.GET("", deliveryController.GetAddresses)
+			addresses.POST("", deliveryController.CreateAddress)
+			addresses.PUT("/:id", deliveryController.UpdateAddress)
+			addresses.DELETE("/:id", deliveryController.DeleteAddress)
+		}
+
 		// Payment routes
 		payments := api.Group("/payments")
 		payments.Use(middleware.AuthMiddleware(cfg))
 		{
-			payments.POST("/qris", paymentController.InitiateQRISPayment)
+			payments.POST("/qris", middleware.Idempotency(idempotencyService), paymentController.InitiateQRISPayment)
 			payments.POST("/verify", paymentController.VerifyPayment)
 			payments.GET("/status/:payment_id", paymentController.GetPaymentStatus)
 		}
@@ -294,12 +399,40 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 				menuAdmin.POST("/categories", menuController.CreateCategory)
 				menuAdmin.PUT("/categories/:id", menuController.UpdateCategory)
 				menuAdmin.DELETE("/categories/:id", menuController.DeleteCategory)
+				menuAdmin.PUT("/categories/:id/schedules", menuController.SetCategorySchedules)
+				menuAdmin.GET("/categories/:id/translations", menuController.GetCategoryTranslations)
+				menuAdmin.PUT("/categories/:id/translations/:locale", menuController.SetCategoryTranslation)
+				menuAdmin.DELETE("/categories/:id/translations/:locale", menuController.DeleteCategoryTranslation)
 
 				// Menu item management
 				menuAdmin.POST("/items", menuController.CreateMenuItem)
 				menuAdmin.PUT("/items/:id", menuController.UpdateMenuItem)
 				menuAdmin.DELETE("/items/:id", menuController.DeleteMenuItem)
 				menuAdmin.PATCH("/items/:id/availability", menuController.UpdateMenuItemAvailability)
+				menuAdmin.PUT("/items/:id/schedules", menuController.SetMenuItemSchedules)
+				menuAdmin.PUT("/items/:id/bundle", menuController.SetMenuItemBundle)
+				menuAdmin.PUT("/items/:id/image", menuImageController.UploadMenuItemImage)
+				menuAdmin.DELETE("/items/:id/image", menuImageController.DeleteMenuItemImage)
+				menuAdmin.GET("/items/:id/prices", menuVersionController.GetPrices)
+				menuAdmin.POST("/items/:id/prices", menuVersionController.SchedulePrice)
+				menuAdmin.DELETE("/items/:id/prices/:price_id", menuVersionController.CancelScheduledPrice)
+
+				// Bulk export and import
+				menuAdmin.GET("/export", menuImportController.ExportMenu)
+				menuAdmin.POST("/import", menuImportController.ImportMenu)
+				menuAdmin.GET("/items/:id/translations", menuController.GetMenuItemTranslations)
+				menuAdmin.PUT("/items/:id/translations/:locale", menuController.SetMenuItemTranslation)
+				menuAdmin.DELETE("/items/:id/translations/:locale", menuController.DeleteMenuItemTranslation)
+
+				// Drafts, publishing and rollback
+				menuAdmin.GET("/versions", menuVersionController.GetVersions)
+				menuAdmin.POST("/versions", menuVersionController.CreateDraft)
+				menuAdmin.GET("/versions/:id", menuVersionController.GetVersion)
+				menuAdmin.PUT("/versions/:id", menuVersionController.UpdateDraft)
+				menuAdmin.DELETE("/versions/:id", menuVersionController.DiscardDraft)
+				menuAdmin.GET("/versions/:id/preview", menuVersionController.PreviewVersion)
+				menuAdmin.POST("/versions/:id/publish", menuVersionController.PublishDraft)
+				menuAdmin.POST("/versions/:id/rollback", menuVersionController.RollbackToVersion)
 			}
 
 			// Order management (admin and cashier)
@@ -307,25 +440,82 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 			{
 				orders.GET("", orderManagementController.GetAllOrders)
 				orders.GET("/:id", orderManagementController.GetOrderByID)
+				orders.GET("/:id/history", orderManagementController.GetOrderHistory)
 				orders.PATCH("/:id/status", orderManagementController.UpdateOrderStatus)
 				orders.PUT("/:id/items", orderManagementController.UpdateOrderItems)
 				orders.DELETE("/:id", orderManagementController.DeleteOrder)
+				orders.POST("/:id/cancel", middleware.Idempotency(idempotencyService), cancellationController.CancelOrder)
+				orders.POST("/:id/rounds", middleware.Idempotency(idempotencyService), orderManagementController.AddOrderRound)
+				orders.POST("/:id/courses/:course/hold", courseController.HoldCourse)
+				orders.POST("/:id/courses/:course/fire", courseController.FireCourse)
+				orders.POST("/:id/courses/:course/served", courseController.ServeCourse)
 				orders.GET("/statistics", orderManagementController.GetOrderStatistics)
 				orders.GET("/revenue", orderManagementController.GetDailyRevenue)
 			}
 
+			// Cancellations waiting for a manager (admin only)
+			cancellations := admin.Group("/cancellations")
+			cancellations.Use(middleware.RoleMiddleware("admin"))
+			{
+				cancellations.GET("", cancellationController.GetCancellations)
+				cancellations.GET("/:id", cancellationController.GetCancellationByID)
+				cancellations.POST("/:id/approve", middleware.Idempotency(idempotencyService), cancellationController.ApproveCancellation)
+				cancellations.POST("/:id/reject", cancellationController.RejectCancellation)
+			}
+
 			// Payment management
 			paymentAdmin := admin.Group("/payments")
 			{
 				paymentAdmin.GET("", paymentManagementController.GetAllPayments)
 				paymentAdmin.GET("/:id", paymentManagementController.GetPaymentByID)
 				paymentAdmin.PATCH("/:id/status", paymentManagementController.UpdatePaymentStatus)
-				paymentAdmin.POST("/:id/refund", paymentManagementController.ProcessRefund)
+				paymentAdmin.POST("/:id/refund", middleware.Idempotency(idempotencyService), paymentManagementController.ProcessRefund)
 				paymentAdmin.DELETE("/:id", paymentManagementController.DeletePayment)
 				paymentAdmin.GET("/statistics", paymentManagementController.GetPaymentStatistics)
 				paymentAdmin.GET("/revenue", paymentManagementController.GetDailyRevenueByPayment)
 			}
 
+			// Delivery zones (admin only)
+			zones := admin.Group("/delivery-zones")
+			zones.Use(middleware.RoleMiddleware("admin"))
+			{
+				zones.GET("", deliveryController.GetZones)
+				zones.POST("", deliveryController.CreateZone)
+				zones.PUT("/:id", deliveryController.UpdateZone)
+				zones.DELETE("/:id", deliveryController.DeleteZone)
+			}
+
+			// Delivery platform SKU mappings (admin only)
+			integrations := admin.Group("/integrations")
+			integrations.Use(middleware.RoleMiddleware("admin"))
+			{
+				integrations.GET("/mappings", integrationController.GetMappings)
+				integrations.PUT("/mappings", integrationController.SetMapping)
+				integrations.DELETE("/mappings/:id", integrationController.DeleteMapping)
+			}
+
+			// Kitchen reports
+			kitchenAdmin := admin.Group("/kitchen")
+			kitchenAdmin.Use(middleware.RoleMiddleware("admin"))
+			{
+				kitchenAdmin.GET("/prep-times/overruns", kitchenController.GetPrepTimeOverruns)
+			}
+
+			// Printer registry and print queue
+			admin.GET("/printers/status", printerController.GetPrinterStatus)
+			admin.GET("/print-jobs", printerController.GetPrintJobs)
+			admin.POST("/print-jobs/:id/retry", printerController.RetryPrintJob)
+
+			printers := admin.Group("/printers")
+			printers.Use(middleware.RoleMiddleware("admin"))
+			{
+				printers.GET("", printerController.GetPrinters)
+				printers.POST("", printerController.CreatePrinter)
+				printers.PUT("/:id", printerController.UpdatePrinter)
+				printers.DELETE("/:id", printerController.DeletePrinter)
+				printers.POST("/:id/test", printerController.PrintTestPage)
+			}
+
 			// Database seeding routes
 			seed := admin.Group("/seed")
 			{
@@ -346,8 +536,21 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 				orders.GET("", orderManagementController.GetAllOrders)
 				orders.GET("/:id", orderManagementController.GetOrderByID)
 				orders.PATCH("/:id/status", orderManagementController.UpdateOrderStatus)
+				orders.POST("/:id/collect", orderManagementController.MarkOrderCollected)
+				orders.POST("/:id/cancel", middleware.Idempotency(idempotencyService), cancellationController.CancelOrder)
+				orders.POST("/:id/rounds", middleware.Idempotency(idempotencyService), orderManagementController.AddOrderRound)
+				orders.POST("/:id/courses/:course/hold", courseController.HoldCourse)
+				orders.POST("/:id/courses/:course/fire", courseController.FireCourse)
+				orders.POST("/:id/courses/:course/served", courseController.ServeCourse)
+				orders.GET("/:id/tickets", receiptController.GetOrderStations)
+				orders.GET("/:id/tickets/:station", receiptController.GetKitchenTicket)
+				orders.POST("/:id/tickets/print", printerController.PrintKitchenTickets)
+				orders.POST("/:id/delivery/assign", deliveryController.AssignDriver)
 			}
 
+			// Delivery dispatch
+			staff.GET("/deliveries", deliveryController.GetDeliveries)
+
 			// Menu availability updates
 			menu := staff.Group("/menu")
 			{
@@ -361,23 +564,50 @@ func setupRouter(cfg *config.Config, authController *controllers.AuthController,
 		cashier.Use(middleware.RoleMiddleware("cashier", "admin"))
 		{
 			// Cashier order processing
-			cashier.POST("/orders", orderController.CreateCashierOrder)
+			cashier.POST("/orders", middleware.Idempotency(idempotencyService), orderController.CreateCashierOrder)
+			cashier.POST("/orders/:id/collect", orderManagementController.MarkOrderCollected)
+			cashier.POST("/orders/:id/cancel", middleware.Idempotency(idempotencyService), cancellationController.CancelOrder)
+			cashier.POST("/orders/:id/rounds", middleware.Idempotency(idempotencyService), orderManagementController.AddOrderRound)
+			cashier.POST("/orders/:id/courses/:course/hold", courseController.HoldCourse)
+			cashier.POST("/orders/:id/courses/:course/fire", courseController.FireCourse)
+			cashier.POST("/orders/:id/courses/:course/served", courseController.ServeCourse)
+			cashier.GET("/orders/:id/receipt", receiptController.GetReceipt)
+			cashier.GET("/orders/:id/tickets", receiptController.GetOrderStations)
+			cashier.GET("/orders/:id/tickets/:station", receiptController.GetKitchenTicket)
+			cashier.POST("/orders/:id/receipt/print", printerController.PrintReceipt)
+			cashier.POST("/orders/:id/tickets/print", printerController.PrintKitchenTickets)
 
 			// Cash payment processing
 			payments := cashier.Group("/payments")
 			{
-				payments.POST("/cash", paymentController.ProcessCashPayment)
+				payments.POST("/cash", middleware.Idempotency(idempotencyService), paymentController.ProcessCashPayment)
 				payments.GET("", paymentManagementController.GetAllPayments)
 				payments.GET("/:id", paymentManagementController.GetPaymentByID)
-				payments.POST("/:id/refund", paymentManagementController.ProcessRefund)
+				payments.POST("/:id/refund", middleware.Idempotency(idempotencyService), paymentManagementController.ProcessRefund)
 				payments.POST("/reconcile", paymentManagementController.ReconcileCashPayments)
 				payments.GET("/statistics", paymentManagementController.GetPaymentStatistics)
 			}
 		}
+
+		// Driver routes (driver and admin access)
+		driver := api.Group("/driver")
+		driver.Use(middleware.AuthMiddleware(cfg))
+		driver.Use(middleware.RoleMiddleware("driver", "admin"))
+		{
+			driver.GET("/deliveries", deliveryController.GetDriverDeliveries)
+			driver.POST("/deliveries/:id/picked-up", deliveryController.MarkPickedUp)
+			driver.POST("/deliveries/:id/delivered", deliveryController.MarkDelivered)
+		}
 	}
 
 	// WebSocket for kitchen updates
 	router.GET("/kitchen/updates", middleware.WSAuthMiddleware(cfg), kitchenController.HandleWebSocket)
 
+	// Server-Sent Events fallback for clients that cannot hold a WebSocket open
+	router.GET("/kitchen/events", middleware.WSAuthMiddleware(cfg), kitchenController.HandleEventStream)
+
+	// Customer-scoped live order tracking
+	router.GET("/orders/events", middleware.StreamAuthMiddleware(cfg), orderTrackingController.HandleEventStream)
+
 	return router
 }
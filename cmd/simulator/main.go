@@ -0,0 +1,159 @@
+// Command simulator posts sample delivery platform order webhooks to a local
+// API, signed like the platforms sign them, for testing the integration
+// without a merchant account:
+//
+//	go run ./cmd/simulator -platform gofood -skus NASI-GORENG,ES-TEH -count 3
+//
+// The SKUs must be mapped to menu items first (PUT /admin/integrations/mappings).
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/aggregators"
+	"recursiveDine/internal/config"
+)
+
+var customers = []struct {
+	name  string
+	phone string
+	notes string
+}{
+	{"Budi Santoso", "+6281234567890", ""},
+	{"Siti Rahma", "+6281398765432", "Tidak pedas"},
+	{"Andi Wijaya", "+6285712345678", "Extra sambal please"},
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	baseURL := flag.String("url", fmt.Sprintf("http://localhost:%s/api/v1", cfg.ServerPort), "API base URL")
+	platform := flag.String("platform", "grabfood", "Platform to simulate: "+strings.Join(aggregators.Platforms(), ", "))
+	secret := flag.String("secret", "", "Webhook secret (default: the platform's entry in PLATFORM_WEBHOOK_SECRETS)")
+	skuList := flag.String("skus", "NASI-GORENG,ES-TEH", "Comma-separated SKUs to order")
+	count := flag.Int("count", 1, "Number of orders to send")
+	interval := flag.Duration("interval", 2*time.Second, "Pause between orders")
+	resend := flag.Bool("resend", false, "Send every order twice, like a platform retrying a webhook")
+	flag.Parse()
+
+	if _, ok := aggregators.Lookup(*platform); !ok {
+		log.Fatalf("Unknown platform %q. Must be one of: %s", *platform, strings.Join(aggregators.Platforms(), ", "))
+	}
+	if *secret == "" {
+		*secret = cfg.PlatformWebhookSecret(*platform)
+	}
+	if *secret == "" {
+		log.Fatalf("No webhook secret for %s: pass -secret or set PLATFORM_WEBHOOK_SECRETS", *platform)
+	}
+
+	skus := strings.Split(*skuList, ",")
+	endpoint := fmt.Sprintf("%s/integrations/%s/orders", strings.TrimRight(*baseURL, "/"), *platform)
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	for i := 0; i < *count; i++ {
+		if i > 0 {
+			time.Sleep(*interval)
+		}
+
+		body, err := samplePayload(*platform, skus)
+		if err != nil {
+			log.Fatal("Failed to build payload:", err)
+		}
+
+		attempts := 1
+		if *resend {
+			attempts = 2
+		}
+		for attempt := 0; attempt < attempts; attempt++ {
+			if err := post(client, endpoint, *secret, body); err != nil {
+				log.Printf("Order %d: %v", i+1, err)
+			}
+		}
+	}
+}
+
+func post(client *http.Client, endpoint, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(aggregators.SignatureHeader, "sha256="+aggregators.Sign(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(resp.Body)
+	fmt.Printf("%s %s\n", resp.Status, bytes.TrimSpace(response))
+	return nil
+}
+
+// samplePayload builds an order in the platform's own format with a random
+// customer and quantities.
+func samplePayload(platform string, skus []string) ([]byte, error) {
+	customer := customers[rand.Intn(len(customers))]
+	number := fmt.Sprintf("%03d", rand.Intn(1000))
+	orderID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), number)
+
+	switch platform {
+	case "gofood":
+		event := aggregators.GoFoodEvent{
+			Header: aggregators.GoFoodHeader{
+				EventName: "gofood.order.merchant_accepted",
+				EventID:   orderID,
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			Body: aggregators.GoFoodBody{
+				Order: aggregators.GoFoodOrder{
+					OrderNumber: "F-" + orderID,
+					PIN:         number,
+					Notes:       customer.notes,
+				},
+				Customer: aggregators.GoFoodCustomer{Name: customer.name, Phone: customer.phone},
+			},
+		}
+		for _, sku := range skus {
+			event.Body.Order.OrderItems = append(event.Body.Order.OrderItems, aggregators.GoFoodItem{
+				ExternalID: strings.TrimSpace(sku),
+				Name:       strings.TrimSpace(sku),
+				Quantity:   1 + rand.Intn(2),
+			})
+		}
+		return json.Marshal(event)
+	default:
+		order := aggregators.GrabFoodOrder{
+			OrderID:          "GF-" + orderID,
+			ShortOrderNumber: "GF-" + number,
+			OrderTime:        time.Now().Format(time.RFC3339),
+			Eater: aggregators.GrabFoodEater{
+				Name:         customer.name,
+				MobileNumber: customer.phone,
+				Comment:      customer.notes,
+			},
+			FeatureFlags: aggregators.GrabFoodFlags{OrderType: "DeliveredByGrab"},
+		}
+		for _, sku := range skus {
+			order.Items = append(order.Items, aggregators.GrabFoodItem{
+				ID:       strings.TrimSpace(sku),
+				Name:     strings.TrimSpace(sku),
+				Quantity: 1 + rand.Intn(2),
+			})
+		}
+		return json.Marshal(order)
+	}
+}
@@ -0,0 +1,111 @@
+// Package aggregators turns order webhooks from third-party food delivery
+// platforms (GrabFood, GoFood and the like) into one normalized order shape.
+// Each platform has an Adapter that parses its own payload; webhooks are
+// authenticated with an HMAC-SHA256 signature of the raw request body.
+package aggregators
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, optionally
+// prefixed with "sha256=".
+const SignatureHeader = "X-Signature"
+
+// Order is an external order in the normalized schema every adapter produces.
+type Order struct {
+	Platform      string `json:"platform"`
+	ExternalID    string `json:"external_id"`    // The platform's order ID, unique per platform
+	DisplayNumber string `json:"display_number"` // Short number the platform's driver quotes at the counter
+	CustomerName  string `json:"customer_name"`
+	CustomerPhone string `json:"customer_phone"`
+	Notes         string `json:"notes"`
+	Items         []Item `json:"items"`
+}
+
+// Item is one line of an external order. SKU is the merchant's own item code
+// configured on the platform.
+type Item struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"` // As listed on the platform, for error messages
+	Quantity int    `json:"quantity"`
+	Notes    string `json:"notes"`
+}
+
+// Adapter parses the order payload of one platform.
+type Adapter interface {
+	Platform() string
+	DisplayName() string
+	Parse(body []byte) (*Order, error)
+}
+
+var adapters = map[string]Adapter{
+	"grabfood": grabFood{},
+	"gofood":   goFood{},
+}
+
+// Lookup returns the adapter of a platform.
+func Lookup(platform string) (Adapter, bool) {
+	adapter, ok := adapters[platform]
+	return adapter, ok
+}
+
+// Platforms lists the supported platforms.
+func Platforms() []string {
+	platforms := make([]string, 0, len(adapters))
+	for platform := range adapters {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// Sign returns the signature of a webhook body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a webhook signature in constant time.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(Sign(secret, body))
+	if err != nil {
+		return false
+	}
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, given)
+}
+
+// validate checks what every adapter needs before an order can be created.
+func (o *Order) validate() error {
+	if o.ExternalID == "" {
+		return errors.New("order ID is missing")
+	}
+	if o.CustomerPhone == "" {
+		return errors.New("customer phone is missing")
+	}
+	if len(o.Items) == 0 {
+		return errors.New("order has no items")
+	}
+	for _, item := range o.Items {
+		if item.SKU == "" {
+			return errors.New("item " + item.Name + " has no SKU")
+		}
+		if item.Quantity < 1 {
+			return errors.New("item " + item.SKU + " has an invalid quantity")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package aggregators
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// GoFoodEvent is the webhook a GoFood-style platform sends when the
+// merchant's order is placed.
+type GoFoodEvent struct {
+	Header GoFoodHeader `json:"header"`
+	Body   GoFoodBody   `json:"body"`
+}
+
+type GoFoodHeader struct {
+	EventName string `json:"event_name"` // e.g. gofood.order.merchant_accepted
+	EventID   string `json:"event_id"`
+	Timestamp string `json:"timestamp"`
+}
+
+type GoFoodBody struct {
+	Order    GoFoodOrder    `json:"order"`
+	Customer GoFoodCustomer `json:"customer"`
+}
+
+type GoFoodOrder struct {
+	OrderNumber string       `json:"order_number"`
+	PIN         string       `json:"pin"` // Quoted by the driver at pickup
+	OrderTotal  float64      `json:"order_total"`
+	Notes       string       `json:"notes"`
+	OrderItems  []GoFoodItem `json:"order_items"`
+}
+
+type GoFoodCustomer struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+type GoFoodItem struct {
+	ExternalID string  `json:"external_id"` // Merchant SKU
+	Name       string  `json:"name"`
+	Quantity   int     `json:"quantity"`
+	Price      float64 `json:"price"`
+	Notes      string  `json:"notes"`
+}
+
+type goFood struct{}
+
+func (goFood) Platform() string    { return "gofood" }
+func (goFood) DisplayName() string { return "GoFood" }
+
+func (a goFood) Parse(body []byte) (*Order, error) {
+	var event GoFoodEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, errors.New("invalid GoFood payload")
+	}
+
+	order := &Order{
+		Platform:      a.Platform(),
+		ExternalID:    event.Body.Order.OrderNumber,
+		DisplayNumber: event.Body.Order.PIN,
+		CustomerName:  event.Body.Customer.Name,
+		CustomerPhone: event.Body.Customer.Phone,
+		Notes:         event.Body.Order.Notes,
+	}
+	for _, item := range event.Body.Order.OrderItems {
+		order.Items = append(order.Items, Item{
+			SKU:      item.ExternalID,
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Notes:    item.Notes,
+		})
+	}
+
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,76 @@
+package aggregators
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// GrabFoodOrder is the order payload a GrabFood-style platform submits.
+type GrabFoodOrder struct {
+	OrderID          string         `json:"orderID"`
+	ShortOrderNumber string         `json:"shortOrderNumber"`
+	MerchantID       string         `json:"merchantID"`
+	OrderTime        string         `json:"orderTime"`
+	Eater            GrabFoodEater  `json:"eater"`
+	Items            []GrabFoodItem `json:"items"`
+	Price            GrabFoodPrice  `json:"price"`
+	FeatureFlags     GrabFoodFlags  `json:"featureFlags"`
+}
+
+type GrabFoodEater struct {
+	Name         string `json:"name"`
+	MobileNumber string `json:"mobileNumber"`
+	Comment      string `json:"comment"`
+}
+
+type GrabFoodItem struct {
+	ID             string `json:"id"` // Merchant SKU
+	GrabItemID     string `json:"grabItemID"`
+	Name           string `json:"name"`
+	Quantity       int    `json:"quantity"`
+	Price          int64  `json:"price"` // Minor units
+	Specifications string `json:"specifications"`
+}
+
+type GrabFoodPrice struct {
+	Subtotal     int64 `json:"subtotal"`
+	EaterPayment int64 `json:"eaterPayment"`
+}
+
+type GrabFoodFlags struct {
+	OrderType string `json:"orderType"` // e.g. DeliveredByGrab, TakeAway
+}
+
+type grabFood struct{}
+
+func (grabFood) Platform() string    { return "grabfood" }
+func (grabFood) DisplayName() string { return "GrabFood" }
+
+func (a grabFood) Parse(body []byte) (*Order, error) {
+	var payload GrabFoodOrder
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("invalid GrabFood payload")
+	}
+
+	order := &Order{
+		Platform:      a.Platform(),
+		ExternalID:    payload.OrderID,
+		DisplayNumber: payload.ShortOrderNumber,
+		CustomerName:  payload.Eater.Name,
+		CustomerPhone: payload.Eater.MobileNumber,
+		Notes:         payload.Eater.Comment,
+	}
+	for _, item := range payload.Items {
+		order.Items = append(order.Items, Item{
+			SKU:      item.ID,
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Notes:    item.Specifications,
+		})
+	}
+
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
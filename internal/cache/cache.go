@@ -0,0 +1,106 @@
+// Package cache keeps data that is expensive to read, such as the menu,
+// between requests. Entries live behind the Store interface so a single
+// server can keep them in memory while several servers share them in Redis.
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrMiss is returned when nothing is stored under a key.
+var ErrMiss = errors.New("cache miss")
+
+// Store keeps values under string keys.
+type Store interface {
+	// Get returns the value stored under a key, or ErrMiss.
+	Get(key string) ([]byte, error)
+	// Set stores a value under a key for ttl, or until it is deleted when ttl
+	// is zero.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Incr adds one to the counter stored under a key, starting from zero,
+	// and returns the new value. Counters do not expire.
+	Incr(key string) (int64, error)
+}
+
+// MemoryStore keeps entries in the memory of this process. Expired entries
+// are dropped when they are read or overwritten, and swept out as new ones
+// are stored.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	entries map[string]memoryEntry
+	sweepAt time.Time
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time // Zero for entries that do not expire
+}
+
+// memorySweepInterval is how often Set drops expired entries, so keys that
+// are never read again do not pile up.
+const memorySweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	if entry.expired(time.Now()) {
+		delete(s.entries, key)
+		return nil, ErrMiss
+	}
+	return entry.value, nil
+}
+
+func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.After(s.sweepAt) {
+		for k, entry := range s.entries {
+			if entry.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.sweepAt = now.Add(memorySweepInterval)
+	}
+
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStore) Incr(key string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var counter int64
+	if entry, ok := s.entries[key]; ok {
+		parsed, err := strconv.ParseInt(string(entry.value), 10, 64)
+		if err != nil {
+			return 0, errors.New("value is not a counter")
+		}
+		counter = parsed
+	}
+	counter++
+	s.entries[key] = memoryEntry{value: []byte(strconv.FormatInt(counter, 10))}
+	return counter, nil
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds every Redis call, so a slow or unreachable Redis
+// degrades to reading from the database instead of stalling requests.
+const redisTimeout = 500 * time.Millisecond
+
+// RedisStore keeps entries in Redis, where every server of the restaurant
+// shares them.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(address, password string, db int) *RedisStore {
+	return &RedisStore{client: redis.NewClient(&redis.Options{
+		Addr:         address,
+		Password:     password,
+		DB:           db,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})}
+}
+
+// Ping checks that Redis can be reached.
+func (s *RedisStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	value, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) Incr(key string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Incr(ctx, key).Result()
+}
@@ -2,6 +2,10 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Embedded so RESTAURANT_TIMEZONE works in minimal containers
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +15,41 @@ type Config struct {
 	ServerPort  string
 	Environment string
 
+	// Restaurant configuration
+	Timezone          string
+	MenuLocale        string // Language menu names and descriptions are written in; translations cover the others
+	RestaurantName    string
+	RestaurantAddress string
+	RestaurantPhone   string
+	RestaurantTaxID   string // NPWP printed on receipts
+	ReceiptFooter     string
+
+	// Kitchen configuration
+	DefaultPrepMinutes    int // Used for menu items without enough prep-time history
+	KitchenParallelOrders int // Number of orders the kitchen works on at the same time
+	CourseAutoFireMinutes int // Minutes after a course is served before the next fires by itself; 0 leaves it to waiters
+
+	// Scheduling configuration
+	OpeningHours         string // Pickup window for scheduled orders, e.g. 10:00-22:00
+	PickupSlotMinutes    int    // Length of a pickup slot
+	PickupSlotCapacity   int    // Scheduled orders accepted per slot; 0 means unlimited
+	ScheduledLeadMinutes int    // How long before the pickup time a scheduled order enters the kitchen queue
+	ScheduleMaxDays      int    // How many days ahead orders can be scheduled
+
+	// Printing configuration
+	PrintMaxAttempts int // Delivery attempts before a print job is marked failed
+
+	// Idempotency configuration
+	IdempotencyKeyTTLHours int // How long responses are kept for replay under their Idempotency-Key
+
+	// Delivery platform configuration
+	PlatformWebhookSecrets string // Signing secret per platform, e.g. grabfood=secret1,gofood=secret2
+
+	// Media configuration
+	MediaDir         string // Directory uploaded images are stored in
+	MediaBaseURL     string // URL path or address uploaded images are served from
+	ImageMaxUploadMB int    // Largest image that can be uploaded
+
 	// Database configuration
 	DBHost     string
 	DBPort     string
@@ -29,6 +68,10 @@ type Config struct {
 	RedisPassword string
 	RedisDB       int
 
+	// Cache configuration
+	MenuCache           string // Where the menu is cached: memory, redis or off
+	MenuCacheTTLSeconds int    // Longest a cached menu is served; writes through the API invalidate it straight away
+
 	// Payment configuration
 	QRISMerchantID string
 	QRISSecretKey  string
@@ -37,6 +80,8 @@ type Config struct {
 	// Security configuration
 	RateLimitPerMinute int
 	EncryptionKey      string
+
+	location *time.Location
 }
 
 func Load() (*Config, error) {
@@ -51,6 +96,34 @@ func Load() (*Config, error) {
 		ServerPort:  getEnv("APP_PORT", "8002"),
 		Environment: getEnv("APP_ENV", "development"),
 
+		Timezone:          getEnv("RESTAURANT_TIMEZONE", "Asia/Jakarta"),
+		MenuLocale:        strings.ToLower(getEnv("MENU_LOCALE", "id")),
+		RestaurantName:    getEnv("RESTAURANT_NAME", "RecursiveDine"),
+		RestaurantAddress: getEnv("RESTAURANT_ADDRESS", ""),
+		RestaurantPhone:   getEnv("RESTAURANT_PHONE", ""),
+		RestaurantTaxID:   getEnv("RESTAURANT_TAX_ID", ""),
+		ReceiptFooter:     getEnv("RECEIPT_FOOTER", "Terima kasih - Thank you!"),
+
+		DefaultPrepMinutes:    getEnvNumber("DEFAULT_PREP_MINUTES", 15),
+		KitchenParallelOrders: getEnvNumber("KITCHEN_PARALLEL_ORDERS", 3),
+		CourseAutoFireMinutes: getEnvNumber("COURSE_AUTO_FIRE_MINUTES", 0),
+
+		OpeningHours:         getEnv("OPENING_HOURS", "10:00-22:00"),
+		PickupSlotMinutes:    getEnvNumber("PICKUP_SLOT_MINUTES", 15),
+		PickupSlotCapacity:   getEnvNumber("PICKUP_SLOT_CAPACITY", 10),
+		ScheduledLeadMinutes: getEnvNumber("SCHEDULED_LEAD_MINUTES", 30),
+		ScheduleMaxDays:      getEnvNumber("SCHEDULE_MAX_DAYS", 7),
+
+		PrintMaxAttempts: getEnvNumber("PRINT_MAX_ATTEMPTS", 10),
+
+		IdempotencyKeyTTLHours: getEnvNumber("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+
+		PlatformWebhookSecrets: getEnv("PLATFORM_WEBHOOK_SECRETS", ""),
+
+		MediaDir:         getEnv("MEDIA_DIR", "uploads"),
+		MediaBaseURL:     getEnv("MEDIA_BASE_URL", "/media"),
+		ImageMaxUploadMB: getEnvNumber("IMAGE_MAX_UPLOAD_MB", 5),
+
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -66,6 +139,9 @@ func Load() (*Config, error) {
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       getEnvInt("REDIS_DB", 0),
 
+		MenuCache:           strings.ToLower(getEnv("MENU_CACHE", "memory")),
+		MenuCacheTTLSeconds: getEnvNumber("MENU_CACHE_TTL_SECONDS", 300),
+
 		QRISMerchantID:  getEnv("QRIS_MERCHANT_ID", ""),
 		QRISSecretKey:   getEnv("QRIS_SECRET_KEY", ""),
 		QRISCallbackURL: getEnv("QRIS_CALLBACK_URL", ""),
@@ -73,10 +149,41 @@ func Load() (*Config, error) {
 		RateLimitPerMinute: getEnvInt("RATE_LIMIT_PER_MINUTE", 100),
 		EncryptionKey:      getEnv("ENCRYPTION_KEY", "change-this-32-character-key!!!"),
 	}
+	cfg.location = loadLocation(cfg.Timezone)
 
 	return cfg, nil
 }
 
+// Location returns the restaurant's local time zone, used for business dates
+// such as the daily pickup number reset. Falls back to UTC when unknown.
+func (c *Config) Location() *time.Location {
+	if c.location != nil {
+		return c.location
+	}
+	// Configs built without Load have no cached zone
+	return loadLocation(c.Timezone)
+}
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// PlatformWebhookSecret returns the secret order webhooks of a delivery
+// platform are signed with, or "" when the platform is not set up.
+func (c *Config) PlatformWebhookSecret(platform string) string {
+	for _, entry := range strings.Split(c.PlatformWebhookSecrets, ",") {
+		name, secret, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if ok && name == platform {
+			return secret
+		}
+	}
+	return ""
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -108,3 +215,13 @@ func parseIntOrDefault(s string, defaultValue int) int {
 		return defaultValue
 	}
 }
+
+// getEnvNumber reads an integer setting, falling back to the default when it
+// is unset or not a number.
+func getEnvNumber(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// actorID returns the authenticated user behind the request for the order
+// history, or 0 when there is none (e.g. payment provider webhooks).
+func actorID(c *gin.Context) uint {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(uint)
+	return id
+}
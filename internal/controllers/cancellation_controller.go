@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CancellationController struct {
+	cancellationService *services.CancellationService
+	orderService        *services.OrderService
+}
+
+func NewCancellationController(cancellationService *services.CancellationService, orderService *services.OrderService) *CancellationController {
+	return &CancellationController{
+		cancellationService: cancellationService,
+		orderService:        orderService,
+	}
+}
+
+// @Summary Cancel order
+// @Description Cancel an order with a reason code. Paid orders are refunded and the kitchen is told to stop the ticket. Once the kitchen is preparing the order, cancellations by staff and cashiers wait for an admin to approve them (202)
+// @Tags cancellations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being cancelled"
+// @Param request body services.CancelOrderRequest true "Cancellation reason"
+// @Success 200 {object} repositories.OrderCancellation
+// @Success 202 {object} repositories.OrderCancellation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /staff/orders/{id}/cancel [post]
+func (ctrl *CancellationController) CancelOrder(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req services.CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, _ := c.Get("user_role")
+	roleName, _ := role.(string)
+
+	cancellation, err := ctrl.cancellationService.CancelOrder(uint(orderID), &req, expectedVersion, actorID(c), roleName)
+	if err != nil {
+		ctrl.respondError(c, err, uint(orderID))
+		return
+	}
+
+	if cancellation.Status == repositories.CancellationPendingApproval {
+		c.JSON(http.StatusAccepted, cancellation)
+		return
+	}
+	c.JSON(http.StatusOK, cancellation)
+}
+
+// @Summary List cancellations
+// @Description List order cancellations, e.g. those waiting for approval (admin only)
+// @Tags cancellations
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Filter by status (pending_approval, rejected, completed)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/cancellations [get]
+func (ctrl *CancellationController) GetCancellations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	cancellations, total, err := ctrl.cancellationService.GetCancellations(page, limit, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cancellations": cancellations,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+		"total_pages":   (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// @Summary Get cancellation
+// @Description Get an order cancellation by ID (admin only)
+// @Tags cancellations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Cancellation ID"
+// @Success 200 {object} repositories.OrderCancellation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/cancellations/{id} [get]
+func (ctrl *CancellationController) GetCancellationByID(c *gin.Context) {
+	cancellationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation ID"})
+		return
+	}
+
+	cancellation, err := ctrl.cancellationService.GetCancellationByID(uint(cancellationID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellation)
+}
+
+// @Summary Approve cancellation
+// @Description Approve a cancellation that is waiting for a manager. The order is cancelled, refunded if paid, and the kitchen is told to stop the ticket (admin only)
+// @Tags cancellations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Cancellation ID"
+// @Param request body services.CancellationDecisionRequest false "Decision note"
+// @Success 200 {object} repositories.OrderCancellation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /admin/cancellations/{id}/approve [post]
+func (ctrl *CancellationController) ApproveCancellation(c *gin.Context) {
+	ctrl.decide(c, ctrl.cancellationService.ApproveCancellation)
+}
+
+// @Summary Reject cancellation
+// @Description Reject a cancellation that is waiting for a manager; the order carries on (admin only)
+// @Tags cancellations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Cancellation ID"
+// @Param request body services.CancellationDecisionRequest false "Decision note"
+// @Success 200 {object} repositories.OrderCancellation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/cancellations/{id}/reject [post]
+func (ctrl *CancellationController) RejectCancellation(c *gin.Context) {
+	ctrl.decide(c, ctrl.cancellationService.RejectCancellation)
+}
+
+func (ctrl *CancellationController) decide(c *gin.Context, decide func(id uint, note string, actorID uint) (*repositories.OrderCancellation, error)) {
+	cancellationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation ID"})
+		return
+	}
+
+	// The note is optional, so an empty body is fine
+	var req services.CancellationDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	cancellation, err := decide(uint(cancellationID), req.Note, actorID(c))
+	if err != nil {
+		var orderID uint
+		if existing, lookupErr := ctrl.cancellationService.GetCancellationByID(uint(cancellationID)); lookupErr == nil {
+			orderID = existing.OrderID
+		}
+		ctrl.respondError(c, err, orderID)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellation)
+}
+
+func (ctrl *CancellationController) respondError(c *gin.Context, err error, orderID uint) {
+	switch {
+	case services.IsVersionConflict(err):
+		current, _ := ctrl.orderService.GetOrderByIDAdmin(orderID)
+		respondOrderConflict(c, err, current)
+	case errors.Is(err, services.ErrCancellationPending), errors.Is(err, services.ErrCancellationDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCancellationReason), errors.Is(err, services.ErrOrderNotCancellable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "order not found", err.Error() == "cancellation not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
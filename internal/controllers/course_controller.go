@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CourseController struct {
+	courseService *services.CourseService
+	orderService  *services.OrderService
+}
+
+func NewCourseController(courseService *services.CourseService, orderService *services.OrderService) *CourseController {
+	return &CourseController{
+		courseService: courseService,
+		orderService:  orderService,
+	}
+}
+
+// @Summary Hold course
+// @Description Keep a course of a dine-in order from going to the kitchen until it is fired, e.g. while the table is still deciding
+// @Tags courses
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param course path string true "Course (starter, main, dessert)"
+// @Success 200 {object} repositories.Order
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /staff/orders/{id}/courses/{course}/hold [post]
+func (ctrl *CourseController) HoldCourse(c *gin.Context) {
+	ctrl.transition(c, ctrl.courseService.HoldCourse)
+}
+
+// @Summary Fire course
+// @Description Send a waiting or held course of a dine-in order to the kitchen and print its tickets. An order that was ready or served goes back to preparing
+// @Tags courses
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param course path string true "Course (starter, main, dessert)"
+// @Success 200 {object} repositories.Order
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /staff/orders/{id}/courses/{course}/fire [post]
+func (ctrl *CourseController) FireCourse(c *gin.Context) {
+	ctrl.transition(c, ctrl.courseService.FireCourse)
+}
+
+// @Summary Serve course
+// @Description Record that a fired course reached the table. With COURSE_AUTO_FIRE_MINUTES set, the next course fires by itself after that many minutes
+// @Tags courses
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param course path string true "Course (starter, main, dessert)"
+// @Success 200 {object} repositories.Order
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /staff/orders/{id}/courses/{course}/served [post]
+func (ctrl *CourseController) ServeCourse(c *gin.Context) {
+	ctrl.transition(c, ctrl.courseService.ServeCourse)
+}
+
+func (ctrl *CourseController) transition(c *gin.Context, transition func(orderID uint, course repositories.CourseType, actorID uint) (*repositories.Order, error)) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := transition(uint(orderID), repositories.CourseType(c.Param("course")), actorID(c))
+	if err != nil {
+		switch {
+		case services.IsVersionConflict(err):
+			current, _ := ctrl.orderService.GetOrderByIDAdmin(uint(orderID))
+			respondOrderConflict(c, err, current)
+		case errors.Is(err, repositories.ErrCourseChanged):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err.Error() == "order not found", err.Error() == "course not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	setVersionETag(c, order.Version)
+	c.JSON(http.StatusOK, order)
+}
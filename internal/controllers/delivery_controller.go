@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeliveryController struct {
+	deliveryService *services.DeliveryService
+}
+
+func NewDeliveryController(deliveryService *services.DeliveryService) *DeliveryController {
+	return &DeliveryController{
+		deliveryService: deliveryService,
+	}
+}
+
+// @Summary Quote delivery
+// @Description Public check of whether a location is inside a delivery zone, with the zone's delivery fee and minimum order
+// @Tags delivery
+// @Produce json
+// @Param latitude query number true "Latitude"
+// @Param longitude query number true "Longitude"
+// @Success 200 {object} services.DeliveryQuote
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /delivery/quote [get]
+func (ctrl *DeliveryController) QuoteDelivery(c *gin.Context) {
+	latitude, latErr := strconv.ParseFloat(c.Query("latitude"), 64)
+	longitude, lngErr := strconv.ParseFloat(c.Query("longitude"), 64)
+	if latErr != nil || lngErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude are required"})
+		return
+	}
+
+	quote, err := ctrl.deliveryService.QuoteDelivery(latitude, longitude)
+	if err != nil {
+		if errors.Is(err, services.ErrOutsideDeliveryArea) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// @Summary List delivery zones
+// @Description List all delivery zones in the order they are matched (admin only)
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/delivery-zones [get]
+func (ctrl *DeliveryController) GetZones(c *gin.Context) {
+	zones, err := ctrl.deliveryService.GetZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"zones": zones})
+}
+
+// @Summary Create delivery zone
+// @Description Add a radius or polygon delivery zone with its fee and minimum order (admin only)
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.DeliveryZoneRequest true "Zone data"
+// @Success 201 {object} repositories.DeliveryZone
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/delivery-zones [post]
+func (ctrl *DeliveryController) CreateZone(c *gin.Context) {
+	var req services.DeliveryZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := ctrl.deliveryService.CreateZone(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// @Summary Update delivery zone
+// @Description Update a delivery zone (admin only)
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Zone ID"
+// @Param request body services.DeliveryZoneRequest true "Zone data"
+// @Success 200 {object} repositories.DeliveryZone
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/delivery-zones/{id} [put]
+func (ctrl *DeliveryController) UpdateZone(c *gin.Context) {
+	zoneID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	var req services.DeliveryZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := ctrl.deliveryService.UpdateZone(uint(zoneID), &req)
+	if err != nil {
+		if err.Error() == "delivery zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// @Summary Delete delivery zone
+// @Description Delete a delivery zone. Orders already placed keep their fee (admin only)
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Zone ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/delivery-zones/{id} [delete]
+func (ctrl *DeliveryController) DeleteZone(c *gin.Context) {
+	zoneID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	if err := ctrl.deliveryService.DeleteZone(uint(zoneID)); err != nil {
+		if err.Error() == "delivery zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delivery zone deleted successfully"})
+}
+
+// @Summary List saved addresses
+// @Description List the delivery addresses saved by the current customer
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /addresses [get]
+func (ctrl *DeliveryController) GetAddresses(c *gin.Context) {
+	addresses, err := ctrl.deliveryService.GetAddresses(actorID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+// @Summary Save address
+// @Description Save a delivery address for the current customer, to be used as delivery.address_id when ordering
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.AddressRequest true "Address"
+// @Success 201 {object} repositories.CustomerAddress
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /addresses [post]
+func (ctrl *DeliveryController) CreateAddress(c *gin.Context) {
+	var req services.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := ctrl.deliveryService.CreateAddress(actorID(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// @Summary Update address
+// @Description Update a saved delivery address of the current customer
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Address ID"
+// @Param request body services.AddressRequest true "Address"
+// @Success 200 {object} repositories.CustomerAddress
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /addresses/{id} [put]
+func (ctrl *DeliveryController) UpdateAddress(c *gin.Context) {
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	var req services.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := ctrl.deliveryService.UpdateAddress(uint(addressID), actorID(c), &req)
+	if err != nil {
+		if err.Error() == "address not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// @Summary Delete address
+// @Description Delete a saved delivery address of the current customer
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Address ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /addresses/{id} [delete]
+func (ctrl *DeliveryController) DeleteAddress(c *gin.Context) {
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	if err := ctrl.deliveryService.DeleteAddress(uint(addressID), actorID(c)); err != nil {
+		if err.Error() == "address not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+}
+
+// @Summary List open deliveries
+// @Description Dispatch view of the deliveries that have not been delivered yet, with their zone and driver
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /staff/deliveries [get]
+func (ctrl *DeliveryController) GetDeliveries(c *gin.Context) {
+	deliveries, err := ctrl.deliveryService.GetDeliveries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// @Summary Assign driver
+// @Description Give a delivery order to a driver, or hand it to another driver before it is picked up
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body services.AssignDriverRequest true "Driver"
+// @Success 200 {object} repositories.OrderDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /staff/orders/{id}/delivery/assign [post]
+func (ctrl *DeliveryController) AssignDriver(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req services.AssignDriverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivery, err := ctrl.deliveryService.AssignDriver(uint(orderID), req.DriverID, actorID(c))
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// @Summary List my deliveries
+// @Description The deliveries assigned to the current driver that are not delivered yet
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /driver/deliveries [get]
+func (ctrl *DeliveryController) GetDriverDeliveries(c *gin.Context) {
+	deliveries, err := ctrl.deliveryService.GetDriverDeliveries(actorID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// @Summary Mark delivery picked up
+// @Description Record that the assigned driver collected the ready order from the restaurant
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} repositories.OrderDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /driver/deliveries/{id}/picked-up [post]
+func (ctrl *DeliveryController) MarkPickedUp(c *gin.Context) {
+	ctrl.driverTransition(c, ctrl.deliveryService.MarkPickedUp)
+}
+
+// @Summary Mark delivered
+// @Description Record that the order was handed to the customer. The order is served from then on
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} repositories.OrderDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /driver/deliveries/{id}/delivered [post]
+func (ctrl *DeliveryController) MarkDelivered(c *gin.Context) {
+	ctrl.driverTransition(c, ctrl.deliveryService.MarkDelivered)
+}
+
+func (ctrl *DeliveryController) driverTransition(c *gin.Context, transition func(orderID, driverID uint) (*repositories.OrderDelivery, error)) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	delivery, err := transition(uint(orderID), actorID(c))
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func respondDeliveryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrDeliveryChanged), services.IsVersionConflict(err):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "order not found", err.Error() == "delivery not found", err.Error() == "driver not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
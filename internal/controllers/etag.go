@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"recursiveDine/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setVersionETag exposes the row version of a record as its entity tag.
+func setVersionETag(c *gin.Context, version uint) {
+	c.Header("ETag", strconv.Quote(strconv.FormatUint(uint64(version), 10)))
+}
+
+// ifMatchVersion reads the version a client expects to overwrite from the
+// If-Match header. It returns 0 when the header is absent or "*".
+func ifMatchVersion(c *gin.Context) (uint, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || version == 0 {
+		return 0, errors.New("invalid If-Match header")
+	}
+	return uint(version), nil
+}
+
+// respondOrderConflict answers a stale write with 409 Conflict and the order
+// as it is now, so the client can reapply its change with the new ETag.
+func respondOrderConflict(c *gin.Context, err error, current *repositories.Order) {
+	if current != nil {
+		setVersionETag(c, current.Version)
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "current": current})
+}
+
+// respondPaymentConflict is respondOrderConflict for payments.
+func respondPaymentConflict(c *gin.Context, err error, current *repositories.Payment) {
+	if current != nil {
+		setVersionETag(c, current.Version)
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "current": current})
+}
+
+// respondJSONWithETag answers with body and a strong entity tag of its
+// content. When the client already holds that content, per If-None-Match, it
+// answers 304 Not Modified without a body. Clients must revalidate before
+// reusing a stored response, so a changed menu shows up on the next request.
+func respondJSONWithETag(c *gin.Context, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := strconv.Quote(base64.RawURLEncoding.EncodeToString(sum[:]))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if ifNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// ifNoneMatch reports whether an If-None-Match header names etag. As GET
+// requests allow, tags are compared weakly.
+func ifNoneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/aggregators"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IntegrationController struct {
+	integrationService *services.IntegrationService
+}
+
+func NewIntegrationController(integrationService *services.IntegrationService) *IntegrationController {
+	return &IntegrationController{
+		integrationService: integrationService,
+	}
+}
+
+// @Summary Receive platform order
+// @Description Order webhook for third-party delivery platforms (grabfood, gofood). The body is the platform's own payload, signed with HMAC-SHA256 in the X-Signature header. Resending an order that was already received returns it with status 200
+// @Tags integrations
+// @Accept json
+// @Produce json
+// @Param platform path string true "Platform (grabfood, gofood)"
+// @Param X-Signature header string true "Hex HMAC-SHA256 of the body"
+// @Success 201 {object} map[string]interface{}
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /integrations/{platform}/orders [post]
+func (ctrl *IntegrationController) ReceiveOrder(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	order, created, err := ctrl.integrationService.ReceiveOrder(c.Param("platform"), body, c.GetHeader(aggregators.SignatureHeader))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownPlatform):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case services.IsVersionConflict(err):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"order_id":      order.ID,
+		"external_id":   order.ExternalID,
+		"status":        order.Status,
+		"pickup_number": order.PickupNumber,
+		"total_amount":  order.TotalAmount,
+	})
+}
+
+// @Summary List SKU mappings
+// @Description List which menu item each delivery platform SKU stands for (admin only)
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Param platform query string false "Only this platform"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/integrations/mappings [get]
+func (ctrl *IntegrationController) GetMappings(c *gin.Context) {
+	mappings, err := ctrl.integrationService.GetMappings(c.Query("platform"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mappings": mappings, "platforms": aggregators.Platforms()})
+}
+
+// @Summary Set SKU mapping
+// @Description Map a delivery platform SKU to a menu item, replacing an earlier mapping of the SKU (admin only)
+// @Tags integrations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.SKUMappingRequest true "Mapping"
+// @Success 200 {object} repositories.ExternalItemMapping
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/integrations/mappings [put]
+func (ctrl *IntegrationController) SetMapping(c *gin.Context) {
+	var req services.SKUMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mapping, err := ctrl.integrationService.SetMapping(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// @Summary Delete SKU mapping
+// @Description Remove a SKU mapping. Orders with the SKU are rejected until it is mapped again (admin only)
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Mapping ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/integrations/mappings/{id} [delete]
+func (ctrl *IntegrationController) DeleteMapping(c *gin.Context) {
+	mappingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping ID"})
+		return
+	}
+
+	if err := ctrl.integrationService.DeleteMapping(uint(mappingID)); err != nil {
+		if err.Error() == "mapping not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mapping deleted successfully"})
+}
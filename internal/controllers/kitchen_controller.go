@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"recursiveDine/internal/services"
 
@@ -11,7 +14,8 @@ import (
 )
 
 type KitchenController struct {
-	kitchenService *services.KitchenService
+	kitchenService  *services.KitchenService
+	prepTimeService *services.PrepTimeService
 }
 
 var upgrader = websocket.Upgrader{
@@ -21,9 +25,10 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func NewKitchenController(kitchenService *services.KitchenService) *KitchenController {
+func NewKitchenController(kitchenService *services.KitchenService, prepTimeService *services.PrepTimeService) *KitchenController {
 	return &KitchenController{
-		kitchenService: kitchenService,
+		kitchenService:  kitchenService,
+		prepTimeService: prepTimeService,
 	}
 }
 
@@ -34,6 +39,7 @@ func NewKitchenController(kitchenService *services.KitchenService) *KitchenContr
 // @Produce json
 // @Security BearerAuth
 // @Param token query string true "JWT token for authentication"
+// @Param last_event_id query int false "Resume after this event ID instead of receiving a fresh snapshot"
 // @Success 101 {string} string "Switching Protocols"
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
@@ -47,23 +53,77 @@ func (ctrl *KitchenController) HandleWebSocket(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	// Add client to kitchen service
-	ctrl.kitchenService.AddClient(conn)
-	defer ctrl.kitchenService.RemoveClient(conn)
+	events, backlog, err := ctrl.kitchenService.Subscribe(lastEventID(c))
+	if err != nil {
+		log.Printf("Error subscribing to kitchen events: %v", err)
+		return
+	}
+	defer ctrl.kitchenService.Unsubscribe(events)
 
 	// Handle incoming messages
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			// Read message from client
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				log.Printf("Error reading message: %v", err)
+				return
+			}
+
+			// For now, we don't handle incoming messages from clients
+			// In a more advanced implementation, you might handle client commands
+			// like requesting specific order details, updating order status, etc.
+		}
+	}()
+
+	for _, event := range backlog {
+		if err := conn.WriteMessage(websocket.TextMessage, event.Data); err != nil {
+			log.Printf("Error replaying kitchen events: %v", err)
+			return
+		}
+	}
+
 	for {
-		// Read message from client
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("Error reading message: %v", err)
-			break
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, event.Data); err != nil {
+				log.Printf("Error writing to client: %v", err)
+				return
+			}
 		}
+	}
+}
 
-		// For now, we don't handle incoming messages from clients
-		// In a more advanced implementation, you might handle client commands
-		// like requesting specific order details, updating order status, etc.
+// @Summary Server-Sent Events stream for kitchen updates
+// @Description Same feed as the kitchen WebSocket, for devices and proxies that cannot keep a WebSocket open.
+// @Description Reconnecting clients send Last-Event-ID (or last_event_id) to receive only the events they missed.
+// @Tags kitchen
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param token query string true "JWT token for authentication"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param last_event_id query int false "ID of the last event received"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /kitchen/events [get]
+func (ctrl *KitchenController) HandleEventStream(c *gin.Context) {
+	events, backlog, err := ctrl.kitchenService.Subscribe(lastEventID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	defer ctrl.kitchenService.Unsubscribe(events)
+
+	streamEvents(c, events, backlog)
 }
 
 // @Summary Get active kitchen orders
@@ -90,6 +150,54 @@ func (ctrl *KitchenController) GetActiveOrders(c *gin.Context) {
 	})
 }
 
+// @Summary Get prep-time overrun report
+// @Description List menu items that consistently take longer than their estimated prep time
+// @Tags kitchen
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Look-back window in days" default(30)
+// @Param min_samples query int false "Minimum completed orders per item" default(5)
+// @Param min_rate query number false "Minimum share of samples over estimate (0-1)" default(0.6)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/kitchen/prep-times/overruns [get]
+func (ctrl *KitchenController) GetPrepTimeOverruns(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+		return
+	}
+
+	minSamples, err := strconv.Atoi(c.DefaultQuery("min_samples", "5"))
+	if err != nil || minSamples < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_samples"})
+		return
+	}
+
+	minRate, err := strconv.ParseFloat(c.DefaultQuery("min_rate", "0.6"), 64)
+	if err != nil || minRate < 0 || minRate > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_rate"})
+		return
+	}
+
+	report, err := ctrl.prepTimeService.GetOverrunReport(days, minSamples, minRate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":        days,
+		"min_samples": minSamples,
+		"min_rate":    minRate,
+		"items":       report,
+	})
+}
+
 // @Summary Broadcast order update
 // @Description Broadcast order status update to all connected kitchen clients
 // @Tags kitchen
@@ -136,3 +244,57 @@ func (ctrl *KitchenController) BroadcastUpdate(c *gin.Context) {
 		"client_count": ctrl.kitchenService.GetClientCount(),
 	})
 }
+
+// lastEventID reads the resume position from the Last-Event-ID header that
+// EventSource sends on reconnect, falling back to the last_event_id query
+// parameter for WebSocket clients.
+func lastEventID(c *gin.Context) uint64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// streamEvents writes the backlog followed by live kitchen events as
+// text/event-stream until the client disconnects or the subscription closes.
+func streamEvents(c *gin.Context, events <-chan services.KitchenEvent, backlog []services.KitchenEvent) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+	c.Status(http.StatusOK)
+
+	for _, event := range backlog {
+		writeEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(c, event)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeEvent(c *gin.Context, event services.KitchenEvent) {
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
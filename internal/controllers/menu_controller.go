@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"recursiveDine/internal/repositories"
 	"recursiveDine/internal/services"
@@ -20,40 +24,126 @@ func NewMenuController(menuService *services.MenuService) *MenuController {
 	}
 }
 
+// menuOptions reads how a guest wants to see the menu: in which language,
+// leaving out which allergens and for which diets. The optional at query
+// parameter shows the menu as it will be at another time, e.g. the pickup
+// time of a pre-order.
+func menuOptions(c *gin.Context) (services.MenuOptions, bool) {
+	// The response depends on the guest's language
+	c.Header("Vary", "Accept-Language")
+	opts := services.MenuOptions{At: time.Now(), Locales: preferredLocales(c)}
+	if err := opts.SetFilters(c.Query("exclude_allergens"), c.Query("diet")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return opts, false
+	}
+
+	if at := c.Query("at"); at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid at. Use RFC 3339, e.g. 2026-10-18T08:00:00+07:00"})
+			return opts, false
+		}
+		opts.At = t
+	}
+	return opts, true
+}
+
+// preferredLocales lists the languages a guest asked for, most preferred
+// first: those in the lang query parameter, then the Accept-Language header
+// by quality.
+func preferredLocales(c *gin.Context) []string {
+	var locales []string
+	if lang := c.Query("lang"); lang != "" {
+		locales = append(locales, strings.Split(lang, ",")...)
+	}
+
+	type accepted struct {
+		locale  string
+		quality float64
+	}
+	var header []accepted
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if locale == "" || locale == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			header = append(header, accepted{locale: locale, quality: quality})
+		}
+	}
+	sort.SliceStable(header, func(i, j int) bool { return header[i].quality > header[j].quality })
+
+	for _, entry := range header {
+		locales = append(locales, entry.locale)
+	}
+	return locales
+}
+
 // @Summary Get complete menu
-// @Description Get all menu categories with their items
+// @Description Get all menu categories with their items that are being served now, or at the given time, in the guest's language
 // @Tags menu
 // @Accept json
 // @Produce json
+// @Param at query string false "RFC 3339 time to show the menu for (default now)"
+// @Param lang query string false "Preferred languages, e.g. en or en-AU,en; overrides Accept-Language"
+// @Param exclude_allergens query string false "Leave out dishes containing any of these allergens, e.g. peanut,gluten"
+// @Param diet query string false "Only dishes suiting all of these diets, e.g. vegetarian,halal"
+// @Param Accept-Language header string false "Preferred languages"
+// @Param If-None-Match header string false "ETag of the menu the client already has"
 // @Success 200 {array} repositories.MenuCategory
+// @Success 304 "The menu has not changed"
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /menu [get]
 func (ctrl *MenuController) GetMenu(c *gin.Context) {
-	menu, err := ctrl.menuService.GetCompleteMenu()
+	opts, ok := menuOptions(c)
+	if !ok {
+		return
+	}
+
+	menu, err := ctrl.menuService.GetCompleteMenu(opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, menu)
+	respondJSONWithETag(c, menu)
 }
 
 // @Summary Get menu categories
-// @Description Get all menu categories
+// @Description Get all menu categories that are being served now, or at the given time
 // @Tags menu
 // @Accept json
 // @Produce json
+// @Param at query string false "RFC 3339 time to show the categories for (default now)"
+// @Param lang query string false "Preferred languages; overrides Accept-Language"
+// @Param exclude_allergens query string false "Leave out dishes containing any of these allergens, e.g. peanut,gluten"
+// @Param diet query string false "Only dishes suiting all of these diets, e.g. vegetarian,halal"
+// @Param If-None-Match header string false "ETag of the categories the client already has"
 // @Success 200 {array} repositories.MenuCategory
+// @Success 304 "The categories have not changed"
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /menu/categories [get]
 func (ctrl *MenuController) GetCategories(c *gin.Context) {
-	categories, err := ctrl.menuService.GetAllCategories()
+	opts, ok := menuOptions(c)
+	if !ok {
+		return
+	}
+
+	categories, err := ctrl.menuService.GetAllCategories(opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, categories)
+	respondJSONWithETag(c, categories)
 }
 
 // @Summary Get menu items by category
@@ -62,6 +152,10 @@ func (ctrl *MenuController) GetCategories(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param category_id query int true "Category ID"
+// @Param at query string false "RFC 3339 time to show the items for (default now)"
+// @Param lang query string false "Preferred languages; overrides Accept-Language"
+// @Param exclude_allergens query string false "Leave out dishes containing any of these allergens, e.g. peanut,gluten"
+// @Param diet query string false "Only dishes suiting all of these diets, e.g. vegetarian,halal"
 // @Success 200 {array} repositories.MenuItem
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -76,7 +170,12 @@ func (ctrl *MenuController) GetMenuItemsByCategory(c *gin.Context) {
 		return
 	}
 
-	items, err := ctrl.menuService.GetMenuItemsByCategory(req.CategoryID)
+	opts, ok := menuOptions(c)
+	if !ok {
+		return
+	}
+
+	items, err := ctrl.menuService.GetMenuItemsByCategory(req.CategoryID, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -86,12 +185,16 @@ func (ctrl *MenuController) GetMenuItemsByCategory(c *gin.Context) {
 }
 
 // @Summary Search menu items
-// @Description Search menu items by name or description
+// @Description Search menu items by name, category or description in any language the menu is written or translated in. Results are ranked best first, tolerate typos and carry highlighted text with matches wrapped in <mark> tags
 // @Tags menu
 // @Accept json
 // @Produce json
 // @Param q query string true "Search query"
-// @Success 200 {array} repositories.MenuItem
+// @Param at query string false "RFC 3339 time to search the menu for (default now)"
+// @Param lang query string false "Preferred languages; overrides Accept-Language"
+// @Param exclude_allergens query string false "Leave out dishes containing any of these allergens, e.g. peanut,gluten"
+// @Param diet query string false "Only dishes suiting all of these diets, e.g. vegetarian,halal"
+// @Success 200 {array} services.MenuSearchResult
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /menu/items/search [get]
@@ -102,7 +205,12 @@ func (ctrl *MenuController) SearchItems(c *gin.Context) {
 		return
 	}
 
-	items, err := ctrl.menuService.SearchMenuItems(query)
+	opts, ok := menuOptions(c)
+	if !ok {
+		return
+	}
+
+	items, err := ctrl.menuService.SearchMenuItems(query, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -111,6 +219,16 @@ func (ctrl *MenuController) SearchItems(c *gin.Context) {
 	c.JSON(http.StatusOK, items)
 }
 
+// @Summary Get menu tags
+// @Description List the allergen and dietary tags menu items can carry and the menu can be filtered on
+// @Tags menu
+// @Produce json
+// @Success 200 {object} map[string][]string
+// @Router /menu/tags [get]
+func (ctrl *MenuController) GetMenuTags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"allergens": services.Allergens, "diets": services.DietaryTags})
+}
+
 // @Summary Get menu item by ID
 // @Description Get detailed information about a specific menu item
 // @Tags menu
@@ -233,6 +351,46 @@ func (ctrl *MenuController) DeleteCategory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
 
+// @Summary Set category schedules
+// @Description Replace the dayparts in which a category is on the menu, e.g. breakfast from 06:00 to 10:30 on weekdays. An empty list serves it all day (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param request body []services.MenuScheduleRequest true "Schedules"
+// @Success 200 {object} repositories.MenuCategory
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/categories/{id}/schedules [put]
+func (ctrl *MenuController) SetCategorySchedules(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req []services.MenuScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := ctrl.menuService.SetCategorySchedules(uint(categoryID), req)
+	if err != nil {
+		if err.Error() == "category not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
 // CRUD operations for menu items (Admin only)
 
 // @Summary Create menu item
@@ -246,6 +404,7 @@ func (ctrl *MenuController) DeleteCategory(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /admin/menu/items [post]
 func (ctrl *MenuController) CreateMenuItem(c *gin.Context) {
 	var item repositories.MenuItem
@@ -255,6 +414,10 @@ func (ctrl *MenuController) CreateMenuItem(c *gin.Context) {
 	}
 
 	if err := ctrl.menuService.CreateMenuItem(&item); err != nil {
+		if errors.Is(err, services.ErrDuplicateSKU) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -275,6 +438,7 @@ func (ctrl *MenuController) CreateMenuItem(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /admin/menu/items/{id} [put]
 func (ctrl *MenuController) UpdateMenuItem(c *gin.Context) {
 	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -291,6 +455,14 @@ func (ctrl *MenuController) UpdateMenuItem(c *gin.Context) {
 
 	item.ID = uint(itemID)
 	if err := ctrl.menuService.UpdateMenuItem(&item); err != nil {
+		if errors.Is(err, services.ErrInvalidMenuTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrDuplicateSKU) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -326,6 +498,86 @@ func (ctrl *MenuController) DeleteMenuItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Menu item deleted successfully"})
 }
 
+// @Summary Set menu item schedules
+// @Description Replace the dayparts in which a menu item is on the menu. The item is only served while its category is too. An empty list serves it whenever its category is (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param request body []services.MenuScheduleRequest true "Schedules"
+// @Success 200 {object} repositories.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/schedules [put]
+func (ctrl *MenuController) SetMenuItemSchedules(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	var req []services.MenuScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := ctrl.menuService.SetMenuItemSchedules(uint(itemID), req)
+	if err != nil {
+		if err.Error() == "menu item not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Set menu item bundle
+// @Description Make a menu item a bundle of component slots, e.g. a set meal. A slot with one option is fixed; with more, the guest chooses one, optionally for an upcharge. An empty list makes the item a plain item again (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param request body []services.MenuBundleSlotRequest true "Bundle slots"
+// @Success 200 {object} repositories.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/bundle [put]
+func (ctrl *MenuController) SetMenuItemBundle(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	var req []services.MenuBundleSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := ctrl.menuService.SetMenuItemBundle(uint(itemID), req)
+	if err != nil {
+		if err.Error() == "menu item not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
 // @Summary Update menu item availability
 // @Description Update menu item availability (admin/staff only)
 // @Tags menu
@@ -368,3 +620,207 @@ func (ctrl *MenuController) UpdateMenuItemAvailability(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Menu item marked as " + status})
 }
+
+// Translations (Admin only)
+
+// @Summary List category translations
+// @Description List the translations of a menu category (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Success 200 {array} repositories.MenuTranslation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/categories/{id}/translations [get]
+func (ctrl *MenuController) GetCategoryTranslations(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	translations, err := ctrl.menuService.GetCategoryTranslations(uint(categoryID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// @Summary Set category translation
+// @Description Add or replace the name and description of a menu category in a locale (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param locale path string true "Locale, e.g. en"
+// @Param request body services.TranslationRequest true "Translation"
+// @Success 200 {object} repositories.MenuTranslation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/categories/{id}/translations/{locale} [put]
+func (ctrl *MenuController) SetCategoryTranslation(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req services.TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := ctrl.menuService.SetCategoryTranslation(uint(categoryID), c.Param("locale"), &req)
+	if err != nil {
+		if err.Error() == "category not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// @Summary Delete category translation
+// @Description Remove the translation of a menu category into a locale (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param locale path string true "Locale, e.g. en"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/categories/{id}/translations/{locale} [delete]
+func (ctrl *MenuController) DeleteCategoryTranslation(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := ctrl.menuService.DeleteCategoryTranslation(uint(categoryID), c.Param("locale")); err != nil {
+		if err.Error() == "translation not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted successfully"})
+}
+
+// @Summary List menu item translations
+// @Description List the translations of a menu item (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Success 200 {array} repositories.MenuTranslation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/translations [get]
+func (ctrl *MenuController) GetMenuItemTranslations(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	translations, err := ctrl.menuService.GetMenuItemTranslations(uint(itemID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// @Summary Set menu item translation
+// @Description Add or replace the name and description of a menu item in a locale (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param locale path string true "Locale, e.g. en"
+// @Param request body services.TranslationRequest true "Translation"
+// @Success 200 {object} repositories.MenuTranslation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/translations/{locale} [put]
+func (ctrl *MenuController) SetMenuItemTranslation(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	var req services.TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := ctrl.menuService.SetMenuItemTranslation(uint(itemID), c.Param("locale"), &req)
+	if err != nil {
+		if err.Error() == "menu item not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// @Summary Delete menu item translation
+// @Description Remove the translation of a menu item into a locale (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param locale path string true "Locale, e.g. en"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/translations/{locale} [delete]
+func (ctrl *MenuController) DeleteMenuItemTranslation(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	if err := ctrl.menuService.DeleteMenuItemTranslation(uint(itemID), c.Param("locale")); err != nil {
+		if err.Error() == "translation not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted successfully"})
+}
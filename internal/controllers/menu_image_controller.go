@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"recursiveDine/internal/media"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MenuImageController struct {
+	menuImageService *services.MenuImageService
+}
+
+func NewMenuImageController(menuImageService *services.MenuImageService) *MenuImageController {
+	return &MenuImageController{
+		menuImageService: menuImageService,
+	}
+}
+
+// @Summary Upload menu item image
+// @Description Upload a JPEG, PNG or GIF image of a menu item as the image form field. Thumbnail and medium variants are generated, and the image replaces the item's previous one (admin only)
+// @Tags menu
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param image formData file true "Image file"
+// @Success 200 {object} repositories.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /admin/menu/items/{id}/image [put]
+func (ctrl *MenuImageController) UploadMenuItemImage(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	// Leave room for the multipart envelope around the image
+	maxBytes := ctrl.menuImageService.MaxUploadBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBytes)+1<<20)
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": media.ErrImageTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An image file is required in the image form field"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := ctrl.menuImageService.SetMenuItemImage(uint(itemID), data)
+	if err != nil {
+		respondMenuImageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Delete menu item image
+// @Description Remove the uploaded image of a menu item (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Success 200 {object} repositories.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/image [delete]
+func (ctrl *MenuImageController) DeleteMenuItemImage(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	item, err := ctrl.menuImageService.DeleteMenuItemImage(uint(itemID))
+	if err != nil {
+		respondMenuImageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Get media file
+// @Description Download an uploaded file such as a menu item image. Files never change under their URL, so they may be cached indefinitely
+// @Tags menu
+// @Produce image/jpeg,image/png,image/gif
+// @Param key path string true "Media key, e.g. menu-items/12/3f2a9c0b1d4e-thumbnail.jpg"
+// @Success 200 {file} file
+// @Success 304 "Not modified"
+// @Failure 404 {object} map[string]string
+// @Router /media/{key} [get]
+func (ctrl *MenuImageController) ServeMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	object, err := ctrl.menuImageService.OpenMedia(key)
+	if errors.Is(err, media.ErrNotFound) || errors.Is(err, media.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": media.ErrNotFound.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer object.Body.Close()
+
+	// Keys contain a hash of the content, so a file never changes under its
+	// URL and the key itself serves as the entity tag
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", strconv.Quote(path.Base(key)))
+	c.Header("Content-Type", object.ContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, path.Base(key), object.ModTime, object.Body)
+}
+
+func respondMenuImageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case err.Error() == "menu item not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
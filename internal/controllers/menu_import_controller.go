@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMenuFileBytes caps the size of an imported menu file.
+const maxMenuFileBytes = 10 << 20
+
+type MenuImportController struct {
+	menuImportService *services.MenuImportService
+}
+
+func NewMenuImportController(menuImportService *services.MenuImportService) *MenuImportController {
+	return &MenuImportController{
+		menuImportService: menuImportService,
+	}
+}
+
+// @Summary Export menu
+// @Description Download every category and item, including inactive categories and unavailable items, as JSON or CSV. CSV files hold items only (admin only)
+// @Tags menu
+// @Produce json,text/csv
+// @Security BearerAuth
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} services.MenuFile
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/menu/export [get]
+func (ctrl *MenuImportController) ExportMenu(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	file, err := ctrl.menuImportService.ExportMenu()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("menu-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, file)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteMenuCSV(&buf, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// @Summary Import menu
+// @Description Create and update categories and items from a JSON or CSV menu file, sent as the request body or as the file field of a multipart form. With dry_run=true nothing is written and the response previews every change. Files with invalid rows are not applied at all and get 422 with the errors per row (admin only)
+// @Tags menu
+// @Accept json,text/csv,multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "json or csv; by default taken from the content type or file name"
+// @Param dry_run query bool false "Preview the changes without applying them"
+// @Success 200 {object} services.MenuImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 422 {object} services.MenuImportResult
+// @Router /admin/menu/import [post]
+func (ctrl *MenuImportController) ImportMenu(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMenuFileBytes)
+	data, format, err := readMenuFile(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "menu file is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var file *services.MenuFile
+	var rowErrors []services.ImportRowError
+	if format == "csv" {
+		file, rowErrors, err = services.ParseMenuCSV(data)
+	} else {
+		file, err = services.ParseMenuJSON(data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.menuImportService.ImportMenu(file, rowErrors, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// readMenuFile returns an uploaded menu file and whether it is json or csv.
+// The format query parameter wins over the content type or file name.
+func readMenuFile(c *gin.Context) ([]byte, string, error) {
+	format := c.Query("format")
+	var data []byte
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("a menu file is required in the file form field")
+		}
+		upload, err := header.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer upload.Close()
+		if data, err = io.ReadAll(upload); err != nil {
+			return nil, "", err
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(path.Ext(header.Filename)), ".")
+		}
+	} else {
+		var err error
+		if data, err = io.ReadAll(c.Request.Body); err != nil {
+			return nil, "", err
+		}
+		if format == "" {
+			switch c.ContentType() {
+			case "text/csv":
+				format = "csv"
+			case "application/json":
+				format = "json"
+			}
+		}
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, "", errors.New("the menu file is empty")
+	}
+	if format != "json" && format != "csv" {
+		return nil, "", errors.New("unknown menu file format. Send JSON or CSV, or set format=json or format=csv")
+	}
+	return data, format, nil
+}
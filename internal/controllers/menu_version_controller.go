@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MenuVersionController struct {
+	menuVersionService *services.MenuVersionService
+}
+
+func NewMenuVersionController(menuVersionService *services.MenuVersionService) *MenuVersionController {
+	return &MenuVersionController{
+		menuVersionService: menuVersionService,
+	}
+}
+
+// Prices
+
+// @Summary Get menu item prices
+// @Description Get the current price of a menu item, its scheduled price changes and its price history (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Success 200 {object} services.MenuItemPrices
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/prices [get]
+func (ctrl *MenuVersionController) GetPrices(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	prices, err := ctrl.menuVersionService.GetPrices(uint(itemID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prices)
+}
+
+// @Summary Schedule price change
+// @Description Change the price of a menu item from a future time on. The change is applied automatically once due (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param request body services.SchedulePriceRequest true "New price and when it takes effect"
+// @Success 201 {object} repositories.MenuItemPrice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/prices [post]
+func (ctrl *MenuVersionController) SchedulePrice(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	var req services.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, err := ctrl.menuVersionService.SchedulePrice(uint(itemID), &req, actorID(c))
+	if err != nil {
+		if err.Error() == "menu item not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, price)
+}
+
+// @Summary Cancel scheduled price change
+// @Description Withdraw a price change that has not taken effect yet (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu item ID"
+// @Param price_id path int true "Price change ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/items/{id}/prices/{price_id} [delete]
+func (ctrl *MenuVersionController) CancelScheduledPrice(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+	priceID, err := strconv.ParseUint(c.Param("price_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price change ID"})
+		return
+	}
+
+	if err := ctrl.menuVersionService.CancelScheduledPrice(uint(itemID), uint(priceID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled price change cancelled"})
+}
+
+// Versions
+
+// @Summary List menu versions
+// @Description List the draft and published menu versions, newest first (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} repositories.MenuVersion
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/menu/versions [get]
+func (ctrl *MenuVersionController) GetVersions(c *gin.Context) {
+	versions, err := ctrl.menuVersionService.GetVersions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// @Summary Start menu draft
+// @Description Start a draft from the live menu. Only one draft can be open at a time (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]string false "Note describing the draft"
+// @Success 201 {object} services.MenuVersionDetail
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/menu/versions [post]
+func (ctrl *MenuVersionController) CreateDraft(c *gin.Context) {
+	var req struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	version, err := ctrl.menuVersionService.CreateDraft(req.Note, actorID(c))
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// @Summary Get menu version
+// @Description Get a menu version with its menu (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu version ID"
+// @Success 200 {object} services.MenuVersionDetail
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/versions/{id} [get]
+func (ctrl *MenuVersionController) GetVersion(c *gin.Context) {
+	versionID, ok := menuVersionID(c)
+	if !ok {
+		return
+	}
+
+	version, err := ctrl.menuVersionService.GetVersion(versionID)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// @Summary Update menu draft
+// @Description Replace the menu of a draft, in the JSON export format, and optionally its note. The menu is checked when the draft is previewed or published (admin only)
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu version ID"
+// @Param request body map[string]interface{} true "note and content"
+// @Success 200 {object} services.MenuVersionDetail
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/menu/versions/{id} [put]
+func (ctrl *MenuVersionController) UpdateDraft(c *gin.Context) {
+	versionID, ok := menuVersionID(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMenuFileBytes)
+	var req struct {
+		Note    *string         `json:"note"`
+		Content json.RawMessage `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	file, err := services.ParseMenuJSON(req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := ctrl.menuVersionService.UpdateDraft(versionID, req.Note, file)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// @Summary Discard menu draft
+// @Description Delete a draft without publishing it (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu version ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/menu/versions/{id} [delete]
+func (ctrl *MenuVersionController) DiscardDraft(c *gin.Context) {
+	versionID, ok := menuVersionID(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.menuVersionService.DiscardDraft(versionID); err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Draft discarded"})
+}
+
+// @Summary Preview menu version
+// @Description Show what publishing a draft, or rolling back to a published version, would change on the live menu. Nothing is written (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu version ID"
+// @Success 200 {object} services.MenuImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/menu/versions/{id}/preview [get]
+func (ctrl *MenuVersionController) PreviewVersion(c *gin.Context) {
+	versionID, ok := menuVersionID(c)
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.menuVersionService.PreviewVersion(versionID)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Publish menu draft
+// @Description Make the live menu match a draft in one transaction. Categories and items the draft leaves out are taken off the menu. A draft with invalid rows is not published and gets 422 with the errors per row (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu version ID"
+// @Success 200 {object} services.MenuPublishResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} services.MenuPublishResult
+// @Router /admin/menu/versions/{id}/publish [post]
+func (ctrl *MenuVersionController) PublishDraft(c *gin.Context) {
+	versionID, ok := menuVersionID(c)
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.menuVersionService.PublishDraft(versionID, actorID(c))
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+	respondPublishResult(c, result)
+}
+
+// @Summary Roll back menu
+// @Description Publish the menu of an earlier published version again, as a new version (admin only)
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID of the published version to roll back to"
+// @Success 200 {object} services.MenuPublishResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} services.MenuPublishResult
+// @Router /admin/menu/versions/{id}/rollback [post]
+func (ctrl *MenuVersionController) RollbackToVersion(c *gin.Context) {
+	versionID, ok := menuVersionID(c)
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.menuVersionService.RollbackToVersion(versionID, actorID(c))
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+	respondPublishResult(c, result)
+}
+
+func respondPublishResult(c *gin.Context, result *services.MenuPublishResult) {
+	if len(result.Changes.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func menuVersionID(c *gin.Context) (uint, bool) {
+	versionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu version ID"})
+		return 0, false
+	}
+	return uint(versionID), true
+}
+
+func (ctrl *MenuVersionController) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDraftExists), errors.Is(err, services.ErrNotDraft),
+		errors.Is(err, services.ErrNotPublished), errors.Is(err, services.ErrCurrentVersion):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "menu version not found", err.Error() == "draft not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
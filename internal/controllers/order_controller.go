@@ -94,6 +94,7 @@ func (ctrl *OrderController) GetOrder(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	c.JSON(http.StatusOK, order)
 }
 
@@ -160,12 +161,14 @@ func (ctrl *OrderController) GetOrders(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being changed"
 // @Param request body map[string]string true "New status"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /orders/{id}/status [patch]
 func (ctrl *OrderController) UpdateOrderStatus(c *gin.Context) {
 	var uriReq struct {
@@ -191,6 +194,8 @@ func (ctrl *OrderController) UpdateOrderStatus(c *gin.Context) {
 	switch req.Status {
 	case "pending":
 		status = repositories.OrderStatusPending
+	case "scheduled":
+		status = repositories.OrderStatusScheduled
 	case "confirmed":
 		status = repositories.OrderStatusConfirmed
 	case "preparing":
@@ -200,13 +205,25 @@ func (ctrl *OrderController) UpdateOrderStatus(c *gin.Context) {
 	case "served":
 		status = repositories.OrderStatusServed
 	case "cancelled":
-		status = repositories.OrderStatusCancelled
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrCancelWithReason.Error()})
+		return
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
 		return
 	}
 
-	if err := ctrl.orderService.UpdateOrderStatus(uriReq.ID, status); err != nil {
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.orderService.UpdateOrderStatus(uriReq.ID, status, expectedVersion, actorID(c)); err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.orderService.GetOrderByID(uriReq.ID)
+			respondOrderConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -252,6 +269,8 @@ func (ctrl *OrderController) GetOrdersByStatus(c *gin.Context) {
 	switch statusParam {
 	case "pending":
 		status = repositories.OrderStatusPending
+	case "scheduled":
+		status = repositories.OrderStatusScheduled
 	case "confirmed":
 		status = repositories.OrderStatusConfirmed
 	case "preparing":
@@ -282,12 +301,12 @@ func (ctrl *OrderController) GetOrdersByStatus(c *gin.Context) {
 }
 
 // @Summary Get orders by order type
-// @Description Get all orders filtered by order type (dine_in or takeaway)
+// @Description Get all orders filtered by order type (dine_in, takeaway or delivery)
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param type query string true "Order type (dine_in or takeaway)"
+// @Param type query string true "Order type (dine_in, takeaway or delivery)"
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Items per page (default: 10)"
 // @Success 200 {array} repositories.Order
@@ -320,8 +339,10 @@ func (ctrl *OrderController) GetOrdersByType(c *gin.Context) {
 		orderType = repositories.OrderTypeDineIn
 	case "takeaway":
 		orderType = repositories.OrderTypeTakeaway
+	case "delivery":
+		orderType = repositories.OrderTypeDelivery
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order type. Must be 'dine_in' or 'takeaway'"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order type. Must be 'dine_in', 'takeaway' or 'delivery'"})
 		return
 	}
 
@@ -369,7 +390,7 @@ func (ctrl *OrderController) GetTakeawayOrdersReady(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param status query string true "Order status"
-// @Param type query string true "Order type (dine_in or takeaway)"
+// @Param type query string true "Order type (dine_in, takeaway or delivery)"
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Items per page (default: 10)"
 // @Success 200 {array} repositories.Order
@@ -407,6 +428,8 @@ func (ctrl *OrderController) GetOrdersByStatusAndType(c *gin.Context) {
 	switch statusParam {
 	case "pending":
 		status = repositories.OrderStatusPending
+	case "scheduled":
+		status = repositories.OrderStatusScheduled
 	case "confirmed":
 		status = repositories.OrderStatusConfirmed
 	case "preparing":
@@ -429,8 +452,10 @@ func (ctrl *OrderController) GetOrdersByStatusAndType(c *gin.Context) {
 		orderType = repositories.OrderTypeDineIn
 	case "takeaway":
 		orderType = repositories.OrderTypeTakeaway
+	case "delivery":
+		orderType = repositories.OrderTypeDelivery
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order type. Must be 'dine_in' or 'takeaway'"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order type. Must be 'dine_in', 'takeaway' or 'delivery'"})
 		return
 	}
 
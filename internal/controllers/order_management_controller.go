@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -85,22 +86,54 @@ func (ctrl *OrderManagementController) GetOrderByID(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	c.JSON(http.StatusOK, order)
 }
 
+// @Summary Get order history
+// @Description Get the audit trail of an order: status changes, overrides, item edits, payments and refunds with actor, time, previous/new values and reason (admin only)
+// @Tags orders-management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {array} repositories.OrderEvent
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/orders/{id}/history [get]
+func (ctrl *OrderManagementController) GetOrderHistory(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	events, err := ctrl.orderService.GetOrderHistory(uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
 // @Summary Update order status
-// @Description Update order status (staff/admin only)
+// @Description Update order status (staff/admin only). Moving an order outside the normal workflow is an override and requires a reason
 // @Tags orders-management
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being changed"
 // @Param request body map[string]string true "Order status"
 // @Success 200 {object} repositories.Order
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /admin/orders/{id}/status [patch]
 func (ctrl *OrderManagementController) UpdateOrderStatus(c *gin.Context) {
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -111,6 +144,7 @@ func (ctrl *OrderManagementController) UpdateOrderStatus(c *gin.Context) {
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"` // Required when skipping the normal workflow
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -119,7 +153,7 @@ func (ctrl *OrderManagementController) UpdateOrderStatus(c *gin.Context) {
 	}
 
 	// Validate status
-	validStatuses := []string{"pending", "confirmed", "preparing", "ready", "delivered", "cancelled"}
+	validStatuses := []string{"pending", "confirmed", "preparing", "ready", "served", "cancelled"}
 	isValid := false
 	for _, status := range validStatuses {
 		if status == req.Status {
@@ -133,12 +167,28 @@ func (ctrl *OrderManagementController) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	order, err := ctrl.orderService.UpdateOrderStatusAdmin(uint(orderID), req.Status)
+	expectedVersion, err := ifMatchVersion(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := ctrl.orderService.UpdateOrderStatusAdmin(uint(orderID), req.Status, expectedVersion, req.Reason, actorID(c))
+	if err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.orderService.GetOrderByIDAdmin(uint(orderID))
+			respondOrderConflict(c, err, current)
+			return
+		}
+		if errors.Is(err, services.ErrOverrideReasonRequired) || errors.Is(err, services.ErrCancelWithReason) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	c.JSON(http.StatusOK, order)
 }
 
@@ -162,7 +212,7 @@ func (ctrl *OrderManagementController) DeleteOrder(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.orderService.DeleteOrder(uint(orderID)); err != nil {
+	if err := ctrl.orderService.DeleteOrder(uint(orderID), actorID(c)); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -227,12 +277,14 @@ func (ctrl *OrderManagementController) GetDailyRevenue(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being changed"
 // @Param request body []repositories.OrderItem true "Order items"
 // @Success 200 {object} repositories.Order
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /admin/orders/{id}/items [put]
 func (ctrl *OrderManagementController) UpdateOrderItems(c *gin.Context) {
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -247,11 +299,112 @@ func (ctrl *OrderManagementController) UpdateOrderItems(c *gin.Context) {
 		return
 	}
 
-	order, err := ctrl.orderService.UpdateOrderItems(uint(orderID), items)
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := ctrl.orderService.UpdateOrderItems(uint(orderID), items, expectedVersion, actorID(c))
 	if err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.orderService.GetOrderByIDAdmin(uint(orderID))
+			respondOrderConflict(c, err, current)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setVersionETag(c, order.Version)
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary Add order round
+// @Description Append a round of items to a dine-in order that is already in the kitchen (staff/cashier/admin). Items sent earlier stay as they are; the round gets its own kitchen tickets and the VAT is recomputed. Ready or served orders go back to preparing
+// @Tags orders-management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being changed"
+// @Param request body services.AddOrderRoundRequest true "Round items"
+// @Success 201 {object} repositories.Order
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /staff/orders/{id}/rounds [post]
+func (ctrl *OrderManagementController) AddOrderRound(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req services.AddOrderRoundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := ctrl.orderService.AddOrderRound(uint(orderID), &req, expectedVersion, actorID(c))
+	if err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.orderService.GetOrderByIDAdmin(uint(orderID))
+			respondOrderConflict(c, err, current)
+			return
+		}
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setVersionETag(c, order.Version)
+	c.JSON(http.StatusCreated, order)
+}
+
+// @Summary Mark takeaway order collected
+// @Description Record that a ready takeaway order was handed to the customer (staff/cashier/admin)
+// @Tags orders-management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} repositories.Order
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /staff/orders/{id}/collect [post]
+func (ctrl *OrderManagementController) MarkOrderCollected(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := ctrl.orderService.MarkOrderCollected(uint(orderID), actorID(c))
+	if err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.orderService.GetOrderByIDAdmin(uint(orderID))
+			respondOrderConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	c.JSON(http.StatusOK, order)
 }
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderTrackingController struct {
+	trackingService *services.OrderTrackingService
+}
+
+func NewOrderTrackingController(trackingService *services.OrderTrackingService) *OrderTrackingController {
+	return &OrderTrackingController{
+		trackingService: trackingService,
+	}
+}
+
+// @Summary Live order tracking stream
+// @Description Server-Sent Events stream of status changes for the authenticated user's orders,
+// @Description including the estimated ready time and a pickup_ready event for takeaway orders.
+// @Tags orders
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param token query string false "JWT token, for clients that cannot set the Authorization header"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param last_event_id query int false "ID of the last event received"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orders/events [get]
+func (ctrl *OrderTrackingController) HandleEventStream(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	events, backlog, cancel, err := ctrl.trackingService.Subscribe(userID.(uint), lastEventID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cancel()
+
+	streamEvents(c, events, backlog)
+}
+
+// @Summary Get pickup board
+// @Description Public "now preparing / ready for pickup" board for the lobby screen. Contains pickup numbers only, no customer data.
+// @Tags orders
+// @Produce json
+// @Success 200 {object} services.PickupBoard
+// @Failure 500 {object} map[string]string
+// @Router /pickup/board [get]
+func (ctrl *OrderTrackingController) GetPickupBoard(c *gin.Context) {
+	board, err := ctrl.trackingService.GetPickupBoard()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// @Summary Pickup board stream
+// @Description Server-Sent Events stream that pushes the whole pickup board whenever a takeaway order changes
+// @Tags orders
+// @Produce text/event-stream
+// @Success 200 {string} string "text/event-stream"
+// @Failure 500 {object} map[string]string
+// @Router /pickup/board/events [get]
+func (ctrl *OrderTrackingController) HandleBoardStream(c *gin.Context) {
+	events, backlog, cancel, err := ctrl.trackingService.SubscribeBoard()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cancel()
+
+	streamEvents(c, events, backlog)
+}
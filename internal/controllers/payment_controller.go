@@ -38,7 +38,7 @@ func (ctrl *PaymentController) InitiateQRISPayment(c *gin.Context) {
 		return
 	}
 
-	response, err := ctrl.paymentService.InitiateQRISPayment(&req)
+	response, err := ctrl.paymentService.InitiateQRISPayment(&req, actorID(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -57,6 +57,7 @@ func (ctrl *PaymentController) InitiateQRISPayment(c *gin.Context) {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /payments/verify [post]
 func (ctrl *PaymentController) VerifyPayment(c *gin.Context) {
 	var req services.PaymentVerificationRequest
@@ -66,6 +67,11 @@ func (ctrl *PaymentController) VerifyPayment(c *gin.Context) {
 	}
 
 	if err := ctrl.paymentService.VerifyPayment(&req); err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.paymentService.GetPaymentByTransactionID(req.TransactionID)
+			respondPaymentConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -101,6 +107,7 @@ func (ctrl *PaymentController) GetPaymentStatus(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, payment.Version)
 	c.JSON(http.StatusOK, payment)
 }
 
@@ -132,6 +139,7 @@ func (ctrl *PaymentController) GetPaymentByOrderID(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, payment.Version)
 	c.JSON(http.StatusOK, payment)
 }
 
@@ -143,6 +151,7 @@ func (ctrl *PaymentController) GetPaymentByOrderID(c *gin.Context) {
 // @Param request body services.PaymentVerificationRequest true "Payment webhook data"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /payments/webhook [post]
 func (ctrl *PaymentController) PaymentWebhook(c *gin.Context) {
 	var req services.PaymentVerificationRequest
@@ -155,6 +164,11 @@ func (ctrl *PaymentController) PaymentWebhook(c *gin.Context) {
 	// to ensure it's coming from the legitimate payment provider
 
 	if err := ctrl.paymentService.VerifyPayment(&req); err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.paymentService.GetPaymentByTransactionID(req.TransactionID)
+			respondPaymentConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -163,7 +177,7 @@ func (ctrl *PaymentController) PaymentWebhook(c *gin.Context) {
 }
 
 // @Summary Process cash payment
-// @Description Process cash payment for an order (cashier only)
+// @Description Process cash payment for an order, or for the unpaid balance of add-on rounds on a paid dine-in order (cashier only)
 // @Tags payments
 // @Accept json
 // @Produce json
@@ -173,6 +187,7 @@ func (ctrl *PaymentController) PaymentWebhook(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /payments/cash [post]
 func (ctrl *PaymentController) ProcessCashPayment(c *gin.Context) {
 	var req struct {
@@ -186,7 +201,13 @@ func (ctrl *PaymentController) ProcessCashPayment(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.paymentService.ProcessCashPayment(req.OrderID, req.AmountPaid, req.ChangeAmount); err != nil {
+	if err := ctrl.paymentService.ProcessCashPayment(req.OrderID, req.AmountPaid, req.ChangeAmount, actorID(c)); err != nil {
+		if services.IsVersionConflict(err) {
+			// Another cashier changed the order first, most likely by taking the same payment
+			current, _ := ctrl.paymentService.GetPaymentByOrderID(req.OrderID)
+			respondPaymentConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -206,6 +227,7 @@ func (ctrl *PaymentController) ProcessCashPayment(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /payments/{payment_id}/refund [post]
 func (ctrl *PaymentController) RefundPayment(c *gin.Context) {
 	paymentID, err := strconv.ParseUint(c.Param("payment_id"), 10, 32)
@@ -223,7 +245,12 @@ func (ctrl *PaymentController) RefundPayment(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.paymentService.RefundPayment(uint(paymentID), req.Reason); err != nil {
+	if err := ctrl.paymentService.RefundPayment(uint(paymentID), req.Reason, actorID(c)); err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.paymentService.GetPaymentStatus(uint(paymentID))
+			respondPaymentConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
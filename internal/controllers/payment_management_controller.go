@@ -87,6 +87,7 @@ func (ctrl *PaymentManagementController) GetPaymentByID(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, payment.Version)
 	c.JSON(http.StatusOK, payment)
 }
 
@@ -97,12 +98,14 @@ func (ctrl *PaymentManagementController) GetPaymentByID(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Payment ID"
+// @Param If-Match header string false "ETag of the payment version being changed"
 // @Param request body map[string]string true "Payment status"
 // @Success 200 {object} repositories.Payment
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /admin/payments/{id}/status [patch]
 func (ctrl *PaymentManagementController) UpdatePaymentStatus(c *gin.Context) {
 	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -113,6 +116,7 @@ func (ctrl *PaymentManagementController) UpdatePaymentStatus(c *gin.Context) {
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -135,12 +139,24 @@ func (ctrl *PaymentManagementController) UpdatePaymentStatus(c *gin.Context) {
 		return
 	}
 
-	payment, err := ctrl.paymentService.UpdatePaymentStatus(uint(paymentID), req.Status)
+	expectedVersion, err := ifMatchVersion(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := ctrl.paymentService.UpdatePaymentStatus(uint(paymentID), req.Status, expectedVersion, req.Reason, actorID(c))
+	if err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.paymentService.GetPaymentByIDAdmin(uint(paymentID))
+			respondPaymentConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	setVersionETag(c, payment.Version)
 	c.JSON(http.StatusOK, payment)
 }
 
@@ -157,6 +173,7 @@ func (ctrl *PaymentManagementController) UpdatePaymentStatus(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /admin/payments/{id}/refund [post]
 func (ctrl *PaymentManagementController) ProcessRefund(c *gin.Context) {
 	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -175,8 +192,13 @@ func (ctrl *PaymentManagementController) ProcessRefund(c *gin.Context) {
 		return
 	}
 
-	payment, err := ctrl.paymentService.ProcessRefund(uint(paymentID), req.Amount, req.Reason)
+	payment, err := ctrl.paymentService.ProcessRefund(uint(paymentID), req.Amount, req.Reason, actorID(c))
 	if err != nil {
+		if services.IsVersionConflict(err) {
+			current, _ := ctrl.paymentService.GetPaymentByIDAdmin(uint(paymentID))
+			respondPaymentConflict(c, err, current)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrinterController struct {
+	printService *services.PrintService
+}
+
+func NewPrinterController(printService *services.PrintService) *PrinterController {
+	return &PrinterController{
+		printService: printService,
+	}
+}
+
+// @Summary List printers
+// @Description List all registered printers (admin only)
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} repositories.Printer
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/printers [get]
+func (ctrl *PrinterController) GetPrinters(c *gin.Context) {
+	printers, err := ctrl.printService.GetPrinters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, printers)
+}
+
+// @Summary Register printer
+// @Description Register a raw TCP (port 9100) or spool directory printer and the stations or terminal it serves (admin only)
+// @Tags printers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.PrinterRequest true "Printer data"
+// @Success 201 {object} repositories.Printer
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/printers [post]
+func (ctrl *PrinterController) CreatePrinter(c *gin.Context) {
+	var req services.PrinterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	printer, err := ctrl.printService.CreatePrinter(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, printer)
+}
+
+// @Summary Update printer
+// @Description Update a registered printer (admin only)
+// @Tags printers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Printer ID"
+// @Param request body services.PrinterRequest true "Printer data"
+// @Success 200 {object} repositories.Printer
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/printers/{id} [put]
+func (ctrl *PrinterController) UpdatePrinter(c *gin.Context) {
+	printerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid printer ID"})
+		return
+	}
+
+	var req services.PrinterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	printer, err := ctrl.printService.UpdatePrinter(uint(printerID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, printer)
+}
+
+// @Summary Delete printer
+// @Description Remove a printer from the registry (admin only)
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Printer ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/printers/{id} [delete]
+func (ctrl *PrinterController) DeletePrinter(c *gin.Context) {
+	printerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid printer ID"})
+		return
+	}
+
+	if err := ctrl.printService.DeletePrinter(uint(printerID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Printer deleted successfully"})
+}
+
+// @Summary Print test page
+// @Description Queue a test page on a printer (admin only)
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Printer ID"
+// @Success 202 {object} repositories.PrintJob
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/printers/{id}/test [post]
+func (ctrl *PrinterController) PrintTestPage(c *gin.Context) {
+	printerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid printer ID"})
+		return
+	}
+
+	job, err := ctrl.printService.QueueTestPage(uint(printerID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary Get printer status
+// @Description Connection status and queue depth of every printer, for the manager dashboard
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/printers/status [get]
+func (ctrl *PrinterController) GetPrinterStatus(c *gin.Context) {
+	reports, err := ctrl.printService.GetPrinterStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	offline := 0
+	for _, report := range reports {
+		if report.IsActive && report.Status == repositories.PrinterStatusOffline {
+			offline++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"printers":         reports,
+		"offline_printers": offline,
+	})
+}
+
+// @Summary List print jobs
+// @Description List queued, printed and failed print jobs
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Filter by status (queued, printed, failed)"
+// @Param printer_id query int false "Filter by printer"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/print-jobs [get]
+func (ctrl *PrinterController) GetPrintJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+	printerID, _ := strconv.ParseUint(c.Query("printer_id"), 10, 32)
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	jobs, total, err := ctrl.printService.GetJobs(page, limit, status, uint(printerID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":        jobs,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// @Summary Retry print job
+// @Description Put a failed or stuck print job back in the queue
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Print job ID"
+// @Success 200 {object} repositories.PrintJob
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/print-jobs/{id}/retry [post]
+func (ctrl *PrinterController) RetryPrintJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid print job ID"})
+		return
+	}
+
+	job, err := ctrl.printService.RetryJob(uint(jobID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Print receipt
+// @Description Queue the receipt of an order on the printer of a cashier terminal, or on a specific printer
+// @Tags printers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body map[string]interface{} true "terminal or printer_id"
+// @Success 202 {object} repositories.PrintJob
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /cashier/orders/{id}/receipt/print [post]
+func (ctrl *PrinterController) PrintReceipt(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req struct {
+		Terminal  string `json:"terminal"`
+		PrinterID uint   `json:"printer_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := ctrl.printService.QueueReceipt(uint(orderID), req.Terminal, req.PrinterID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary Reprint kitchen tickets
+// @Description Queue the kitchen tickets of an order again on the printers of its stations
+// @Tags printers
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /staff/orders/{id}/tickets/print [post]
+func (ctrl *PrinterController) PrintKitchenTickets(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	jobs, err := ctrl.printService.QueueKitchenTickets(uint(orderID), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"jobs": jobs})
+}
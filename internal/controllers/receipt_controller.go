@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReceiptController struct {
+	receiptService *services.ReceiptService
+}
+
+func NewReceiptController(receiptService *services.ReceiptService) *ReceiptController {
+	return &ReceiptController{
+		receiptService: receiptService,
+	}
+}
+
+// @Summary Get order receipt
+// @Description Render the customer receipt for an order as ESC/POS bytes, plain text or PDF (cashier/admin only)
+// @Tags receipts
+// @Produce octet-stream,plain,application/pdf
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param format query string false "escpos, text or pdf" default(escpos)
+// @Param width query int false "Paper width in mm: 58 or 80" default(80)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /cashier/orders/{id}/receipt [get]
+func (ctrl *ReceiptController) GetReceipt(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	format, width, ok := printOptions(c)
+	if !ok {
+		return
+	}
+
+	doc, err := ctrl.receiptService.RenderReceipt(uint(orderID), format, width)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendDocument(c, doc, fmt.Sprintf("receipt-%d", orderID))
+}
+
+// @Summary List kitchen stations for an order
+// @Description List the stations (e.g. kitchen, bar) that receive a ticket for the order, overall and per add-on round
+// @Tags receipts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /staff/orders/{id}/tickets [get]
+func (ctrl *ReceiptController) GetOrderStations(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	stations, err := ctrl.receiptService.GetOrderStations(uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	rounds, err := ctrl.receiptService.GetOrderRoundStations(uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order_id": orderID,
+		"stations": stations,
+		"rounds":   rounds,
+	})
+}
+
+// @Summary Get kitchen ticket
+// @Description Render the kitchen chit for one station of an order as ESC/POS bytes, plain text or PDF
+// @Tags receipts
+// @Produce octet-stream,plain,application/pdf
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param station path string true "Station name, e.g. kitchen or bar"
+// @Param format query string false "escpos, text or pdf" default(escpos)
+// @Param width query int false "Paper width in mm: 58 or 80" default(80)
+// @Param round query int false "Add-on round; every item the station has received when omitted"
+// @Param course query string false "Course of the round's ticket (starter, main, dessert)"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /staff/orders/{id}/tickets/{station} [get]
+func (ctrl *ReceiptController) GetKitchenTicket(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	format, width, ok := printOptions(c)
+	if !ok {
+		return
+	}
+
+	round, err := strconv.Atoi(c.DefaultQuery("round", "0"))
+	if err != nil || round < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid round"})
+		return
+	}
+
+	ticket := printing.Ticket{
+		Station: c.Param("station"),
+		Round:   round,
+		Course:  repositories.CourseType(c.Query("course")),
+	}
+	doc, err := ctrl.receiptService.RenderKitchenTicket(uint(orderID), ticket, format, width)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendDocument(c, doc, fmt.Sprintf("ticket-%d-%s", orderID, ticket.Station))
+}
+
+func printOptions(c *gin.Context) (printing.Format, printing.PaperWidth, bool) {
+	format, err := printing.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", 0, false
+	}
+
+	width, err := printing.ParsePaperWidth(c.Query("width"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", 0, false
+	}
+
+	return format, width, true
+}
+
+func sendDocument(c *gin.Context, doc *services.RenderedDocument, name string) {
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", name+"."+doc.Format.Extension()))
+	c.Data(http.StatusOK, doc.ContentType, doc.Data)
+}
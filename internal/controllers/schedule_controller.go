@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleController struct {
+	scheduleService *services.ScheduleService
+}
+
+func NewScheduleController(scheduleService *services.ScheduleService) *ScheduleController {
+	return &ScheduleController{
+		scheduleService: scheduleService,
+	}
+}
+
+// @Summary Get pickup slots
+// @Description Public list of the pickup slots that can still be booked for a scheduled takeaway order on a given day, with how many orders each already holds
+// @Tags orders
+// @Produce json
+// @Param date query string false "Business date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /pickup/slots [get]
+func (ctrl *ScheduleController) GetPickupSlots(c *gin.Context) {
+	slots, err := ctrl.scheduleService.GetPickupSlots(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"slots": slots})
+}
@@ -0,0 +1,180 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+
+	_ "image/gif" // Registers the GIF decoder
+)
+
+var (
+	// ErrUnsupportedImage is returned for uploads that are not a JPEG, PNG or
+	// GIF image.
+	ErrUnsupportedImage = errors.New("unsupported image type. Upload a JPEG, PNG or GIF image")
+	// ErrImageTooLarge is returned for uploads over the size limit or with
+	// more pixels than can safely be decoded.
+	ErrImageTooLarge = errors.New("image is too large")
+)
+
+// Images wider or taller than maxImageDimension, or with more than
+// maxImagePixels pixels, are refused before decoding so a small file cannot
+// claim gigabytes of memory.
+const (
+	maxImageDimension = 8000
+	maxImagePixels    = 40_000_000
+	jpegQuality       = 85
+)
+
+// Variant is a size images are scaled down to, keeping their aspect ratio.
+// Images already smaller are not scaled up.
+type Variant struct {
+	Name    string
+	MaxSize int // Longest side in pixels
+}
+
+// Variants are the sizes generated for every uploaded image.
+var Variants = []Variant{
+	{Name: "thumbnail", MaxSize: 200},
+	{Name: "medium", MaxSize: 800},
+}
+
+// File is encoded image data and the extension to store it under.
+type File struct {
+	Data []byte
+	Ext  string // e.g. .jpg
+}
+
+// Image is an uploaded image, as uploaded, plus its scaled-down variants.
+type Image struct {
+	Hash     string // Short content hash, so every upload gets new keys
+	Width    int
+	Height   int
+	Original File
+	Variants map[string]File
+}
+
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ProcessImage checks an upload is an image of at most maxBytes bytes and
+// generates its variants. JPEG variants stay JPEG; PNG and GIF variants are
+// PNG to keep transparency. Only the first frame of an animated GIF is used.
+func ProcessImage(data []byte, maxBytes int) (*Image, error) {
+	if len(data) > maxBytes {
+		return nil, fmt.Errorf("%w: the limit is %d KB", ErrImageTooLarge, maxBytes/1024)
+	}
+	ext, ok := imageTypes[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width > maxImageDimension || config.Height > maxImageDimension || config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: images may be at most %d pixels wide and high", ErrImageTooLarge, maxImageDimension)
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return nil, ErrUnsupportedImage
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	sum := sha256.Sum256(data)
+	processed := &Image{
+		Hash:     hex.EncodeToString(sum[:6]),
+		Width:    config.Width,
+		Height:   config.Height,
+		Original: File{Data: data, Ext: ext},
+		Variants: make(map[string]File, len(Variants)),
+	}
+	for _, variant := range Variants {
+		file, err := encode(fit(img, variant.MaxSize), ext == ".jpg")
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants[variant.Name] = file
+	}
+	return processed, nil
+}
+
+func encode(img image.Image, asJPEG bool) (File, error) {
+	var buf bytes.Buffer
+	if asJPEG {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return File{}, err
+		}
+		return File{Data: buf.Bytes(), Ext: ".jpg"}, nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return File{}, err
+	}
+	return File{Data: buf.Bytes(), Ext: ".png"}, nil
+}
+
+// fit scales an image down so its longest side is at most maxSize pixels.
+func fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	scale := float64(maxSize) / float64(max(w, h))
+	dw := max(int(math.Round(float64(w)*scale)), 1)
+	dh := max(int(math.Round(float64(h)*scale)), 1)
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	return shrink(src, dw, dh)
+}
+
+// shrink scales an image down by averaging the block of source pixels each
+// destination pixel covers. Colours are premultiplied by alpha, so
+// transparent pixels do not darken the edges around them.
+func shrink(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := max((y+1)*sh/dh, y0+1)
+		for x := 0; x < dw; x++ {
+			x0 := x * sw / dw
+			x1 := max((x+1)*sw/dw, x0+1)
+
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				i := sy*src.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += uint64(src.Pix[i+c])
+					}
+					i += 4
+				}
+			}
+
+			n := uint64((y1 - y0) * (x1 - x0))
+			j := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[j+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,144 @@
+// Package media stores uploaded files such as menu item images and turns
+// images into the sizes the apps display. Files live behind the Storage
+// interface so the local disk can later be swapped for object storage.
+package media
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no file is stored under a key.
+	ErrNotFound = errors.New("media not found")
+	// ErrInvalidKey is returned for keys that could escape the storage, e.g.
+	// ones containing "..".
+	ErrInvalidKey = errors.New("invalid media key")
+)
+
+// Storage keeps files under slash-separated keys such as
+// menu-items/12/3f2a9c-thumbnail.jpg.
+type Storage interface {
+	// Save stores data under a key, replacing what was there.
+	Save(key string, data []byte) error
+	// Open returns the file stored under a key, or ErrNotFound.
+	Open(key string) (*Object, error)
+	// Delete removes the file stored under a key. Deleting a missing file is
+	// not an error.
+	Delete(key string) error
+	// URL returns where clients can download the file stored under a key.
+	URL(key string) string
+}
+
+// Object is an open stored file. The caller closes Body.
+type Object struct {
+	Body        io.ReadSeekCloser
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+}
+
+// ContentType guesses the MIME type of a key from its extension.
+func ContentType(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// LocalStorage keeps files in a directory on the local disk and serves them
+// under a base URL, e.g. /media.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *LocalStorage) Save(key string, data []byte) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStorage) Open(key string) (*Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+	return &Object{Body: file, Size: info.Size(), ModTime: info.ModTime(), ContentType: ContentType(key)}, nil
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// path maps a key to a file inside the storage directory.
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." || strings.HasPrefix(part, ".") {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
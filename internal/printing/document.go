@@ -0,0 +1,280 @@
+// Package printing renders orders into printable documents: customer receipts
+// and kitchen tickets ("chits"), as ESC/POS byte streams for thermal printers,
+// plain text or PDF.
+package printing
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+type Format string
+
+const (
+	FormatESCPOS Format = "escpos"
+	FormatText   Format = "text"
+	FormatPDF    Format = "pdf"
+)
+
+// PaperWidth is the roll width of a thermal printer in millimetres.
+type PaperWidth int
+
+const (
+	Paper58mm PaperWidth = 58
+	Paper80mm PaperWidth = 80
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Line is one logical line of a document. When Right is set the line is laid
+// out as two columns (e.g. item name and price); Rule draws a separator.
+type Line struct {
+	Text  string
+	Right string
+	Align Align
+	Bold  bool
+	Large bool // Double width and height where the output supports it
+	Rule  bool
+}
+
+// Document is a printer-independent ticket that the renderers lay out for a
+// given paper width.
+type Document struct {
+	Title string
+	Lines []Line
+}
+
+func (d *Document) Add(line Line) {
+	d.Lines = append(d.Lines, line)
+}
+
+func (d *Document) Text(text string) {
+	d.Add(Line{Text: text})
+}
+
+func (d *Document) Center(text string) {
+	d.Add(Line{Text: text, Align: AlignCenter})
+}
+
+func (d *Document) Columns(left, right string) {
+	d.Add(Line{Text: left, Right: right})
+}
+
+func (d *Document) Rule() {
+	d.Add(Line{Rule: true})
+}
+
+func (d *Document) Blank() {
+	d.Add(Line{})
+}
+
+// ParseFormat validates a format name, defaulting to ESC/POS.
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case "", FormatESCPOS:
+		return FormatESCPOS, nil
+	case FormatText:
+		return FormatText, nil
+	case FormatPDF:
+		return FormatPDF, nil
+	default:
+		return "", errors.New("invalid format. Must be 'escpos', 'text' or 'pdf'")
+	}
+}
+
+// ParsePaperWidth validates a paper width, defaulting to 80mm.
+func ParsePaperWidth(value string) (PaperWidth, error) {
+	switch strings.TrimSuffix(strings.ToLower(value), "mm") {
+	case "", "80":
+		return Paper80mm, nil
+	case "58":
+		return Paper58mm, nil
+	default:
+		return 0, errors.New("invalid paper width. Must be 58 or 80")
+	}
+}
+
+// Columns is the number of characters per line in the printer's default font.
+func (w PaperWidth) Columns() int {
+	if w == Paper58mm {
+		return 32
+	}
+	return 48
+}
+
+// ContentType returns the MIME type of documents rendered in this format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatPDF:
+		return "application/pdf"
+	case FormatText:
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Extension returns the file extension used when downloading the document.
+func (f Format) Extension() string {
+	switch f {
+	case FormatPDF:
+		return "pdf"
+	case FormatText:
+		return "txt"
+	default:
+		return "bin"
+	}
+}
+
+// Render lays the document out for the paper width in the requested format.
+func Render(doc *Document, format Format, width PaperWidth) ([]byte, error) {
+	switch format {
+	case FormatESCPOS:
+		return RenderESCPOS(doc, width), nil
+	case FormatText:
+		return RenderText(doc, width), nil
+	case FormatPDF:
+		return RenderPDF(doc, width), nil
+	default:
+		return nil, errors.New("unsupported format")
+	}
+}
+
+// printedLine is a Line after layout: exactly one row of output.
+type printedLine struct {
+	text  string
+	bold  bool
+	large bool
+}
+
+// layout wraps and pads the document into rows of cols characters. Large rows
+// only fit half as many characters when the output can print them enlarged.
+func layout(doc *Document, cols int, enlarge bool) []printedLine {
+	var rows []printedLine
+	for _, line := range doc.Lines {
+		width := cols
+		large := line.Large && enlarge
+		if large {
+			width = cols / 2
+		}
+
+		if line.Rule {
+			rows = append(rows, printedLine{text: strings.Repeat("-", cols)})
+			continue
+		}
+
+		for _, text := range layoutLine(line, width) {
+			rows = append(rows, printedLine{text: text, bold: line.Bold, large: large})
+		}
+	}
+	return rows
+}
+
+func layoutLine(line Line, width int) []string {
+	if line.Right != "" {
+		right := truncate(line.Right, width)
+		leftWidth := width - utf8.RuneCountInString(right) - 1
+		if leftWidth < 1 {
+			return []string{padLeft(right, width)}
+		}
+
+		// The amount goes on the first row so it lines up with the item name
+		rows := wrap(line.Text, leftWidth)
+		rows[0] = padRight(rows[0], leftWidth) + " " + right
+		return rows
+	}
+
+	wrapped := wrap(line.Text, width)
+	for i, text := range wrapped {
+		switch line.Align {
+		case AlignCenter:
+			wrapped[i] = center(text, width)
+		case AlignRight:
+			wrapped[i] = padLeft(text, width)
+		}
+	}
+	return wrapped
+}
+
+// wrap breaks text on spaces into rows of at most width characters, hard
+// splitting words that are longer than a row. Leading spaces are kept as an
+// indent on every row. Blank text yields one empty row.
+func wrap(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	if indent := len(text) - len(strings.TrimLeft(text, " ")); indent > 0 && indent < width {
+		rows := wrap(text[indent:], width-indent)
+		for i := range rows {
+			rows[i] = strings.Repeat(" ", indent) + rows[i]
+		}
+		return rows
+	}
+
+	var rows []string
+	current := ""
+	for _, word := range words {
+		for utf8.RuneCountInString(word) > width {
+			if current != "" {
+				rows = append(rows, current)
+				current = ""
+			}
+			runes := []rune(word)
+			rows = append(rows, string(runes[:width]))
+			word = string(runes[width:])
+		}
+
+		switch {
+		case current == "":
+			current = word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+			current += " " + word
+		default:
+			rows = append(rows, current)
+			current = word
+		}
+	}
+	if current != "" {
+		rows = append(rows, current)
+	}
+	return rows
+}
+
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return text
+}
+
+func padRight(text string, width int) string {
+	if n := width - utf8.RuneCountInString(text); n > 0 {
+		return text + strings.Repeat(" ", n)
+	}
+	return text
+}
+
+func padLeft(text string, width int) string {
+	if n := width - utf8.RuneCountInString(text); n > 0 {
+		return strings.Repeat(" ", n) + text
+	}
+	return text
+}
+
+func center(text string, width int) string {
+	n := width - utf8.RuneCountInString(text)
+	if n <= 0 {
+		return text
+	}
+	return strings.Repeat(" ", n/2) + text
+}
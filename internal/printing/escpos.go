@@ -0,0 +1,55 @@
+package printing
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ESC/POS command bytes understood by common 58mm/80mm thermal printers.
+var (
+	escInit       = []byte{0x1B, 0x40}             // ESC @
+	escBoldOn     = []byte{0x1B, 0x45, 0x01}       // ESC E 1
+	escBoldOff    = []byte{0x1B, 0x45, 0x00}       // ESC E 0
+	escLargeOn    = []byte{0x1D, 0x21, 0x11}       // GS ! double width and height
+	escLargeOff   = []byte{0x1D, 0x21, 0x00}       // GS ! normal size
+	escFeedAndCut = []byte{0x1D, 0x56, 0x42, 0x03} // GS V B: feed 3 lines, partial cut
+)
+
+// RenderESCPOS renders the document as a raw ESC/POS stream ending in a paper
+// cut. Text is sent in the printer's default code page, so characters outside
+// printable ASCII are replaced.
+func RenderESCPOS(doc *Document, width PaperWidth) []byte {
+	var buf bytes.Buffer
+	buf.Write(escInit)
+
+	for _, row := range layout(doc, width.Columns(), true) {
+		if row.bold {
+			buf.Write(escBoldOn)
+		}
+		if row.large {
+			buf.Write(escLargeOn)
+		}
+
+		buf.WriteString(toASCII(strings.TrimRight(row.text, " ")))
+		buf.WriteByte('\n')
+
+		if row.large {
+			buf.Write(escLargeOff)
+		}
+		if row.bold {
+			buf.Write(escBoldOff)
+		}
+	}
+
+	buf.Write(escFeedAndCut)
+	return buf.Bytes()
+}
+
+func toASCII(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7E {
+			return '?'
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,108 @@
+package printing
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfPointsPerMM = 72 / 25.4
+	pdfMargin      = 6.0
+	// Courier glyphs are 600 units wide, i.e. 0.6 of the font size.
+	pdfCharWidth = 0.6
+	pdfLeading   = 1.25
+)
+
+// RenderPDF renders the document as a single-page PDF sized like the paper
+// roll, using the built-in Courier fonts so no fonts need to be embedded.
+func RenderPDF(doc *Document, width PaperWidth) []byte {
+	cols := width.Columns()
+	rows := layout(doc, cols, true)
+
+	pageWidth := float64(width) * pdfPointsPerMM
+	fontSize := (pageWidth - 2*pdfMargin) / (float64(cols) * pdfCharWidth)
+
+	pageHeight := 2 * pdfMargin
+	for _, row := range rows {
+		pageHeight += rowLeading(row, fontSize)
+	}
+
+	var content bytes.Buffer
+	y := pageHeight - pdfMargin
+	for _, row := range rows {
+		leading := rowLeading(row, fontSize)
+		y -= leading
+
+		font, size := "F1", fontSize
+		if row.bold {
+			font = "F2"
+		}
+		if row.large {
+			size *= 2
+		}
+
+		text := strings.TrimRight(row.text, " ")
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+			font, size, pdfMargin, y+0.25*leading, pdfEscape(text))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		fmt.Sprintf("<< /Title (%s) /Producer (RecursiveDine) >>", pdfEscape(doc.Title)),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, len(objects), xref)
+
+	return out.Bytes()
+}
+
+func rowLeading(row printedLine, fontSize float64) float64 {
+	if row.large {
+		return 2 * fontSize * pdfLeading
+	}
+	return fontSize * pdfLeading
+}
+
+// pdfEscape converts text to a WinAnsi (Latin-1) PDF string literal body.
+func pdfEscape(text string) string {
+	var buf bytes.Buffer
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r >= 0x20 && r <= 0x7E:
+			buf.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&buf, "\\%03o", r)
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
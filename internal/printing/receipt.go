@@ -0,0 +1,191 @@
+package printing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+// StoreInfo is the restaurant header and footer printed on receipts.
+type StoreInfo struct {
+	Name     string
+	Address  string
+	Phone    string
+	TaxID    string // NPWP
+	Footer   string
+	Location *time.Location
+}
+
+// BuildReceipt lays out the customer receipt for an order. Orders without a
+// completed payment are printed as a bill instead.
+func BuildReceipt(order *repositories.Order, store StoreInfo) *Document {
+	paid := order.Payment != nil && order.Payment.Status == repositories.PaymentStatusCompleted
+
+	doc := &Document{Title: fmt.Sprintf("Receipt #%d", order.ID)}
+	if !paid {
+		doc.Title = fmt.Sprintf("Bill #%d", order.ID)
+	}
+
+	doc.Add(Line{Text: store.Name, Align: AlignCenter, Bold: true, Large: true})
+	if store.Address != "" {
+		doc.Center(store.Address)
+	}
+	if store.Phone != "" {
+		doc.Center("Tel. " + store.Phone)
+	}
+	if store.TaxID != "" {
+		doc.Center("NPWP " + store.TaxID)
+	}
+	doc.Rule()
+
+	doc.Columns(fmt.Sprintf("Order #%d", order.ID), orderTypeLabel(order))
+	if label := orderLocationLabel(order); label != "" {
+		doc.Add(Line{Text: label, Bold: true})
+	}
+	doc.Columns("Date", localTime(order.CreatedAt, store.Location).Format("02/01/2006 15:04"))
+	if order.CashierName != "" {
+		doc.Columns("Cashier", order.CashierName)
+	}
+	if order.CustomerName != "" {
+		doc.Columns("Customer", order.CustomerName)
+	}
+	if order.Delivery != nil {
+		doc.Text("Deliver to: " + order.Delivery.Address)
+		if order.Delivery.Notes != "" {
+			doc.Text("   * " + order.Delivery.Notes)
+		}
+	}
+	doc.Rule()
+
+	for _, item := range order.OrderItems {
+		doc.Columns(fmt.Sprintf("%dx %s", item.Quantity, item.MenuItem.Name), formatAmount(item.TotalPrice))
+		if item.Quantity > 1 {
+			doc.Text("   @ " + formatAmount(item.UnitPrice))
+		}
+		for _, component := range item.Components {
+			text := fmt.Sprintf("   - %s", component.MenuItem.Name)
+			if component.Quantity > 1 {
+				text = fmt.Sprintf("   - %dx %s", component.Quantity, component.MenuItem.Name)
+			}
+			if component.Upcharge > 0 {
+				text += " (+" + formatAmount(component.Upcharge) + ")"
+			}
+			doc.Text(text)
+		}
+		if item.SpecialRequest != "" {
+			doc.Text("   * " + item.SpecialRequest)
+		}
+	}
+	doc.Rule()
+
+	doc.Columns("Subtotal", formatAmount(order.SubtotalAmount))
+	if order.DeliveryFee > 0 {
+		doc.Columns("Delivery fee", formatAmount(order.DeliveryFee))
+	}
+	doc.Columns(vatLabel(order), formatAmount(order.VATAmount))
+	doc.Add(Line{Text: "TOTAL", Right: "Rp " + formatAmount(order.TotalAmount), Bold: true})
+	doc.Rule()
+
+	if paid {
+		payment := order.Payment
+		doc.Columns("Payment", paymentMethodLabel(payment.Method))
+		if payment.Method == repositories.PaymentMethodCash && payment.AmountTendered > 0 {
+			doc.Columns("Cash", formatAmount(payment.AmountTendered))
+			doc.Columns("Change", formatAmount(payment.ChangeAmount))
+		}
+		if payment.TransactionID != "" {
+			doc.Text("Ref " + payment.TransactionID)
+		}
+	} else {
+		doc.Add(Line{Text: "UNPAID", Align: AlignCenter, Bold: true})
+	}
+	doc.Rule()
+
+	if store.Footer != "" {
+		doc.Center(store.Footer)
+	}
+	doc.Blank()
+
+	return doc
+}
+
+func orderTypeLabel(order *repositories.Order) string {
+	switch order.OrderType {
+	case repositories.OrderTypeTakeaway:
+		return "Takeaway"
+	case repositories.OrderTypeDelivery:
+		return "Delivery"
+	default:
+		return "Dine-in"
+	}
+}
+
+// orderLocationLabel tells staff where the order goes: a table or a pickup
+// number, which drivers also collect delivery orders by.
+func orderLocationLabel(order *repositories.Order) string {
+	if order.OrderType == repositories.OrderTypeTakeaway || order.OrderType == repositories.OrderTypeDelivery {
+		if order.PickupNumber != "" {
+			return "Pickup " + order.PickupNumber
+		}
+		return ""
+	}
+	if order.Table != nil {
+		return fmt.Sprintf("Table %d", order.Table.Number)
+	}
+	return ""
+}
+
+func vatLabel(order *repositories.Order) string {
+	// The delivery fee is taxed along with the items
+	taxable := order.SubtotalAmount + order.DeliveryFee
+	if taxable <= 0 {
+		return "PPN"
+	}
+	rate := math.Round(order.VATAmount / taxable * 100)
+	return fmt.Sprintf("PPN %d%%", int(rate))
+}
+
+func paymentMethodLabel(method repositories.PaymentMethod) string {
+	switch method {
+	case repositories.PaymentMethodQRIS:
+		return "QRIS"
+	case repositories.PaymentMethodCash:
+		return "Cash"
+	case repositories.PaymentMethodPlatform:
+		return "Paid online"
+	default:
+		return string(method)
+	}
+}
+
+func localTime(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// formatAmount formats rupiah without decimals and with dot thousands
+// separators, e.g. 55000 -> "55.000".
+func formatAmount(amount float64) string {
+	value := int64(math.Round(amount))
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	digits := strconv.FormatInt(value, 10)
+	var groups []string
+	for len(digits) > 3 {
+		groups = append([]string{digits[len(digits)-3:]}, groups...)
+		digits = digits[:len(digits)-3]
+	}
+	groups = append([]string{digits}, groups...)
+
+	return sign + strings.Join(groups, ".")
+}
@@ -0,0 +1,18 @@
+package printing
+
+import (
+	"bytes"
+	"strings"
+)
+
+// RenderText renders the document as monospaced plain text, e.g. for email or
+// on-screen previews. Plain text has no sizes, so large lines are laid out at
+// full width like the rest.
+func RenderText(doc *Document, width PaperWidth) []byte {
+	var buf bytes.Buffer
+	for _, row := range layout(doc, width.Columns(), false) {
+		buf.WriteString(strings.TrimRight(row.text, " "))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,261 @@
+package printing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"recursiveDine/internal/repositories"
+)
+
+// DefaultStation receives items whose menu item and category name no station.
+const DefaultStation = "kitchen"
+
+// ItemStation returns the preparation station of an order item: the menu
+// item's own station if set, otherwise its category's. The menu item and its
+// category must be preloaded.
+func ItemStation(item *repositories.OrderItem) string {
+	if station := strings.ToLower(strings.TrimSpace(item.MenuItem.Station)); station != "" {
+		return station
+	}
+	if station := strings.ToLower(strings.TrimSpace(item.MenuItem.Category.Station)); station != "" {
+		return station
+	}
+	return DefaultStation
+}
+
+// KitchenItem is one line the kitchen prepares. Bundle names the bundle a
+// component line came from.
+type KitchenItem struct {
+	repositories.OrderItem
+	Bundle string
+}
+
+// KitchenItems explodes an order item into what the kitchen prepares: the
+// item itself, or one line per component of a bundle so each goes to its own
+// station. The components' menu items must be preloaded.
+func KitchenItems(item *repositories.OrderItem) []KitchenItem {
+	if len(item.Components) == 0 {
+		return []KitchenItem{{OrderItem: *item}}
+	}
+	lines := make([]KitchenItem, 0, len(item.Components))
+	for _, component := range item.Components {
+		line := *item
+		line.MenuItemID = component.MenuItemID
+		line.MenuItem = component.MenuItem
+		line.Quantity = item.Quantity * component.Quantity
+		line.Components = nil
+		lines = append(lines, KitchenItem{OrderItem: line, Bundle: item.MenuItem.Name})
+	}
+	return lines
+}
+
+// ItemRound returns the add-on round of an order item. Items stored before
+// rounds existed belong to the first round.
+func ItemRound(item *repositories.OrderItem) int {
+	if item.Round < 1 {
+		return 1
+	}
+	return item.Round
+}
+
+// ItemSent reports whether an item has gone to the kitchen. Items without a
+// course go with the order; course items once their course has been fired.
+// The order's courses must be preloaded.
+func ItemSent(order *repositories.Order, item *repositories.OrderItem) bool {
+	if item.Course == "" {
+		return true
+	}
+	for _, course := range order.Courses {
+		if course.Course == item.Course {
+			return course.Status == repositories.CourseFired || course.Status == repositories.CourseServed
+		}
+	}
+	return false
+}
+
+// Ticket identifies one kitchen ticket of an order: the items of one station
+// from one round and course. A zero Round stands for every item of the
+// station that was sent to the kitchen.
+type Ticket struct {
+	Station string
+	Round   int
+	Course  repositories.CourseType
+}
+
+// Tickets lists the tickets for the items of the order that were sent to the
+// kitchen, by round, course and station.
+func Tickets(order *repositories.Order) []Ticket {
+	seen := make(map[Ticket]bool)
+	var tickets []Ticket
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		if !ItemSent(order, item) {
+			continue
+		}
+		for _, line := range KitchenItems(item) {
+			ticket := Ticket{Station: ItemStation(&line.OrderItem), Round: ItemRound(item), Course: item.Course}
+			if !seen[ticket] {
+				seen[ticket] = true
+				tickets = append(tickets, ticket)
+			}
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		a, b := tickets[i], tickets[j]
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		if a.Course != b.Course {
+			return repositories.CourseSequence[a.Course] < repositories.CourseSequence[b.Course]
+		}
+		return a.Station < b.Station
+	})
+	return tickets
+}
+
+// Stations lists the stations that have received items of the order.
+func Stations(order *repositories.Order) []string {
+	seen := make(map[string]bool)
+	var stations []string
+	for _, ticket := range Tickets(order) {
+		if !seen[ticket.Station] {
+			seen[ticket.Station] = true
+			stations = append(stations, ticket.Station)
+		}
+	}
+	sort.Strings(stations)
+	return stations
+}
+
+// BuildKitchenTicket lays out the chit for one ticket of the order, listing
+// only the items of its station, round and course. Prices are left off;
+// quantities and requests are enlarged so they can be read from across the
+// pass.
+func BuildKitchenTicket(order *repositories.Order, ticket Ticket, store StoreInfo) (*Document, error) {
+	ticket.Station = strings.ToLower(strings.TrimSpace(ticket.Station))
+
+	items := ticketItems(order, ticket)
+	if len(items) == 0 {
+		return nil, errors.New("order has no items for this station")
+	}
+
+	doc := &Document{Title: fmt.Sprintf("Order #%d - %s%s", order.ID, ticket.Station, ticketSuffix(ticket))}
+
+	doc.Add(Line{Text: strings.ToUpper(ticket.Station), Align: AlignCenter, Bold: true, Large: true})
+	doc.Add(Line{Text: fmt.Sprintf("#%d %s", order.ID, strings.ToUpper(orderTypeLabel(order))), Align: AlignCenter, Bold: true, Large: true})
+	if label := orderLocationLabel(order); label != "" {
+		doc.Add(Line{Text: label, Align: AlignCenter, Bold: true, Large: true})
+	}
+	addTicketHeader(doc, ticket)
+	doc.Columns(localTime(order.CreatedAt, store.Location).Format("02/01 15:04"), order.CustomerName)
+	doc.Rule()
+
+	for _, item := range items {
+		doc.Add(Line{Text: fmt.Sprintf("%d x %s", item.Quantity, item.MenuItem.Name), Bold: true, Large: true})
+		if item.Bundle != "" {
+			doc.Add(Line{Text: "  [" + item.Bundle + "]"})
+		}
+		if len(item.MenuItem.Allergens) > 0 {
+			doc.Add(Line{Text: "  ! CONTAINS " + strings.ToUpper(tagList(item.MenuItem.Allergens)), Bold: true})
+		}
+		if len(item.MenuItem.DietaryTags) > 0 {
+			doc.Add(Line{Text: "  (" + tagList(item.MenuItem.DietaryTags) + ")"})
+		}
+		if item.SpecialRequest != "" {
+			doc.Add(Line{Text: "  >> " + item.SpecialRequest, Bold: true})
+		}
+	}
+
+	if order.SpecialNotes != "" {
+		doc.Rule()
+		doc.Add(Line{Text: "NOTE: " + order.SpecialNotes, Bold: true})
+	}
+	doc.Rule()
+	doc.Blank()
+
+	return doc, nil
+}
+
+// tagList joins allergen or dietary tags for printing, e.g. "tree nut, egg".
+func tagList(tags []string) string {
+	return strings.ReplaceAll(strings.Join(tags, ", "), "_", " ")
+}
+
+// BuildVoidTicket lays out the slip that tells a station to stop preparing a
+// cancelled order. It lists the items of the voided ticket like the original
+// so the cook can match the two.
+func BuildVoidTicket(order *repositories.Order, ticket Ticket, store StoreInfo) (*Document, error) {
+	ticket.Station = strings.ToLower(strings.TrimSpace(ticket.Station))
+
+	items := ticketItems(order, ticket)
+	if len(items) == 0 {
+		return nil, errors.New("order has no items for this station")
+	}
+
+	doc := &Document{Title: fmt.Sprintf("VOID Order #%d - %s%s", order.ID, ticket.Station, ticketSuffix(ticket))}
+
+	doc.Add(Line{Text: "*** VOID ***", Align: AlignCenter, Bold: true, Large: true})
+	doc.Add(Line{Text: strings.ToUpper(ticket.Station), Align: AlignCenter, Bold: true, Large: true})
+	doc.Add(Line{Text: fmt.Sprintf("#%d %s", order.ID, strings.ToUpper(orderTypeLabel(order))), Align: AlignCenter, Bold: true, Large: true})
+	if label := orderLocationLabel(order); label != "" {
+		doc.Add(Line{Text: label, Align: AlignCenter, Bold: true, Large: true})
+	}
+	addTicketHeader(doc, ticket)
+	doc.Columns(localTime(order.CreatedAt, store.Location).Format("02/01 15:04"), order.CustomerName)
+	doc.Rule()
+
+	for _, item := range items {
+		doc.Add(Line{Text: fmt.Sprintf("%d x %s", item.Quantity, item.MenuItem.Name), Bold: true})
+	}
+
+	doc.Rule()
+	doc.Add(Line{Text: "ORDER CANCELLED - DO NOT PREPARE", Align: AlignCenter, Bold: true})
+	doc.Blank()
+
+	return doc, nil
+}
+
+// ticketItems returns the items sent to the kitchen that belong on a ticket,
+// with bundles exploded into their components.
+func ticketItems(order *repositories.Order, ticket Ticket) []KitchenItem {
+	var items []KitchenItem
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		if !ItemSent(order, item) {
+			continue
+		}
+		if ticket.Round != 0 && (ItemRound(item) != ticket.Round || item.Course != ticket.Course) {
+			continue
+		}
+		for _, line := range KitchenItems(item) {
+			if ItemStation(&line.OrderItem) == ticket.Station {
+				items = append(items, line)
+			}
+		}
+	}
+	return items
+}
+
+// addTicketHeader marks tickets of add-on rounds and courses so the pass can
+// tell them from the original order.
+func addTicketHeader(doc *Document, ticket Ticket) {
+	if ticket.Round > 1 {
+		doc.Add(Line{Text: fmt.Sprintf("ADD-ON ROUND %d", ticket.Round), Align: AlignCenter, Bold: true})
+	}
+	if ticket.Course != "" {
+		doc.Add(Line{Text: fmt.Sprintf("-- %s --", strings.ToUpper(string(ticket.Course))), Align: AlignCenter, Bold: true, Large: true})
+	}
+}
+
+func ticketSuffix(ticket Ticket) string {
+	var suffix string
+	if ticket.Course != "" {
+		suffix += " " + string(ticket.Course)
+	}
+	if ticket.Round > 1 {
+		suffix += fmt.Sprintf(" (round %d)", ticket.Round)
+	}
+	return suffix
+}
@@ -0,0 +1,85 @@
+package printing
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultRawPort is the raw (JetDirect) port network thermal printers listen on.
+const DefaultRawPort = "9100"
+
+// SendTCP writes the document to a printer's raw TCP port. The address may
+// omit the port, in which case 9100 is used.
+func SendTCP(address string, data []byte, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", withDefaultPort(address), timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(data)
+	return err
+}
+
+// ProbeTCP checks that the printer accepts connections without printing.
+func ProbeTCP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", withDefaultPort(address), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// SendSpool drops the document into a spool directory for a print daemon
+// (CUPS, lpd or a vendor agent) to pick up. Files are written under a
+// temporary name and renamed, so the daemon never sees a partial job.
+func SendSpool(dir, name string, data []byte) error {
+	if err := ProbeSpool(dir); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// ProbeSpool checks that the spool directory exists.
+func ProbeSpool(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("spool path %s is not a directory", dir)
+	}
+	return nil
+}
+
+// ValidateTCPAddress checks a printer address of the form host or host:port.
+func ValidateTCPAddress(address string) error {
+	host, _, err := net.SplitHostPort(withDefaultPort(address))
+	if err != nil || host == "" {
+		return errors.New("invalid printer address. Use host or host:port")
+	}
+	return nil
+}
+
+func withDefaultPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, DefaultRawPort)
+	}
+	return address
+}
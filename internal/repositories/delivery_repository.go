@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrDeliveryChanged is returned when a delivery is no longer in the status a
+// transition expected, e.g. because another driver claimed it first.
+var ErrDeliveryChanged = errors.New("delivery was changed by another request")
+
+type DeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewDeliveryRepository(db *gorm.DB) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+// Zone operations
+func (r *DeliveryRepository) CreateZone(zone *DeliveryZone) error {
+	return r.db.Create(zone).Error
+}
+
+func (r *DeliveryRepository) GetZoneByID(id uint) (*DeliveryZone, error) {
+	var zone DeliveryZone
+	err := r.db.First(&zone, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("delivery zone not found")
+	}
+	return &zone, err
+}
+
+func (r *DeliveryRepository) GetZones() ([]DeliveryZone, error) {
+	var zones []DeliveryZone
+	err := r.db.Order("priority ASC, id ASC").Find(&zones).Error
+	return zones, err
+}
+
+// GetActiveZones returns the zones orders can be delivered to, in the order
+// they are matched.
+func (r *DeliveryRepository) GetActiveZones() ([]DeliveryZone, error) {
+	var zones []DeliveryZone
+	err := r.db.Where("is_active = ?", true).Order("priority ASC, id ASC").Find(&zones).Error
+	return zones, err
+}
+
+func (r *DeliveryRepository) UpdateZone(zone *DeliveryZone) error {
+	return r.db.Save(zone).Error
+}
+
+func (r *DeliveryRepository) DeleteZone(id uint) error {
+	return r.db.Delete(&DeliveryZone{}, id).Error
+}
+
+// Address operations
+func (r *DeliveryRepository) CreateAddress(address *CustomerAddress) error {
+	return r.db.Create(address).Error
+}
+
+// GetAddress returns a saved address of the given user.
+func (r *DeliveryRepository) GetAddress(id, userID uint) (*CustomerAddress, error) {
+	var address CustomerAddress
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("address not found")
+	}
+	return &address, err
+}
+
+func (r *DeliveryRepository) GetAddressesByUserID(userID uint) ([]CustomerAddress, error) {
+	var addresses []CustomerAddress
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *DeliveryRepository) UpdateAddress(address *CustomerAddress) error {
+	return r.db.Save(address).Error
+}
+
+func (r *DeliveryRepository) DeleteAddress(id, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&CustomerAddress{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("address not found")
+	}
+	return nil
+}
+
+// Delivery operations
+func (r *DeliveryRepository) Create(delivery *OrderDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *DeliveryRepository) GetByOrderID(orderID uint) (*OrderDelivery, error) {
+	var delivery OrderDelivery
+	err := r.db.Preload("Zone").Preload("Driver").Where("order_id = ?", orderID).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("delivery not found")
+	}
+	return &delivery, err
+}
+
+// GetByDriverID returns the deliveries of a driver in the given statuses,
+// with their orders.
+func (r *DeliveryRepository) GetByDriverID(driverID uint, statuses []DeliveryStatus) ([]OrderDelivery, error) {
+	var deliveries []OrderDelivery
+	err := r.db.Where("driver_id = ? AND status IN ?", driverID, statuses).
+		Preload("Order.OrderItems.MenuItem").
+		Preload("Zone").
+		Order("assigned_at ASC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetByStatus returns the deliveries in the given statuses with their orders,
+// oldest first.
+func (r *DeliveryRepository) GetByStatus(statuses []DeliveryStatus) ([]OrderDelivery, error) {
+	var deliveries []OrderDelivery
+	err := r.db.Where("status IN ?", statuses).
+		Preload("Order").
+		Preload("Zone").
+		Preload("Driver").
+		Order("created_at ASC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Transition moves a delivery to another status, provided it is still in one
+// of the from statuses. It fails with ErrDeliveryChanged otherwise.
+func (r *DeliveryRepository) Transition(id uint, from []DeliveryStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&OrderDelivery{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeliveryChanged
+	}
+	return nil
+}
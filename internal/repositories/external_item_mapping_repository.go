@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExternalItemMappingRepository struct {
+	db *gorm.DB
+}
+
+func NewExternalItemMappingRepository(db *gorm.DB) *ExternalItemMappingRepository {
+	return &ExternalItemMappingRepository{db: db}
+}
+
+// GetByPlatform lists the SKU mappings of a platform, or of every platform
+// when platform is empty.
+func (r *ExternalItemMappingRepository) GetByPlatform(platform string) ([]ExternalItemMapping, error) {
+	var mappings []ExternalItemMapping
+	query := r.db.Preload("MenuItem")
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	err := query.Order("platform ASC, sku ASC").Find(&mappings).Error
+	return mappings, err
+}
+
+// GetBySKUs returns the mappings of the given SKUs on a platform. SKUs without
+// a mapping are left out.
+func (r *ExternalItemMappingRepository) GetBySKUs(platform string, skus []string) ([]ExternalItemMapping, error) {
+	var mappings []ExternalItemMapping
+	err := r.db.Where("platform = ? AND sku IN ?", platform, skus).Find(&mappings).Error
+	return mappings, err
+}
+
+// Upsert maps a SKU to a menu item, replacing an earlier mapping of the SKU.
+func (r *ExternalItemMappingRepository) Upsert(mapping *ExternalItemMapping) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "platform"}, {Name: "sku"}},
+		DoUpdates: clause.AssignmentColumns([]string{"menu_item_id", "updated_at"}),
+	}).Create(mapping).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Preload("MenuItem").Where("platform = ? AND sku = ?", mapping.Platform, mapping.SKU).First(mapping).Error
+}
+
+func (r *ExternalItemMappingRepository) Delete(id uint) error {
+	result := r.db.Delete(&ExternalItemMapping{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("mapping not found")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve inserts the key unless the user already used it. It reports
+// whether the key was newly reserved and otherwise returns the stored record.
+func (r *IdempotencyRepository) Reserve(record *IdempotencyKey) (bool, *IdempotencyKey, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil, nil
+	}
+
+	var existing IdempotencyKey
+	err := r.db.Where("user_id = ? AND key = ?", record.UserID, record.Key).First(&existing).Error
+	if err != nil {
+		return false, nil, err
+	}
+	return false, &existing, nil
+}
+
+func (r *IdempotencyRepository) Complete(id uint, responseCode int, contentType string, body []byte) error {
+	return r.db.Model(&IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        IdempotencyCompleted,
+		"response_code": responseCode,
+		"content_type":  contentType,
+		"response_body": body,
+	}).Error
+}
+
+func (r *IdempotencyRepository) Delete(id uint) error {
+	return r.db.Delete(&IdempotencyKey{}, id).Error
+}
+
+// DeleteExpired removes keys whose replay window has passed.
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -2,9 +2,9 @@ package repositories
 
 import (
 	"errors"
-	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MenuRepository struct {
@@ -22,7 +22,7 @@ func (r *MenuRepository) CreateCategory(category *MenuCategory) error {
 
 func (r *MenuRepository) GetCategoryByID(id uint) (*MenuCategory, error) {
 	var category MenuCategory
-	err := r.db.Preload("MenuItems").First(&category, id).Error
+	err := r.db.Preload("MenuItems").Preload("Schedules").Preload("Translations").First(&category, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, errors.New("category not found")
 	}
@@ -34,6 +34,11 @@ func (r *MenuRepository) GetAllCategories() ([]MenuCategory, error) {
 	err := r.db.Where("is_active = ?", true).
 		Order("sort_order ASC").
 		Preload("MenuItems", "is_available = ?", true).
+		Preload("MenuItems.Schedules").
+		Preload("MenuItems.Translations").
+		Scopes(preloadBundleSlots("MenuItems.")).
+		Preload("Schedules").
+		Preload("Translations").
 		Find(&categories).Error
 	return categories, err
 }
@@ -53,18 +58,41 @@ func (r *MenuRepository) CreateMenuItem(item *MenuItem) error {
 
 func (r *MenuRepository) GetMenuItemByID(id uint) (*MenuItem, error) {
 	var item MenuItem
-	err := r.db.Preload("Category").First(&item, id).Error
+	err := r.db.Preload("Category.Schedules").Preload("Schedules").Preload("Translations").
+		Scopes(preloadBundleSlots("")).
+		First(&item, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, errors.New("menu item not found")
 	}
 	return &item, err
 }
 
+// GetMenuItemBySKU returns the item with a SKU, or nil when there is none.
+func (r *MenuRepository) GetMenuItemBySKU(sku string) (*MenuItem, error) {
+	var item MenuItem
+	err := r.db.Where("sku = ?", sku).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &item, err
+}
+
+// GetMenuForExport returns every category with every item, including
+// inactive categories and unavailable items, in menu order.
+func (r *MenuRepository) GetMenuForExport() ([]MenuCategory, error) {
+	var categories []MenuCategory
+	err := r.db.Order("sort_order ASC, id ASC").
+		Preload("MenuItems", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC, id ASC") }).
+		Find(&categories).Error
+	return categories, err
+}
+
 func (r *MenuRepository) GetAllMenuItems() ([]MenuItem, error) {
 	var items []MenuItem
 	err := r.db.Where("is_available = ?", true).
 		Order("sort_order ASC").
-		Preload("Category").
+		Preload("Category.Schedules").
+		Preload("Schedules").
 		Find(&items).Error
 	return items, err
 }
@@ -73,18 +101,11 @@ func (r *MenuRepository) GetMenuItemsByCategory(categoryID uint) ([]MenuItem, er
 	var items []MenuItem
 	err := r.db.Where("category_id = ? AND is_available = ?", categoryID, true).
 		Order("sort_order ASC").
-		Preload("Category").
-		Find(&items).Error
-	return items, err
-}
-
-func (r *MenuRepository) SearchMenuItems(query string) ([]MenuItem, error) {
-	var items []MenuItem
-	searchQuery := "%" + strings.ToLower(query) + "%"
-	err := r.db.Where("is_available = ? AND (LOWER(name) LIKE ? OR LOWER(description) LIKE ?)", 
-		true, searchQuery, searchQuery).
-		Order("sort_order ASC").
-		Preload("Category").
+		Preload("Category.Schedules").
+		Preload("Category.Translations").
+		Preload("Schedules").
+		Preload("Translations").
+		Scopes(preloadBundleSlots("")).
 		Find(&items).Error
 	return items, err
 }
@@ -100,7 +121,9 @@ func (r *MenuRepository) DeleteMenuItem(id uint) error {
 func (r *MenuRepository) GetMenuItemsByIDs(ids []uint) ([]MenuItem, error) {
 	var items []MenuItem
 	err := r.db.Where("id IN ? AND is_available = ?", ids, true).
-		Preload("Category").
+		Preload("Category.Schedules").
+		Preload("Schedules").
+		Scopes(preloadBundleSlots("")).
 		Find(&items).Error
 	return items, err
 }
@@ -109,6 +132,20 @@ func (r *MenuRepository) UpdateMenuItemAvailability(id uint, available bool) err
 	return r.db.Model(&MenuItem{}).Where("id = ?", id).Update("is_available", available).Error
 }
 
+// UpdateMenuItemPrice sets the price of a menu item without touching its
+// other fields.
+func (r *MenuRepository) UpdateMenuItemPrice(id uint, price float64) error {
+	return r.db.Model(&MenuItem{}).Where("id = ?", id).Update("price", price).Error
+}
+
+// UpdateMenuItemImage sets the image of a menu item without touching its
+// other fields. Empty values remove the image.
+func (r *MenuRepository) UpdateMenuItemImage(id uint, imageURL string, variants, keys map[string]string) error {
+	return r.db.Model(&MenuItem{ID: id}).
+		Select("image_url", "image_variants", "image_keys").
+		Updates(&MenuItem{ImageURL: imageURL, ImageVariants: variants, ImageKeys: keys}).Error
+}
+
 func (r *MenuRepository) GetCompleteMenu() ([]MenuCategory, error) {
 	var categories []MenuCategory
 	err := r.db.Where("is_active = ?", true).
@@ -116,6 +153,139 @@ func (r *MenuRepository) GetCompleteMenu() ([]MenuCategory, error) {
 		Preload("MenuItems", func(db *gorm.DB) *gorm.DB {
 			return db.Where("is_available = ?", true).Order("sort_order ASC")
 		}).
+		Preload("MenuItems.Schedules").
+		Preload("MenuItems.Translations").
+		Scopes(preloadBundleSlots("MenuItems.")).
+		Preload("Schedules").
+		Preload("Translations").
 		Find(&categories).Error
 	return categories, err
 }
+
+// preloadBundleSlots loads the slots of bundle items, in menu order, with
+// their options. Prefix is the path to the items, e.g. "MenuItems.".
+func preloadBundleSlots(prefix string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Preload(prefix+"BundleSlots", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC, id ASC") }).
+			Preload(prefix+"BundleSlots.Options", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC, id ASC") }).
+			Preload(prefix + "BundleSlots.Options.MenuItem")
+	}
+}
+
+// ReplaceBundleSlots swaps the slots of a bundle item, with their options,
+// for the given ones. No slots turns the bundle back into a plain item.
+func (r *MenuRepository) ReplaceBundleSlots(bundleItemID uint, slots []MenuBundleSlot) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		existing := tx.Model(&MenuBundleSlot{}).Select("id").Where("bundle_item_id = ?", bundleItemID)
+		if err := tx.Where("slot_id IN (?)", existing).Delete(&MenuBundleOption{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bundle_item_id = ?", bundleItemID).Delete(&MenuBundleSlot{}).Error; err != nil {
+			return err
+		}
+		if len(slots) == 0 {
+			return nil
+		}
+		for i := range slots {
+			slots[i].ID = 0
+			slots[i].BundleItemID = bundleItemID
+			for j := range slots[i].Options {
+				slots[i].Options[j].ID = 0
+			}
+		}
+		return tx.Omit("Options.MenuItem").Create(&slots).Error
+	})
+}
+
+// GetBundlesContaining returns the bundle items that offer the menu item in
+// one of their slots.
+func (r *MenuRepository) GetBundlesContaining(menuItemID uint) ([]MenuItem, error) {
+	var items []MenuItem
+	err := r.db.Where("id IN (?)", r.db.Model(&MenuBundleSlot{}).Select("bundle_item_id").
+		Where("id IN (?)", r.db.Model(&MenuBundleOption{}).Select("slot_id").Where("menu_item_id = ?", menuItemID))).
+		Find(&items).Error
+	return items, err
+}
+
+// ReplaceCategorySchedules swaps the schedules of a category for the given ones.
+func (r *MenuRepository) ReplaceCategorySchedules(categoryID uint, schedules []MenuSchedule) error {
+	for i := range schedules {
+		schedules[i].CategoryID = &categoryID
+		schedules[i].MenuItemID = nil
+	}
+	return r.replaceSchedules("category_id", categoryID, schedules)
+}
+
+// ReplaceMenuItemSchedules swaps the schedules of a menu item for the given ones.
+func (r *MenuRepository) ReplaceMenuItemSchedules(menuItemID uint, schedules []MenuSchedule) error {
+	for i := range schedules {
+		schedules[i].CategoryID = nil
+		schedules[i].MenuItemID = &menuItemID
+	}
+	return r.replaceSchedules("menu_item_id", menuItemID, schedules)
+}
+
+func (r *MenuRepository) replaceSchedules(column string, id uint, schedules []MenuSchedule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(column+" = ?", id).Delete(&MenuSchedule{}).Error; err != nil {
+			return err
+		}
+		if len(schedules) == 0 {
+			return nil
+		}
+		for i := range schedules {
+			schedules[i].ID = 0
+		}
+		return tx.Create(&schedules).Error
+	})
+}
+
+// UpsertCategoryTranslation adds or replaces the translation of a category
+// into translation.Locale.
+func (r *MenuRepository) UpsertCategoryTranslation(categoryID uint, translation *MenuTranslation) error {
+	translation.CategoryID = &categoryID
+	translation.MenuItemID = nil
+	return r.upsertTranslation("category_id", translation)
+}
+
+// UpsertMenuItemTranslation adds or replaces the translation of a menu item
+// into translation.Locale.
+func (r *MenuRepository) UpsertMenuItemTranslation(menuItemID uint, translation *MenuTranslation) error {
+	translation.CategoryID = nil
+	translation.MenuItemID = &menuItemID
+	return r.upsertTranslation("menu_item_id", translation)
+}
+
+func (r *MenuRepository) upsertTranslation(column string, translation *MenuTranslation) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: column}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+	}).Create(translation).Error
+	if err != nil {
+		return err
+	}
+	owner := translation.CategoryID
+	if owner == nil {
+		owner = translation.MenuItemID
+	}
+	return r.db.Where(column+" = ? AND locale = ?", owner, translation.Locale).First(translation).Error
+}
+
+func (r *MenuRepository) DeleteCategoryTranslation(categoryID uint, locale string) error {
+	return r.deleteTranslation("category_id", categoryID, locale)
+}
+
+func (r *MenuRepository) DeleteMenuItemTranslation(menuItemID uint, locale string) error {
+	return r.deleteTranslation("menu_item_id", menuItemID, locale)
+}
+
+func (r *MenuRepository) deleteTranslation(column string, id uint, locale string) error {
+	result := r.db.Where(column+" = ? AND locale = ?", id, locale).Delete(&MenuTranslation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("translation not found")
+	}
+	return nil
+}
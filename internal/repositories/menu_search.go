@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"strings"
+
+	"recursiveDine/internal/search"
+
+	"gorm.io/gorm"
+)
+
+// menuSearchLimit caps the number of items a search returns.
+const menuSearchLimit = 50
+
+// MenuSearchHit is a menu item found by a search and how well it matched.
+type MenuSearchHit struct {
+	Item  MenuItem
+	Score float64
+}
+
+// SearchMenuItems finds available items in active categories by their name,
+// category name or description in any language the menu is written or
+// translated in, best match first. Names weigh more than category names,
+// which weigh more than descriptions, and misspelt words still match.
+func (r *MenuRepository) SearchMenuItems(query string) ([]MenuSearchHit, error) {
+	if len(search.Terms(query)) == 0 {
+		return []MenuSearchHit{}, nil
+	}
+	if r.db.Dialector.Name() == "postgres" {
+		return r.searchMenuItemsPostgres(query)
+	}
+	return r.searchMenuItemsInProcess(query)
+}
+
+// menuSearchSQL ranks items with full-text search, which matches whole words
+// and prefixes, plus trigram word similarity on names, which catches typos.
+// It needs the pg_trgm extension (migration 022). The documents are built per
+// query, so no index applies; menus are small enough for a scan.
+const menuSearchSQL = `
+WITH documents AS (
+	SELECT i.id,
+		i.name || ' ' || COALESCE(it.names, '') AS names,
+		setweight(to_tsvector('simple', i.name || ' ' || COALESCE(it.names, '')), 'A') ||
+		setweight(to_tsvector('simple', c.name || ' ' || COALESCE(ct.names, '')), 'B') ||
+		setweight(to_tsvector('simple', COALESCE(i.description, '') || ' ' || COALESCE(it.descriptions, '')), 'C') AS document
+	FROM menu_items i
+	JOIN menu_categories c ON c.id = i.category_id AND c.deleted_at IS NULL AND c.is_active
+	LEFT JOIN (
+		SELECT menu_item_id, string_agg(name, ' ') AS names, string_agg(COALESCE(description, ''), ' ') AS descriptions
+		FROM menu_translations WHERE menu_item_id IS NOT NULL GROUP BY menu_item_id
+	) it ON it.menu_item_id = i.id
+	LEFT JOIN (
+		SELECT category_id, string_agg(name, ' ') AS names
+		FROM menu_translations WHERE category_id IS NOT NULL GROUP BY category_id
+	) ct ON ct.category_id = c.id
+	WHERE i.deleted_at IS NULL AND i.is_available
+)
+SELECT id, ts_rank('{0.3, 0.3, 0.6, 1.0}', document, to_tsquery('simple', @tsquery)) + word_similarity(@query, names) AS score
+FROM documents
+WHERE document @@ to_tsquery('simple', @tsquery) OR word_similarity(@query, names) > 0.45
+ORDER BY score DESC, id
+LIMIT @limit`
+
+func (r *MenuRepository) searchMenuItemsPostgres(query string) ([]MenuSearchHit, error) {
+	var ranked []struct {
+		ID    uint
+		Score float64
+	}
+	err := r.db.Raw(menuSearchSQL, map[string]interface{}{
+		"query":   strings.ToLower(query),
+		"tsquery": search.TSQuery(query),
+		"limit":   menuSearchLimit,
+	}).Scan(&ranked).Error
+	if err != nil || len(ranked) == 0 {
+		return []MenuSearchHit{}, err
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, row := range ranked {
+		ids[i] = row.ID
+	}
+	var items []MenuItem
+	if err := r.searchPreloads().Where("id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]MenuItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	hits := make([]MenuSearchHit, 0, len(ranked))
+	for _, row := range ranked {
+		if item, ok := byID[row.ID]; ok {
+			hits = append(hits, MenuSearchHit{Item: item, Score: row.Score})
+		}
+	}
+	return hits, nil
+}
+
+// searchMenuItemsInProcess ranks every available item with search.Rank, for
+// databases without full-text search such as SQLite. Menus are small enough
+// for this to be quick.
+func (r *MenuRepository) searchMenuItemsInProcess(query string) ([]MenuSearchHit, error) {
+	var items []MenuItem
+	if err := r.searchPreloads().Where("is_available = ?", true).Order("sort_order ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	candidates := make([]MenuItem, 0, len(items))
+	documents := make([][]search.Field, 0, len(items))
+	for _, item := range items {
+		if item.Category.ID == 0 || !item.Category.IsActive {
+			continue
+		}
+		candidates = append(candidates, item)
+		documents = append(documents, menuSearchFields(&item))
+	}
+
+	ranked := search.Rank(query, documents)
+	hits := make([]MenuSearchHit, 0, min(len(ranked), menuSearchLimit))
+	for _, hit := range ranked[:min(len(ranked), menuSearchLimit)] {
+		hits = append(hits, MenuSearchHit{Item: candidates[hit.Index], Score: hit.Score})
+	}
+	return hits, nil
+}
+
+// menuSearchFields lists the text of an item and its translations with the
+// weight a match on it counts for.
+func menuSearchFields(item *MenuItem) []search.Field {
+	fields := []search.Field{
+		{Text: item.Name, Weight: search.WeightName},
+		{Text: item.Category.Name, Weight: search.WeightCategory},
+		{Text: item.Description, Weight: search.WeightDescription},
+	}
+	for _, translation := range item.Translations {
+		fields = append(fields,
+			search.Field{Text: translation.Name, Weight: search.WeightName},
+			search.Field{Text: translation.Description, Weight: search.WeightDescription})
+	}
+	for _, translation := range item.Category.Translations {
+		fields = append(fields, search.Field{Text: translation.Name, Weight: search.WeightCategory})
+	}
+	return fields
+}
+
+func (r *MenuRepository) searchPreloads() *gorm.DB {
+	return r.db.
+		Preload("Category.Schedules").
+		Preload("Category.Translations").
+		Preload("Schedules").
+		Preload("Translations")
+}
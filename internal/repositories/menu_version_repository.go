@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MenuVersionRepository struct {
+	db *gorm.DB
+}
+
+func NewMenuVersionRepository(db *gorm.DB) *MenuVersionRepository {
+	return &MenuVersionRepository{db: db}
+}
+
+// Price history
+
+func (r *MenuVersionRepository) CreatePrice(price *MenuItemPrice) error {
+	return r.db.Create(price).Error
+}
+
+// GetPrices returns the price history of a menu item, scheduled changes
+// included, latest first.
+func (r *MenuVersionRepository) GetPrices(menuItemID uint) ([]MenuItemPrice, error) {
+	var prices []MenuItemPrice
+	err := r.db.Where("menu_item_id = ?", menuItemID).
+		Order("effective_at DESC, id DESC").
+		Find(&prices).Error
+	return prices, err
+}
+
+// GetDuePrices returns the scheduled price changes whose time has come, in
+// the order they take effect.
+func (r *MenuVersionRepository) GetDuePrices(now time.Time) ([]MenuItemPrice, error) {
+	var prices []MenuItemPrice
+	err := r.db.Where("applied_at IS NULL AND effective_at <= ?", now).
+		Order("effective_at ASC, id ASC").
+		Find(&prices).Error
+	return prices, err
+}
+
+// MarkPriceApplied records that a scheduled price change was copied onto its
+// item. It reports false when the change was applied or cancelled meanwhile.
+func (r *MenuVersionRepository) MarkPriceApplied(id uint, at time.Time) (bool, error) {
+	result := r.db.Model(&MenuItemPrice{}).Where("id = ? AND applied_at IS NULL", id).Update("applied_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteScheduledPrice cancels a price change that has not been applied yet.
+func (r *MenuVersionRepository) DeleteScheduledPrice(menuItemID, id uint) error {
+	result := r.db.Where("id = ? AND menu_item_id = ? AND applied_at IS NULL", id, menuItemID).Delete(&MenuItemPrice{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("scheduled price change not found")
+	}
+	return nil
+}
+
+// Versions
+
+func (r *MenuVersionRepository) Create(version *MenuVersion) error {
+	return r.db.Create(version).Error
+}
+
+func (r *MenuVersionRepository) GetByID(id uint) (*MenuVersion, error) {
+	var version MenuVersion
+	err := r.db.First(&version, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("menu version not found")
+	}
+	return &version, err
+}
+
+// GetAll lists the menu versions, newest first, without their content.
+func (r *MenuVersionRepository) GetAll() ([]MenuVersion, error) {
+	var versions []MenuVersion
+	err := r.db.Omit("content").Order("id DESC").Find(&versions).Error
+	return versions, err
+}
+
+// GetDraft returns the open draft, or nil when there is none.
+func (r *MenuVersionRepository) GetDraft() (*MenuVersion, error) {
+	var version MenuVersion
+	err := r.db.Where("status = ?", MenuVersionDraft).First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &version, err
+}
+
+// GetCurrent returns the version published last, or nil when none has been.
+func (r *MenuVersionRepository) GetCurrent() (*MenuVersion, error) {
+	var version MenuVersion
+	err := r.db.Where("status = ?", MenuVersionPublished).Order("published_at DESC, id DESC").First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &version, err
+}
+
+func (r *MenuVersionRepository) Update(version *MenuVersion) error {
+	return r.db.Save(version).Error
+}
+
+// DeleteDraft discards a draft. Published versions are kept.
+func (r *MenuVersionRepository) DeleteDraft(id uint) error {
+	result := r.db.Where("id = ? AND status = ?", id, MenuVersionDraft).Delete(&MenuVersion{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("draft not found")
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ const (
 	RoleStaff    UserRole = "staff"
 	RoleCashier  UserRole = "cashier"
 	RoleAdmin    UserRole = "admin"
+	RoleDriver   UserRole = "driver"
 )
 
 type User struct {
@@ -41,36 +42,168 @@ type Table struct {
 }
 
 type MenuCategory struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	SortOrder   int            `json:"sort_order" gorm:"default:0"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
-	MenuItems   []MenuItem     `json:"menu_items,omitempty" gorm:"foreignKey:CategoryID"`
+	ID           uint              `json:"id" gorm:"primaryKey"`
+	Name         string            `json:"name" gorm:"not null"`
+	Description  string            `json:"description"`
+	IsActive     bool              `json:"is_active" gorm:"default:true"`
+	SortOrder    int               `json:"sort_order" gorm:"default:0"`
+	Station      string            `json:"station" gorm:"type:varchar(50);default:kitchen"` // Where items are prepared, e.g. kitchen or bar
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt    `json:"-" gorm:"index"`
+	MenuItems    []MenuItem        `json:"menu_items,omitempty" gorm:"foreignKey:CategoryID"`
+	Schedules    []MenuSchedule    `json:"schedules,omitempty" gorm:"foreignKey:CategoryID"`
+	Translations []MenuTranslation `json:"translations,omitempty" gorm:"foreignKey:CategoryID"`
 }
 
 type MenuItem struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	CategoryID  uint           `json:"category_id" gorm:"not null"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	Price       float64        `json:"price" gorm:"not null"`
-	ImageURL    string         `json:"image_url"`
-	IsAvailable bool           `json:"is_available" gorm:"default:true"`
-	SortOrder   int            `json:"sort_order" gorm:"default:0"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
-	Category    MenuCategory   `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	CategoryID    uint              `json:"category_id" gorm:"not null"`
+	SKU           *string           `json:"sku,omitempty" gorm:"type:varchar(100);uniqueIndex"` // The restaurant's own item code, used to match items on import
+	Name          string            `json:"name" gorm:"not null"`
+	Description   string            `json:"description"`
+	Price         float64           `json:"price" gorm:"not null"`
+	ImageURL      string            `json:"image_url"`
+	ImageVariants map[string]string `json:"image_variants,omitempty" gorm:"type:text;serializer:json"` // URLs of the sizes of an uploaded image, e.g. thumbnail
+	ImageKeys     map[string]string `json:"-" gorm:"type:text;serializer:json"`                        // Where the files of an uploaded image are stored, to remove them when replaced
+	IsAvailable   bool              `json:"is_available" gorm:"default:true"`
+	SortOrder     int               `json:"sort_order" gorm:"default:0"`
+	Station       string            `json:"station,omitempty" gorm:"type:varchar(50)"`               // Overrides the category station when set
+	Allergens     []string          `json:"allergens,omitempty" gorm:"type:text;serializer:json"`    // Allergens the dish contains, e.g. peanut
+	DietaryTags   []string          `json:"dietary_tags,omitempty" gorm:"type:text;serializer:json"` // Diets the dish suits, e.g. halal
+	Nutrition     *NutritionFacts   `json:"nutrition,omitempty" gorm:"type:text;serializer:json"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt    `json:"-" gorm:"index"`
+	Category      MenuCategory      `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Schedules     []MenuSchedule    `json:"schedules,omitempty" gorm:"foreignKey:MenuItemID"`
+	Translations  []MenuTranslation `json:"translations,omitempty" gorm:"foreignKey:MenuItemID"`
+	BundleSlots   []MenuBundleSlot  `json:"bundle_slots,omitempty" gorm:"foreignKey:BundleItemID"` // Set on bundles such as a Paket Hemat
+}
+
+// MenuBundleSlot is one component of a bundle item. A slot with a single
+// option is fixed; otherwise the guest chooses one of its options, e.g. the
+// drink of a set meal. An item with slots is a bundle and is sold at its own
+// price plus the upcharges of the options chosen.
+type MenuBundleSlot struct {
+	ID           uint               `json:"id" gorm:"primaryKey"`
+	BundleItemID uint               `json:"bundle_item_id" gorm:"not null;index"`
+	Name         string             `json:"name" gorm:"not null"`
+	Quantity     int                `json:"quantity" gorm:"not null;default:1"` // Servings of the chosen option in one bundle
+	SortOrder    int                `json:"sort_order" gorm:"default:0"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	Options      []MenuBundleOption `json:"options" gorm:"foreignKey:SlotID"`
+}
+
+// MenuBundleOption is a menu item that can fill a bundle slot.
+type MenuBundleOption struct {
+	ID         uint     `json:"id" gorm:"primaryKey"`
+	SlotID     uint     `json:"slot_id" gorm:"not null;index"`
+	MenuItemID uint     `json:"menu_item_id" gorm:"not null;index"`
+	Upcharge   float64  `json:"upcharge" gorm:"not null;default:0"` // Added to the bundle price when chosen
+	IsDefault  bool     `json:"is_default" gorm:"default:false"`    // Chosen when an order leaves the slot open
+	SortOrder  int      `json:"sort_order" gorm:"default:0"`
+	MenuItem   MenuItem `json:"menu_item" gorm:"foreignKey:MenuItemID"`
+}
+
+// MenuTranslation is the name and description of a menu category or item in
+// another language than MENU_LOCALE, which the category or item itself is
+// written in.
+type MenuTranslation struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CategoryID  *uint     `json:"category_id,omitempty" gorm:"uniqueIndex:idx_menu_translations_category_locale"`
+	MenuItemID  *uint     `json:"menu_item_id,omitempty" gorm:"uniqueIndex:idx_menu_translations_item_locale"`
+	Locale      string    `json:"locale" gorm:"type:varchar(20);not null;uniqueIndex:idx_menu_translations_category_locale;uniqueIndex:idx_menu_translations_item_locale"` // e.g. en or en-au
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NutritionFacts are the nutrition values of one serving. Values that have
+// not been measured are left out.
+type NutritionFacts struct {
+	Calories      *float64 `json:"calories,omitempty"` // kcal
+	Protein       *float64 `json:"protein_g,omitempty"`
+	Carbohydrates *float64 `json:"carbohydrates_g,omitempty"`
+	Sugar         *float64 `json:"sugar_g,omitempty"`
+	Fat           *float64 `json:"fat_g,omitempty"`
+	Sodium        *float64 `json:"sodium_mg,omitempty"`
+}
+
+// MenuSchedule is a window in which a menu category or item can be ordered,
+// e.g. breakfast on weekdays from 06:00 to 10:30. Categories and items without
+// schedules are always on the menu; otherwise they are on it inside any of
+// their schedules. Times and dates are in the restaurant's time zone.
+type MenuSchedule struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CategoryID *uint     `json:"category_id,omitempty" gorm:"index"`
+	MenuItemID *uint     `json:"menu_item_id,omitempty" gorm:"index"`
+	Name       string    `json:"name,omitempty" gorm:"type:varchar(50)"`          // Daypart shown to guests, e.g. Breakfast
+	Days       []string  `json:"days,omitempty" gorm:"type:text;serializer:json"` // mon..sun; every day when empty
+	StartTime  string    `json:"start_time,omitempty" gorm:"type:varchar(5)"`     // HH:MM; all day when both times are empty
+	EndTime    string    `json:"end_time,omitempty" gorm:"type:varchar(5)"`       // Before StartTime when the window runs past midnight
+	StartDate  string    `json:"start_date,omitempty" gorm:"type:varchar(10)"`    // YYYY-MM-DD, inclusive
+	EndDate    string    `json:"end_date,omitempty" gorm:"type:varchar(10)"`      // YYYY-MM-DD, inclusive
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type PriceSource string
+
+const (
+	PriceSourceInitial   PriceSource = "initial"   // Price the item was created with
+	PriceSourceEdit      PriceSource = "edit"      // Changed by updating the item
+	PriceSourceImport    PriceSource = "import"    // Changed by a menu import
+	PriceSourceScheduled PriceSource = "scheduled" // Future-dated change, applied once due
+	PriceSourcePublish   PriceSource = "publish"   // Changed by publishing a menu version
+	PriceSourceRollback  PriceSource = "rollback"  // Changed by rolling back to a menu version
+)
+
+// MenuItemPrice is one entry in the price history of a menu item. Scheduled
+// entries wait with an empty AppliedAt until their EffectiveAt has passed,
+// when the price is copied onto the item.
+type MenuItemPrice struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	MenuItemID    uint        `json:"menu_item_id" gorm:"not null;index"`
+	Price         float64     `json:"price" gorm:"not null"`
+	Source        PriceSource `json:"source" gorm:"not null;type:varchar(20)"`
+	EffectiveAt   time.Time   `json:"effective_at" gorm:"not null;index"`
+	AppliedAt     *time.Time  `json:"applied_at,omitempty" gorm:"index"`
+	MenuVersionID *uint       `json:"menu_version_id,omitempty"` // Version whose publish or rollback set the price
+	CreatedByID   *uint       `json:"created_by_id,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+type MenuVersionStatus string
+
+const (
+	MenuVersionDraft     MenuVersionStatus = "draft"
+	MenuVersionPublished MenuVersionStatus = "published"
+)
+
+// MenuVersion is a complete copy of the menu. A draft is edited without
+// touching the live menu until it is published; a published version keeps
+// the menu as it was published so it can be rolled back to.
+type MenuVersion struct {
+	ID               uint              `json:"id" gorm:"primaryKey"`
+	Status           MenuVersionStatus `json:"status" gorm:"not null;type:varchar(20);default:draft;index"`
+	Note             string            `json:"note,omitempty"`
+	Content          string            `json:"-" gorm:"type:text;not null"`   // The menu in the JSON export format
+	RolledBackFromID *uint             `json:"rolled_back_from_id,omitempty"` // Published version this one restored
+	CreatedByID      *uint             `json:"created_by_id,omitempty"`
+	PublishedByID    *uint             `json:"published_by_id,omitempty"`
+	PublishedAt      *time.Time        `json:"published_at,omitempty" gorm:"index"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
 }
 
 type OrderStatus string
 
 const (
 	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusScheduled OrderStatus = "scheduled" // Paid, waiting outside the kitchen queue until its release time
 	OrderStatusConfirmed OrderStatus = "confirmed"
 	OrderStatusPreparing OrderStatus = "preparing"
 	OrderStatusReady     OrderStatus = "ready"
@@ -83,6 +216,16 @@ type OrderType string
 const (
 	OrderTypeDineIn   OrderType = "dine_in"
 	OrderTypeTakeaway OrderType = "takeaway"
+	OrderTypeDelivery OrderType = "delivery"
+)
+
+// OrderSource is where an order was placed: the customer app, the cashier's
+// POS or a third-party delivery platform such as "grabfood".
+type OrderSource string
+
+const (
+	OrderSourceApp OrderSource = "app"
+	OrderSourcePOS OrderSource = "pos"
 )
 
 type Order struct {
@@ -91,38 +234,137 @@ type Order struct {
 	TableID                 uint           `json:"table_id"` // Optional for takeaway orders
 	OrderType               OrderType      `json:"order_type" gorm:"not null;default:dine_in"`
 	Status                  OrderStatus    `json:"status" gorm:"not null;default:pending"`
-	SubtotalAmount          float64        `json:"subtotal_amount" gorm:"not null"`      // Amount before tax
-	VATAmount               float64        `json:"vat_amount" gorm:"not null;default:0"` // VAT 10% in Indonesia
-	TotalAmount             float64        `json:"total_amount" gorm:"not null"`         // Final amount including VAT
+	Source                  OrderSource    `json:"source" gorm:"not null;type:varchar(30);default:app;uniqueIndex:idx_orders_source_external_id"`
+	ExternalID              *string        `json:"external_id,omitempty" gorm:"type:varchar(100);uniqueIndex:idx_orders_source_external_id"` // Order ID on the source platform
+	SubtotalAmount          float64        `json:"subtotal_amount" gorm:"not null"`                                                          // Amount before tax
+	DeliveryFee             float64        `json:"delivery_fee" gorm:"not null;default:0"`                                                   // Taxed together with the items
+	VATAmount               float64        `json:"vat_amount" gorm:"not null;default:0"`                                                     // VAT 10% in Indonesia
+	TotalAmount             float64        `json:"total_amount" gorm:"not null"`                                                             // Final amount including VAT
 	CustomerName            string         `json:"customer_name" gorm:"type:varchar(255)"`
 	CustomerPhone           string         `json:"customer_phone" gorm:"type:varchar(20)"` // For takeaway notifications
 	CashierName             string         `json:"cashier_name" gorm:"type:varchar(255)"`
 	SpecialNotes            string         `json:"special_notes"`
-	EstimatedCompletionTime *time.Time     `json:"estimated_completion_time"` // For takeaway orders
+	EstimatedCompletionTime *time.Time     `json:"estimated_completion_time"`                             // Expected ready time shown to customers
+	PickupNumber            string         `json:"pickup_number,omitempty" gorm:"type:varchar(10);index"` // e.g. A-042, resets daily
+	ScheduledFor            *time.Time     `json:"scheduled_for,omitempty" gorm:"index"`                  // Requested pickup time of a pre-order
+	ReleaseAt               *time.Time     `json:"release_at,omitempty" gorm:"index"`                     // When a scheduled order enters the kitchen queue
+	CollectedAt             *time.Time     `json:"collected_at,omitempty"`                                // When a takeaway order was handed over
+	ConfirmedAt             *time.Time     `json:"confirmed_at,omitempty"`                                // Entered the kitchen queue
+	ReadyAt                 *time.Time     `json:"ready_at,omitempty"`                                    // Left the kitchen
+	Version                 uint           `json:"version" gorm:"not null;default:1"`                     // Optimistic lock, exposed as ETag
 	CreatedAt               time.Time      `json:"created_at"`
 	UpdatedAt               time.Time      `json:"updated_at"`
 	DeletedAt               gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relations
-	User       User        `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Table      *Table      `json:"table,omitempty" gorm:"foreignKey:TableID"` // Nullable for takeaway
-	OrderItems []OrderItem `json:"order_items,omitempty" gorm:"foreignKey:OrderID"`
-	Payment    *Payment    `json:"payment,omitempty" gorm:"foreignKey:OrderID"`
+	User       User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Table      *Table         `json:"table,omitempty" gorm:"foreignKey:TableID"` // Nullable for takeaway
+	OrderItems []OrderItem    `json:"order_items,omitempty" gorm:"foreignKey:OrderID"`
+	Courses    []OrderCourse  `json:"courses,omitempty" gorm:"foreignKey:OrderID"` // Pacing of dine-in courses
+	Payment    *Payment       `json:"payment,omitempty" gorm:"foreignKey:OrderID"`
+	Delivery   *OrderDelivery `json:"delivery,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+type CourseType string
+
+const (
+	CourseStarter CourseType = "starter"
+	CourseMain    CourseType = "main"
+	CourseDessert CourseType = "dessert"
+)
+
+// CourseSequence is the order in which courses reach the table.
+var CourseSequence = map[CourseType]int{
+	CourseStarter: 1,
+	CourseMain:    2,
+	CourseDessert: 3,
+}
+
+type CourseStatus string
+
+const (
+	CourseWaiting CourseStatus = "waiting" // Fires with the order (first course) or after the previous course
+	CourseHeld    CourseStatus = "held"    // Waits for a waiter to fire it
+	CourseFired   CourseStatus = "fired"   // Sent to the kitchen
+	CourseServed  CourseStatus = "served"
+)
+
+// OrderCourse tracks when one course of a dine-in order goes to the kitchen.
+// Items of a course only reach kitchen tickets and displays once it is fired.
+type OrderCourse struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	OrderID   uint         `json:"order_id" gorm:"not null;uniqueIndex:idx_order_courses_order_course"`
+	Course    CourseType   `json:"course" gorm:"not null;type:varchar(20);uniqueIndex:idx_order_courses_order_course"`
+	Status    CourseStatus `json:"status" gorm:"not null;type:varchar(20);default:waiting;index"`
+	FireAt    *time.Time   `json:"fire_at,omitempty" gorm:"index"` // Automatic firing, set once the previous course is served
+	FiredAt   *time.Time   `json:"fired_at,omitempty"`
+	ServedAt  *time.Time   `json:"served_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// PickupCounter hands out the daily sequence behind takeaway pickup numbers.
+type PickupCounter struct {
+	BusinessDate string `gorm:"primaryKey;type:varchar(10)"` // YYYY-MM-DD in restaurant local time
+	LastNumber   int    `gorm:"not null;default:0"`
+}
+
+// PickupSlotLock is a row per pickup slot that orders booking into the slot
+// lock, so the slot's bookings are counted one order at a time.
+type PickupSlotLock struct {
+	SlotStart time.Time `gorm:"primaryKey"`
+	LockedAt  time.Time `gorm:"not null"`
+}
+
+// PrepTimeSample is one observed prep time for a menu item, from the item
+// going to the kitchen until its order was ready, together with the estimate
+// that was in effect at the time.
+type PrepTimeSample struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	MenuItemID       uint      `json:"menu_item_id" gorm:"not null;index"`
+	OrderID          uint      `json:"order_id" gorm:"not null;index"`
+	OrderItemID      *uint     `json:"order_item_id,omitempty" gorm:"index"` // Nil for samples taken per order, before items were timed
+	ActualSeconds    int       `json:"actual_seconds" gorm:"not null"`
+	EstimatedSeconds int       `json:"estimated_seconds" gorm:"not null"`
+	CreatedAt        time.Time `json:"created_at" gorm:"index"`
 }
 
 type OrderItem struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrderID        uint       `json:"order_id" gorm:"not null"`
+	MenuItemID     uint       `json:"menu_item_id" gorm:"not null"`
+	Quantity       int        `json:"quantity" gorm:"not null"`
+	UnitPrice      float64    `json:"unit_price" gorm:"not null"`
+	TotalPrice     float64    `json:"total_price" gorm:"not null"`
+	SpecialRequest string     `json:"special_request"`
+	Round          int        `json:"round" gorm:"not null;default:1"`                              // Add-on round the item was ordered in; 1 is the original order
+	Course         CourseType `json:"course,omitempty" gorm:"type:varchar(20);not null;default:''"` // Empty for items sent with the order
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relations
+	Order      Order                `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	MenuItem   MenuItem             `json:"menu_item,omitempty" gorm:"foreignKey:MenuItemID"`
+	Components []OrderItemComponent `json:"components,omitempty" gorm:"foreignKey:OrderItemID"` // What a bundle was made up of
+}
+
+// OrderItemComponent is the menu item served in one slot of an ordered
+// bundle. The bundle's unit price is split over its components so sales can
+// be reported per dish: each gets a share of the bundle's own price in
+// proportion to its list price, plus the upcharge of its option. The shares
+// of a bundle add up to its unit price.
+type OrderItemComponent struct {
 	ID             uint      `json:"id" gorm:"primaryKey"`
-	OrderID        uint      `json:"order_id" gorm:"not null"`
-	MenuItemID     uint      `json:"menu_item_id" gorm:"not null"`
-	Quantity       int       `json:"quantity" gorm:"not null"`
-	UnitPrice      float64   `json:"unit_price" gorm:"not null"`
-	TotalPrice     float64   `json:"total_price" gorm:"not null"`
-	SpecialRequest string    `json:"special_request"`
+	OrderItemID    uint      `json:"order_item_id" gorm:"not null;index"`
+	SlotID         uint      `json:"slot_id" gorm:"not null"`
+	SlotName       string    `json:"slot_name"` // Kept in case the bundle is changed later
+	MenuItemID     uint      `json:"menu_item_id" gorm:"not null;index"`
+	Quantity       int       `json:"quantity" gorm:"not null;default:1"` // Servings in one bundle
+	Upcharge       float64   `json:"upcharge" gorm:"not null;default:0"`
+	AllocatedPrice float64   `json:"allocated_price" gorm:"not null;default:0"` // Share of one bundle's unit price
 	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
 
 	// Relations
-	Order    Order    `json:"order,omitempty" gorm:"foreignKey:OrderID"`
 	MenuItem MenuItem `json:"menu_item,omitempty" gorm:"foreignKey:MenuItemID"`
 }
 
@@ -139,23 +381,312 @@ const (
 type PaymentMethod string
 
 const (
-	PaymentMethodQRIS PaymentMethod = "qris"
-	PaymentMethodCash PaymentMethod = "cash"
+	PaymentMethodQRIS     PaymentMethod = "qris"
+	PaymentMethodCash     PaymentMethod = "cash"
+	PaymentMethodPlatform PaymentMethod = "platform" // Collected by a delivery platform
 )
 
 type Payment struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	OrderID        uint           `json:"order_id" gorm:"uniqueIndex;not null"`
+	Method         PaymentMethod  `json:"method" gorm:"not null"`
+	Status         PaymentStatus  `json:"status" gorm:"not null;default:pending"`
+	Amount         float64        `json:"amount" gorm:"not null"`
+	QRISData       string         `json:"qris_data,omitempty"` // Encrypted QRIS payload
+	TransactionID  string         `json:"transaction_id,omitempty"`
+	ExternalID     string         `json:"external_id,omitempty"`
+	AmountTendered float64        `json:"amount_tendered,omitempty"` // Cash handed over by the customer
+	ChangeAmount   float64        `json:"change_amount,omitempty"`
+	Version        uint           `json:"version" gorm:"not null;default:1"` // Optimistic lock, exposed as ETag
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relations
+	Order Order `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+type PrinterConnection string
+
+const (
+	PrinterConnectionTCP   PrinterConnection = "tcp"   // Raw socket, usually port 9100
+	PrinterConnectionSpool PrinterConnection = "spool" // Files dropped into a spool directory
+)
+
+type PrinterStatus string
+
+const (
+	PrinterStatusUnknown PrinterStatus = "unknown"
+	PrinterStatusOnline  PrinterStatus = "online"
+	PrinterStatusOffline PrinterStatus = "offline"
+)
+
+// Printer is a registered ticket printer. Kitchen tickets are routed by
+// Stations, receipts by the cashier Terminal the printer sits next to.
+type Printer struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	Name       string            `json:"name" gorm:"uniqueIndex;not null;type:varchar(100)"`
+	Connection PrinterConnection `json:"connection" gorm:"not null;type:varchar(20)"`
+	Address    string            `json:"address" gorm:"not null"`                       // host:port or spool directory
+	PaperWidth int               `json:"paper_width" gorm:"not null;default:80"`        // 58 or 80 (mm)
+	Format     string            `json:"format" gorm:"type:varchar(10);default:escpos"` // escpos, text or pdf
+	Stations   string            `json:"stations"`                                      // Comma-separated, e.g. "kitchen,grill"
+	Terminal   string            `json:"terminal" gorm:"type:varchar(100);index"`       // Cashier terminal printing receipts here
+	IsActive   bool              `json:"is_active" gorm:"default:true"`
+	Status     PrinterStatus     `json:"status" gorm:"type:varchar(20);default:unknown"`
+	LastError  string            `json:"last_error,omitempty"`
+	LastSeenAt *time.Time        `json:"last_seen_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+type PrintJobKind string
+
+const (
+	PrintJobReceipt       PrintJobKind = "receipt"
+	PrintJobKitchenTicket PrintJobKind = "kitchen_ticket"
+	PrintJobTestPage      PrintJobKind = "test_page"
+	PrintJobVoidTicket    PrintJobKind = "void_ticket"
+)
+
+type PrintJobStatus string
+
+const (
+	PrintJobQueued    PrintJobStatus = "queued"
+	PrintJobPrinted   PrintJobStatus = "printed"
+	PrintJobFailed    PrintJobStatus = "failed"
+	PrintJobCancelled PrintJobStatus = "cancelled" // Withdrawn before printing because the order was cancelled
+)
+
+// PrintJob is a rendered document waiting for, or sent to, a printer. The
+// bytes are rendered when the job is queued so retries print the same ticket.
+type PrintJob struct {
 	ID            uint           `json:"id" gorm:"primaryKey"`
-	OrderID       uint           `json:"order_id" gorm:"uniqueIndex;not null"`
-	Method        PaymentMethod  `json:"method" gorm:"not null"`
-	Status        PaymentStatus  `json:"status" gorm:"not null;default:pending"`
-	Amount        float64        `json:"amount" gorm:"not null"`
-	QRISData      string         `json:"qris_data,omitempty"` // Encrypted QRIS payload
-	TransactionID string         `json:"transaction_id,omitempty"`
-	ExternalID    string         `json:"external_id,omitempty"`
+	PrinterID     uint           `json:"printer_id" gorm:"not null;index"`
+	OrderID       *uint          `json:"order_id,omitempty" gorm:"index"`
+	Kind          PrintJobKind   `json:"kind" gorm:"not null;type:varchar(20)"`
+	Station       string         `json:"station,omitempty" gorm:"type:varchar(50)"`
+	Round         int            `json:"round,omitempty" gorm:"not null;default:0"`                    // Order round of a kitchen or void ticket
+	Course        CourseType     `json:"course,omitempty" gorm:"type:varchar(20);not null;default:''"` // Course of a kitchen or void ticket
+	Data          []byte         `json:"-" gorm:"not null"`
+	Status        PrintJobStatus `json:"status" gorm:"not null;type:varchar(20);default:queued;index"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	LastError     string         `json:"last_error,omitempty"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"index"`
+	PrintedAt     *time.Time     `json:"printed_at,omitempty"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relations
-	Order Order `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	Printer Printer `json:"printer,omitempty" gorm:"foreignKey:PrinterID"`
+}
+
+type IdempotencyStatus string
+
+const (
+	IdempotencyProcessing IdempotencyStatus = "processing"
+	IdempotencyCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so retries of the same request can be replayed
+// instead of executed again.
+type IdempotencyKey struct {
+	ID           uint              `json:"id" gorm:"primaryKey"`
+	UserID       uint              `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key          string            `json:"key" gorm:"not null;type:varchar(255);uniqueIndex:idx_idempotency_user_key"`
+	Method       string            `json:"method" gorm:"not null;type:varchar(10)"`
+	Path         string            `json:"path" gorm:"not null;type:varchar(255)"`
+	RequestHash  string            `json:"request_hash" gorm:"not null;type:varchar(64)"` // SHA-256 of method, path and body
+	Status       IdempotencyStatus `json:"status" gorm:"not null;type:varchar(20);default:processing"`
+	ResponseCode int               `json:"response_code"`
+	ResponseBody []byte            `json:"-"`
+	ContentType  string            `json:"content_type" gorm:"type:varchar(100)"`
+	ExpiresAt    time.Time         `json:"expires_at" gorm:"not null;index"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+type OrderEventType string
+
+const (
+	OrderEventCreated              OrderEventType = "order_created"
+	OrderEventStatusChanged        OrderEventType = "status_changed"
+	OrderEventStatusOverridden     OrderEventType = "status_override" // Admin jump outside the normal workflow
+	OrderEventItemsUpdated         OrderEventType = "items_updated"
+	OrderEventRoundAdded           OrderEventType = "round_added" // Add-on round appended to an order in the kitchen
+	OrderEventCourseHeld           OrderEventType = "course_held"
+	OrderEventCourseFired          OrderEventType = "course_fired"
+	OrderEventCourseServed         OrderEventType = "course_served"
+	OrderEventDriverAssigned       OrderEventType = "driver_assigned"
+	OrderEventDeliveryPickedUp     OrderEventType = "delivery_picked_up"
+	OrderEventDelivered            OrderEventType = "delivered"
+	OrderEventPaymentInitiated     OrderEventType = "payment_initiated"
+	OrderEventPaymentReceived      OrderEventType = "payment_received"
+	OrderEventPaymentStatusChanged OrderEventType = "payment_status_changed"
+	OrderEventPaymentRefunded      OrderEventType = "payment_refunded"
+	OrderEventDeleted              OrderEventType = "order_deleted"
+	OrderEventCancellationRequest  OrderEventType = "cancellation_requested"
+	OrderEventCancellationRejected OrderEventType = "cancellation_rejected"
+)
+
+// OrderEvent is one entry in the audit trail of an order: who changed what,
+// when, from which value to which, and why.
+type OrderEvent struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	OrderID       uint           `json:"order_id" gorm:"not null;index"`
+	PaymentID     *uint          `json:"payment_id,omitempty"`
+	Type          OrderEventType `json:"type" gorm:"not null;type:varchar(30)"`
+	ActorID       *uint          `json:"actor_id,omitempty"` // Nil for system actions such as payment webhooks
+	PreviousValue string         `json:"previous_value,omitempty"`
+	NewValue      string         `json:"new_value,omitempty"`
+	Reason        string         `json:"reason,omitempty"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"index"`
+
+	// Relations
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// EventCursor records how far a background consumer has worked through the
+// order events, so it resumes where it stopped after a restart.
+type EventCursor struct {
+	Consumer    string `gorm:"primaryKey;type:varchar(50)"`
+	LastEventID uint   `gorm:"not null;default:0"`
+	UpdatedAt   time.Time
+}
+
+type CancellationReason string
+
+const (
+	CancellationCustomerRequest CancellationReason = "customer_request"
+	CancellationCustomerNoShow  CancellationReason = "customer_no_show"
+	CancellationOutOfStock      CancellationReason = "out_of_stock"
+	CancellationKitchenError    CancellationReason = "kitchen_error"
+	CancellationPaymentIssue    CancellationReason = "payment_issue"
+	CancellationDuplicateOrder  CancellationReason = "duplicate_order"
+	CancellationOther           CancellationReason = "other" // Requires a note
+)
+
+type CancellationStatus string
+
+const (
+	CancellationPendingApproval CancellationStatus = "pending_approval"
+	CancellationRejected        CancellationStatus = "rejected"
+	CancellationCompleted       CancellationStatus = "completed"
+)
+
+// OrderCancellation records why an order was cancelled, who asked for it and,
+// for orders the kitchen had already started, which manager decided on it.
+type OrderCancellation struct {
+	ID            uint               `json:"id" gorm:"primaryKey"`
+	OrderID       uint               `json:"order_id" gorm:"not null;index"`
+	ReasonCode    CancellationReason `json:"reason_code" gorm:"not null;type:varchar(30)"`
+	Note          string             `json:"note,omitempty"`
+	Status        CancellationStatus `json:"status" gorm:"not null;type:varchar(20);index"`
+	OrderStatus   OrderStatus        `json:"order_status" gorm:"type:text"` // Order status when the cancellation was requested
+	RequestedByID uint               `json:"requested_by_id" gorm:"not null"`
+	DecidedByID   *uint              `json:"decided_by_id,omitempty"` // Manager who approved or rejected the request
+	DecisionNote  string             `json:"decision_note,omitempty"`
+	DecidedAt     *time.Time         `json:"decided_at,omitempty"`
+	RefundAmount  float64            `json:"refund_amount" gorm:"not null;default:0"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+
+	// Relations
+	Order       *Order `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	RequestedBy *User  `json:"requested_by,omitempty" gorm:"foreignKey:RequestedByID"`
+	DecidedBy   *User  `json:"decided_by,omitempty" gorm:"foreignKey:DecidedByID"`
+}
+
+// CustomerAddress is a delivery address saved by a customer.
+type CustomerAddress struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	Label     string         `json:"label" gorm:"type:varchar(50)"` // e.g. Home, Office
+	Address   string         `json:"address" gorm:"not null"`
+	Latitude  float64        `json:"latitude" gorm:"not null"`
+	Longitude float64        `json:"longitude" gorm:"not null"`
+	Notes     string         `json:"notes,omitempty"` // Directions for the driver
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type DeliveryZoneType string
+
+const (
+	DeliveryZoneRadius  DeliveryZoneType = "radius"  // Within RadiusKm of the center
+	DeliveryZonePolygon DeliveryZoneType = "polygon" // Inside the polygon
+)
+
+// GeoPoint is a [latitude, longitude] pair.
+type GeoPoint [2]float64
+
+// DeliveryZone is an area the restaurant delivers to, with its own fee and
+// minimum order value. When zones overlap, the one with the lowest priority
+// number wins.
+type DeliveryZone struct {
+	ID           uint             `json:"id" gorm:"primaryKey"`
+	Name         string           `json:"name" gorm:"not null;type:varchar(100)"`
+	Type         DeliveryZoneType `json:"type" gorm:"not null;type:varchar(20)"`
+	CenterLat    float64          `json:"center_lat,omitempty"`
+	CenterLng    float64          `json:"center_lng,omitempty"`
+	RadiusKm     float64          `json:"radius_km,omitempty"`
+	Polygon      []GeoPoint       `json:"polygon,omitempty" gorm:"type:text;serializer:json"`
+	Fee          float64          `json:"fee" gorm:"not null;default:0"`
+	MinimumOrder float64          `json:"minimum_order" gorm:"not null;default:0"` // Item subtotal required, before VAT
+	Priority     int              `json:"priority" gorm:"not null;default:0"`
+	IsActive     bool             `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt   `json:"-" gorm:"index"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // Waiting for a driver
+	DeliveryAssigned  DeliveryStatus = "assigned"  // Driver on the way to the restaurant
+	DeliveryPickedUp  DeliveryStatus = "picked_up" // Driver on the way to the customer
+	DeliveryDelivered DeliveryStatus = "delivered"
+)
+
+// OrderDelivery is where a delivery order goes and how far along its driver
+// is. The address is copied from the request so later edits to a saved
+// address do not change orders already placed.
+type OrderDelivery struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	OrderID     uint           `json:"order_id" gorm:"not null;uniqueIndex"`
+	ZoneID      uint           `json:"zone_id" gorm:"not null;index"`
+	Address     string         `json:"address" gorm:"not null"`
+	Latitude    float64        `json:"latitude" gorm:"not null"`
+	Longitude   float64        `json:"longitude" gorm:"not null"`
+	Notes       string         `json:"notes,omitempty"`
+	Status      DeliveryStatus `json:"status" gorm:"not null;type:varchar(20);default:pending;index"`
+	DriverID    *uint          `json:"driver_id,omitempty" gorm:"index"`
+	AssignedAt  *time.Time     `json:"assigned_at,omitempty"`
+	PickedUpAt  *time.Time     `json:"picked_up_at,omitempty"`
+	DeliveredAt *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	// Relations
+	Order  *Order        `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	Zone   *DeliveryZone `json:"zone,omitempty" gorm:"foreignKey:ZoneID"`
+	Driver *User         `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+}
+
+// ExternalItemMapping links the SKU a delivery platform sends for an item to
+// the menu item it stands for.
+type ExternalItemMapping struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Platform   string    `json:"platform" gorm:"not null;type:varchar(30);uniqueIndex:idx_external_item_mappings_platform_sku"`
+	SKU        string    `json:"sku" gorm:"not null;type:varchar(100);uniqueIndex:idx_external_item_mappings_platform_sku"`
+	MenuItemID uint      `json:"menu_item_id" gorm:"not null;index"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relations
+	MenuItem *MenuItem `json:"menu_item,omitempty" gorm:"foreignKey:MenuItemID"`
 }
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderCancellationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderCancellationRepository(db *gorm.DB) *OrderCancellationRepository {
+	return &OrderCancellationRepository{db: db}
+}
+
+func (r *OrderCancellationRepository) Create(cancellation *OrderCancellation) error {
+	return r.db.Create(cancellation).Error
+}
+
+func (r *OrderCancellationRepository) Update(cancellation *OrderCancellation) error {
+	return r.db.Omit(clause.Associations).Save(cancellation).Error
+}
+
+func (r *OrderCancellationRepository) GetByID(id uint) (*OrderCancellation, error) {
+	var cancellation OrderCancellation
+	err := r.db.Preload("Order").
+		Preload("RequestedBy").
+		Preload("DecidedBy").
+		First(&cancellation, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("cancellation not found")
+	}
+	return &cancellation, err
+}
+
+// GetPendingByOrderID returns the cancellation of an order that is waiting
+// for a manager, if there is one.
+func (r *OrderCancellationRepository) GetPendingByOrderID(orderID uint) (*OrderCancellation, error) {
+	var cancellation OrderCancellation
+	err := r.db.Where("order_id = ? AND status = ?", orderID, CancellationPendingApproval).
+		First(&cancellation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("cancellation not found")
+	}
+	return &cancellation, err
+}
+
+func (r *OrderCancellationRepository) GetPaginated(offset, limit int, status string) ([]OrderCancellation, int64, error) {
+	var cancellations []OrderCancellation
+	var total int64
+
+	query := r.db.Model(&OrderCancellation{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Preload("Order").
+		Preload("RequestedBy").
+		Preload("DecidedBy").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&cancellations).Error
+
+	return cancellations, total, err
+}
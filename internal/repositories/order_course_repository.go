@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrCourseChanged is returned when a course is no longer in the status a
+// transition expected, e.g. because the auto-fire worker fired it first.
+var ErrCourseChanged = errors.New("course was changed by another request")
+
+type OrderCourseRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderCourseRepository(db *gorm.DB) *OrderCourseRepository {
+	return &OrderCourseRepository{db: db}
+}
+
+func (r *OrderCourseRepository) GetByOrderID(orderID uint) ([]OrderCourse, error) {
+	var courses []OrderCourse
+	err := r.db.Where("order_id = ?", orderID).Order("id ASC").Find(&courses).Error
+	return courses, err
+}
+
+// Transition moves a course to another status, provided it is still in one of
+// the from statuses. It fails with ErrCourseChanged otherwise.
+func (r *OrderCourseRepository) Transition(id uint, from []CourseStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&OrderCourse{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCourseChanged
+	}
+	return nil
+}
+
+// Replace makes courses the complete set of courses of an order. Courses that
+// are kept retain their row and status.
+func (r *OrderCourseRepository) Replace(orderID uint, courses []OrderCourse) error {
+	keep := make([]CourseType, 0, len(courses))
+	for i := range courses {
+		courses[i].OrderID = orderID
+		keep = append(keep, courses[i].Course)
+	}
+
+	remove := r.db.Where("order_id = ?", orderID)
+	if len(keep) > 0 {
+		remove = remove.Where("course NOT IN ?", keep)
+	}
+	if err := remove.Delete(&OrderCourse{}).Error; err != nil {
+		return err
+	}
+
+	for i := range courses {
+		if courses[i].ID != 0 {
+			continue
+		}
+		if err := r.db.Create(&courses[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDue returns waiting courses whose automatic firing time has passed.
+func (r *OrderCourseRepository) GetDue(now time.Time) ([]OrderCourse, error) {
+	var courses []OrderCourse
+	err := r.db.Where("status = ? AND fire_at <= ?", CourseWaiting, now).
+		Order("fire_at ASC").
+		Find(&courses).Error
+	return courses, err
+}
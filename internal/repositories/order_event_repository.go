@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderEventRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderEventRepository(db *gorm.DB) *OrderEventRepository {
+	return &OrderEventRepository{db: db}
+}
+
+func (r *OrderEventRepository) Create(event *OrderEvent) error {
+	return r.db.Create(event).Error
+}
+
+// GetByOrderID returns the history of an order, oldest first.
+func (r *OrderEventRepository) GetByOrderID(orderID uint) ([]OrderEvent, error) {
+	var events []OrderEvent
+	err := r.db.Where("order_id = ?", orderID).
+		Preload("Actor").
+		Order("created_at ASC, id ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// GetAfter returns up to limit events of any order with an ID above afterID,
+// in ID order.
+func (r *OrderEventRepository) GetAfter(afterID uint, limit int) ([]OrderEvent, error) {
+	var events []OrderEvent
+	err := r.db.Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// GetCursor returns the last event a consumer has handled, or 0 when it has
+// no cursor yet.
+func (r *OrderEventRepository) GetCursor(consumer string) (uint, error) {
+	var cursor EventCursor
+	err := r.db.Where("consumer = ?", consumer).Limit(1).Find(&cursor).Error
+	return cursor.LastEventID, err
+}
+
+// InitCursor places a consumer that has never run at the newest event, so it
+// starts with new events instead of the whole history. Existing cursors are
+// left alone.
+func (r *OrderEventRepository) InitCursor(consumer string) error {
+	var lastID uint
+	if err := r.db.Model(&OrderEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error; err != nil {
+		return err
+	}
+
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&EventCursor{Consumer: consumer, LastEventID: lastID}).Error
+}
+
+func (r *OrderEventRepository) SaveCursor(consumer string, eventID uint) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "consumer"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_event_id", "updated_at"}),
+	}).Create(&EventCursor{Consumer: consumer, LastEventID: eventID, UpdatedAt: time.Now()}).Error
+}
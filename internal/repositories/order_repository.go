@@ -2,10 +2,15 @@ package repositories
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrOrderNotFound is returned when an order does not exist or was deleted.
+var ErrOrderNotFound = errors.New("order not found")
+
 type OrderRepository struct {
 	db *gorm.DB
 }
@@ -24,10 +29,13 @@ func (r *OrderRepository) GetByID(id uint) (*Order, error) {
 		Preload("Table").
 		Preload("OrderItems").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Components.MenuItem").
+		Preload("Courses").
+		Preload("Delivery").
 		Preload("Payment").
 		First(&order, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("order not found")
+		return nil, ErrOrderNotFound
 	}
 	return &order, err
 }
@@ -117,6 +125,52 @@ func (r *OrderRepository) GetTakeawayOrdersReady() ([]Order, error) {
 	return orders, err
 }
 
+// GetPickupBoardOrders returns takeaway orders that are in the kitchen or
+// waiting for collection, without any customer relations.
+func (r *OrderRepository) GetPickupBoardOrders() ([]Order, error) {
+	var orders []Order
+	err := r.db.Where("order_type = ? AND status IN ? AND pickup_number <> ''", OrderTypeTakeaway,
+		[]OrderStatus{OrderStatusConfirmed, OrderStatusPreparing, OrderStatusReady}).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetByExternalID finds the order a delivery platform sent earlier under the
+// same order ID.
+func (r *OrderRepository) GetByExternalID(source OrderSource, externalID string) (*Order, error) {
+	var order Order
+	err := r.db.Preload("Payment").Where("source = ? AND external_id = ?", source, externalID).First(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return &order, err
+}
+
+// NextPickupSequence atomically increments and returns the pickup counter for
+// the given business date, starting at 1 on the first call of the day.
+func (r *OrderRepository) NextPickupSequence(businessDate string) (int, error) {
+	var counter PickupCounter
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "business_date"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"last_number": gorm.Expr("pickup_counters.last_number + 1")}),
+		}).Create(&PickupCounter{BusinessDate: businessDate, LastNumber: 1}).Error
+		if err != nil {
+			return err
+		}
+		return tx.First(&counter, "business_date = ?", businessDate).Error
+	})
+	return counter.LastNumber, err
+}
+
+func (r *OrderRepository) MarkCollected(orderID, version uint, collectedAt time.Time) error {
+	return versionedUpdate(r.db, &Order{}, orderID, version, map[string]interface{}{
+		"status":       OrderStatusServed,
+		"collected_at": collectedAt,
+	}, "order not found")
+}
+
 func (r *OrderRepository) GetAll(limit, offset int) ([]Order, error) {
 	var orders []Order
 	err := r.db.Preload("User").
@@ -131,12 +185,37 @@ func (r *OrderRepository) GetAll(limit, offset int) ([]Order, error) {
 	return orders, err
 }
 
-func (r *OrderRepository) Update(order *Order) error {
-	return r.db.Save(order).Error
+// UpdateStatus changes the status of an order that is still at the given
+// version, failing with ErrVersionConflict if it was changed meanwhile.
+func (r *OrderRepository) UpdateStatus(orderID, version uint, status OrderStatus) error {
+	updates := map[string]interface{}{"status": status}
+
+	// Kitchen timestamps feed the prep-time estimates
+	switch status {
+	case OrderStatusConfirmed:
+		updates["confirmed_at"] = time.Now()
+	case OrderStatusReady:
+		updates["ready_at"] = time.Now()
+	}
+
+	return versionedUpdate(r.db, &Order{}, orderID, version, updates, "order not found")
+}
+
+// UpdateEstimatedCompletionTime refreshes the ETA without bumping the
+// version: it is derived from the kitchen queue, not edited by staff.
+func (r *OrderRepository) UpdateEstimatedCompletionTime(orderID uint, estimated time.Time) error {
+	return r.db.Model(&Order{}).Where("id = ?", orderID).Update("estimated_completion_time", estimated).Error
 }
 
-func (r *OrderRepository) UpdateStatus(orderID uint, status OrderStatus) error {
-	return r.db.Model(&Order{}).Where("id = ?", orderID).Update("status", status).Error
+// GetQueuedOrders returns orders that have not left the kitchen yet, oldest
+// first, with their items so prep times can be estimated.
+func (r *OrderRepository) GetQueuedOrders() ([]Order, error) {
+	var orders []Order
+	err := r.db.Where("status IN ?", []OrderStatus{OrderStatusPending, OrderStatusConfirmed, OrderStatusPreparing}).
+		Preload("OrderItems").
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
 }
 
 func (r *OrderRepository) Delete(id uint) error {
@@ -145,7 +224,7 @@ func (r *OrderRepository) Delete(id uint) error {
 
 func (r *OrderRepository) GetActiveOrders() ([]Order, error) {
 	var orders []Order
-	err := r.db.Where("status IN ?", []OrderStatus{OrderStatusPending, OrderStatusConfirmed, OrderStatusPreparing}).
+	err := r.db.Where("status IN ?", []OrderStatus{OrderStatusPending, OrderStatusScheduled, OrderStatusConfirmed, OrderStatusPreparing}).
 		Preload("User").
 		Preload("Table").
 		Preload("OrderItems").
@@ -155,12 +234,23 @@ func (r *OrderRepository) GetActiveOrders() ([]Order, error) {
 	return orders, err
 }
 
+func (r *OrderRepository) GetActiveOrdersByUserID(userID uint) ([]Order, error) {
+	var orders []Order
+	err := r.db.Where("user_id = ? AND status IN ?", userID, []OrderStatus{OrderStatusPending, OrderStatusScheduled, OrderStatusConfirmed, OrderStatusPreparing, OrderStatusReady}).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
 func (r *OrderRepository) GetKitchenOrders() ([]Order, error) {
 	var orders []Order
 	err := r.db.Where("status IN ?", []OrderStatus{OrderStatusConfirmed, OrderStatusPreparing}).
 		Preload("Table").
 		Preload("OrderItems").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Components.MenuItem").
+		Preload("Courses").
+		Preload("Delivery").
 		Order("created_at ASC").
 		Find(&orders).Error
 	return orders, err
@@ -224,10 +314,13 @@ func (r *OrderRepository) GetByIDWithDetails(id uint) (*Order, error) {
 		Preload("OrderItems").
 		Preload("OrderItems.MenuItem").
 		Preload("OrderItems.MenuItem.Category").
+		Preload("OrderItems.Components.MenuItem.Category").
+		Preload("Courses").
+		Preload("Delivery").
 		Preload("Payment").
 		First(&order, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("order not found")
+		return nil, ErrOrderNotFound
 	}
 	return &order, err
 }
@@ -251,8 +344,13 @@ func (r *OrderRepository) GetDailyRevenue(from, to string) (map[string]interface
 }
 
 func (r *OrderRepository) UpdateOrderItems(orderID uint, items []OrderItem) error {
-	// Delete existing items
-	err := r.db.Where("order_id = ?", orderID).Delete(&OrderItem{}).Error
+	// Delete existing items, with the components of bundles
+	existing := r.db.Model(&OrderItem{}).Select("id").Where("order_id = ?", orderID)
+	err := r.db.Where("order_item_id IN (?)", existing).Delete(&OrderItemComponent{}).Error
+	if err != nil {
+		return err
+	}
+	err = r.db.Where("order_id = ?", orderID).Delete(&OrderItem{}).Error
 	if err != nil {
 		return err
 	}
@@ -269,6 +367,61 @@ func (r *OrderRepository) UpdateOrderItems(orderID uint, items []OrderItem) erro
 	return nil
 }
 
-func (r *OrderRepository) UpdateOrder(order *Order) error {
-	return r.db.Save(order).Error
+// AppendItems adds items to an order that is still at the given version and
+// stores its new totals and status, leaving the existing items untouched.
+func (r *OrderRepository) AppendItems(orderID, version uint, items []OrderItem, subtotal, vat, total float64, status OrderStatus) error {
+	err := versionedUpdate(r.db, &Order{}, orderID, version, map[string]interface{}{
+		"subtotal_amount": subtotal,
+		"vat_amount":      vat,
+		"total_amount":    total,
+		"status":          status,
+	}, "order not found")
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].OrderID = orderID
+	}
+	return r.db.Create(&items).Error
+}
+
+// UpdateAmounts rewrites the order totals without touching its relations.
+func (r *OrderRepository) UpdateAmounts(orderID, version uint, subtotal, vat, total float64) error {
+	return versionedUpdate(r.db, &Order{}, orderID, version, map[string]interface{}{
+		"subtotal_amount": subtotal,
+		"vat_amount":      vat,
+		"total_amount":    total,
+	}, "order not found")
+}
+
+// GetScheduledPickupTimes returns the pickup times of scheduled orders in
+// [from, to) that still hold their slot.
+func (r *OrderRepository) GetScheduledPickupTimes(from, to time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.db.Model(&Order{}).
+		Where("scheduled_for >= ? AND scheduled_for < ? AND status <> ?", from, to, OrderStatusCancelled).
+		Order("scheduled_for ASC").
+		Pluck("scheduled_for", &times).Error
+	return times, err
+}
+
+// LockPickupSlot locks the pickup slot starting at slotStart until the
+// surrounding transaction ends. Bookings into the same slot wait for each
+// other, so each one counts the orders committed before it.
+func (r *OrderRepository) LockPickupSlot(slotStart time.Time) error {
+	now := time.Now()
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slot_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"locked_at": now}),
+	}).Create(&PickupSlotLock{SlotStart: slotStart, LockedAt: now}).Error
+}
+
+// GetDueScheduledOrders returns scheduled orders whose release time has come.
+func (r *OrderRepository) GetDueScheduledOrders(now time.Time) ([]Order, error) {
+	var orders []Order
+	err := r.db.Where("status = ? AND release_at <= ?", OrderStatusScheduled, now).
+		Order("release_at ASC").
+		Find(&orders).Error
+	return orders, err
 }
@@ -65,12 +65,27 @@ func (r *PaymentRepository) GetByStatus(status PaymentStatus, limit, offset int)
 	return payments, err
 }
 
+// Update writes the payment columns if the payment is still at the version
+// that was read, failing with ErrVersionConflict if it was changed meanwhile.
 func (r *PaymentRepository) Update(payment *Payment) error {
-	return r.db.Save(payment).Error
+	err := versionedUpdate(r.db, &Payment{}, payment.ID, payment.Version, map[string]interface{}{
+		"method":          payment.Method,
+		"status":          payment.Status,
+		"amount":          payment.Amount,
+		"qris_data":       payment.QRISData,
+		"transaction_id":  payment.TransactionID,
+		"external_id":     payment.ExternalID,
+		"amount_tendered": payment.AmountTendered,
+		"change_amount":   payment.ChangeAmount,
+	}, "payment not found")
+	if err == nil {
+		payment.Version++
+	}
+	return err
 }
 
-func (r *PaymentRepository) UpdateStatus(paymentID uint, status PaymentStatus) error {
-	return r.db.Model(&Payment{}).Where("id = ?", paymentID).Update("status", status).Error
+func (r *PaymentRepository) UpdateStatus(paymentID, version uint, status PaymentStatus) error {
+	return versionedUpdate(r.db, &Payment{}, paymentID, version, map[string]interface{}{"status": status}, "payment not found")
 }
 
 func (r *PaymentRepository) Delete(id uint) error {
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PrepTimeRepository struct {
+	db *gorm.DB
+}
+
+// PrepTimeStat aggregates the prep-time samples of one menu item.
+type PrepTimeStat struct {
+	MenuItemID          uint    `json:"menu_item_id"`
+	MenuItemName        string  `json:"menu_item_name"`
+	SampleCount         int     `json:"sample_count"`
+	AvgActualSeconds    float64 `json:"avg_actual_seconds"`
+	AvgEstimatedSeconds float64 `json:"avg_estimated_seconds"`
+	OverrunCount        int     `json:"overrun_count"`
+}
+
+func NewPrepTimeRepository(db *gorm.DB) *PrepTimeRepository {
+	return &PrepTimeRepository{db: db}
+}
+
+func (r *PrepTimeRepository) CreateSamples(samples []PrepTimeSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	return r.db.Create(&samples).Error
+}
+
+// GetSampledOrderItems returns the IDs of the order's items that already
+// have a sample.
+func (r *PrepTimeRepository) GetSampledOrderItems(orderID uint) (map[uint]bool, error) {
+	var ids []uint
+	err := r.db.Model(&PrepTimeSample{}).
+		Where("order_id = ? AND order_item_id IS NOT NULL", orderID).
+		Pluck("order_item_id", &ids).Error
+
+	sampled := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		sampled[id] = true
+	}
+	return sampled, err
+}
+
+// GetRecentSamples returns up to limit of the newest samples per menu item.
+func (r *PrepTimeRepository) GetRecentSamples(menuItemIDs []uint, limit int) (map[uint][]PrepTimeSample, error) {
+	result := make(map[uint][]PrepTimeSample, len(menuItemIDs))
+	for _, id := range menuItemIDs {
+		var samples []PrepTimeSample
+		err := r.db.Where("menu_item_id = ?", id).
+			Order("created_at DESC").
+			Limit(limit).
+			Find(&samples).Error
+		if err != nil {
+			return nil, err
+		}
+		result[id] = samples
+	}
+	return result, nil
+}
+
+// GetStatsSince aggregates samples per menu item created after since. A sample
+// counts as an overrun when the actual time exceeded the estimate by more
+// than the given tolerance (e.g. 0.1 for 10%).
+func (r *PrepTimeRepository) GetStatsSince(since time.Time, tolerance float64) ([]PrepTimeStat, error) {
+	var stats []PrepTimeStat
+	err := r.db.Table("prep_time_samples").
+		Select(`prep_time_samples.menu_item_id,
+			menu_items.name AS menu_item_name,
+			COUNT(*) AS sample_count,
+			AVG(prep_time_samples.actual_seconds) AS avg_actual_seconds,
+			AVG(prep_time_samples.estimated_seconds) AS avg_estimated_seconds,
+			SUM(CASE WHEN prep_time_samples.actual_seconds > prep_time_samples.estimated_seconds * ? THEN 1 ELSE 0 END) AS overrun_count`, 1+tolerance).
+		Joins("JOIN menu_items ON menu_items.id = prep_time_samples.menu_item_id").
+		Where("prep_time_samples.created_at >= ?", since).
+		Group("prep_time_samples.menu_item_id, menu_items.name").
+		Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PrinterRepository struct {
+	db *gorm.DB
+}
+
+// PrinterQueueStats summarises the print queue of one printer.
+type PrinterQueueStats struct {
+	PrinterID    uint       `json:"printer_id"`
+	QueuedJobs   int        `json:"queued_jobs"`
+	FailedJobs   int        `json:"failed_jobs"`
+	OldestQueued *time.Time `json:"oldest_queued,omitempty"`
+}
+
+func NewPrinterRepository(db *gorm.DB) *PrinterRepository {
+	return &PrinterRepository{db: db}
+}
+
+func (r *PrinterRepository) Create(printer *Printer) error {
+	return r.db.Create(printer).Error
+}
+
+func (r *PrinterRepository) GetByID(id uint) (*Printer, error) {
+	var printer Printer
+	err := r.db.First(&printer, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("printer not found")
+	}
+	return &printer, err
+}
+
+func (r *PrinterRepository) GetAll() ([]Printer, error) {
+	var printers []Printer
+	err := r.db.Order("name ASC").Find(&printers).Error
+	return printers, err
+}
+
+func (r *PrinterRepository) GetActive() ([]Printer, error) {
+	var printers []Printer
+	err := r.db.Where("is_active = ?", true).Order("name ASC").Find(&printers).Error
+	return printers, err
+}
+
+func (r *PrinterRepository) GetActiveByTerminal(terminal string) (*Printer, error) {
+	var printer Printer
+	err := r.db.Where("terminal = ? AND is_active = ?", terminal, true).First(&printer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("no printer registered for this terminal")
+	}
+	return &printer, err
+}
+
+func (r *PrinterRepository) Update(printer *Printer) error {
+	return r.db.Save(printer).Error
+}
+
+func (r *PrinterRepository) Delete(id uint) error {
+	return r.db.Delete(&Printer{}, id).Error
+}
+
+// UpdateStatus records the outcome of the last contact with a printer.
+func (r *PrinterRepository) UpdateStatus(id uint, status PrinterStatus, lastError string, seenAt *time.Time) error {
+	updates := map[string]interface{}{
+		"status":     status,
+		"last_error": lastError,
+	}
+	if seenAt != nil {
+		updates["last_seen_at"] = *seenAt
+	}
+	return r.db.Model(&Printer{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *PrinterRepository) CreateJob(job *PrintJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *PrinterRepository) GetJobByID(id uint) (*PrintJob, error) {
+	var job PrintJob
+	err := r.db.Preload("Printer").First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("print job not found")
+	}
+	return &job, err
+}
+
+// HasOrderJob reports whether a job of the given kind was already queued for
+// the order's station, round and course, so each kitchen ticket of an order
+// is only printed once.
+func (r *PrinterRepository) HasOrderJob(orderID uint, kind PrintJobKind, station string, round int, course CourseType) (bool, error) {
+	var count int64
+	err := r.db.Model(&PrintJob{}).
+		Where("order_id = ? AND kind = ? AND station = ? AND round = ? AND course = ?", orderID, kind, station, round, course).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CancelUnprintedOrderJobs withdraws the jobs of the given kind for an order
+// that have not printed yet, so neither the worker nor a manual retry prints
+// them. It returns the number of jobs withdrawn.
+func (r *PrinterRepository) CancelUnprintedOrderJobs(orderID uint, kind PrintJobKind) (int64, error) {
+	result := r.db.Model(&PrintJob{}).
+		Where("order_id = ? AND kind = ? AND status IN ?", orderID, kind, []PrintJobStatus{PrintJobQueued, PrintJobFailed}).
+		Update("status", PrintJobCancelled)
+	return result.RowsAffected, result.Error
+}
+
+// GetPrintedOrderJobs returns the jobs of the given kind that were printed for
+// an order, with their printer preloaded.
+func (r *PrinterRepository) GetPrintedOrderJobs(orderID uint, kind PrintJobKind) ([]PrintJob, error) {
+	var jobs []PrintJob
+	err := r.db.Omit("data").
+		Preload("Printer").
+		Where("order_id = ? AND kind = ? AND status = ?", orderID, kind, PrintJobPrinted).
+		Order("id ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// GetOrderJobs returns the jobs of the given kind queued for an order, without
+// their rendered bytes.
+func (r *PrinterRepository) GetOrderJobs(orderID uint, kind PrintJobKind) ([]PrintJob, error) {
+	var jobs []PrintJob
+	err := r.db.Omit("data").
+		Where("order_id = ? AND kind = ?", orderID, kind).
+		Order("id ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// GetDueJobs returns queued jobs whose next attempt is due, oldest first, with
+// their printer preloaded. A job waits while an older queued job for the same
+// printer is backing off, so a printer's tickets come out in order and an
+// offline printer is tried once per retry rather than once per job.
+func (r *PrinterRepository) GetDueJobs(now time.Time, limit int) ([]PrintJob, error) {
+	var jobs []PrintJob
+	err := r.db.Preload("Printer").
+		Where("status = ? AND next_attempt_at <= ?", PrintJobQueued, now).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM print_jobs earlier
+			WHERE earlier.printer_id = print_jobs.printer_id AND earlier.id < print_jobs.id
+				AND earlier.status = ? AND earlier.next_attempt_at > ?
+		)`, PrintJobQueued, now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *PrinterRepository) UpdateJob(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&PrintJob{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *PrinterRepository) GetJobsPaginated(offset, limit int, status string, printerID uint) ([]PrintJob, int64, error) {
+	var jobs []PrintJob
+	var total int64
+
+	query := r.db.Model(&PrintJob{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if printerID != 0 {
+		query = query.Where("printer_id = ?", printerID)
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The rendered bytes are not part of the listing
+	err = query.Omit("data").
+		Preload("Printer").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&jobs).Error
+
+	return jobs, total, err
+}
+
+// GetQueueStats returns queued and failed job counts per printer.
+func (r *PrinterRepository) GetQueueStats() (map[uint]PrinterQueueStats, error) {
+	var jobs []PrintJob
+	err := r.db.Select("id, printer_id, status, created_at").
+		Where("status IN ?", []PrintJobStatus{PrintJobQueued, PrintJobFailed}).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint]PrinterQueueStats)
+	for _, job := range jobs {
+		stat := stats[job.PrinterID]
+		stat.PrinterID = job.PrinterID
+		if job.Status == PrintJobQueued {
+			stat.QueuedJobs++
+			if stat.OldestQueued == nil || job.CreatedAt.Before(*stat.OldestQueued) {
+				createdAt := job.CreatedAt
+				stat.OldestQueued = &createdAt
+			}
+		} else {
+			stat.FailedJobs++
+		}
+		stats[job.PrinterID] = stat
+	}
+	return stats, nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+)
+
+// UnitOfWork exposes repositories bound to a single database transaction.
+// They must not be used after the transaction function has returned.
+type UnitOfWork struct {
+	Orders        *OrderRepository
+	Payments      *PaymentRepository
+	Menu          *MenuRepository
+	MenuVersions  *MenuVersionRepository
+	Events        *OrderEventRepository
+	Cancellations *OrderCancellationRepository
+	Courses       *OrderCourseRepository
+	Deliveries    *DeliveryRepository
+	ItemMappings  *ExternalItemMappingRepository
+}
+
+// Transactor runs multi-step writes as one atomic unit of work.
+type Transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTransaction calls fn with transaction-scoped repositories. The
+// transaction is committed when fn returns nil and rolled back when it
+// returns an error or panics.
+func (t *Transactor) WithinTransaction(fn func(uow *UnitOfWork) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&UnitOfWork{
+			Orders:        NewOrderRepository(tx),
+			Payments:      NewPaymentRepository(tx),
+			Menu:          NewMenuRepository(tx),
+			MenuVersions:  NewMenuVersionRepository(tx),
+			Events:        NewOrderEventRepository(tx),
+			Cancellations: NewOrderCancellationRepository(tx),
+			Courses:       NewOrderCourseRepository(tx),
+			Deliveries:    NewDeliveryRepository(tx),
+			ItemMappings:  NewExternalItemMappingRepository(tx),
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned when a versioned write finds that the row
+// was changed by another request after the caller read it.
+var ErrVersionConflict = errors.New("record was modified by another request, reload it and try again")
+
+// versionedUpdate applies updates to the row with the given ID only if it is
+// still at the expected version, and bumps the version in the same statement.
+func versionedUpdate(db *gorm.DB, model interface{}, id, version uint, updates map[string]interface{}, notFound string) error {
+	updates["version"] = gorm.Expr("version + 1")
+
+	result := db.Model(model).Where("id = ? AND version = ?", id, version).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing matched: either the row is gone or someone else got there first
+	var count int64
+	if err := db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New(notFound)
+	}
+	return ErrVersionConflict
+}
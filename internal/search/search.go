@@ -0,0 +1,248 @@
+// Package search ranks and highlights menu search results. The database does
+// the ranking where it can (Postgres full-text search with trigram
+// similarity); Score is the in-process equivalent for databases without it.
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of the fields of a menu item, so a name match ranks above a
+// category match, which ranks above a description match.
+const (
+	WeightName        = 1.0
+	WeightCategory    = 0.6
+	WeightDescription = 0.3
+)
+
+// Field is a piece of text of a document and the weight its matches count for.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Terms splits a query into lowercase words.
+func Terms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// TSQuery turns a query into a Postgres tsquery where every word must match,
+// either exactly or as the start of a word, e.g. "nasi gor" -> "nasi:* & gor:*".
+// Words only hold letters and digits, so the result needs no further escaping.
+func TSQuery(query string) string {
+	terms := Terms(query)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Score rates how well a query matches a document, or returns 0 when some
+// word of the query matches nowhere. Each word counts with its best match:
+// exact, then as a prefix, then within a typo or two.
+func Score(query string, fields []Field) float64 {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return 0
+	}
+
+	var score float64
+	for _, term := range terms {
+		best := 0.0
+		for _, field := range fields {
+			for _, word := range Terms(field.Text) {
+				if quality := matchQuality(term, word) * field.Weight; quality > best {
+					best = quality
+				}
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		score += best
+	}
+	return score / float64(len(terms))
+}
+
+// matchQuality rates how well a word of the text matches a query term, from
+// 1 for the same word down to 0 for no match.
+func matchQuality(term, word string) float64 {
+	switch {
+	case term == word:
+		return 1
+	case strings.HasPrefix(word, term):
+		return 0.8
+	}
+
+	distance := editDistance(term, word)
+	switch {
+	case distance <= typosAllowed(term):
+		return 0.7 - 0.15*float64(distance-1)
+	case len([]rune(term)) >= 4 && len([]rune(word)) > len([]rune(term)):
+		// A misspelt prefix, e.g. "gorn" while typing "goreng"
+		prefix := string([]rune(word)[:len([]rune(term))])
+		if editDistance(term, prefix) <= typosAllowed(term) {
+			return 0.5
+		}
+	}
+	return 0
+}
+
+// typosAllowed is how many edits a term may be away from a word: none for
+// short words, where a typo usually makes another word.
+func typosAllowed(term string) int {
+	switch n := len([]rune(term)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance is the Damerau-Levenshtein distance between two words, with
+// swapped neighbouring letters counting as one edit.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// Highlight HTML-escapes text and wraps the words that match the query in
+// <mark> tags.
+func Highlight(text, query string) string {
+	terms := Terms(query)
+	var b strings.Builder
+	for _, token := range tokenize(text) {
+		if token.word && matches(terms, strings.ToLower(token.text)) {
+			b.WriteString("<mark>" + html.EscapeString(token.text) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(token.text))
+		}
+	}
+	return b.String()
+}
+
+// Snippet returns about the given number of words of text around its first
+// match of the query, highlighted, with "…" where text was cut. It returns
+// an empty string when the query does not match the text.
+func Snippet(text, query string, words int) string {
+	terms := Terms(query)
+	tokens := tokenize(text)
+
+	var wordAt []int // Index in tokens of each word
+	first := -1
+	for i, token := range tokens {
+		if !token.word {
+			continue
+		}
+		if first < 0 && matches(terms, strings.ToLower(token.text)) {
+			first = len(wordAt)
+		}
+		wordAt = append(wordAt, i)
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := max(first-words/3, 0)
+	end := min(start+words, len(wordAt))
+	start = max(end-words, 0)
+
+	from, to := wordAt[start], wordAt[end-1]+1
+	snippet := Highlight(joinTokens(tokens[from:to]), query)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(wordAt) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func matches(terms []string, word string) bool {
+	for _, term := range terms {
+		if matchQuality(term, word) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type token struct {
+	text string
+	word bool
+}
+
+// tokenize splits text into words and the text between them, so it can be
+// put back together unchanged.
+func tokenize(text string) []token {
+	var tokens []token
+	var current []rune
+	inWord := false
+	for _, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if len(current) > 0 && isWord != inWord {
+			tokens = append(tokens, token{text: string(current), word: inWord})
+			current = current[:0]
+		}
+		current = append(current, r)
+		inWord = isWord
+	}
+	if len(current) > 0 {
+		tokens = append(tokens, token{text: string(current), word: inWord})
+	}
+	return tokens
+}
+
+func joinTokens(tokens []token) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString(token.text)
+	}
+	return b.String()
+}
+
+// Hit is a document matched by a search and its score.
+type Hit struct {
+	Index int // Position of the document in the searched slice
+	Score float64
+}
+
+// Rank scores every document against the query and returns those that match,
+// best first.
+func Rank(query string, documents [][]Field) []Hit {
+	var hits []Hit
+	for i, fields := range documents {
+		if score := Score(query, fields); score > 0 {
+			hits = append(hits, Hit{Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits
+}
@@ -149,6 +149,8 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 			userRole = repositories.RoleStaff
 		case "cashier":
 			userRole = repositories.RoleCashier
+		case "driver":
+			userRole = repositories.RoleDriver
 		case "customer":
 			userRole = repositories.RoleCustomer
 		default:
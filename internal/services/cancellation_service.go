@@ -0,0 +1,307 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+var (
+	// ErrInvalidCancellationReason is returned for an unknown reason code or
+	// for "other" without a note.
+	ErrInvalidCancellationReason = errors.New("invalid cancellation reason")
+	// ErrOrderNotCancellable is returned for orders that were already served
+	// or cancelled.
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+	// ErrCancellationPending is returned when an order already has a
+	// cancellation waiting for a manager.
+	ErrCancellationPending = errors.New("order already has a cancellation waiting for approval")
+	// ErrCancellationDecided is returned when approving or rejecting a
+	// cancellation that is no longer waiting for approval.
+	ErrCancellationDecided = errors.New("cancellation was already decided")
+	// ErrCancelWithReason is returned when a status update tries to cancel an
+	// order, which would skip the reason, approval and refund.
+	ErrCancelWithReason = errors.New("orders are cancelled through the cancel endpoint with a reason code")
+)
+
+var cancellationReasons = map[repositories.CancellationReason]string{
+	repositories.CancellationCustomerRequest: "Customer request",
+	repositories.CancellationCustomerNoShow:  "Customer did not show up",
+	repositories.CancellationOutOfStock:      "Out of stock",
+	repositories.CancellationKitchenError:    "Kitchen error",
+	repositories.CancellationPaymentIssue:    "Payment issue",
+	repositories.CancellationDuplicateOrder:  "Duplicate order",
+	repositories.CancellationOther:           "Other",
+}
+
+// CancellationService cancels orders with a reason code. Orders the kitchen
+// has not started are cancelled straight away; once an order is preparing a
+// manager (admin) has to approve. Paid orders are refunded in the same
+// transaction, and the kitchen is told to stop the ticket.
+//
+// Returning stock is not implemented: the system has no stock or inventory
+// records to return it to, and adding them is a feature of its own. The
+// cancelled items stay on the order, with the reason, for whoever reconciles
+// stock by hand.
+type CancellationService struct {
+	cancellationRepo *repositories.OrderCancellationRepository
+	orderRepo        *repositories.OrderRepository
+	paymentService   *PaymentService
+	kitchenService   *KitchenService
+	transactor       *repositories.Transactor
+}
+
+type CancelOrderRequest struct {
+	ReasonCode repositories.CancellationReason `json:"reason_code" binding:"required"`
+	Note       string                          `json:"note"`
+}
+
+type CancellationDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+func NewCancellationService(cancellationRepo *repositories.OrderCancellationRepository, orderRepo *repositories.OrderRepository, paymentService *PaymentService, kitchenService *KitchenService, transactor *repositories.Transactor) *CancellationService {
+	return &CancellationService{
+		cancellationRepo: cancellationRepo,
+		orderRepo:        orderRepo,
+		paymentService:   paymentService,
+		kitchenService:   kitchenService,
+		transactor:       transactor,
+	}
+}
+
+// CancelOrder cancels an order, or files the cancellation for approval when
+// the kitchen has started on the order and the requester is not a manager.
+// The returned cancellation's status tells the two apart.
+func (s *CancellationService) CancelOrder(orderID uint, req *CancelOrderRequest, expectedVersion, actorID uint, actorRole string) (*repositories.OrderCancellation, error) {
+	req.Note = strings.TrimSpace(req.Note)
+	if err := validateCancellationReason(req); err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(expectedVersion, order.Version); err != nil {
+		return nil, err
+	}
+
+	if err := checkCancellable(order); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.cancellationRepo.GetPendingByOrderID(orderID); err == nil {
+		return nil, ErrCancellationPending
+	}
+
+	cancellation := &repositories.OrderCancellation{
+		OrderID:       orderID,
+		ReasonCode:    req.ReasonCode,
+		Note:          req.Note,
+		OrderStatus:   order.Status,
+		RequestedByID: actorID,
+	}
+
+	if needsApproval(order) && actorRole != string(repositories.RoleAdmin) {
+		cancellation.Status = repositories.CancellationPendingApproval
+		err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+			if err := uow.Cancellations.Create(cancellation); err != nil {
+				return errors.New("failed to create cancellation")
+			}
+
+			return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+				OrderID:       orderID,
+				Type:          repositories.OrderEventCancellationRequest,
+				PreviousValue: string(order.Status),
+				Reason:        describeCancellation(cancellation),
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return cancellation, nil
+	}
+
+	// Managers approve their own cancellations
+	if needsApproval(order) {
+		now := time.Now()
+		cancellation.DecidedByID = &actorID
+		cancellation.DecidedAt = &now
+	}
+
+	if err := s.complete(order, cancellation, actorID); err != nil {
+		return nil, err
+	}
+	return cancellation, nil
+}
+
+// ApproveCancellation lets a manager carry out a cancellation that was waiting
+// for approval.
+func (s *CancellationService) ApproveCancellation(id uint, note string, actorID uint) (*repositories.OrderCancellation, error) {
+	cancellation, err := s.cancellationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cancellation.Status != repositories.CancellationPendingApproval {
+		return nil, ErrCancellationDecided
+	}
+
+	order, err := s.orderRepo.GetByID(cancellation.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCancellable(order); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cancellation.DecidedByID = &actorID
+	cancellation.DecidedAt = &now
+	cancellation.DecisionNote = strings.TrimSpace(note)
+
+	if err := s.complete(order, cancellation, actorID); err != nil {
+		return nil, err
+	}
+	return s.cancellationRepo.GetByID(id)
+}
+
+// RejectCancellation turns down a cancellation that was waiting for approval.
+// The order carries on as before.
+func (s *CancellationService) RejectCancellation(id uint, note string, actorID uint) (*repositories.OrderCancellation, error) {
+	cancellation, err := s.cancellationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cancellation.Status != repositories.CancellationPendingApproval {
+		return nil, ErrCancellationDecided
+	}
+
+	now := time.Now()
+	cancellation.Status = repositories.CancellationRejected
+	cancellation.DecidedByID = &actorID
+	cancellation.DecidedAt = &now
+	cancellation.DecisionNote = strings.TrimSpace(note)
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Cancellations.Update(cancellation); err != nil {
+			return errors.New("failed to update cancellation")
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID: cancellation.OrderID,
+			Type:    repositories.OrderEventCancellationRejected,
+			Reason:  cancellation.DecisionNote,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cancellationRepo.GetByID(id)
+}
+
+func (s *CancellationService) GetCancellations(page, limit int, status string) ([]repositories.OrderCancellation, int64, error) {
+	offset := (page - 1) * limit
+	return s.cancellationRepo.GetPaginated(offset, limit, status)
+}
+
+func (s *CancellationService) GetCancellationByID(id uint) (*repositories.OrderCancellation, error) {
+	return s.cancellationRepo.GetByID(id)
+}
+
+// complete cancels the order, settles its payment and stores the
+// cancellation in one transaction, then tells the kitchen.
+func (s *CancellationService) complete(order *repositories.Order, cancellation *repositories.OrderCancellation, actorID uint) error {
+	reason := describeCancellation(cancellation)
+	cancellation.Status = repositories.CancellationCompleted
+
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateStatus(order.ID, order.Version, repositories.OrderStatusCancelled); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+
+		if err := recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       order.ID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(repositories.OrderStatusCancelled),
+			Reason:        reason,
+		}); err != nil {
+			return err
+		}
+
+		if payment, err := uow.Payments.GetByOrderID(order.ID); err == nil {
+			switch payment.Status {
+			case repositories.PaymentStatusCompleted:
+				if err := s.paymentService.refundWithin(uow, payment, reason, actorID); err != nil {
+					return err
+				}
+				cancellation.RefundAmount = payment.Amount
+			case repositories.PaymentStatusPending:
+				if err := s.paymentService.cancelPendingWithin(uow, payment, reason, actorID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if cancellation.ID == 0 {
+			if err := uow.Cancellations.Create(cancellation); err != nil {
+				return errors.New("failed to create cancellation")
+			}
+			return nil
+		}
+		if err := uow.Cancellations.Update(cancellation); err != nil {
+			return errors.New("failed to update cancellation")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The kitchen display drops the order and the print queue voids its tickets
+	s.kitchenService.BroadcastStatusChange(order.ID)
+	return nil
+}
+
+// describeCancellation renders the reason as recorded in the order history,
+// e.g. "Out of stock: no more rendang".
+func describeCancellation(cancellation *repositories.OrderCancellation) string {
+	label := cancellationReasons[cancellation.ReasonCode]
+	if cancellation.Note == "" {
+		return label
+	}
+	return label + ": " + cancellation.Note
+}
+
+func validateCancellationReason(req *CancelOrderRequest) error {
+	if _, ok := cancellationReasons[req.ReasonCode]; !ok {
+		return ErrInvalidCancellationReason
+	}
+	if req.ReasonCode == repositories.CancellationOther && req.Note == "" {
+		return fmt.Errorf("%w: a note is required for reason \"other\"", ErrInvalidCancellationReason)
+	}
+	return nil
+}
+
+func checkCancellable(order *repositories.Order) error {
+	switch order.Status {
+	case repositories.OrderStatusServed, repositories.OrderStatusCancelled:
+		return fmt.Errorf("%w: order is %s", ErrOrderNotCancellable, order.Status)
+	}
+	return nil
+}
+
+// needsApproval reports whether the kitchen has started on the order, after
+// which only a manager may cancel it.
+func needsApproval(order *repositories.Order) bool {
+	return order.Status == repositories.OrderStatusPreparing || order.Status == repositories.OrderStatusReady
+}
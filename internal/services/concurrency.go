@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+
+	"recursiveDine/internal/repositories"
+)
+
+// checkVersion enforces an If-Match precondition. An expected version of 0
+// means the client sent none and the write only guards against races.
+func checkVersion(expected, current uint) error {
+	if expected != 0 && expected != current {
+		return repositories.ErrVersionConflict
+	}
+	return nil
+}
+
+// writeError hides storage errors behind msg but keeps version conflicts
+// intact so the API can answer them with 409 Conflict.
+func writeError(err error, msg string) error {
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// IsVersionConflict reports whether err means the record changed since the
+// caller read it.
+func IsVersionConflict(err error) bool {
+	return errors.Is(err, repositories.ErrVersionConflict)
+}
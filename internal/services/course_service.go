@@ -0,0 +1,366 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/repositories"
+)
+
+// courseFirePollInterval is how often the worker looks for courses that are
+// due to fire automatically.
+const courseFirePollInterval = 15 * time.Second
+
+// ErrInvalidCourse is returned for a course other than starter, main or
+// dessert.
+var ErrInvalidCourse = errors.New("invalid course. Must be 'starter', 'main' or 'dessert'")
+
+// CourseService paces the courses of dine-in orders. Items can be grouped into
+// starters, mains and desserts; a course only reaches the kitchen once it is
+// fired. The first course fires when the order reaches the kitchen unless a
+// waiter holds it. With COURSE_AUTO_FIRE_MINUTES set, the next course fires by
+// itself that many minutes after the previous one is served; otherwise
+// waiters fire it by hand.
+type CourseService struct {
+	courseRepo     *repositories.OrderCourseRepository
+	orderRepo      *repositories.OrderRepository
+	kitchenService *KitchenService
+	transactor     *repositories.Transactor
+	config         *config.Config
+}
+
+func NewCourseService(courseRepo *repositories.OrderCourseRepository, orderRepo *repositories.OrderRepository, kitchenService *KitchenService, transactor *repositories.Transactor, config *config.Config) *CourseService {
+	return &CourseService{
+		courseRepo:     courseRepo,
+		orderRepo:      orderRepo,
+		kitchenService: kitchenService,
+		transactor:     transactor,
+		config:         config,
+	}
+}
+
+// Start fires courses whose automatic firing time has passed. The first
+// course and the scheduling of the next one are handled by the status changes
+// themselves, see paceCourses.
+func (s *CourseService) Start() {
+	go func() {
+		ticker := time.NewTicker(courseFirePollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.FireDueCourses(); err != nil {
+				log.Printf("Error firing due courses: %v", err)
+			}
+		}
+	}()
+}
+
+// HoldCourse keeps a course from firing until a waiter fires it.
+func (s *CourseService) HoldCourse(orderID uint, course repositories.CourseType, actorID uint) (*repositories.Order, error) {
+	order, target, err := s.getCourse(orderID, course)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == repositories.OrderStatusCancelled {
+		return nil, errors.New("order is cancelled")
+	}
+	if target.Status != repositories.CourseWaiting {
+		return nil, fmt.Errorf("cannot hold a course that is %s", target.Status)
+	}
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Courses.Transition(target.ID, []repositories.CourseStatus{repositories.CourseWaiting}, map[string]interface{}{
+			"status":  repositories.CourseHeld,
+			"fire_at": nil,
+		}); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventCourseHeld,
+			PreviousValue: string(target.Status),
+			NewValue:      string(course),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.kitchenService.BroadcastOrderUpdate(orderID, "course_held")
+	return s.orderRepo.GetByIDWithDetails(orderID)
+}
+
+// FireCourse sends a waiting or held course to the kitchen. An order that was
+// ready or served goes back to preparing.
+func (s *CourseService) FireCourse(orderID uint, course repositories.CourseType, actorID uint) (*repositories.Order, error) {
+	order, target, err := s.getCourse(orderID, course)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fire(order, target, actorID, ""); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetByIDWithDetails(orderID)
+}
+
+// ServeCourse records that a fired course reached the table. The next course
+// is scheduled to fire automatically when auto-firing is configured.
+func (s *CourseService) ServeCourse(orderID uint, course repositories.CourseType, actorID uint) (*repositories.Order, error) {
+	order, target, err := s.getCourse(orderID, course)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Status != repositories.CourseFired {
+		return nil, fmt.Errorf("cannot serve a course that is %s", target.Status)
+	}
+
+	if err := s.serve(order, target, actorID); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetByIDWithDetails(orderID)
+}
+
+// FireDueCourses fires the courses whose automatic firing time has passed.
+func (s *CourseService) FireDueCourses() error {
+	due, err := s.courseRepo.GetDue(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, course := range due {
+		order, err := s.orderRepo.GetByID(course.OrderID)
+		if err != nil {
+			log.Printf("Error loading order %d to fire its %s: %v", course.OrderID, course.Course, err)
+			continue
+		}
+
+		err = s.fire(order, &course, 0, fmt.Sprintf("fired automatically %d minutes after the previous course was served", s.config.CourseAutoFireMinutes))
+		if err != nil && !errors.Is(err, repositories.ErrCourseChanged) {
+			log.Printf("Error firing %s of order %d: %v", course.Course, course.OrderID, err)
+		}
+	}
+	return nil
+}
+
+func (s *CourseService) fire(order *repositories.Order, course *repositories.OrderCourse, actorID uint, reason string) error {
+	switch order.Status {
+	case repositories.OrderStatusPending:
+		return errors.New("order has not been sent to the kitchen yet")
+	case repositories.OrderStatusCancelled:
+		return errors.New("order is cancelled")
+	}
+	if course.Status != repositories.CourseWaiting && course.Status != repositories.CourseHeld {
+		return fmt.Errorf("cannot fire a course that is %s", course.Status)
+	}
+
+	status := order.Status
+	if status == repositories.OrderStatusReady || status == repositories.OrderStatusServed {
+		status = repositories.OrderStatusPreparing
+	}
+
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := fireCourse(uow, order.ID, course, actorID, reason); err != nil {
+			return err
+		}
+
+		if status == order.Status {
+			return nil
+		}
+		if err := uow.Orders.UpdateStatus(order.ID, order.Version, status); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       order.ID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(status),
+			Reason:        fmt.Sprintf("%s fired", course.Course),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.kitchenService.BroadcastOrderUpdate(order.ID, "course_fired")
+	return nil
+}
+
+func (s *CourseService) serve(order *repositories.Order, course *repositories.OrderCourse, actorID uint) error {
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		return serveCourse(uow, order.ID, order.Courses, course, actorID, s.config)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.kitchenService.BroadcastOrderUpdate(order.ID, "course_served")
+	return nil
+}
+
+// paceCourses keeps the courses of an order in step with a status change made
+// in the same transaction. The first course fires when the order reaches the
+// kitchen, and fired courses are served with the order, which schedules the
+// next course. Orders without courses are left alone.
+func paceCourses(uow *repositories.UnitOfWork, orderID uint, status repositories.OrderStatus, actorID uint, cfg *config.Config) error {
+	if status != repositories.OrderStatusConfirmed && status != repositories.OrderStatusServed {
+		return nil
+	}
+
+	courses, err := uow.Courses.GetByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+	courses = sortedCourses(courses)
+	if len(courses) == 0 {
+		return nil
+	}
+
+	if status == repositories.OrderStatusConfirmed {
+		// A waiter may hold the first course, or have fired one already
+		for _, course := range courses {
+			if course.Status == repositories.CourseFired || course.Status == repositories.CourseServed {
+				return nil
+			}
+		}
+		if courses[0].Status != repositories.CourseWaiting {
+			return nil
+		}
+		return fireCourse(uow, orderID, &courses[0], actorID, "order reached the kitchen")
+	}
+
+	for i := range courses {
+		if courses[i].Status != repositories.CourseFired {
+			continue
+		}
+		if err := serveCourse(uow, orderID, courses, &courses[i], actorID, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fireCourse sends a waiting or held course to the kitchen. The print queue
+// picks up the course's tickets from the event it records.
+func fireCourse(uow *repositories.UnitOfWork, orderID uint, course *repositories.OrderCourse, actorID uint, reason string) error {
+	if err := uow.Courses.Transition(course.ID, []repositories.CourseStatus{repositories.CourseWaiting, repositories.CourseHeld}, map[string]interface{}{
+		"status":   repositories.CourseFired,
+		"fired_at": time.Now(),
+		"fire_at":  nil,
+	}); err != nil {
+		return err
+	}
+
+	return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+		OrderID:       orderID,
+		Type:          repositories.OrderEventCourseFired,
+		PreviousValue: string(course.Status),
+		NewValue:      string(course.Course),
+		Reason:        reason,
+	})
+}
+
+// serveCourse marks a fired course served and, with auto-firing configured,
+// sets when the next waiting course fires.
+func serveCourse(uow *repositories.UnitOfWork, orderID uint, courses []repositories.OrderCourse, course *repositories.OrderCourse, actorID uint, cfg *config.Config) error {
+	now := time.Now()
+	if err := uow.Courses.Transition(course.ID, []repositories.CourseStatus{repositories.CourseFired}, map[string]interface{}{
+		"status":    repositories.CourseServed,
+		"served_at": now,
+	}); err != nil {
+		return err
+	}
+
+	var reason string
+	next := nextCourse(courses, course.Course)
+	if next != nil && next.Status == repositories.CourseWaiting && cfg.CourseAutoFireMinutes > 0 {
+		fireAt := now.Add(time.Duration(cfg.CourseAutoFireMinutes) * time.Minute)
+		if err := uow.Courses.Transition(next.ID, []repositories.CourseStatus{repositories.CourseWaiting}, map[string]interface{}{
+			"fire_at": fireAt,
+		}); err != nil && !errors.Is(err, repositories.ErrCourseChanged) {
+			return err
+		}
+		reason = fmt.Sprintf("%s fires at %s", next.Course, fireAt.In(cfg.Location()).Format("15:04"))
+	}
+
+	return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+		OrderID:       orderID,
+		Type:          repositories.OrderEventCourseServed,
+		PreviousValue: string(course.Status),
+		NewValue:      string(course.Course),
+		Reason:        reason,
+	})
+}
+
+func (s *CourseService) getCourse(orderID uint, course repositories.CourseType) (*repositories.Order, *repositories.OrderCourse, error) {
+	if _, ok := repositories.CourseSequence[course]; !ok {
+		return nil, nil, ErrInvalidCourse
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range order.Courses {
+		if order.Courses[i].Course == course {
+			return order, &order.Courses[i], nil
+		}
+	}
+	return nil, nil, errors.New("course not found")
+}
+
+// orderCourses validates the courses of new order items and returns a
+// waiting course for each course used, in serving order. Items without a
+// course go to the kitchen with the order.
+func orderCourses(orderType repositories.OrderType, items []CreateOrderItemRequest) ([]repositories.OrderCourse, error) {
+	used := make([]repositories.CourseType, 0, len(items))
+	for _, item := range items {
+		used = append(used, item.Course)
+	}
+	return planCourses(orderType, used)
+}
+
+func planCourses(orderType repositories.OrderType, used []repositories.CourseType) ([]repositories.OrderCourse, error) {
+	seen := make(map[repositories.CourseType]bool)
+	var courses []repositories.OrderCourse
+	for _, course := range used {
+		if course == "" || seen[course] {
+			continue
+		}
+		if _, ok := repositories.CourseSequence[course]; !ok {
+			return nil, ErrInvalidCourse
+		}
+		if orderType != repositories.OrderTypeDineIn {
+			return nil, errors.New("courses are only available for dine-in orders")
+		}
+		seen[course] = true
+		courses = append(courses, repositories.OrderCourse{Course: course, Status: repositories.CourseWaiting})
+	}
+	return sortedCourses(courses), nil
+}
+
+func sortedCourses(courses []repositories.OrderCourse) []repositories.OrderCourse {
+	sorted := append([]repositories.OrderCourse(nil), courses...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return repositories.CourseSequence[sorted[i].Course] < repositories.CourseSequence[sorted[j].Course]
+	})
+	return sorted
+}
+
+// nextCourse returns the course served after the given one, if the order has
+// one.
+func nextCourse(courses []repositories.OrderCourse, after repositories.CourseType) *repositories.OrderCourse {
+	for _, course := range sortedCourses(courses) {
+		if repositories.CourseSequence[course.Course] > repositories.CourseSequence[after] {
+			return &course
+		}
+	}
+	return nil
+}
@@ -0,0 +1,521 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+// earthRadiusKm is the mean Earth radius used for radius zones.
+const earthRadiusKm = 6371.0
+
+// ErrOutsideDeliveryArea is returned for an address no active zone covers.
+var ErrOutsideDeliveryArea = errors.New("address is outside our delivery area")
+
+// DeliveryService manages delivery zones, customers' saved addresses and the
+// driver side of delivery orders: assignment, pickup and drop-off.
+type DeliveryService struct {
+	deliveryRepo   *repositories.DeliveryRepository
+	orderRepo      *repositories.OrderRepository
+	userRepo       *repositories.UserRepository
+	kitchenService *KitchenService
+	transactor     *repositories.Transactor
+}
+
+// DeliveryRequest is where a delivery order goes: a saved address of the
+// customer or one entered with the order.
+type DeliveryRequest struct {
+	AddressID uint    `json:"address_id"`
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Notes     string  `json:"notes"` // Directions for the driver
+}
+
+// DeliveryZoneRequest describes a zone as either a circle around a center
+// point or a polygon of [latitude, longitude] points.
+type DeliveryZoneRequest struct {
+	Name         string                        `json:"name" binding:"required"`
+	Type         repositories.DeliveryZoneType `json:"type" binding:"required"`
+	CenterLat    float64                       `json:"center_lat"`
+	CenterLng    float64                       `json:"center_lng"`
+	RadiusKm     float64                       `json:"radius_km"`
+	Polygon      []repositories.GeoPoint       `json:"polygon"`
+	Fee          float64                       `json:"fee"`
+	MinimumOrder float64                       `json:"minimum_order"`
+	Priority     int                           `json:"priority"` // Lower numbers are matched first where zones overlap
+	IsActive     *bool                         `json:"is_active"`
+}
+
+type AddressRequest struct {
+	Label     string  `json:"label"`
+	Address   string  `json:"address" binding:"required"`
+	Latitude  float64 `json:"latitude" binding:"required"`
+	Longitude float64 `json:"longitude" binding:"required"`
+	Notes     string  `json:"notes"`
+}
+
+type AssignDriverRequest struct {
+	DriverID uint `json:"driver_id" binding:"required"`
+}
+
+// DeliveryQuote tells a customer whether an address is delivered to and at
+// what fee, before they place the order.
+type DeliveryQuote struct {
+	ZoneID       uint    `json:"zone_id"`
+	ZoneName     string  `json:"zone_name"`
+	Fee          float64 `json:"fee"`
+	MinimumOrder float64 `json:"minimum_order"`
+}
+
+func NewDeliveryService(deliveryRepo *repositories.DeliveryRepository, orderRepo *repositories.OrderRepository, userRepo *repositories.UserRepository, kitchenService *KitchenService, transactor *repositories.Transactor) *DeliveryService {
+	return &DeliveryService{
+		deliveryRepo:   deliveryRepo,
+		orderRepo:      orderRepo,
+		userRepo:       userRepo,
+		kitchenService: kitchenService,
+		transactor:     transactor,
+	}
+}
+
+// Zone management
+
+func (s *DeliveryService) GetZones() ([]repositories.DeliveryZone, error) {
+	return s.deliveryRepo.GetZones()
+}
+
+func (s *DeliveryService) GetZoneByID(id uint) (*repositories.DeliveryZone, error) {
+	return s.deliveryRepo.GetZoneByID(id)
+}
+
+func (s *DeliveryService) CreateZone(req *DeliveryZoneRequest) (*repositories.DeliveryZone, error) {
+	zone := &repositories.DeliveryZone{IsActive: true}
+	if err := applyZoneRequest(zone, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.deliveryRepo.CreateZone(zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (s *DeliveryService) UpdateZone(id uint, req *DeliveryZoneRequest) (*repositories.DeliveryZone, error) {
+	zone, err := s.deliveryRepo.GetZoneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyZoneRequest(zone, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.deliveryRepo.UpdateZone(zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (s *DeliveryService) DeleteZone(id uint) error {
+	if _, err := s.deliveryRepo.GetZoneByID(id); err != nil {
+		return err
+	}
+	return s.deliveryRepo.DeleteZone(id)
+}
+
+// QuoteDelivery finds the zone covering a location and its fee.
+func (s *DeliveryService) QuoteDelivery(latitude, longitude float64) (*DeliveryQuote, error) {
+	zones, err := s.deliveryRepo.GetActiveZones()
+	if err != nil {
+		return nil, errors.New("failed to fetch delivery zones")
+	}
+
+	zone := matchZone(zones, latitude, longitude)
+	if zone == nil {
+		return nil, ErrOutsideDeliveryArea
+	}
+	return &DeliveryQuote{ZoneID: zone.ID, ZoneName: zone.Name, Fee: zone.Fee, MinimumOrder: zone.MinimumOrder}, nil
+}
+
+// Saved addresses
+
+func (s *DeliveryService) GetAddresses(userID uint) ([]repositories.CustomerAddress, error) {
+	return s.deliveryRepo.GetAddressesByUserID(userID)
+}
+
+func (s *DeliveryService) CreateAddress(userID uint, req *AddressRequest) (*repositories.CustomerAddress, error) {
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		return nil, err
+	}
+
+	address := &repositories.CustomerAddress{
+		UserID:    userID,
+		Label:     req.Label,
+		Address:   req.Address,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Notes:     req.Notes,
+	}
+	if err := s.deliveryRepo.CreateAddress(address); err != nil {
+		return nil, errors.New("failed to save address")
+	}
+	return address, nil
+}
+
+func (s *DeliveryService) UpdateAddress(id, userID uint, req *AddressRequest) (*repositories.CustomerAddress, error) {
+	address, err := s.deliveryRepo.GetAddress(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		return nil, err
+	}
+
+	address.Label = req.Label
+	address.Address = req.Address
+	address.Latitude = req.Latitude
+	address.Longitude = req.Longitude
+	address.Notes = req.Notes
+	if err := s.deliveryRepo.UpdateAddress(address); err != nil {
+		return nil, errors.New("failed to update address")
+	}
+	return address, nil
+}
+
+func (s *DeliveryService) DeleteAddress(id, userID uint) error {
+	return s.deliveryRepo.DeleteAddress(id, userID)
+}
+
+// Driver flow
+
+// GetDeliveries lists the deliveries that are not delivered yet, for the
+// dispatch screen.
+func (s *DeliveryService) GetDeliveries() ([]repositories.OrderDelivery, error) {
+	return s.deliveryRepo.GetByStatus([]repositories.DeliveryStatus{
+		repositories.DeliveryPending,
+		repositories.DeliveryAssigned,
+		repositories.DeliveryPickedUp,
+	})
+}
+
+// GetDriverDeliveries lists the deliveries a driver still has to complete.
+func (s *DeliveryService) GetDriverDeliveries(driverID uint) ([]repositories.OrderDelivery, error) {
+	return s.deliveryRepo.GetByDriverID(driverID, []repositories.DeliveryStatus{
+		repositories.DeliveryAssigned,
+		repositories.DeliveryPickedUp,
+	})
+}
+
+// AssignDriver gives a delivery to a driver, or to another driver before it
+// has been picked up.
+func (s *DeliveryService) AssignDriver(orderID, driverID, actorID uint) (*repositories.OrderDelivery, error) {
+	order, delivery, err := s.getDelivery(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case repositories.OrderStatusPending, repositories.OrderStatusScheduled:
+		return nil, errors.New("order has not been sent to the kitchen yet")
+	case repositories.OrderStatusServed, repositories.OrderStatusCancelled:
+		return nil, fmt.Errorf("cannot assign a driver to a %s order", order.Status)
+	}
+	if delivery.Status != repositories.DeliveryPending && delivery.Status != repositories.DeliveryAssigned {
+		return nil, fmt.Errorf("cannot assign a driver to a delivery that is %s", delivery.Status)
+	}
+
+	driver, err := s.userRepo.GetByID(driverID)
+	if err != nil || driver.Role != repositories.RoleDriver || !driver.IsActive {
+		return nil, errors.New("driver not found")
+	}
+
+	previous := ""
+	if delivery.Driver != nil {
+		previous = delivery.Driver.Name
+	}
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Deliveries.Transition(delivery.ID, []repositories.DeliveryStatus{delivery.Status}, map[string]interface{}{
+			"status":      repositories.DeliveryAssigned,
+			"driver_id":   driver.ID,
+			"assigned_at": time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventDriverAssigned,
+			PreviousValue: previous,
+			NewValue:      driver.Name,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.kitchenService.BroadcastOrderUpdate(orderID, "delivery_update")
+	return s.deliveryRepo.GetByOrderID(orderID)
+}
+
+// MarkPickedUp records that the assigned driver collected a ready order.
+func (s *DeliveryService) MarkPickedUp(orderID, driverID uint) (*repositories.OrderDelivery, error) {
+	order, delivery, err := s.getDriverDelivery(orderID, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery.Status != repositories.DeliveryAssigned {
+		return nil, fmt.Errorf("cannot pick up a delivery that is %s", delivery.Status)
+	}
+	if order.Status != repositories.OrderStatusReady {
+		return nil, errors.New("order is not ready for pickup yet")
+	}
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Deliveries.Transition(delivery.ID, []repositories.DeliveryStatus{repositories.DeliveryAssigned}, map[string]interface{}{
+			"status":       repositories.DeliveryPickedUp,
+			"picked_up_at": time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, driverID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventDeliveryPickedUp,
+			PreviousValue: string(delivery.Status),
+			NewValue:      string(repositories.DeliveryPickedUp),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.kitchenService.BroadcastOrderUpdate(orderID, "delivery_update")
+	return s.deliveryRepo.GetByOrderID(orderID)
+}
+
+// MarkDelivered records the drop-off. The order is served at that point.
+func (s *DeliveryService) MarkDelivered(orderID, driverID uint) (*repositories.OrderDelivery, error) {
+	order, delivery, err := s.getDriverDelivery(orderID, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery.Status != repositories.DeliveryPickedUp {
+		return nil, fmt.Errorf("cannot deliver a delivery that is %s", delivery.Status)
+	}
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Deliveries.Transition(delivery.ID, []repositories.DeliveryStatus{repositories.DeliveryPickedUp}, map[string]interface{}{
+			"status":       repositories.DeliveryDelivered,
+			"delivered_at": time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		if err := recordOrderEvent(uow, driverID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventDelivered,
+			PreviousValue: string(delivery.Status),
+			NewValue:      string(repositories.DeliveryDelivered),
+		}); err != nil {
+			return err
+		}
+
+		if order.Status == repositories.OrderStatusServed {
+			return nil
+		}
+		if err := uow.Orders.UpdateStatus(orderID, order.Version, repositories.OrderStatusServed); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+		return recordOrderEvent(uow, driverID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(repositories.OrderStatusServed),
+			Reason:        "delivered",
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.kitchenService.BroadcastStatusChange(orderID)
+	return s.deliveryRepo.GetByOrderID(orderID)
+}
+
+func (s *DeliveryService) getDelivery(orderID uint) (*repositories.Order, *repositories.OrderDelivery, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.OrderType != repositories.OrderTypeDelivery {
+		return nil, nil, errors.New("order is not a delivery order")
+	}
+
+	delivery, err := s.deliveryRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, delivery, nil
+}
+
+// getDriverDelivery is getDelivery for the driver the delivery is assigned to.
+func (s *DeliveryService) getDriverDelivery(orderID, driverID uint) (*repositories.Order, *repositories.OrderDelivery, error) {
+	order, delivery, err := s.getDelivery(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if delivery.DriverID == nil || *delivery.DriverID != driverID {
+		return nil, nil, errors.New("delivery not found")
+	}
+	return order, delivery, nil
+}
+
+// planDelivery resolves the address of a new delivery order and the zone that
+// covers it, and checks the zone's minimum order value.
+func planDelivery(deliveryRepo *repositories.DeliveryRepository, userID uint, req *DeliveryRequest, subtotal float64) (*repositories.OrderDelivery, error) {
+	delivery := &repositories.OrderDelivery{
+		Address:   strings.TrimSpace(req.Address),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Notes:     req.Notes,
+		Status:    repositories.DeliveryPending,
+	}
+
+	if req.AddressID != 0 {
+		saved, err := deliveryRepo.GetAddress(req.AddressID, userID)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Address = saved.Address
+		delivery.Latitude = saved.Latitude
+		delivery.Longitude = saved.Longitude
+		if delivery.Notes == "" {
+			delivery.Notes = saved.Notes
+		}
+	}
+
+	if delivery.Address == "" {
+		return nil, errors.New("delivery address is required")
+	}
+	if err := validateCoordinates(delivery.Latitude, delivery.Longitude); err != nil {
+		return nil, err
+	}
+
+	zones, err := deliveryRepo.GetActiveZones()
+	if err != nil {
+		return nil, errors.New("failed to fetch delivery zones")
+	}
+	zone := matchZone(zones, delivery.Latitude, delivery.Longitude)
+	if zone == nil {
+		return nil, ErrOutsideDeliveryArea
+	}
+	if subtotal < zone.MinimumOrder {
+		return nil, fmt.Errorf("minimum order for delivery to %s is %s", zone.Name, formatAmount(zone.MinimumOrder))
+	}
+
+	delivery.ZoneID = zone.ID
+	delivery.Zone = zone
+	return delivery, nil
+}
+
+// matchZone returns the first zone, by priority, that covers the location.
+func matchZone(zones []repositories.DeliveryZone, latitude, longitude float64) *repositories.DeliveryZone {
+	for i := range zones {
+		zone := &zones[i]
+		switch zone.Type {
+		case repositories.DeliveryZoneRadius:
+			if distanceKm(zone.CenterLat, zone.CenterLng, latitude, longitude) <= zone.RadiusKm {
+				return zone
+			}
+		case repositories.DeliveryZonePolygon:
+			if insidePolygon(zone.Polygon, latitude, longitude) {
+				return zone
+			}
+		}
+	}
+	return nil
+}
+
+// distanceKm is the great-circle distance between two points.
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// insidePolygon casts a ray from the point and counts the edges it crosses.
+// Zones are small enough to treat latitude and longitude as plane
+// coordinates.
+func insidePolygon(polygon []repositories.GeoPoint, latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lngI := polygon[i][0], polygon[i][1]
+		latJ, lngJ := polygon[j][0], polygon[j][1]
+		if (lngI > longitude) != (lngJ > longitude) &&
+			latitude < (latJ-latI)*(longitude-lngI)/(lngJ-lngI)+latI {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func applyZoneRequest(zone *repositories.DeliveryZone, req *DeliveryZoneRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("zone name is required")
+	}
+	if req.Fee < 0 || req.MinimumOrder < 0 {
+		return errors.New("fee and minimum order cannot be negative")
+	}
+
+	switch req.Type {
+	case repositories.DeliveryZoneRadius:
+		if req.RadiusKm <= 0 {
+			return errors.New("radius_km must be greater than 0 for radius zones")
+		}
+		if err := validateCoordinates(req.CenterLat, req.CenterLng); err != nil {
+			return err
+		}
+		zone.CenterLat, zone.CenterLng, zone.RadiusKm = req.CenterLat, req.CenterLng, req.RadiusKm
+		zone.Polygon = nil
+	case repositories.DeliveryZonePolygon:
+		if len(req.Polygon) < 3 {
+			return errors.New("polygon zones need at least 3 points")
+		}
+		for _, point := range req.Polygon {
+			if err := validateCoordinates(point[0], point[1]); err != nil {
+				return err
+			}
+		}
+		zone.CenterLat, zone.CenterLng, zone.RadiusKm = 0, 0, 0
+		zone.Polygon = req.Polygon
+	default:
+		return errors.New("invalid zone type. Must be 'radius' or 'polygon'")
+	}
+
+	zone.Name = req.Name
+	zone.Type = req.Type
+	zone.Fee = req.Fee
+	zone.MinimumOrder = req.MinimumOrder
+	zone.Priority = req.Priority
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func validateCoordinates(latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 || (latitude == 0 && longitude == 0) {
+		return errors.New("invalid coordinates")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/repositories"
+)
+
+// A reserved key whose request has not finished after this long is treated
+// as abandoned (e.g. the server restarted) and may be taken over by a retry.
+const idempotencyLockTimeout = time.Minute
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+type IdempotencyService struct {
+	idempotencyRepo *repositories.IdempotencyRepository
+	config          *config.Config
+}
+
+// IdempotentRequest is a request that carried an Idempotency-Key header.
+type IdempotentRequest struct {
+	UserID uint
+	Key    string
+	Method string
+	Path   string
+	Body   []byte
+}
+
+func NewIdempotencyService(idempotencyRepo *repositories.IdempotencyRepository, config *config.Config) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		config:          config,
+	}
+}
+
+// Start purges keys whose replay window has passed, once an hour.
+func (s *IdempotencyService) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.idempotencyRepo.DeleteExpired(time.Now()); err != nil {
+				log.Printf("Error purging expired idempotency keys: %v", err)
+			}
+		}
+	}()
+}
+
+// Begin reserves the key for a request. When the same request was already
+// completed it returns the stored record with replay set, and the caller
+// should send the stored response instead of processing the request again.
+func (s *IdempotencyService) Begin(req *IdempotentRequest) (*repositories.IdempotencyKey, bool, error) {
+	now := time.Now()
+	hash := requestHash(req.Method, req.Path, req.Body)
+
+	record := &repositories.IdempotencyKey{
+		UserID:      req.UserID,
+		Key:         req.Key,
+		Method:      req.Method,
+		Path:        req.Path,
+		RequestHash: hash,
+		Status:      repositories.IdempotencyProcessing,
+		ExpiresAt:   now.Add(time.Duration(s.config.IdempotencyKeyTTLHours) * time.Hour),
+	}
+
+	// A second attempt is only needed after clearing a stale reservation
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, existing, err := s.idempotencyRepo.Reserve(record)
+		if err != nil {
+			return nil, false, errors.New("failed to reserve idempotency key")
+		}
+		if reserved {
+			return record, false, nil
+		}
+
+		expired := existing.ExpiresAt.Before(now)
+		if !expired && existing.RequestHash != hash {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+
+		abandoned := existing.Status == repositories.IdempotencyProcessing && now.Sub(existing.UpdatedAt) > idempotencyLockTimeout
+		if expired || abandoned {
+			if err := s.idempotencyRepo.Delete(existing.ID); err != nil {
+				return nil, false, errors.New("failed to reserve idempotency key")
+			}
+			continue
+		}
+
+		if existing.Status == repositories.IdempotencyProcessing {
+			return nil, false, ErrIdempotencyKeyInFlight
+		}
+		return existing, true, nil
+	}
+
+	return nil, false, ErrIdempotencyKeyInFlight
+}
+
+// Finish stores the response for replay. Errors are not stored: a retry with
+// the same key runs the request again, so a conflict or a rejected request
+// can be retried once its cause is fixed instead of failing for good.
+func (s *IdempotencyService) Finish(record *repositories.IdempotencyKey, responseCode int, contentType string, body []byte) {
+	var err error
+	if responseCode >= 400 {
+		err = s.idempotencyRepo.Delete(record.ID)
+	} else {
+		err = s.idempotencyRepo.Complete(record.ID, responseCode, contentType, body)
+	}
+	if err != nil {
+		log.Printf("Error storing response for idempotency key %q: %v", record.Key, err)
+	}
+}
+
+// Release frees a reserved key whose request did not complete.
+func (s *IdempotencyService) Release(record *repositories.IdempotencyKey) {
+	if err := s.idempotencyRepo.Delete(record.ID); err != nil {
+		log.Printf("Error releasing idempotency key %q: %v", record.Key, err)
+	}
+}
+
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
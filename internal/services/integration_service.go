@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"recursiveDine/internal/aggregators"
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/repositories"
+)
+
+var (
+	ErrUnknownPlatform  = errors.New("unknown platform")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrUnmappedSKU      = errors.New("unmapped SKU")
+)
+
+// IntegrationService takes in orders from third-party delivery platforms.
+// Each platform's webhook is verified and parsed by its adapter, the SKUs are
+// mapped to menu items and the order is created like a cashier order, then
+// confirmed as paid since the platform collected the payment.
+type IntegrationService struct {
+	mappingRepo    *repositories.ExternalItemMappingRepository
+	orderRepo      *repositories.OrderRepository
+	menuRepo       *repositories.MenuRepository
+	userRepo       *repositories.UserRepository
+	orderService   *OrderService
+	paymentService *PaymentService
+	config         *config.Config
+}
+
+type SKUMappingRequest struct {
+	Platform   string `json:"platform" binding:"required"`
+	SKU        string `json:"sku" binding:"required"`
+	MenuItemID uint   `json:"menu_item_id" binding:"required"`
+}
+
+func NewIntegrationService(mappingRepo *repositories.ExternalItemMappingRepository, orderRepo *repositories.OrderRepository, menuRepo *repositories.MenuRepository, userRepo *repositories.UserRepository, orderService *OrderService, paymentService *PaymentService, config *config.Config) *IntegrationService {
+	return &IntegrationService{
+		mappingRepo:    mappingRepo,
+		orderRepo:      orderRepo,
+		menuRepo:       menuRepo,
+		userRepo:       userRepo,
+		orderService:   orderService,
+		paymentService: paymentService,
+		config:         config,
+	}
+}
+
+// ReceiveOrder ingests an order webhook. Platforms retry webhooks, so an order
+// that was already received is returned again with created set to false.
+func (s *IntegrationService) ReceiveOrder(platform string, body []byte, signature string) (order *repositories.Order, created bool, err error) {
+	adapter, ok := aggregators.Lookup(platform)
+	if !ok {
+		return nil, false, ErrUnknownPlatform
+	}
+	if !aggregators.VerifySignature(s.config.PlatformWebhookSecret(platform), body, signature) {
+		return nil, false, ErrInvalidSignature
+	}
+
+	external, err := adapter.Parse(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	source := repositories.OrderSource(platform)
+	if existing, err := s.orderRepo.GetByExternalID(source, external.ExternalID); err == nil {
+		// A retry after the order was created but not yet confirmed finishes the job
+		if existing.Status == repositories.OrderStatusPending && existing.Payment == nil {
+			if err := s.paymentService.RecordPlatformPayment(existing.ID, platform, external.ExternalID); err != nil {
+				return nil, false, err
+			}
+		}
+		order, err := s.orderRepo.GetByID(existing.ID)
+		return order, false, err
+	}
+
+	items, err := s.mapItems(platform, external.Items)
+	if err != nil {
+		return nil, false, err
+	}
+
+	account, err := s.platformAccount(adapter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	customerName := external.CustomerName
+	if customerName == "" {
+		customerName = adapter.DisplayName() + " customer"
+	}
+
+	// Drivers quote the platform's order number at the counter, so it leads
+	// the notes printed on tickets and receipts
+	notes := fmt.Sprintf("%s #%s", adapter.DisplayName(), external.DisplayNumber)
+	if external.DisplayNumber == "" {
+		notes = fmt.Sprintf("%s #%s", adapter.DisplayName(), external.ExternalID)
+	}
+	if external.Notes != "" {
+		notes += ". " + external.Notes
+	}
+
+	response, err := s.orderService.CreateCashierOrder(account.ID, &CashierOrderRequest{
+		OrderType:     repositories.OrderTypeTakeaway,
+		CustomerName:  customerName,
+		CustomerPhone: external.CustomerPhone,
+		CashierName:   adapter.DisplayName(),
+		SpecialNotes:  notes,
+		Items:         items,
+		Source:        source,
+		ExternalID:    external.ExternalID,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.paymentService.RecordPlatformPayment(response.ID, platform, external.ExternalID); err != nil {
+		return nil, false, err
+	}
+
+	order, err = s.orderRepo.GetByID(response.ID)
+	return order, true, err
+}
+
+// mapItems turns the platform's item lines into order items, failing with
+// every SKU that has no mapping so they can all be set up at once.
+func (s *IntegrationService) mapItems(platform string, lines []aggregators.Item) ([]CreateOrderItemRequest, error) {
+	skus := make([]string, 0, len(lines))
+	for _, line := range lines {
+		skus = append(skus, line.SKU)
+	}
+
+	mappings, err := s.mappingRepo.GetBySKUs(platform, skus)
+	if err != nil {
+		return nil, errors.New("failed to fetch SKU mappings")
+	}
+	menuItemIDs := make(map[string]uint, len(mappings))
+	for _, mapping := range mappings {
+		menuItemIDs[mapping.SKU] = mapping.MenuItemID
+	}
+
+	items := make([]CreateOrderItemRequest, 0, len(lines))
+	var missing []string
+	for _, line := range lines {
+		menuItemID, ok := menuItemIDs[line.SKU]
+		if !ok {
+			missing = append(missing, line.SKU)
+			continue
+		}
+		items = append(items, CreateOrderItemRequest{
+			MenuItemID:     menuItemID,
+			Quantity:       line.Quantity,
+			SpecialRequest: line.Notes,
+		})
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnmappedSKU, strings.Join(missing, ", "))
+	}
+	return items, nil
+}
+
+// platformAccount returns the user that orders of a platform are placed
+// under, creating it on the first order. The account cannot log in.
+func (s *IntegrationService) platformAccount(adapter aggregators.Adapter) (*repositories.User, error) {
+	username := "platform-" + adapter.Platform()
+	if user, err := s.userRepo.GetByUsername(username); err == nil {
+		return user, nil
+	}
+
+	user := &repositories.User{
+		Name:     adapter.DisplayName(),
+		Username: username,
+		Email:    username + "@platforms.local",
+		Password: "!", // Not a bcrypt hash, so no password matches
+		Role:     repositories.RoleCustomer,
+		IsActive: true,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, errors.New("failed to create platform account")
+	}
+	return user, nil
+}
+
+// SKU mappings
+
+func (s *IntegrationService) GetMappings(platform string) ([]repositories.ExternalItemMapping, error) {
+	return s.mappingRepo.GetByPlatform(platform)
+}
+
+func (s *IntegrationService) SetMapping(req *SKUMappingRequest) (*repositories.ExternalItemMapping, error) {
+	if _, ok := aggregators.Lookup(req.Platform); !ok {
+		return nil, fmt.Errorf("unknown platform. Must be one of: %s", strings.Join(aggregators.Platforms(), ", "))
+	}
+	if _, err := s.menuRepo.GetMenuItemByID(req.MenuItemID); err != nil {
+		return nil, errors.New("menu item not found")
+	}
+
+	mapping := &repositories.ExternalItemMapping{
+		Platform:   req.Platform,
+		SKU:        strings.TrimSpace(req.SKU),
+		MenuItemID: req.MenuItemID,
+	}
+	if err := s.mappingRepo.Upsert(mapping); err != nil {
+		return nil, errors.New("failed to save SKU mapping")
+	}
+	return mapping, nil
+}
+
+func (s *IntegrationService) DeleteMapping(id uint) error {
+	return s.mappingRepo.Delete(id)
+}
@@ -4,75 +4,105 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
 
+	"recursiveDine/internal/printing"
 	"recursiveDine/internal/repositories"
-
-	"github.com/gorilla/websocket"
 )
 
+// kitchenEventBacklog is the number of recent events kept in memory so that
+// reconnecting websocket and SSE clients can resume from their last event ID.
+const kitchenEventBacklog = 256
+
+// kitchenSubscriberBuffer is the number of events a listener may fall behind
+// before it is disconnected.
+const kitchenSubscriberBuffer = 32
+
+// KitchenService is the live kitchen feed. Events are numbered and handed to
+// every listener under one lock, so each listener sees them in ID order and a
+// resuming listener's backlog never overlaps its live events.
 type KitchenService struct {
-	orderRepo *repositories.OrderRepository
-	clients   map[*websocket.Conn]bool
-	broadcast chan []byte
-	mutex     sync.RWMutex
+	orderRepo   *repositories.OrderRepository
+	subscribers map[chan KitchenEvent]bool
+	events      []KitchenEvent
+	lastEventID uint64
+	mutex       sync.RWMutex
 }
 
 type KitchenUpdate struct {
-	Type    string                `json:"type"`
-	OrderID uint                  `json:"order_id"`
-	Order   *repositories.Order   `json:"order,omitempty"`
+	EventID uint64                   `json:"event_id"`
+	Type    string                   `json:"type"`
+	OrderID uint                     `json:"order_id"`
+	Order   *repositories.Order      `json:"order,omitempty"`
 	Status  repositories.OrderStatus `json:"status,omitempty"`
 }
 
+// KitchenEvent is a single entry of the kitchen feed. Data holds the JSON
+// encoded KitchenUpdate exactly as it is sent to websocket and SSE clients;
+// OrderID, UserID and Order let other listeners filter and re-shape the event.
+type KitchenEvent struct {
+	ID      uint64
+	Type    string
+	Data    []byte
+	OrderID uint
+	UserID  uint
+	Order   *repositories.Order
+}
+
 func NewKitchenService(orderRepo *repositories.OrderRepository) *KitchenService {
-	service := &KitchenService{
-		orderRepo: orderRepo,
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan []byte),
+	return &KitchenService{
+		orderRepo:   orderRepo,
+		subscribers: make(map[chan KitchenEvent]bool),
+		// Seed event IDs from the clock so IDs held by clients from before a
+		// restart never collide with new ones and fall back to a snapshot.
+		lastEventID: uint64(time.Now().UnixMilli()),
 	}
+}
 
-	// Start the broadcast goroutine
-	go service.handleMessages()
+// Subscribe registers a listener for the websocket and SSE endpoints. It
+// returns the events missed since lastEventID, or a single initial_orders
+// snapshot event when the client is new or too far behind. The snapshot is
+// read after registering, so it is never older than the live events that
+// follow it.
+func (s *KitchenService) Subscribe(lastEventID uint64) (chan KitchenEvent, []KitchenEvent, error) {
+	ch, missed, position, ok := s.Listen(lastEventID)
+	if ok {
+		return ch, missed, nil
+	}
 
-	return service
+	data, err := s.initialOrdersMessage(position)
+	if err != nil {
+		s.Unsubscribe(ch)
+		return nil, nil, err
+	}
+
+	snapshot := KitchenEvent{ID: position, Type: "initial_orders", Data: data}
+	return ch, []KitchenEvent{snapshot}, nil
 }
 
-func (s *KitchenService) AddClient(conn *websocket.Conn) {
+// Listen registers a raw listener without building a kitchen snapshot. It
+// returns the ID of the last event published before the listener was added;
+// every later event arrives on the channel. The boolean reports whether
+// lastEventID could be resumed; when it is false the caller is responsible
+// for sending its own snapshot as of that ID.
+func (s *KitchenService) Listen(lastEventID uint64) (chan KitchenEvent, []KitchenEvent, uint64, bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.clients[conn] = true
-
-	// Send current kitchen orders to new client
-	orders, err := s.orderRepo.GetKitchenOrders()
-	if err != nil {
-		log.Printf("Error fetching kitchen orders: %v", err)
-		return
-	}
+	ch := make(chan KitchenEvent, kitchenSubscriberBuffer)
+	s.subscribers[ch] = true
 
-	data, err := json.Marshal(map[string]interface{}{
-		"type":   "initial_orders",
-		"orders": orders,
-	})
-	if err != nil {
-		log.Printf("Error marshaling initial orders: %v", err)
-		return
-	}
-
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		log.Printf("Error sending initial orders: %v", err)
-		delete(s.clients, conn)
-		conn.Close()
-	}
+	missed, ok := s.eventsSince(lastEventID)
+	return ch, missed, s.lastEventID, ok
 }
 
-func (s *KitchenService) RemoveClient(conn *websocket.Conn) {
+func (s *KitchenService) Unsubscribe(ch chan KitchenEvent) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, ok := s.clients[conn]; ok {
-		delete(s.clients, conn)
-		conn.Close()
+	if _, ok := s.subscribers[ch]; ok {
+		delete(s.subscribers, ch)
+		close(ch)
 	}
 }
 
@@ -83,10 +113,31 @@ func (s *KitchenService) BroadcastOrderUpdate(orderID uint, updateType string) {
 		return
 	}
 
+	s.publish(order, updateType)
+}
+
+// BroadcastStatusChange publishes an order status change using the update type
+// that matches the new status.
+func (s *KitchenService) BroadcastStatusChange(orderID uint) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		log.Printf("Error fetching order for broadcast: %v", err)
+		return
+	}
+
+	s.publish(order, statusUpdateType(order))
+}
+
+func (s *KitchenService) publish(order *repositories.Order, updateType string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastEventID++
 	update := KitchenUpdate{
+		EventID: s.lastEventID,
 		Type:    updateType,
-		OrderID: orderID,
-		Order:   order,
+		OrderID: order.ID,
+		Order:   kitchenView(order),
 		Status:  order.Status,
 	}
 
@@ -96,37 +147,112 @@ func (s *KitchenService) BroadcastOrderUpdate(orderID uint, updateType string) {
 		return
 	}
 
-	select {
-	case s.broadcast <- data:
+	event := KitchenEvent{
+		ID:      update.EventID,
+		Type:    updateType,
+		Data:    data,
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Order:   order,
+	}
+	s.events = append(s.events, event)
+	if len(s.events) > kitchenEventBacklog {
+		s.events = s.events[len(s.events)-kitchenEventBacklog:]
+	}
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// Slow subscribers are dropped; they resume via Last-Event-ID.
+			log.Printf("Kitchen subscriber too slow, disconnecting")
+			delete(s.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// statusUpdateType maps an order's current status onto the feed event type.
+// Takeaway orders become "pickup_ready" rather than "order_ready" so pickup
+// screens and customers can react without inspecting the order type.
+func statusUpdateType(order *repositories.Order) string {
+	switch order.Status {
+	case repositories.OrderStatusReady:
+		if order.OrderType == repositories.OrderTypeTakeaway {
+			return "pickup_ready"
+		}
+		return "order_ready"
+	case repositories.OrderStatusCancelled:
+		return "order_cancelled"
 	default:
-		log.Printf("Broadcast channel full, dropping message")
+		return "status_update"
 	}
 }
 
-func (s *KitchenService) handleMessages() {
-	for {
-		select {
-		case message := <-s.broadcast:
-			s.mutex.RLock()
-			for client := range s.clients {
-				err := client.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					log.Printf("Error writing to client: %v", err)
-					delete(s.clients, client)
-					client.Close()
-				}
-			}
-			s.mutex.RUnlock()
+// eventsSince returns the buffered events after lastEventID. The boolean is
+// false when there is nothing to resume from (new client, server restart or
+// the requested event has already been evicted from the backlog).
+// Callers must hold the mutex.
+func (s *KitchenService) eventsSince(lastEventID uint64) ([]KitchenEvent, bool) {
+	if lastEventID == 0 || lastEventID > s.lastEventID {
+		return nil, false
+	}
+	if lastEventID == s.lastEventID {
+		return nil, true
+	}
+	if len(s.events) == 0 || s.events[0].ID > lastEventID+1 {
+		return nil, false
+	}
+
+	missed := make([]KitchenEvent, 0, s.lastEventID-lastEventID)
+	for _, event := range s.events {
+		if event.ID > lastEventID {
+			missed = append(missed, event)
 		}
 	}
+	return missed, true
+}
+
+func (s *KitchenService) initialOrdersMessage(eventID uint64) ([]byte, error) {
+	orders, err := s.GetActiveOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"event_id": eventID,
+		"type":     "initial_orders",
+		"orders":   orders,
+	})
 }
 
 func (s *KitchenService) GetActiveOrders() ([]repositories.Order, error) {
-	return s.orderRepo.GetKitchenOrders()
+	orders, err := s.orderRepo.GetKitchenOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range orders {
+		orders[i] = *kitchenView(&orders[i])
+	}
+	return orders, nil
+}
+
+// kitchenView returns the order as the kitchen sees it: items of courses that
+// have not been fired yet are left out.
+func kitchenView(order *repositories.Order) *repositories.Order {
+	view := *order
+	view.OrderItems = make([]repositories.OrderItem, 0, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		if printing.ItemSent(order, &item) {
+			view.OrderItems = append(view.OrderItems, item)
+		}
+	}
+	return &view
 }
 
 func (s *KitchenService) GetClientCount() int {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	return len(s.clients)
+	return len(s.subscribers)
 }
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"recursiveDine/internal/repositories"
+)
+
+// MenuBundleSlotRequest is one component of a bundle. A slot with a single
+// option is fixed; with more, the guest chooses one of them.
+type MenuBundleSlotRequest struct {
+	Name     string                    `json:"name" binding:"required"`
+	Quantity int                       `json:"quantity"` // Servings per bundle; 1 when left out
+	Options  []MenuBundleOptionRequest `json:"options" binding:"required,min=1,dive"`
+}
+
+type MenuBundleOptionRequest struct {
+	MenuItemID uint    `json:"menu_item_id" binding:"required"`
+	Upcharge   float64 `json:"upcharge"`
+	IsDefault  bool    `json:"is_default"` // Served when an order leaves the slot open
+}
+
+// BundleChoiceRequest picks the option of a bundle slot when ordering.
+type BundleChoiceRequest struct {
+	SlotID     uint `json:"slot_id" binding:"required"`
+	MenuItemID uint `json:"menu_item_id" binding:"required"`
+}
+
+// SetMenuItemBundle replaces the slots of a bundle item, making a plain item
+// a bundle. An empty list turns the bundle back into a plain item. Bundles
+// cannot be nested.
+func (s *MenuService) SetMenuItemBundle(menuItemID uint, reqs []MenuBundleSlotRequest) (*repositories.MenuItem, error) {
+	if _, err := s.menuRepo.GetMenuItemByID(menuItemID); err != nil {
+		return nil, err
+	}
+	if len(reqs) > 0 {
+		containing, err := s.menuRepo.GetBundlesContaining(menuItemID)
+		if err != nil {
+			return nil, err
+		}
+		if len(containing) > 0 {
+			return nil, fmt.Errorf("menu item is part of the bundle '%s' and cannot be a bundle itself", containing[0].Name)
+		}
+	}
+
+	slots := make([]repositories.MenuBundleSlot, 0, len(reqs))
+	for i, req := range reqs {
+		slot, err := s.bundleSlot(menuItemID, req)
+		if err != nil {
+			return nil, err
+		}
+		slot.SortOrder = i
+		slots = append(slots, *slot)
+	}
+
+	if err := s.changed(s.menuRepo.ReplaceBundleSlots(menuItemID, slots)); err != nil {
+		return nil, err
+	}
+	return s.menuRepo.GetMenuItemByID(menuItemID)
+}
+
+// bundleSlot validates a slot request and turns it into a slot.
+func (s *MenuService) bundleSlot(bundleItemID uint, req MenuBundleSlotRequest) (*repositories.MenuBundleSlot, error) {
+	slot := &repositories.MenuBundleSlot{Name: strings.TrimSpace(req.Name), Quantity: req.Quantity}
+	if slot.Name == "" {
+		return nil, errors.New("bundle slots need a name")
+	}
+	if slot.Quantity == 0 {
+		slot.Quantity = 1
+	}
+	if slot.Quantity < 0 {
+		return nil, fmt.Errorf("quantity of slot '%s' must be at least 1", slot.Name)
+	}
+	if len(req.Options) == 0 {
+		return nil, fmt.Errorf("slot '%s' needs at least one option", slot.Name)
+	}
+
+	defaults := 0
+	seen := make(map[uint]bool, len(req.Options))
+	for i, option := range req.Options {
+		if option.MenuItemID == bundleItemID {
+			return nil, errors.New("a bundle cannot contain itself")
+		}
+		if seen[option.MenuItemID] {
+			return nil, fmt.Errorf("menu item %d is offered twice in slot '%s'", option.MenuItemID, slot.Name)
+		}
+		seen[option.MenuItemID] = true
+		if option.Upcharge < 0 {
+			return nil, fmt.Errorf("upcharge in slot '%s' cannot be negative", slot.Name)
+		}
+
+		component, err := s.menuRepo.GetMenuItemByID(option.MenuItemID)
+		if err != nil {
+			return nil, fmt.Errorf("menu item %d in slot '%s' not found", option.MenuItemID, slot.Name)
+		}
+		if len(component.BundleSlots) > 0 {
+			return nil, fmt.Errorf("'%s' is a bundle and cannot be part of another", component.Name)
+		}
+
+		if option.IsDefault {
+			defaults++
+		}
+		slot.Options = append(slot.Options, repositories.MenuBundleOption{
+			MenuItemID: option.MenuItemID,
+			Upcharge:   option.Upcharge,
+			IsDefault:  option.IsDefault,
+			SortOrder:  i,
+		})
+	}
+	if defaults > 1 {
+		return nil, fmt.Errorf("slot '%s' can have only one default option", slot.Name)
+	}
+	// A fixed slot always serves its only option
+	if len(slot.Options) == 1 {
+		slot.Options[0].IsDefault = true
+	}
+	return slot, nil
+}
+
+// bundleComponents picks the components of an ordered bundle and works out
+// its unit price: the bundle's own price plus the upcharges of the options
+// chosen. Slots the choices leave open get their default option. Items that
+// are not bundles cost their list price and have no components. The bundle
+// slots must be preloaded.
+func bundleComponents(menuItem *repositories.MenuItem, choices []BundleChoiceRequest) ([]repositories.OrderItemComponent, float64, error) {
+	if len(menuItem.BundleSlots) == 0 {
+		if len(choices) > 0 {
+			return nil, 0, fmt.Errorf("menu item '%s' is not a bundle", menuItem.Name)
+		}
+		return nil, menuItem.Price, nil
+	}
+
+	chosen := make(map[uint]uint, len(choices))
+	for _, choice := range choices {
+		if _, ok := chosen[choice.SlotID]; ok {
+			return nil, 0, fmt.Errorf("slot %d of '%s' is chosen more than once", choice.SlotID, menuItem.Name)
+		}
+		chosen[choice.SlotID] = choice.MenuItemID
+	}
+
+	unitPrice := menuItem.Price
+	components := make([]repositories.OrderItemComponent, 0, len(menuItem.BundleSlots))
+	listPrices := make([]float64, 0, len(menuItem.BundleSlots))
+	for i := range menuItem.BundleSlots {
+		slot := &menuItem.BundleSlots[i]
+		option, err := bundleOption(menuItem, slot, chosen[slot.ID])
+		if err != nil {
+			return nil, 0, err
+		}
+		delete(chosen, slot.ID)
+
+		quantity := slot.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		unitPrice += option.Upcharge
+		components = append(components, repositories.OrderItemComponent{
+			SlotID:     slot.ID,
+			SlotName:   slot.Name,
+			MenuItemID: option.MenuItemID,
+			Quantity:   quantity,
+			Upcharge:   option.Upcharge,
+		})
+		listPrices = append(listPrices, option.MenuItem.Price*float64(quantity))
+	}
+	// Choices left over name slots the bundle does not have
+	for _, choice := range choices {
+		if _, ok := chosen[choice.SlotID]; ok {
+			return nil, 0, fmt.Errorf("'%s' has no slot %d", menuItem.Name, choice.SlotID)
+		}
+	}
+
+	allocateBundlePrice(menuItem.Price, components, listPrices)
+	return components, unitPrice, nil
+}
+
+// bundleOption returns the option chosen for a slot, or its default when
+// nothing was chosen.
+func bundleOption(bundle *repositories.MenuItem, slot *repositories.MenuBundleSlot, menuItemID uint) (*repositories.MenuBundleOption, error) {
+	var option *repositories.MenuBundleOption
+	for i := range slot.Options {
+		candidate := &slot.Options[i]
+		if (menuItemID == 0 && candidate.IsDefault) || (menuItemID != 0 && candidate.MenuItemID == menuItemID) {
+			option = candidate
+			break
+		}
+	}
+	switch {
+	case option == nil && menuItemID == 0:
+		return nil, fmt.Errorf("choose the %s of '%s'", slot.Name, bundle.Name)
+	case option == nil:
+		return nil, fmt.Errorf("menu item %d is not a choice for the %s of '%s'", menuItemID, slot.Name, bundle.Name)
+	}
+
+	// Removed items are not preloaded and leave an empty menu item
+	if option.MenuItem.ID == 0 || !option.MenuItem.IsAvailable {
+		name := option.MenuItem.Name
+		if name == "" {
+			name = fmt.Sprintf("menu item %d", option.MenuItemID)
+		}
+		return nil, fmt.Errorf("'%s' is not available for the %s of '%s'", name, slot.Name, bundle.Name)
+	}
+	return option, nil
+}
+
+// allocateBundlePrice splits the bundle's own price over its components in
+// proportion to their list prices, or evenly when none has a price, and adds
+// each component's upcharge. Shares are rounded to cents with the remainder
+// on the last component, so they add up to the bundle's unit price.
+func allocateBundlePrice(price float64, components []repositories.OrderItemComponent, listPrices []float64) {
+	var listTotal float64
+	for _, listPrice := range listPrices {
+		listTotal += listPrice
+	}
+
+	remaining := price
+	for i := range components {
+		share := roundCents(remaining)
+		if i < len(components)-1 {
+			if listTotal > 0 {
+				share = roundCents(price * listPrices[i] / listTotal)
+			} else {
+				share = roundCents(price / float64(len(components)))
+			}
+			remaining -= share
+		}
+		components[i].AllocatedPrice = roundCents(share + components[i].Upcharge)
+	}
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"recursiveDine/internal/cache"
+	"recursiveDine/internal/repositories"
+)
+
+// menuGenerationKey holds the generation of the cached menu. Every write to
+// the menu moves it on, so entries of earlier generations are never read
+// again and simply expire.
+const menuGenerationKey = "menu:generation"
+
+// MenuCache keeps the menu as read from the database, so guest scans do not
+// each run the menu query with all its preloads. Entries are stored under the
+// generation they were read in: a read that races a write stores the old
+// menu under the old generation, where nobody looks for it. Guest filtering
+// and translation happen after the cache, so one entry serves every guest.
+// When the store fails the menu is read from the database. A nil MenuCache
+// caches nothing.
+type MenuCache struct {
+	store cache.Store
+	ttl   time.Duration
+}
+
+func NewMenuCache(store cache.Store, ttl time.Duration) *MenuCache {
+	if store == nil {
+		return nil
+	}
+	return &MenuCache{store: store, ttl: ttl}
+}
+
+// categories returns the categories cached under name, loading and caching
+// them on a miss.
+func (c *MenuCache) categories(name string, load func() ([]repositories.MenuCategory, error)) ([]repositories.MenuCategory, error) {
+	if c == nil {
+		return load()
+	}
+
+	generation, err := c.generation()
+	if err != nil {
+		log.Printf("Error reading the menu cache: %v", err)
+		return load()
+	}
+	key := fmt.Sprintf("menu:%d:%s", generation, name)
+
+	data, err := c.store.Get(key)
+	if err == nil {
+		var categories []repositories.MenuCategory
+		if err := json.Unmarshal(data, &categories); err == nil {
+			return categories, nil
+		}
+	} else if !errors.Is(err, cache.ErrMiss) {
+		log.Printf("Error reading the menu cache: %v", err)
+	}
+
+	categories, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(categories); err == nil {
+		if err := c.store.Set(key, data, c.ttl); err != nil {
+			log.Printf("Error writing the menu cache: %v", err)
+		}
+	}
+	return categories, nil
+}
+
+func (c *MenuCache) generation() (int64, error) {
+	data, err := c.store.Get(menuGenerationKey)
+	if errors.Is(err, cache.ErrMiss) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(data), 10, 64)
+}
+
+// Invalidate drops the cached menu. It is called once a write to the menu
+// has committed; a failure is logged and the cached menu expires after its
+// TTL instead.
+func (c *MenuCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	if _, err := c.store.Incr(menuGenerationKey); err != nil {
+		log.Printf("Error invalidating the menu cache: %v", err)
+	}
+}
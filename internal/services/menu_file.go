@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MenuFile is a whole menu as it is exported and imported. Items refer to
+// their category by name.
+type MenuFile struct {
+	Categories []MenuCategoryRow `json:"categories"`
+	Items      []MenuItemRow     `json:"items"`
+}
+
+// MenuCategoryRow is a category in a menu file. On import, fields left out
+// keep their current value, or take their default for a new category.
+type MenuCategoryRow struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+	SortOrder   *int    `json:"sort_order,omitempty"`
+	Station     *string `json:"station,omitempty"`
+}
+
+// MenuItemRow is a menu item in a menu file. On import, fields left out keep
+// their current value, or take their default for a new item.
+type MenuItemRow struct {
+	SKU           string   `json:"sku,omitempty"`
+	ExternalCodes []string `json:"external_codes,omitempty"` // Delivery platform SKUs, e.g. grabfood:GF-12
+	Category      string   `json:"category"`
+	Name          string   `json:"name"`
+	Description   *string  `json:"description,omitempty"`
+	Price         *float64 `json:"price,omitempty"`
+	IsAvailable   *bool    `json:"is_available,omitempty"`
+	SortOrder     *int     `json:"sort_order,omitempty"`
+	Station       *string  `json:"station,omitempty"`
+
+	row int // CSV line or position in the JSON list, for error reports
+}
+
+// ImportRowError is a problem with one row of a menu file.
+type ImportRowError struct {
+	Section string `json:"section"` // categories or items
+	Row     int    `json:"row"`     // CSV line, counting the header, or position in the JSON list, from 1
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ErrInvalidMenuFile is returned for menu files that cannot be read at all,
+// as opposed to files with invalid rows.
+var ErrInvalidMenuFile = errors.New("invalid menu file")
+
+// menuCSVColumns are the columns of a CSV menu file. A CSV file holds items
+// only; categories are created by name with default settings.
+var menuCSVColumns = []string{"sku", "external_codes", "category", "name", "description", "price", "is_available", "sort_order", "station"}
+
+// ParseMenuJSON reads a menu file in the JSON export format.
+func ParseMenuJSON(data []byte) (*MenuFile, error) {
+	var file MenuFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMenuFile, err)
+	}
+	for i := range file.Items {
+		file.Items[i].row = i + 1
+	}
+	return &file, nil
+}
+
+// ParseMenuCSV reads a CSV menu file with a header line naming its columns.
+// The category, name and price columns are required. Cells that cannot be
+// read are reported per row; a blank number or true/false cell leaves the
+// field out.
+func ParseMenuCSV(data []byte) (*MenuFile, []ImportRowError, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMenuFile, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsString(menuCSVColumns, name) {
+			return nil, nil, fmt.Errorf("%w: unknown column %q. Columns are: %s", ErrInvalidMenuFile, name, strings.Join(menuCSVColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"category", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("%w: the %s column is required", ErrInvalidMenuFile, required)
+		}
+	}
+
+	file := &MenuFile{}
+	var rowErrors []ImportRowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMenuFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(column string) (string, bool) {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return "", false
+			}
+			return strings.TrimSpace(record[i]), true
+		}
+		fail := func(field, message string) {
+			rowErrors = append(rowErrors, ImportRowError{Section: "items", Row: line, Field: field, Message: message})
+		}
+
+		row := MenuItemRow{row: line}
+		row.SKU, _ = cell("sku")
+		row.Category, _ = cell("category")
+		row.Name, _ = cell("name")
+		if codes, _ := cell("external_codes"); codes != "" {
+			row.ExternalCodes = strings.Split(codes, ";")
+		}
+		if description, ok := cell("description"); ok {
+			row.Description = &description
+		}
+		if station, ok := cell("station"); ok {
+			row.Station = &station
+		}
+		if value, _ := cell("price"); value != "" {
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				fail("price", fmt.Sprintf("%q is not a number", value))
+			}
+			row.Price = &price
+		}
+		if value, _ := cell("is_available"); value != "" {
+			available, err := strconv.ParseBool(value)
+			if err != nil {
+				fail("is_available", fmt.Sprintf("%q is not true or false", value))
+			}
+			row.IsAvailable = &available
+		}
+		if value, _ := cell("sort_order"); value != "" {
+			sortOrder, err := strconv.Atoi(value)
+			if err != nil {
+				fail("sort_order", fmt.Sprintf("%q is not a whole number", value))
+			}
+			row.SortOrder = &sortOrder
+		}
+		file.Items = append(file.Items, row)
+	}
+	return file, rowErrors, nil
+}
+
+// WriteMenuCSV writes the items of a menu file as CSV.
+func WriteMenuCSV(w io.Writer, file *MenuFile) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(menuCSVColumns); err != nil {
+		return err
+	}
+	for _, item := range file.Items {
+		record := []string{
+			item.SKU,
+			strings.Join(item.ExternalCodes, ";"),
+			item.Category,
+			item.Name,
+			stringValue(item.Description),
+			strconv.FormatFloat(floatValue(item.Price), 'f', -1, 64),
+			strconv.FormatBool(item.IsAvailable == nil || *item.IsAvailable),
+			strconv.Itoa(intValue(item.SortOrder)),
+			stringValue(item.Station),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func floatValue(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func intValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/media"
+	"recursiveDine/internal/repositories"
+)
+
+// MenuImageService stores the images of menu items and the scaled-down
+// variants the apps show, e.g. thumbnails in the menu list.
+type MenuImageService struct {
+	menuRepo  *repositories.MenuRepository
+	menuCache *MenuCache
+	storage   media.Storage
+	config    *config.Config
+}
+
+func NewMenuImageService(menuRepo *repositories.MenuRepository, menuCache *MenuCache, storage media.Storage, config *config.Config) *MenuImageService {
+	return &MenuImageService{
+		menuRepo:  menuRepo,
+		menuCache: menuCache,
+		storage:   storage,
+		config:    config,
+	}
+}
+
+// MaxUploadBytes is the largest image that can be uploaded.
+func (s *MenuImageService) MaxUploadBytes() int {
+	return s.config.ImageMaxUploadMB << 20
+}
+
+// SetMenuItemImage stores an uploaded image and its variants and makes it the
+// image of a menu item. ImageURL points at the medium variant. The files of
+// the image it replaces are removed.
+func (s *MenuImageService) SetMenuItemImage(menuItemID uint, data []byte) (*repositories.MenuItem, error) {
+	item, err := s.menuRepo.GetMenuItemByID(menuItemID)
+	if err != nil {
+		return nil, err
+	}
+	img, err := media.ProcessImage(data, s.MaxUploadBytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys include a hash of the image, so a replaced image never shows up
+	// from a cache under the new one's URL
+	files := map[string]media.File{"original": img.Original}
+	for name, file := range img.Variants {
+		files[name] = file
+	}
+	keys := make(map[string]string, len(files))
+	urls := make(map[string]string, len(files))
+	for name, file := range files {
+		key := fmt.Sprintf("menu-items/%d/%s-%s%s", menuItemID, img.Hash, name, file.Ext)
+		if err := s.storage.Save(key, file.Data); err != nil {
+			s.deleteFiles(keys)
+			return nil, err
+		}
+		keys[name] = key
+		urls[name] = s.storage.URL(key)
+	}
+
+	if err := s.menuRepo.UpdateMenuItemImage(menuItemID, urls["medium"], urls, keys); err != nil {
+		s.deleteFiles(keys)
+		return nil, err
+	}
+	s.menuCache.Invalidate()
+	s.deleteStale(item.ImageKeys, keys)
+	return s.menuRepo.GetMenuItemByID(menuItemID)
+}
+
+// DeleteMenuItemImage removes the image of a menu item.
+func (s *MenuImageService) DeleteMenuItemImage(menuItemID uint) (*repositories.MenuItem, error) {
+	item, err := s.menuRepo.GetMenuItemByID(menuItemID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.menuRepo.UpdateMenuItemImage(menuItemID, "", nil, nil); err != nil {
+		return nil, err
+	}
+	s.menuCache.Invalidate()
+	s.deleteFiles(item.ImageKeys)
+	return s.menuRepo.GetMenuItemByID(menuItemID)
+}
+
+// OpenMedia returns a stored file for download.
+func (s *MenuImageService) OpenMedia(key string) (*media.Object, error) {
+	return s.storage.Open(key)
+}
+
+// deleteStale removes the old files that the new image did not overwrite,
+// which uploading the same image again does.
+func (s *MenuImageService) deleteStale(old, current map[string]string) {
+	kept := make(map[string]bool, len(current))
+	for _, key := range current {
+		kept[key] = true
+	}
+	stale := make(map[string]string)
+	for name, key := range old {
+		if !kept[key] {
+			stale[name] = key
+		}
+	}
+	s.deleteFiles(stale)
+}
+
+// deleteFiles removes files on a best-effort basis: the item no longer points
+// at them, so a file left behind only takes up space.
+func (s *MenuImageService) deleteFiles(keys map[string]string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(key); err != nil {
+			log.Printf("Error deleting media file %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"recursiveDine/internal/aggregators"
+	"recursiveDine/internal/repositories"
+)
+
+// Actions of the rows of a menu import.
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+)
+
+// MenuImportService exports the menu as CSV or JSON and imports such files,
+// creating and updating categories and items in one transaction.
+type MenuImportService struct {
+	menuRepo    *repositories.MenuRepository
+	mappingRepo *repositories.ExternalItemMappingRepository
+	menuCache   *MenuCache
+	transactor  *repositories.Transactor
+}
+
+// MenuImportResult previews or reports an import: what happens to every
+// category and item of the file, and the rows that stop it from being
+// applied.
+type MenuImportResult struct {
+	DryRun     bool             `json:"dry_run"`
+	Applied    bool             `json:"applied"`
+	Summary    ImportSummary    `json:"summary"`
+	Categories []ImportChange   `json:"categories"`
+	Items      []ImportChange   `json:"items"`
+	Errors     []ImportRowError `json:"errors"`
+}
+
+type ImportSummary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Errors    int `json:"errors"`
+}
+
+// ImportChange is what an import does to one category or item.
+type ImportChange struct {
+	Row     int                    `json:"row,omitempty"` // 0 for existing categories that only items name
+	Action  string                 `json:"action"`
+	ID      uint                   `json:"id,omitempty"` // Set for existing records, and for new ones once applied
+	Name    string                 `json:"name"`
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is the old and new value of a field; From is nil for new
+// records.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+func NewMenuImportService(menuRepo *repositories.MenuRepository, mappingRepo *repositories.ExternalItemMappingRepository, menuCache *MenuCache, transactor *repositories.Transactor) *MenuImportService {
+	return &MenuImportService{
+		menuRepo:    menuRepo,
+		mappingRepo: mappingRepo,
+		menuCache:   menuCache,
+		transactor:  transactor,
+	}
+}
+
+// ExportMenu returns every category and item, including inactive categories
+// and unavailable items, with the delivery platform SKUs mapped to each item.
+func (s *MenuImportService) ExportMenu() (*MenuFile, error) {
+	return exportMenu(s.menuRepo, s.mappingRepo)
+}
+
+func exportMenu(menuRepo *repositories.MenuRepository, mappingRepo *repositories.ExternalItemMappingRepository) (*MenuFile, error) {
+	categories, err := menuRepo.GetMenuForExport()
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := mappingRepo.GetByPlatform("")
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[uint][]string)
+	for _, mapping := range mappings {
+		codes[mapping.MenuItemID] = append(codes[mapping.MenuItemID], mapping.Platform+":"+mapping.SKU)
+	}
+
+	file := &MenuFile{Categories: []MenuCategoryRow{}, Items: []MenuItemRow{}}
+	for i := range categories {
+		category := &categories[i]
+		file.Categories = append(file.Categories, MenuCategoryRow{
+			Name:        category.Name,
+			Description: &category.Description,
+			IsActive:    &category.IsActive,
+			SortOrder:   &category.SortOrder,
+			Station:     &category.Station,
+		})
+		for j := range category.MenuItems {
+			item := &category.MenuItems[j]
+			file.Items = append(file.Items, MenuItemRow{
+				SKU:           stringValue(item.SKU),
+				ExternalCodes: codes[item.ID],
+				Category:      category.Name,
+				Name:          item.Name,
+				Description:   &item.Description,
+				Price:         &item.Price,
+				IsAvailable:   &item.IsAvailable,
+				SortOrder:     &item.SortOrder,
+				Station:       &item.Station,
+			})
+		}
+	}
+	return file, nil
+}
+
+// ImportMenu matches the categories and items of a menu file to the menu and
+// creates or updates them. Categories are matched by name. Items are matched
+// by SKU, then by delivery platform SKU, then by name within their category.
+// Items missing from the file are left alone. Nothing is written when
+// dryRun is set or when any row is invalid; otherwise the whole file is
+// applied in one transaction. rowErrors are problems found while parsing.
+func (s *MenuImportService) ImportMenu(file *MenuFile, rowErrors []ImportRowError, dryRun bool) (*MenuImportResult, error) {
+	if dryRun {
+		plan, err := planMenuImport(s.menuRepo, s.mappingRepo, file, rowErrors, false)
+		if err != nil {
+			return nil, err
+		}
+		return plan.result(true), nil
+	}
+
+	var result *MenuImportResult
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		plan, err := planMenuImport(uow.Menu, uow.ItemMappings, file, rowErrors, false)
+		if err != nil {
+			return err
+		}
+		if len(plan.errors) == 0 {
+			if err := plan.apply(uow, priceOrigin{source: repositories.PriceSourceImport}); err != nil {
+				return err
+			}
+		}
+		result = plan.result(false)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Applied {
+		s.menuCache.Invalidate()
+	}
+	return result, nil
+}
+
+// menuImportPlan is what an import will do, worked out before anything is
+// written so a dry run shows exactly what applying would.
+type menuImportPlan struct {
+	categoryNames map[uint]string // Names of the existing categories
+	categories    []*plannedCategory
+	items         []*plannedItem
+	errors        []ImportRowError
+}
+
+type plannedCategory struct {
+	change   ImportChange
+	category repositories.MenuCategory
+}
+
+type plannedItem struct {
+	change   ImportChange
+	item     repositories.MenuItem
+	category *plannedCategory
+	newCodes []repositories.ExternalItemMapping
+}
+
+// priceOrigin is why an import changes prices, for the price history.
+type priceOrigin struct {
+	source    repositories.PriceSource
+	versionID *uint
+	actorID   uint
+}
+
+// planMenuImport works out what importing file does. A complete file is the
+// whole menu: categories and items missing from it are taken off the menu.
+func planMenuImport(menuRepo *repositories.MenuRepository, mappingRepo *repositories.ExternalItemMappingRepository, file *MenuFile, rowErrors []ImportRowError, complete bool) (*menuImportPlan, error) {
+	existing, err := menuRepo.GetMenuForExport()
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := mappingRepo.GetByPlatform("")
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &menuImportPlan{categoryNames: make(map[uint]string), errors: append([]ImportRowError{}, rowErrors...)}
+	categoriesByName := make(map[string]*repositories.MenuCategory)
+	items := make(map[uint]*repositories.MenuItem)
+	itemsBySKU := make(map[string]*repositories.MenuItem)
+	itemsByName := make(map[string]*repositories.MenuItem)
+	for i := range existing {
+		category := &existing[i]
+		categoriesByName[nameKey(category.Name)] = category
+		plan.categoryNames[category.ID] = category.Name
+		for j := range category.MenuItems {
+			item := &category.MenuItems[j]
+			items[item.ID] = item
+			if item.SKU != nil {
+				itemsBySKU[*item.SKU] = item
+			}
+			itemsByName[itemNameKey(category.ID, item.Name)] = item
+		}
+	}
+	codeOwners := make(map[string]uint, len(mappings))
+	for _, mapping := range mappings {
+		codeOwners[mapping.Platform+":"+mapping.SKU] = mapping.MenuItemID
+	}
+
+	planned := make(map[string]*plannedCategory)
+	for i, row := range file.Categories {
+		fail := func(field, message string) {
+			plan.errors = append(plan.errors, ImportRowError{Section: "categories", Row: i + 1, Field: field, Message: message})
+		}
+		name := strings.TrimSpace(row.Name)
+		if name == "" {
+			fail("name", "name is required")
+			continue
+		}
+		if planned[nameKey(name)] != nil {
+			fail("name", fmt.Sprintf("category %q is listed more than once", name))
+			continue
+		}
+		entry := plan.planCategory(i+1, name, categoriesByName[nameKey(name)])
+		entry.set("description", &entry.category.Description, row.Description)
+		entry.set("station", &entry.category.Station, row.Station)
+		if row.IsActive != nil {
+			recordChange(&entry.change, "is_active", entry.category.IsActive, *row.IsActive)
+			entry.category.IsActive = *row.IsActive
+		}
+		if row.SortOrder != nil {
+			recordChange(&entry.change, "sort_order", entry.category.SortOrder, *row.SortOrder)
+			entry.category.SortOrder = *row.SortOrder
+		}
+		planned[nameKey(name)] = entry
+	}
+
+	claimed := make(map[uint]int) // Existing items matched so far, and by which row
+	seenSKUs := make(map[string]int)
+	seenCodes := make(map[string]int)
+	seenNames := make(map[string]int)
+	for _, row := range file.Items {
+		failed := false
+		fail := func(field, message string) {
+			plan.errors = append(plan.errors, ImportRowError{Section: "items", Row: row.row, Field: field, Message: message})
+			failed = true
+		}
+
+		sku := strings.TrimSpace(row.SKU)
+		categoryName := strings.TrimSpace(row.Category)
+		name := strings.TrimSpace(row.Name)
+		if categoryName == "" {
+			fail("category", "category is required")
+		}
+		if name == "" {
+			fail("name", "name is required")
+		}
+		if row.Price != nil && *row.Price < 0 {
+			fail("price", "price cannot be negative")
+		}
+		if sku != "" {
+			if other, ok := seenSKUs[sku]; ok {
+				fail("sku", fmt.Sprintf("SKU %q is also used on row %d", sku, other))
+			}
+			seenSKUs[sku] = row.row
+		}
+
+		var codes []repositories.ExternalItemMapping
+		var codeOwner *repositories.MenuItem
+		for _, code := range row.ExternalCodes {
+			platform, platformSKU, ok := strings.Cut(strings.TrimSpace(code), ":")
+			platform, platformSKU = strings.ToLower(strings.TrimSpace(platform)), strings.TrimSpace(platformSKU)
+			if _, known := aggregators.Lookup(platform); !ok || !known || platformSKU == "" {
+				fail("external_codes", fmt.Sprintf("%q is not a platform SKU such as grabfood:GF-12. Platforms are: %s", code, strings.Join(aggregators.Platforms(), ", ")))
+				continue
+			}
+			code = platform + ":" + platformSKU
+			if other, ok := seenCodes[code]; ok {
+				fail("external_codes", fmt.Sprintf("%s is also used on row %d", code, other))
+			}
+			seenCodes[code] = row.row
+			// Mappings of deleted items are moved to the row's item
+			if id, ok := codeOwners[code]; ok && items[id] != nil {
+				if codeOwner != nil && codeOwner.ID != id {
+					fail("external_codes", fmt.Sprintf("the platform SKUs belong to different items (#%d and #%d)", codeOwner.ID, id))
+				}
+				codeOwner = items[id]
+				continue
+			}
+			codes = append(codes, repositories.ExternalItemMapping{Platform: platform, SKU: platformSKU})
+		}
+		if failed {
+			continue
+		}
+
+		// Match the row to an existing item
+		current := itemsBySKU[sku]
+		if sku == "" {
+			current = nil
+		}
+		if codeOwner != nil {
+			if current != nil && current.ID != codeOwner.ID {
+				fail("external_codes", fmt.Sprintf("the platform SKUs belong to item #%d, not to item #%d with SKU %s", codeOwner.ID, current.ID, sku))
+				continue
+			}
+			current = codeOwner
+		}
+		existingCategory := categoriesByName[nameKey(categoryName)]
+		if current == nil && existingCategory != nil {
+			if match := itemsByName[itemNameKey(existingCategory.ID, name)]; match != nil && (match.SKU == nil || sku == "") {
+				current = match
+			}
+		}
+		if current == nil && sku == "" && len(row.ExternalCodes) == 0 {
+			key := nameKey(categoryName) + "\x00" + nameKey(name)
+			if other, ok := seenNames[key]; ok {
+				fail("name", fmt.Sprintf("%s / %s is also listed on row %d; give the items a SKU to tell them apart", categoryName, name, other))
+				continue
+			}
+			seenNames[key] = row.row
+		}
+		if current != nil {
+			if other, ok := claimed[current.ID]; ok {
+				fail("", fmt.Sprintf("matches the same item (#%d) as row %d", current.ID, other))
+				continue
+			}
+			claimed[current.ID] = row.row
+		}
+		if current == nil && row.Price == nil {
+			fail("price", "price is required for new items")
+			continue
+		}
+
+		category := planned[nameKey(categoryName)]
+		if category == nil {
+			category = plan.planCategory(0, categoryName, existingCategory)
+			planned[nameKey(categoryName)] = category
+		}
+		plan.planItem(row, sku, name, current, category, codes)
+	}
+
+	if complete {
+		plan.withdrawMissing(existing, planned, claimed)
+	}
+
+	// Categories created only because items name them report the first row
+	// naming them
+	for _, item := range plan.items {
+		if item.category.change.Row == 0 && item.category.change.Action == ImportCreate {
+			item.category.change.Row = item.change.Row
+		}
+	}
+	sort.SliceStable(plan.errors, func(i, j int) bool {
+		if plan.errors[i].Section != plan.errors[j].Section {
+			return plan.errors[i].Section == "categories"
+		}
+		return plan.errors[i].Row < plan.errors[j].Row
+	})
+	return plan, nil
+}
+
+func (plan *menuImportPlan) planCategory(row int, name string, current *repositories.MenuCategory) *plannedCategory {
+	entry := &plannedCategory{change: ImportChange{Row: row, Action: ImportUnchanged, Name: name}}
+	if current != nil {
+		entry.category = *current
+		entry.category.MenuItems = nil
+		entry.change.ID = current.ID
+		if current.Name != name {
+			recordChange(&entry.change, "name", current.Name, name)
+			entry.category.Name = name
+		}
+	} else {
+		entry.category = repositories.MenuCategory{Name: name, IsActive: true, Station: "kitchen"}
+		entry.change.Action = ImportCreate
+	}
+	plan.categories = append(plan.categories, entry)
+	return entry
+}
+
+func (entry *plannedCategory) set(field string, target *string, value *string) {
+	if value == nil {
+		return
+	}
+	recordChange(&entry.change, field, *target, *value)
+	*target = *value
+}
+
+func (plan *menuImportPlan) planItem(row MenuItemRow, sku, name string, current *repositories.MenuItem, category *plannedCategory, codes []repositories.ExternalItemMapping) {
+	entry := &plannedItem{
+		change:   ImportChange{Row: row.row, Action: ImportUnchanged, Name: name},
+		category: category,
+		newCodes: codes,
+	}
+	if current != nil {
+		entry.item = *current
+		entry.change.ID = current.ID
+		if current.CategoryID != category.category.ID || category.change.Action == ImportCreate {
+			recordChange(&entry.change, "category", plan.categoryNames[current.CategoryID], category.category.Name)
+		}
+	} else {
+		entry.item = repositories.MenuItem{IsAvailable: true}
+		entry.change.Action = ImportCreate
+		recordChange(&entry.change, "category", nil, category.category.Name)
+	}
+
+	if sku != "" && stringValue(entry.item.SKU) != sku {
+		recordChange(&entry.change, "sku", entry.item.SKU, sku)
+		entry.item.SKU = &sku
+	}
+	if entry.item.Name != name {
+		recordChange(&entry.change, "name", entry.item.Name, name)
+		entry.item.Name = name
+	}
+	if row.Description != nil && entry.item.Description != *row.Description {
+		recordChange(&entry.change, "description", entry.item.Description, *row.Description)
+		entry.item.Description = *row.Description
+	}
+	if row.Price != nil && (entry.item.Price != *row.Price || current == nil) {
+		recordChange(&entry.change, "price", entry.item.Price, *row.Price)
+		entry.item.Price = *row.Price
+	}
+	if row.IsAvailable != nil && entry.item.IsAvailable != *row.IsAvailable {
+		recordChange(&entry.change, "is_available", entry.item.IsAvailable, *row.IsAvailable)
+		entry.item.IsAvailable = *row.IsAvailable
+	}
+	if row.SortOrder != nil && entry.item.SortOrder != *row.SortOrder {
+		recordChange(&entry.change, "sort_order", entry.item.SortOrder, *row.SortOrder)
+		entry.item.SortOrder = *row.SortOrder
+	}
+	if row.Station != nil && entry.item.Station != *row.Station {
+		recordChange(&entry.change, "station", entry.item.Station, *row.Station)
+		entry.item.Station = *row.Station
+	}
+	for _, code := range codes {
+		recordChange(&entry.change, "external_codes", nil, code.Platform+":"+code.SKU)
+	}
+	if entry.change.Action == ImportUnchanged && len(entry.change.Changes) > 0 {
+		entry.change.Action = ImportUpdate
+	}
+	plan.items = append(plan.items, entry)
+}
+
+// withdrawMissing takes the categories and items that a complete file leaves
+// out off the menu. They are deactivated rather than deleted, so rolling back
+// to a version that has them brings them back.
+func (plan *menuImportPlan) withdrawMissing(existing []repositories.MenuCategory, planned map[string]*plannedCategory, claimed map[uint]int) {
+	for i := range existing {
+		current := &existing[i]
+		category := planned[nameKey(current.Name)]
+		if category == nil {
+			category = &plannedCategory{change: ImportChange{Action: ImportUnchanged, ID: current.ID, Name: current.Name}, category: *current}
+			category.category.MenuItems = nil
+			if current.IsActive {
+				recordChange(&category.change, "is_active", true, false)
+				category.category.IsActive = false
+				plan.categories = append(plan.categories, category)
+			}
+		}
+		for j := range current.MenuItems {
+			item := &current.MenuItems[j]
+			if _, ok := claimed[item.ID]; ok || !item.IsAvailable {
+				continue
+			}
+			entry := &plannedItem{
+				change:   ImportChange{Action: ImportUnchanged, ID: item.ID, Name: item.Name},
+				item:     *item,
+				category: category,
+			}
+			recordChange(&entry.change, "is_available", true, false)
+			entry.item.IsAvailable = false
+			plan.items = append(plan.items, entry)
+		}
+	}
+}
+
+// recordChange notes a field change and marks an unchanged record as
+// updated. Repeated changes of external_codes are collected into a list.
+func recordChange(change *ImportChange, field string, from, to interface{}) {
+	if change.Action != ImportCreate && from == to {
+		return
+	}
+	if change.Changes == nil {
+		change.Changes = make(map[string]FieldChange)
+	}
+	if change.Action != ImportCreate {
+		change.Action = ImportUpdate
+	} else {
+		from = nil
+	}
+	if field == "external_codes" {
+		added, _ := change.Changes[field].To.([]string)
+		change.Changes[field] = FieldChange{To: append(added, to.(string))}
+		return
+	}
+	change.Changes[field] = FieldChange{From: from, To: to}
+}
+
+// apply writes the plan. Category and item IDs of new records are filled in
+// as they are created. New prices are added to the price history.
+func (plan *menuImportPlan) apply(uow *repositories.UnitOfWork, origin priceOrigin) error {
+	for _, entry := range plan.categories {
+		switch entry.change.Action {
+		case ImportCreate:
+			if err := uow.Menu.CreateCategory(&entry.category); err != nil {
+				return err
+			}
+			entry.change.ID = entry.category.ID
+		case ImportUpdate:
+			if err := uow.Menu.UpdateCategory(&entry.category); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, entry := range plan.items {
+		entry.item.CategoryID = entry.category.category.ID
+		// Relations are left to their own endpoints
+		entry.item.Category = repositories.MenuCategory{}
+		entry.item.Schedules, entry.item.Translations = nil, nil
+		switch entry.change.Action {
+		case ImportCreate:
+			if err := uow.Menu.CreateMenuItem(&entry.item); err != nil {
+				return err
+			}
+			entry.change.ID = entry.item.ID
+		case ImportUpdate:
+			if err := uow.Menu.UpdateMenuItem(&entry.item); err != nil {
+				return err
+			}
+		}
+		if _, ok := entry.change.Changes["price"]; ok {
+			if err := recordPrice(uow, entry.item.ID, entry.item.Price, origin); err != nil {
+				return err
+			}
+		}
+		for _, mapping := range entry.newCodes {
+			mapping.MenuItemID = entry.item.ID
+			if err := uow.ItemMappings.Upsert(&mapping); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (plan *menuImportPlan) result(dryRun bool) *MenuImportResult {
+	result := &MenuImportResult{
+		DryRun:     dryRun,
+		Applied:    !dryRun && len(plan.errors) == 0,
+		Categories: make([]ImportChange, 0, len(plan.categories)),
+		Items:      make([]ImportChange, 0, len(plan.items)),
+		Errors:     plan.errors,
+	}
+	count := func(change ImportChange) {
+		switch change.Action {
+		case ImportCreate:
+			result.Summary.Created++
+		case ImportUpdate:
+			result.Summary.Updated++
+		default:
+			result.Summary.Unchanged++
+		}
+	}
+	for _, entry := range plan.categories {
+		result.Categories = append(result.Categories, entry.change)
+		count(entry.change)
+	}
+	for _, entry := range plan.items {
+		result.Items = append(result.Items, entry.change)
+		count(entry.change)
+	}
+	result.Summary.Errors = len(plan.errors)
+	return result
+}
+
+// nameKey compares names regardless of case and surrounding spaces.
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func itemNameKey(categoryID uint, name string) string {
+	return fmt.Sprintf("%d\x00%s", categoryID, nameKey(name))
+}
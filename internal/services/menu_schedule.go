@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+// MenuScheduleRequest is one window in which a category or item is on the
+// menu. See repositories.MenuSchedule for the meaning of the fields.
+type MenuScheduleRequest struct {
+	Name      string   `json:"name"`
+	Days      []string `json:"days"`
+	StartTime string   `json:"start_time"`
+	EndTime   string   `json:"end_time"`
+	StartDate string   `json:"start_date"`
+	EndDate   string   `json:"end_date"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// menuSchedules validates schedule requests and turns them into schedules.
+func menuSchedules(reqs []MenuScheduleRequest) ([]repositories.MenuSchedule, error) {
+	schedules := make([]repositories.MenuSchedule, 0, len(reqs))
+	for _, req := range reqs {
+		schedule := repositories.MenuSchedule{
+			Name:      strings.TrimSpace(req.Name),
+			Days:      req.Days,
+			StartTime: strings.TrimSpace(req.StartTime),
+			EndTime:   strings.TrimSpace(req.EndTime),
+			StartDate: strings.TrimSpace(req.StartDate),
+			EndDate:   strings.TrimSpace(req.EndDate),
+		}
+		if err := validateMenuSchedule(&schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// validateMenuSchedule checks a schedule, normalizing its day names.
+func validateMenuSchedule(schedule *repositories.MenuSchedule) error {
+	for i, day := range schedule.Days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if _, ok := weekdays[day]; !ok {
+			return fmt.Errorf("invalid day %q. Use mon, tue, wed, thu, fri, sat or sun", day)
+		}
+		schedule.Days[i] = day
+	}
+
+	start, end, err := scheduleTimes(schedule)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return errors.New("schedule start and end time must differ")
+	}
+
+	for _, date := range []string{schedule.StartDate, schedule.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid schedule date %q. Use YYYY-MM-DD", date)
+		}
+	}
+	if schedule.StartDate != "" && schedule.EndDate != "" && schedule.EndDate < schedule.StartDate {
+		return errors.New("schedule end date is before its start date")
+	}
+	return nil
+}
+
+// scheduleTimes returns the window of a schedule in minutes after midnight.
+// A missing start time means midnight and a missing end time the end of the day.
+func scheduleTimes(schedule *repositories.MenuSchedule) (start, end int, err error) {
+	start, end = 0, 24*60
+	if schedule.StartTime != "" {
+		if start, err = clockMinutes(schedule.StartTime); err != nil {
+			return 0, 0, err
+		}
+	}
+	if schedule.EndTime != "" {
+		if end, err = clockMinutes(schedule.EndTime); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, end, nil
+}
+
+func clockMinutes(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %q. Use HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// scheduleOpen reports whether t, in the restaurant's time zone, falls inside
+// the schedule. The days and dates of a window that runs past midnight are
+// those of the evening it starts on, so a Friday 22:00-02:00 window is still
+// open early on Saturday.
+func scheduleOpen(schedule *repositories.MenuSchedule, t time.Time) bool {
+	start, end, err := scheduleTimes(schedule)
+	if err != nil {
+		return false
+	}
+
+	day := t
+	clock := t.Hour()*60 + t.Minute()
+	switch {
+	case start < end:
+		if clock < start || clock >= end {
+			return false
+		}
+	case clock >= start:
+	case clock < end:
+		day = t.AddDate(0, 0, -1)
+	default:
+		return false
+	}
+
+	if len(schedule.Days) > 0 {
+		onDay := false
+		for _, name := range schedule.Days {
+			if weekdays[name] == day.Weekday() {
+				onDay = true
+			}
+		}
+		if !onDay {
+			return false
+		}
+	}
+
+	date := day.Format("2006-01-02")
+	if schedule.StartDate != "" && date < schedule.StartDate {
+		return false
+	}
+	if schedule.EndDate != "" && date > schedule.EndDate {
+		return false
+	}
+	return true
+}
+
+// scheduledAt reports whether a category or item with the given schedules is
+// on the menu at t.
+func scheduledAt(schedules []repositories.MenuSchedule, t time.Time) bool {
+	if len(schedules) == 0 {
+		return true
+	}
+	for i := range schedules {
+		if scheduleOpen(&schedules[i], t) {
+			return true
+		}
+	}
+	return false
+}
+
+// onMenuAt reports whether a menu item and its category are both scheduled at
+// t. The item must have been loaded with its category's schedules.
+func onMenuAt(item *repositories.MenuItem, t time.Time) bool {
+	return scheduledAt(item.Category.Schedules, t) && scheduledAt(item.Schedules, t)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/search"
+)
+
+// snippetWords is roughly how many words of a description a search result
+// shows around the match.
+const snippetWords = 12
+
+// MenuSearchResult is a menu item found by a search, how well it matched and
+// its text with the matching words marked.
+type MenuSearchResult struct {
+	repositories.MenuItem
+	Score     float64       `json:"score"`
+	Highlight MenuHighlight `json:"highlight"`
+}
+
+// MenuHighlight is the HTML-escaped text of a search result with the words
+// that match the query wrapped in <mark> tags. Description is a snippet
+// around the first match and is left out when the description does not match.
+type MenuHighlight struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description,omitempty"`
+}
+
+// highlightMenuItem marks the query in the text the guest sees, after the
+// item has been localized.
+func highlightMenuItem(item *repositories.MenuItem, query string) MenuHighlight {
+	return MenuHighlight{
+		Name:        search.Highlight(item.Name, query),
+		Category:    search.Highlight(item.Category.Name, query),
+		Description: search.Snippet(item.Description, query, snippetWords),
+	}
+}
@@ -1,25 +1,68 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/config"
 	"recursiveDine/internal/repositories"
 )
 
+// ErrDuplicateSKU is returned when a menu item is given the SKU of another.
+var ErrDuplicateSKU = errors.New("another menu item already has this SKU")
+
 type MenuService struct {
-	menuRepo *repositories.MenuRepository
+	menuRepo   *repositories.MenuRepository
+	menuCache  *MenuCache
+	transactor *repositories.Transactor
+	config     *config.Config
 }
 
-func NewMenuService(menuRepo *repositories.MenuRepository) *MenuService {
+// MenuOptions chooses the menu a guest is shown.
+type MenuOptions struct {
+	At               time.Time // Only dayparts served at this time are shown
+	Locales          []string  // Preferred languages, most preferred first
+	ExcludeAllergens []string  // Leave out dishes containing any of these
+	Diets            []string  // Only dishes suiting all of these
+}
+
+func NewMenuService(menuRepo *repositories.MenuRepository, menuCache *MenuCache, transactor *repositories.Transactor, config *config.Config) *MenuService {
 	return &MenuService{
-		menuRepo: menuRepo,
+		menuRepo:   menuRepo,
+		menuCache:  menuCache,
+		transactor: transactor,
+		config:     config,
+	}
+}
+
+// GetCompleteMenu returns the categories and items that are on the menu at
+// the chosen time, leaving out dayparts that are not being served, in the
+// guest's language.
+func (s *MenuService) GetCompleteMenu(opts MenuOptions) ([]repositories.MenuCategory, error) {
+	categories, err := s.menuCache.categories("complete", s.menuRepo.GetCompleteMenu)
+	if err != nil {
+		return nil, err
 	}
+	return s.guestCategories(categories, opts), nil
 }
 
-func (s *MenuService) GetCompleteMenu() ([]repositories.MenuCategory, error) {
-	return s.menuRepo.GetCompleteMenu()
+func (s *MenuService) GetAllCategories(opts MenuOptions) ([]repositories.MenuCategory, error) {
+	categories, err := s.menuCache.categories("categories", s.menuRepo.GetAllCategories)
+	if err != nil {
+		return nil, err
+	}
+	return s.guestCategories(categories, opts), nil
 }
 
-func (s *MenuService) GetAllCategories() ([]repositories.MenuCategory, error) {
-	return s.menuRepo.GetAllCategories()
+// changed invalidates the cached menu once a write has succeeded, passing
+// the write's error through.
+func (s *MenuService) changed(err error) error {
+	if err == nil {
+		s.menuCache.Invalidate()
+	}
+	return err
 }
 
 func (s *MenuService) GetCategoryByID(id uint) (*repositories.MenuCategory, error) {
@@ -37,43 +80,307 @@ func (s *MenuService) GetMenuItemByID(id uint) (*repositories.MenuItem, error) {
 // Category CRUD operations
 
 func (s *MenuService) CreateCategory(category *repositories.MenuCategory) error {
-	return s.menuRepo.CreateCategory(category)
+	for i := range category.Schedules {
+		if err := validateMenuSchedule(&category.Schedules[i]); err != nil {
+			return err
+		}
+	}
+	return s.changed(s.menuRepo.CreateCategory(category))
 }
 
 func (s *MenuService) UpdateCategory(category *repositories.MenuCategory) error {
-	return s.menuRepo.UpdateCategory(category)
+	// Schedules are replaced through SetCategorySchedules
+	category.Schedules = nil
+	return s.changed(s.menuRepo.UpdateCategory(category))
 }
 
 func (s *MenuService) DeleteCategory(id uint) error {
-	return s.menuRepo.DeleteCategory(id)
+	return s.changed(s.menuRepo.DeleteCategory(id))
 }
 
 // Menu Item CRUD operations
 
 func (s *MenuService) CreateMenuItem(item *repositories.MenuItem) error {
-	return s.menuRepo.CreateMenuItem(item)
+	if err := validateMenuItemTags(item); err != nil {
+		return err
+	}
+	if err := s.checkSKU(item); err != nil {
+		return err
+	}
+	for i := range item.Schedules {
+		if err := validateMenuSchedule(&item.Schedules[i]); err != nil {
+			return err
+		}
+	}
+	// Bundle slots are set through SetMenuItemBundle once the item exists
+	item.BundleSlots = nil
+	return s.changed(s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Menu.CreateMenuItem(item); err != nil {
+			return err
+		}
+		return recordPrice(uow, item.ID, item.Price, priceOrigin{source: repositories.PriceSourceInitial})
+	}))
 }
 
 func (s *MenuService) UpdateMenuItem(item *repositories.MenuItem) error {
-	return s.menuRepo.UpdateMenuItem(item)
+	if err := validateMenuItemTags(item); err != nil {
+		return err
+	}
+	existing, err := s.menuRepo.GetMenuItemByID(item.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkSKU(item); err != nil {
+		return err
+	}
+	// Uploaded images are replaced through MenuImageService; other image URLs
+	// can still be set here
+	if len(existing.ImageKeys) > 0 {
+		item.ImageURL, item.ImageVariants, item.ImageKeys = existing.ImageURL, existing.ImageVariants, existing.ImageKeys
+	} else {
+		item.ImageVariants, item.ImageKeys = nil, nil
+	}
+	// Schedules and bundle slots are replaced through SetMenuItemSchedules
+	// and SetMenuItemBundle
+	item.Schedules, item.BundleSlots = nil, nil
+	return s.changed(s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Menu.UpdateMenuItem(item); err != nil {
+			return err
+		}
+		if item.Price == existing.Price {
+			return nil
+		}
+		return recordPrice(uow, item.ID, item.Price, priceOrigin{source: repositories.PriceSourceEdit})
+	}))
+}
+
+// checkSKU trims the SKU of an item, treating a blank one as none, and
+// checks no other item has it.
+func (s *MenuService) checkSKU(item *repositories.MenuItem) error {
+	item.SKU = normalizeSKU(item.SKU)
+	if item.SKU == nil {
+		return nil
+	}
+	other, err := s.menuRepo.GetMenuItemBySKU(*item.SKU)
+	if err != nil {
+		return err
+	}
+	if other != nil && other.ID != item.ID {
+		return ErrDuplicateSKU
+	}
+	return nil
+}
+
+func normalizeSKU(sku *string) *string {
+	if sku == nil || strings.TrimSpace(*sku) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*sku)
+	return &trimmed
 }
 
 func (s *MenuService) DeleteMenuItem(id uint) error {
-	return s.menuRepo.DeleteMenuItem(id)
+	return s.changed(s.menuRepo.DeleteMenuItem(id))
 }
 
 func (s *MenuService) UpdateMenuItemAvailability(id uint, available bool) error {
-	return s.menuRepo.UpdateMenuItemAvailability(id, available)
+	return s.changed(s.menuRepo.UpdateMenuItemAvailability(id, available))
 }
 
-func (s *MenuService) GetMenuItemsByCategory(categoryID uint) ([]repositories.MenuItem, error) {
-	return s.menuRepo.GetMenuItemsByCategory(categoryID)
+func (s *MenuService) GetMenuItemsByCategory(categoryID uint, opts MenuOptions) ([]repositories.MenuItem, error) {
+	items, err := s.menuRepo.GetMenuItemsByCategory(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return s.guestItems(items, opts), nil
 }
 
-func (s *MenuService) SearchMenuItems(query string) ([]repositories.MenuItem, error) {
-	return s.menuRepo.SearchMenuItems(query)
+// SearchMenuItems finds items by their name, category or description in any
+// language the menu is written or translated in, best match first, and marks
+// the matching words in the text shown to the guest.
+func (s *MenuService) SearchMenuItems(query string, opts MenuOptions) ([]MenuSearchResult, error) {
+	hits, err := s.menuRepo.SearchMenuItems(query)
+	if err != nil {
+		return nil, err
+	}
+
+	local := opts.At.In(s.config.Location())
+	chain := localeChain(opts.Locales, s.config.MenuLocale)
+	results := make([]MenuSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		item := hit.Item
+		if !onMenuAt(&item, local) || !opts.suits(&item) {
+			continue
+		}
+		localizeMenuItem(&item, chain)
+		results = append(results, MenuSearchResult{
+			MenuItem:  item,
+			Score:     hit.Score,
+			Highlight: highlightMenuItem(&item, query),
+		})
+	}
+	return results, nil
 }
 
 func (s *MenuService) GetMenuItemsByIDs(ids []uint) ([]repositories.MenuItem, error) {
 	return s.menuRepo.GetMenuItemsByIDs(ids)
 }
+
+// Schedules
+
+// SetCategorySchedules replaces the schedules of a category. An empty list
+// puts the category on the menu all day.
+func (s *MenuService) SetCategorySchedules(categoryID uint, reqs []MenuScheduleRequest) (*repositories.MenuCategory, error) {
+	if _, err := s.menuRepo.GetCategoryByID(categoryID); err != nil {
+		return nil, err
+	}
+	schedules, err := menuSchedules(reqs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.changed(s.menuRepo.ReplaceCategorySchedules(categoryID, schedules)); err != nil {
+		return nil, err
+	}
+	return s.menuRepo.GetCategoryByID(categoryID)
+}
+
+// SetMenuItemSchedules replaces the schedules of a menu item. The item is
+// only served when its category is scheduled as well.
+func (s *MenuService) SetMenuItemSchedules(menuItemID uint, reqs []MenuScheduleRequest) (*repositories.MenuItem, error) {
+	if _, err := s.menuRepo.GetMenuItemByID(menuItemID); err != nil {
+		return nil, err
+	}
+	schedules, err := menuSchedules(reqs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.changed(s.menuRepo.ReplaceMenuItemSchedules(menuItemID, schedules)); err != nil {
+		return nil, err
+	}
+	return s.menuRepo.GetMenuItemByID(menuItemID)
+}
+
+// guestCategories drops the categories and items that are not scheduled at
+// the chosen time or do not pass the guest's filters, along with categories
+// left without items, and translates the rest.
+func (s *MenuService) guestCategories(categories []repositories.MenuCategory, opts MenuOptions) []repositories.MenuCategory {
+	local := opts.At.In(s.config.Location())
+	chain := localeChain(opts.Locales, s.config.MenuLocale)
+	scheduled := make([]repositories.MenuCategory, 0, len(categories))
+	for _, category := range categories {
+		if !scheduledAt(category.Schedules, local) {
+			continue
+		}
+		items := make([]repositories.MenuItem, 0, len(category.MenuItems))
+		for _, item := range category.MenuItems {
+			if scheduledAt(item.Schedules, local) && opts.suits(&item) {
+				items = append(items, item)
+			}
+		}
+		if len(items) == 0 && len(category.MenuItems) > 0 {
+			continue
+		}
+		category.MenuItems = items
+		localizeCategory(&category, chain)
+		scheduled = append(scheduled, category)
+	}
+	return scheduled
+}
+
+func (s *MenuService) guestItems(items []repositories.MenuItem, opts MenuOptions) []repositories.MenuItem {
+	local := opts.At.In(s.config.Location())
+	chain := localeChain(opts.Locales, s.config.MenuLocale)
+	scheduled := make([]repositories.MenuItem, 0, len(items))
+	for i := range items {
+		if onMenuAt(&items[i], local) && opts.suits(&items[i]) {
+			localizeMenuItem(&items[i], chain)
+			scheduled = append(scheduled, items[i])
+		}
+	}
+	return scheduled
+}
+
+// Translations
+
+func (s *MenuService) GetCategoryTranslations(categoryID uint) ([]repositories.MenuTranslation, error) {
+	category, err := s.menuRepo.GetCategoryByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return category.Translations, nil
+}
+
+func (s *MenuService) GetMenuItemTranslations(menuItemID uint) ([]repositories.MenuTranslation, error) {
+	item, err := s.menuRepo.GetMenuItemByID(menuItemID)
+	if err != nil {
+		return nil, err
+	}
+	return item.Translations, nil
+}
+
+// SetCategoryTranslation adds or replaces the translation of a category into
+// a locale other than MENU_LOCALE.
+func (s *MenuService) SetCategoryTranslation(categoryID uint, locale string, req *TranslationRequest) (*repositories.MenuTranslation, error) {
+	translation, err := s.menuTranslation(locale, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.menuRepo.GetCategoryByID(categoryID); err != nil {
+		return nil, err
+	}
+	if err := s.changed(s.menuRepo.UpsertCategoryTranslation(categoryID, translation)); err != nil {
+		return nil, errors.New("failed to save translation")
+	}
+	return translation, nil
+}
+
+// SetMenuItemTranslation adds or replaces the translation of a menu item into
+// a locale other than MENU_LOCALE.
+func (s *MenuService) SetMenuItemTranslation(menuItemID uint, locale string, req *TranslationRequest) (*repositories.MenuTranslation, error) {
+	translation, err := s.menuTranslation(locale, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.menuRepo.GetMenuItemByID(menuItemID); err != nil {
+		return nil, err
+	}
+	if err := s.changed(s.menuRepo.UpsertMenuItemTranslation(menuItemID, translation)); err != nil {
+		return nil, errors.New("failed to save translation")
+	}
+	return translation, nil
+}
+
+func (s *MenuService) DeleteCategoryTranslation(categoryID uint, locale string) error {
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return err
+	}
+	return s.changed(s.menuRepo.DeleteCategoryTranslation(categoryID, locale))
+}
+
+func (s *MenuService) DeleteMenuItemTranslation(menuItemID uint, locale string) error {
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return err
+	}
+	return s.changed(s.menuRepo.DeleteMenuItemTranslation(menuItemID, locale))
+}
+
+func (s *MenuService) menuTranslation(locale string, req *TranslationRequest) (*repositories.MenuTranslation, error) {
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+	if locale == s.config.MenuLocale {
+		return nil, fmt.Errorf("the menu is written in %s; update the category or item itself instead", locale)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	return &repositories.MenuTranslation{
+		Locale:      locale,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+	}, nil
+}
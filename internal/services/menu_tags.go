@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"recursiveDine/internal/repositories"
+)
+
+// ErrInvalidMenuTag is returned for unknown or contradicting allergen and
+// dietary tags.
+var ErrInvalidMenuTag = errors.New("invalid menu tag")
+
+// Allergens are the allergen tags a menu item can carry.
+var Allergens = []string{"peanut", "tree_nut", "gluten", "dairy", "egg", "soy", "fish", "shellfish", "sesame"}
+
+// DietaryTags are the diets a menu item can be marked as suitable for.
+var DietaryTags = []string{"halal", "vegetarian", "vegan", "gluten_free", "dairy_free"}
+
+// dietConflicts lists the allergens a dish marked with a diet cannot contain.
+var dietConflicts = map[string][]string{
+	"vegan":       {"dairy", "egg", "fish", "shellfish"},
+	"vegetarian":  {"fish", "shellfish"},
+	"gluten_free": {"gluten"},
+	"dairy_free":  {"dairy"},
+}
+
+// normalizeTags lowercases tags, accepting tree-nut or "tree nut" for
+// tree_nut, drops repeats and rejects tags outside the vocabulary. A
+// misspelt allergen must not be ignored, or a filter on it would match
+// every dish.
+func normalizeTags(kind string, tags, vocabulary []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		tag = strings.NewReplacer("-", "_", " ", "_").Replace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !containsString(vocabulary, tag) {
+			return nil, fmt.Errorf("%w: unknown %s %q. Must be one of: %s", ErrInvalidMenuTag, kind, tag, strings.Join(vocabulary, ", "))
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
+
+// validateMenuItemTags normalizes the allergen and dietary tags of a menu item
+// and checks they do not contradict each other.
+func validateMenuItemTags(item *repositories.MenuItem) error {
+	allergens, err := normalizeTags("allergen", item.Allergens, Allergens)
+	if err != nil {
+		return err
+	}
+	diets, err := normalizeTags("dietary tag", item.DietaryTags, DietaryTags)
+	if err != nil {
+		return err
+	}
+
+	for _, diet := range diets {
+		for _, allergen := range dietConflicts[diet] {
+			if containsString(allergens, allergen) {
+				return fmt.Errorf("%w: a %s dish cannot contain %s", ErrInvalidMenuTag, diet, allergen)
+			}
+		}
+	}
+
+	if item.Nutrition != nil {
+		facts := item.Nutrition
+		for _, value := range []*float64{facts.Calories, facts.Protein, facts.Carbohydrates, facts.Sugar, facts.Fat, facts.Sodium} {
+			if value != nil && *value < 0 {
+				return errors.New("nutrition values cannot be negative")
+			}
+		}
+	}
+
+	item.Allergens, item.DietaryTags = allergens, diets
+	return nil
+}
+
+// SetFilters reads the comma-separated allergens to leave out and diets every
+// dish must suit, e.g. exclude_allergens=peanut,gluten and diet=vegetarian.
+func (o *MenuOptions) SetFilters(excludeAllergens, diets string) error {
+	var err error
+	if o.ExcludeAllergens, err = normalizeTags("allergen", splitList(excludeAllergens), Allergens); err != nil {
+		return err
+	}
+	o.Diets, err = normalizeTags("diet", splitList(diets), DietaryTags)
+	return err
+}
+
+// suits reports whether a menu item passes the allergen and diet filters.
+func (o *MenuOptions) suits(item *repositories.MenuItem) bool {
+	for _, allergen := range o.ExcludeAllergens {
+		if containsString(item.Allergens, allergen) {
+			return false
+		}
+	}
+	for _, diet := range o.Diets {
+		if !containsString(item.DietaryTags, diet) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"recursiveDine/internal/repositories"
+)
+
+// TranslationRequest is the name and description of a menu category or item
+// in one locale.
+type TranslationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// normalizeLocale lowercases a language tag such as en-AU or zh_Hant and
+// checks its shape.
+func normalizeLocale(locale string) (string, error) {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if !localePattern.MatchString(locale) {
+		return "", errors.New("invalid locale. Use a language tag such as en or en-AU")
+	}
+	return locale, nil
+}
+
+// localeChain lists the locales to look for translations in, most preferred
+// first. Each locale is followed by its base language, so en-au falls back to
+// en. The chain stops at the menu's own locale, whose text is used as is, and
+// invalid or repeated locales are skipped.
+func localeChain(preferred []string, menuLocale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	for _, locale := range preferred {
+		locale, err := normalizeLocale(locale)
+		if err != nil {
+			continue
+		}
+		candidates := []string{locale}
+		if base, _, ok := strings.Cut(locale, "-"); ok {
+			candidates = append(candidates, base)
+		}
+		for _, candidate := range candidates {
+			if candidate == menuLocale {
+				return chain
+			}
+			if !seen[candidate] {
+				seen[candidate] = true
+				chain = append(chain, candidate)
+			}
+		}
+	}
+	return chain
+}
+
+// translationFor returns the first translation along the chain, or nil when
+// the menu's own text should be shown.
+func translationFor(translations []repositories.MenuTranslation, chain []string) *repositories.MenuTranslation {
+	for _, locale := range chain {
+		for i := range translations {
+			if translations[i].Locale == locale {
+				return &translations[i]
+			}
+		}
+	}
+	return nil
+}
+
+// localizeCategory shows a category and its items in the first language of
+// the chain they are translated into. The translations themselves are left
+// out of what guests see.
+func localizeCategory(category *repositories.MenuCategory, chain []string) {
+	if translation := translationFor(category.Translations, chain); translation != nil {
+		category.Name = translation.Name
+		if translation.Description != "" {
+			category.Description = translation.Description
+		}
+	}
+	category.Translations = nil
+	for i := range category.MenuItems {
+		localizeMenuItem(&category.MenuItems[i], chain)
+	}
+}
+
+func localizeMenuItem(item *repositories.MenuItem, chain []string) {
+	if translation := translationFor(item.Translations, chain); translation != nil {
+		item.Name = translation.Name
+		if translation.Description != "" {
+			item.Description = translation.Description
+		}
+	}
+	item.Translations = nil
+	if item.Category.ID != 0 {
+		localizeCategory(&item.Category, chain)
+	}
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+// menuPricePollInterval is how often the worker looks for scheduled price
+// changes that are due.
+const menuPricePollInterval = 30 * time.Second
+
+var (
+	// ErrDraftExists is returned when a draft is started while another one is
+	// still open.
+	ErrDraftExists = errors.New("a draft menu version is already open; publish or discard it first")
+	// ErrNotDraft is returned when a published version is edited or published
+	// again.
+	ErrNotDraft = errors.New("only draft menu versions can be edited or published")
+	// ErrNotPublished is returned when rolling back to a draft.
+	ErrNotPublished = errors.New("only published menu versions can be rolled back to")
+	// ErrCurrentVersion is returned when rolling back to the live version.
+	ErrCurrentVersion = errors.New("this version is already the live menu")
+)
+
+// MenuVersionService keeps the price history of menu items, applies
+// future-dated price changes once they are due, and runs the draft and
+// publish workflow for menu edits. A version holds the whole menu in the
+// menu file format; publishing it makes the live menu match it, taking
+// categories and items it leaves out off the menu.
+type MenuVersionService struct {
+	versionRepo *repositories.MenuVersionRepository
+	menuRepo    *repositories.MenuRepository
+	mappingRepo *repositories.ExternalItemMappingRepository
+	menuCache   *MenuCache
+	transactor  *repositories.Transactor
+}
+
+// MenuItemPrices is the price of a menu item now, the changes scheduled for
+// it and the prices it had.
+type MenuItemPrices struct {
+	MenuItemID uint                         `json:"menu_item_id"`
+	Price      float64                      `json:"price"`
+	Scheduled  []repositories.MenuItemPrice `json:"scheduled"` // Soonest first
+	History    []repositories.MenuItemPrice `json:"history"`   // Latest first
+}
+
+type SchedulePriceRequest struct {
+	Price       *float64  `json:"price" binding:"required"`
+	EffectiveAt time.Time `json:"effective_at" binding:"required"`
+}
+
+// MenuVersionDetail is a menu version together with its menu.
+type MenuVersionDetail struct {
+	repositories.MenuVersion
+	Content *MenuFile `json:"content"`
+}
+
+// MenuPublishResult is the version a publish or rollback made live and what
+// it changed. Version is nil when the menu had invalid rows and nothing was
+// published.
+type MenuPublishResult struct {
+	Version *repositories.MenuVersion `json:"version,omitempty"`
+	Changes *MenuImportResult         `json:"changes"`
+}
+
+func NewMenuVersionService(versionRepo *repositories.MenuVersionRepository, menuRepo *repositories.MenuRepository, mappingRepo *repositories.ExternalItemMappingRepository, menuCache *MenuCache, transactor *repositories.Transactor) *MenuVersionService {
+	return &MenuVersionService{
+		versionRepo: versionRepo,
+		menuRepo:    menuRepo,
+		mappingRepo: mappingRepo,
+		menuCache:   menuCache,
+		transactor:  transactor,
+	}
+}
+
+// Start applies scheduled price changes as they fall due.
+func (s *MenuVersionService) Start() {
+	go func() {
+		ticker := time.NewTicker(menuPricePollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.ApplyDuePrices(); err != nil {
+				log.Printf("Error applying scheduled prices: %v", err)
+			}
+		}
+	}()
+}
+
+// Prices
+
+// GetPrices returns the current price of a menu item with its scheduled
+// changes and price history.
+func (s *MenuVersionService) GetPrices(menuItemID uint) (*MenuItemPrices, error) {
+	item, err := s.menuRepo.GetMenuItemByID(menuItemID)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.versionRepo.GetPrices(menuItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MenuItemPrices{
+		MenuItemID: item.ID,
+		Price:      item.Price,
+		Scheduled:  []repositories.MenuItemPrice{},
+		History:    []repositories.MenuItemPrice{},
+	}
+	for _, price := range prices {
+		if price.AppliedAt == nil {
+			result.Scheduled = append([]repositories.MenuItemPrice{price}, result.Scheduled...)
+		} else {
+			result.History = append(result.History, price)
+		}
+	}
+	return result, nil
+}
+
+// SchedulePrice sets a new price for a menu item from a future time on.
+// Prices that change now are set by updating the item.
+func (s *MenuVersionService) SchedulePrice(menuItemID uint, req *SchedulePriceRequest, actorID uint) (*repositories.MenuItemPrice, error) {
+	if *req.Price < 0 {
+		return nil, errors.New("price cannot be negative")
+	}
+	if !req.EffectiveAt.After(time.Now()) {
+		return nil, errors.New("effective_at must be in the future; update the menu item to change its price now")
+	}
+	if _, err := s.menuRepo.GetMenuItemByID(menuItemID); err != nil {
+		return nil, err
+	}
+
+	price := &repositories.MenuItemPrice{
+		MenuItemID:  menuItemID,
+		Price:       *req.Price,
+		Source:      repositories.PriceSourceScheduled,
+		EffectiveAt: req.EffectiveAt,
+	}
+	if actorID != 0 {
+		price.CreatedByID = &actorID
+	}
+	if err := s.versionRepo.CreatePrice(price); err != nil {
+		return nil, errors.New("failed to schedule price change")
+	}
+	return price, nil
+}
+
+// CancelScheduledPrice withdraws a price change that has not taken effect.
+func (s *MenuVersionService) CancelScheduledPrice(menuItemID, priceID uint) error {
+	return s.versionRepo.DeleteScheduledPrice(menuItemID, priceID)
+}
+
+// ApplyDuePrices copies the scheduled prices whose time has come onto their
+// items, oldest first.
+func (s *MenuVersionService) ApplyDuePrices() error {
+	now := time.Now()
+	due, err := s.versionRepo.GetDuePrices(now)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, price := range due {
+		applied := false
+		err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+			// Another worker may have got there first
+			marked, err := uow.MenuVersions.MarkPriceApplied(price.ID, now)
+			if err != nil || !marked {
+				return err
+			}
+			if err := uow.Menu.UpdateMenuItemPrice(price.MenuItemID, price.Price); err != nil {
+				return err
+			}
+			applied = true
+			return nil
+		})
+		if err != nil {
+			log.Printf("Error applying scheduled price %d of menu item %d: %v", price.ID, price.MenuItemID, err)
+			continue
+		}
+		// Only a committed change needs the cached menu cleared
+		changed = changed || applied
+	}
+	if changed {
+		s.menuCache.Invalidate()
+	}
+	return nil
+}
+
+// recordPrice adds a price that takes effect now to the history of an item.
+func recordPrice(uow *repositories.UnitOfWork, menuItemID uint, amount float64, origin priceOrigin) error {
+	now := time.Now()
+	price := &repositories.MenuItemPrice{
+		MenuItemID:    menuItemID,
+		Price:         amount,
+		Source:        origin.source,
+		EffectiveAt:   now,
+		AppliedAt:     &now,
+		MenuVersionID: origin.versionID,
+	}
+	if origin.actorID != 0 {
+		price.CreatedByID = &origin.actorID
+	}
+	if err := uow.MenuVersions.CreatePrice(price); err != nil {
+		return errors.New("failed to record price history")
+	}
+	return nil
+}
+
+// Versions
+
+// GetVersions lists the menu versions, newest first, without their menus.
+func (s *MenuVersionService) GetVersions() ([]repositories.MenuVersion, error) {
+	return s.versionRepo.GetAll()
+}
+
+func (s *MenuVersionService) GetVersion(id uint) (*MenuVersionDetail, error) {
+	version, err := s.versionRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return versionDetail(version)
+}
+
+// CreateDraft starts a draft from the live menu. Only one draft can be open
+// at a time.
+func (s *MenuVersionService) CreateDraft(note string, actorID uint) (*MenuVersionDetail, error) {
+	var version *repositories.MenuVersion
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		draft, err := uow.MenuVersions.GetDraft()
+		if err != nil {
+			return err
+		}
+		if draft != nil {
+			return ErrDraftExists
+		}
+		file, err := exportMenu(uow.Menu, uow.ItemMappings)
+		if err != nil {
+			return err
+		}
+		content, err := json.Marshal(file)
+		if err != nil {
+			return err
+		}
+
+		version = &repositories.MenuVersion{Status: repositories.MenuVersionDraft, Note: note, Content: string(content)}
+		if actorID != 0 {
+			version.CreatedByID = &actorID
+		}
+		return uow.MenuVersions.Create(version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versionDetail(version)
+}
+
+// UpdateDraft replaces the menu of a draft, and its note when one is given.
+// The menu is checked when the draft is previewed or published.
+func (s *MenuVersionService) UpdateDraft(id uint, note *string, file *MenuFile) (*MenuVersionDetail, error) {
+	version, err := s.versionRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status != repositories.MenuVersionDraft {
+		return nil, ErrNotDraft
+	}
+
+	content, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	version.Content = string(content)
+	if note != nil {
+		version.Note = *note
+	}
+	if err := s.versionRepo.Update(version); err != nil {
+		return nil, errors.New("failed to save draft")
+	}
+	return versionDetail(version)
+}
+
+// DiscardDraft deletes a draft without publishing it.
+func (s *MenuVersionService) DiscardDraft(id uint) error {
+	version, err := s.versionRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if version.Status != repositories.MenuVersionDraft {
+		return ErrNotDraft
+	}
+	return s.versionRepo.DeleteDraft(id)
+}
+
+// PreviewVersion shows what publishing a draft, or rolling back to a
+// published version, would change on the live menu.
+func (s *MenuVersionService) PreviewVersion(id uint) (*MenuImportResult, error) {
+	version, err := s.versionRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseMenuJSON([]byte(version.Content))
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planMenuImport(s.menuRepo, s.mappingRepo, file, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return plan.result(true), nil
+}
+
+// PublishDraft makes the live menu match a draft in one transaction. If any
+// row of the draft is invalid nothing is published and the result lists the
+// errors. The published version keeps the menu as it is after publishing.
+func (s *MenuVersionService) PublishDraft(id uint, actorID uint) (*MenuPublishResult, error) {
+	var result *MenuPublishResult
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		version, err := uow.MenuVersions.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if version.Status != repositories.MenuVersionDraft {
+			return ErrNotDraft
+		}
+		result, err = publishMenu(uow, version, repositories.PriceSourcePublish, actorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Version != nil {
+		s.menuCache.Invalidate()
+	}
+	return result, nil
+}
+
+// RollbackToVersion publishes the menu of an earlier published version again
+// as a new version, so the rollback itself shows up in the version list and
+// can be rolled back too.
+func (s *MenuVersionService) RollbackToVersion(id uint, actorID uint) (*MenuPublishResult, error) {
+	var result *MenuPublishResult
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		target, err := uow.MenuVersions.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if target.Status != repositories.MenuVersionPublished {
+			return ErrNotPublished
+		}
+		current, err := uow.MenuVersions.GetCurrent()
+		if err != nil {
+			return err
+		}
+		if current != nil && current.ID == target.ID {
+			return ErrCurrentVersion
+		}
+
+		version := &repositories.MenuVersion{
+			Note:             fmt.Sprintf("Rollback to version %d", target.ID),
+			Content:          target.Content,
+			RolledBackFromID: &target.ID,
+		}
+		result, err = publishMenu(uow, version, repositories.PriceSourceRollback, actorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Version != nil {
+		s.menuCache.Invalidate()
+	}
+	return result, nil
+}
+
+// publishMenu applies the menu of a version to the live menu and saves the
+// version as published, creating it if it is new. Nothing is written when the
+// menu has invalid rows.
+func publishMenu(uow *repositories.UnitOfWork, version *repositories.MenuVersion, source repositories.PriceSource, actorID uint) (*MenuPublishResult, error) {
+	file, err := ParseMenuJSON([]byte(version.Content))
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planMenuImport(uow.Menu, uow.ItemMappings, file, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.errors) > 0 {
+		return &MenuPublishResult{Changes: plan.result(false)}, nil
+	}
+
+	now := time.Now()
+	version.Status = repositories.MenuVersionPublished
+	version.PublishedAt = &now
+	if actorID != 0 {
+		version.PublishedByID = &actorID
+		if version.ID == 0 {
+			version.CreatedByID = &actorID
+		}
+	}
+	if version.ID == 0 {
+		if err := uow.MenuVersions.Create(version); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := plan.apply(uow, priceOrigin{source: source, versionID: &version.ID, actorID: actorID}); err != nil {
+		return nil, err
+	}
+
+	// Keep the menu as published, with the names of records the version
+	// matched loosely and the SKUs of items it did not mention
+	published, err := exportMenu(uow.Menu, uow.ItemMappings)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.Marshal(published)
+	if err != nil {
+		return nil, err
+	}
+	version.Content = string(content)
+	if err := uow.MenuVersions.Update(version); err != nil {
+		return nil, err
+	}
+	return &MenuPublishResult{Version: version, Changes: plan.result(false)}, nil
+}
+
+func versionDetail(version *repositories.MenuVersion) (*MenuVersionDetail, error) {
+	file, err := ParseMenuJSON([]byte(version.Content))
+	if err != nil {
+		return nil, err
+	}
+	return &MenuVersionDetail{MenuVersion: *version, Content: file}, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+const (
+	// orderEventPollInterval is how often a consumer looks for new events
+	// when the kitchen feed has not woken it.
+	orderEventPollInterval = 5 * time.Second
+	// orderEventBatch is how many events a consumer reads at a time.
+	orderEventBatch = 100
+	// orderEventGapWait is how long a consumer waits for a missing event ID
+	// before it treats the event as rolled back and moves past it.
+	orderEventGapWait = 5 * time.Second
+	// orderEventMaxAttempts is how often an event whose handler fails is
+	// retried before it is skipped.
+	orderEventMaxAttempts = 5
+)
+
+// OrderEventHandler reacts to one order event. Events can be delivered more
+// than once, so handlers must be idempotent.
+type OrderEventHandler func(event *repositories.OrderEvent) error
+
+// OrderEventConsumer works through the order history in the background on
+// behalf of one service. Its position is stored in event_cursors, so events
+// committed while the server was down or the consumer was busy are handled
+// once it catches up. The kitchen feed only wakes it up early.
+type OrderEventConsumer struct {
+	name           string
+	eventRepo      *repositories.OrderEventRepository
+	kitchenService *KitchenService
+	handle         OrderEventHandler
+
+	mutex    sync.Mutex
+	failures int
+	gapAfter uint
+	gapSince time.Time
+}
+
+func NewOrderEventConsumer(name string, eventRepo *repositories.OrderEventRepository, kitchenService *KitchenService, handle OrderEventHandler) *OrderEventConsumer {
+	return &OrderEventConsumer{
+		name:           name,
+		eventRepo:      eventRepo,
+		kitchenService: kitchenService,
+		handle:         handle,
+	}
+}
+
+// Start handles new events in the background until the process exits. A
+// consumer that has never run starts at the newest event.
+func (c *OrderEventConsumer) Start() {
+	if err := c.eventRepo.InitCursor(c.name); err != nil {
+		log.Printf("Error initialising %s event cursor: %v", c.name, err)
+	}
+
+	// Register before returning so no event published after Start is missed
+	wake, _, _, _ := c.kitchenService.Listen(0)
+	go func() {
+		ticker := time.NewTicker(orderEventPollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.Poll(); err != nil {
+				log.Printf("Error handling order events for %s: %v", c.name, err)
+			}
+
+			select {
+			case _, ok := <-wake:
+				if !ok {
+					// The feed drops listeners that fall behind; the events
+					// themselves are still in the table
+					wake, _, _, _ = c.kitchenService.Listen(0)
+				}
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll handles the events committed after the cursor in ID order, moving the
+// cursor past each one. It returns once it has caught up.
+func (c *OrderEventConsumer) Poll() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cursor, err := c.eventRepo.GetCursor(c.name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		events, err := c.eventRepo.GetAfter(cursor, orderEventBatch)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		for i := range events {
+			event := &events[i]
+
+			// IDs are taken when a transaction writes its event but only
+			// become visible when it commits, so a slower transaction may
+			// still fill the gap. Rolled back transactions never do.
+			if cursor != 0 && event.ID != cursor+1 {
+				if c.gapAfter != cursor {
+					c.gapAfter = cursor
+					c.gapSince = time.Now()
+				}
+				if time.Since(c.gapSince) < orderEventGapWait {
+					return nil
+				}
+			}
+
+			if err := c.handle(event); err != nil {
+				c.failures++
+				if c.failures < orderEventMaxAttempts {
+					return fmt.Errorf("order event %d: %w", event.ID, err)
+				}
+				log.Printf("Skipping order event %d for %s after %d attempts: %v", event.ID, c.name, c.failures, err)
+			}
+			c.failures = 0
+
+			if err := c.eventRepo.SaveCursor(c.name, event.ID); err != nil {
+				return err
+			}
+			cursor = event.ID
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"recursiveDine/internal/repositories"
+)
+
+// ErrOverrideReasonRequired is returned when an admin moves an order outside
+// the normal status workflow without saying why.
+var ErrOverrideReasonRequired = errors.New("reason is required to override the status workflow")
+
+// recordOrderEvent appends an entry to an order's history. It is always
+// called inside the unit of work of the change it describes, so the history
+// is committed or rolled back together with that change. An actorID of 0
+// records a system action.
+func recordOrderEvent(uow *repositories.UnitOfWork, actorID uint, event repositories.OrderEvent) error {
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	if err := uow.Events.Create(&event); err != nil {
+		return errors.New("failed to record order history")
+	}
+	return nil
+}
+
+// describeOrderItems renders items as "2x Nasi Goreng, 1x Es Teh" for the
+// history. names maps menu item IDs to names for items whose MenuItem
+// relation is not loaded.
+func describeOrderItems(items []repositories.OrderItem, names map[uint]string) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		name := item.MenuItem.Name
+		if name == "" {
+			name = names[item.MenuItemID]
+		}
+		if name == "" {
+			name = fmt.Sprintf("menu item %d", item.MenuItemID)
+		}
+		parts = append(parts, fmt.Sprintf("%dx %s", item.Quantity, name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func menuItemNames(menuItems []repositories.MenuItem) map[uint]string {
+	names := make(map[uint]string, len(menuItems))
+	for _, menuItem := range menuItems {
+		names[menuItem.ID] = menuItem.Name
+	}
+	return names
+}
+
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// GetOrderHistory returns the audit trail of an order, oldest first. The
+// history of a deleted order stays available.
+func (s *OrderService) GetOrderHistory(orderID uint) ([]repositories.OrderEvent, error) {
+	events, err := s.orderEventRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, errors.New("failed to fetch order history")
+	}
+
+	if len(events) == 0 {
+		if _, err := s.orderRepo.GetByID(orderID); err != nil {
+			return nil, errors.New("order not found")
+		}
+	}
+	return events, nil
+}
@@ -3,14 +3,22 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"recursiveDine/internal/config"
 	"recursiveDine/internal/repositories"
 )
 
 type OrderService struct {
-	orderRepo *repositories.OrderRepository
-	menuRepo  *repositories.MenuRepository
+	orderRepo       *repositories.OrderRepository
+	menuRepo        *repositories.MenuRepository
+	orderEventRepo  *repositories.OrderEventRepository
+	deliveryRepo    *repositories.DeliveryRepository
+	kitchenService  *KitchenService
+	prepTimeService *PrepTimeService
+	transactor      *repositories.Transactor
+	config          *config.Config
 }
 
 type CreateOrderRequest struct {
@@ -19,13 +27,22 @@ type CreateOrderRequest struct {
 	CustomerPhone           string                   `json:"customer_phone"` // Required for takeaway
 	SpecialNotes            string                   `json:"special_notes"`
 	EstimatedCompletionTime *time.Time               `json:"estimated_completion_time"` // For takeaway orders
+	ScheduledFor            *time.Time               `json:"scheduled_for"`             // Pickup time of a pre-ordered takeaway
+	Delivery                *DeliveryRequest         `json:"delivery"`                  // Required for delivery
 	Items                   []CreateOrderItemRequest `json:"items" binding:"required,dive"`
 }
 
 type CreateOrderItemRequest struct {
-	MenuItemID     uint   `json:"menu_item_id" binding:"required"`
-	Quantity       int    `json:"quantity" binding:"required,min=1"`
-	SpecialRequest string `json:"special_request"`
+	MenuItemID     uint                    `json:"menu_item_id" binding:"required"`
+	Quantity       int                     `json:"quantity" binding:"required,min=1"`
+	SpecialRequest string                  `json:"special_request"`
+	Course         repositories.CourseType `json:"course"`                    // Optional for dine-in: starter, main or dessert
+	Components     []BundleChoiceRequest   `json:"components" binding:"dive"` // Choices for the slots of a bundle; open slots get their default
+}
+
+// AddOrderRoundRequest lists the items of an add-on round.
+type AddOrderRoundRequest struct {
+	Items []CreateOrderItemRequest `json:"items" binding:"required,min=1,dive"`
 }
 
 type CashierOrderRequest struct {
@@ -36,7 +53,13 @@ type CashierOrderRequest struct {
 	CashierName             string                   `json:"cashier_name" binding:"required"`
 	SpecialNotes            string                   `json:"special_notes"`
 	EstimatedCompletionTime *time.Time               `json:"estimated_completion_time"` // For takeaway orders
+	ScheduledFor            *time.Time               `json:"scheduled_for"`             // Pickup time of a pre-ordered takeaway
+	Delivery                *DeliveryRequest         `json:"delivery"`                  // Required for delivery
 	Items                   []CreateOrderItemRequest `json:"items" binding:"required,dive"`
+
+	// Set by platform integrations; cashier orders come from the POS
+	Source     repositories.OrderSource `json:"-"`
+	ExternalID string                   `json:"-"`
 }
 
 type OrderResponse struct {
@@ -45,7 +68,9 @@ type OrderResponse struct {
 	TableID                 uint                     `json:"table_id,omitempty"` // Omit if null for takeaway
 	OrderType               repositories.OrderType   `json:"order_type"`
 	Status                  repositories.OrderStatus `json:"status"`
+	Source                  repositories.OrderSource `json:"source"`
 	SubtotalAmount          float64                  `json:"subtotal_amount"`
+	DeliveryFee             float64                  `json:"delivery_fee,omitempty"`
 	VATAmount               float64                  `json:"vat_amount"`
 	TotalAmount             float64                  `json:"total_amount"`
 	CustomerName            string                   `json:"customer_name,omitempty"`
@@ -53,24 +78,33 @@ type OrderResponse struct {
 	CashierName             string                   `json:"cashier_name,omitempty"`
 	SpecialNotes            string                   `json:"special_notes"`
 	EstimatedCompletionTime *string                  `json:"estimated_completion_time,omitempty"`
+	PickupNumber            string                   `json:"pickup_number,omitempty"`
+	ScheduledFor            *string                  `json:"scheduled_for,omitempty"`
 	CreatedAt               string                   `json:"created_at"`
 	OrderItems              []OrderItemResponse      `json:"order_items"`
 }
 
 type OrderItemResponse struct {
-	ID             uint    `json:"id"`
-	MenuItemID     uint    `json:"menu_item_id"`
-	MenuItemName   string  `json:"menu_item_name"`
-	Quantity       int     `json:"quantity"`
-	UnitPrice      float64 `json:"unit_price"`
-	TotalPrice     float64 `json:"total_price"`
-	SpecialRequest string  `json:"special_request"`
+	ID             uint                    `json:"id"`
+	MenuItemID     uint                    `json:"menu_item_id"`
+	MenuItemName   string                  `json:"menu_item_name"`
+	Quantity       int                     `json:"quantity"`
+	UnitPrice      float64                 `json:"unit_price"`
+	TotalPrice     float64                 `json:"total_price"`
+	SpecialRequest string                  `json:"special_request"`
+	Course         repositories.CourseType `json:"course,omitempty"`
 }
 
-func NewOrderService(orderRepo *repositories.OrderRepository, menuRepo *repositories.MenuRepository) *OrderService {
+func NewOrderService(orderRepo *repositories.OrderRepository, menuRepo *repositories.MenuRepository, orderEventRepo *repositories.OrderEventRepository, deliveryRepo *repositories.DeliveryRepository, kitchenService *KitchenService, prepTimeService *PrepTimeService, transactor *repositories.Transactor, config *config.Config) *OrderService {
 	return &OrderService{
-		orderRepo: orderRepo,
-		menuRepo:  menuRepo,
+		orderRepo:       orderRepo,
+		menuRepo:        menuRepo,
+		orderEventRepo:  orderEventRepo,
+		deliveryRepo:    deliveryRepo,
+		kitchenService:  kitchenService,
+		prepTimeService: prepTimeService,
+		transactor:      transactor,
+		config:          config,
 	}
 }
 
@@ -80,18 +114,32 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*repos
 		return nil, err
 	}
 
+	courses, err := orderCourses(req.OrderType, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseAt, err := s.scheduleOrder(req.OrderType, req.ScheduledFor)
+	if err != nil {
+		return nil, err
+	}
+	if req.ScheduledFor != nil {
+		req.EstimatedCompletionTime = req.ScheduledFor
+	}
+
 	// Validate menu items
 	menuItemIDs := make([]uint, len(req.Items))
 	for i, item := range req.Items {
 		menuItemIDs[i] = item.MenuItemID
 	}
 
-	menuItems, err := s.menuRepo.GetMenuItemsByIDs(menuItemIDs)
+	// The same dish may be ordered for more than one course
+	menuItems, err := s.menuRepo.GetMenuItemsByIDs(uniqueIDs(menuItemIDs))
 	if err != nil {
 		return nil, errors.New("failed to fetch menu items")
 	}
 
-	if len(menuItems) != len(req.Items) {
+	if len(menuItems) != len(uniqueIDs(menuItemIDs)) {
 		return nil, errors.New("some menu items are not available")
 	}
 
@@ -100,6 +148,7 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*repos
 	for i := range menuItems {
 		menuItemMap[menuItems[i].ID] = &menuItems[i]
 	}
+	menuTime := s.menuTime(req.ScheduledFor)
 
 	// Calculate total amount and create order items
 	var subtotal float64
@@ -115,37 +164,61 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*repos
 			return nil, fmt.Errorf("menu item '%s' is not available", menuItem.Name)
 		}
 
-		totalPrice := menuItem.Price * float64(item.Quantity)
+		if !onMenuAt(menuItem, menuTime) {
+			return nil, fmt.Errorf("menu item '%s' is not served at this time", menuItem.Name)
+		}
+
+		components, unitPrice, err := bundleComponents(menuItem, item.Components)
+		if err != nil {
+			return nil, err
+		}
+
+		totalPrice := unitPrice * float64(item.Quantity)
 		subtotal += totalPrice
 
 		orderItem := repositories.OrderItem{
 			MenuItemID:     item.MenuItemID,
 			Quantity:       item.Quantity,
-			UnitPrice:      menuItem.Price,
+			UnitPrice:      unitPrice,
 			TotalPrice:     totalPrice,
 			SpecialRequest: item.SpecialRequest,
+			Course:         item.Course,
+			Components:     components,
 		}
 
 		orderItems = append(orderItems, orderItem)
 	}
 
+	delivery, deliveryFee, err := s.planOrderDelivery(userID, req.OrderType, req.Delivery, subtotal)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate VAT and total
-	const vatRate = 0.10
-	vatAmount := subtotal * vatRate
-	totalAmount := subtotal + vatAmount
+	vatAmount, totalAmount := orderTotals(subtotal, deliveryFee)
+
+	if req.EstimatedCompletionTime == nil {
+		req.EstimatedCompletionTime = s.estimateReadyTime(menuItemIDs)
+	}
 
 	// Create order
 	order := &repositories.Order{
 		UserID:                  userID,
 		OrderType:               req.OrderType,
 		Status:                  repositories.OrderStatusPending,
+		Source:                  repositories.OrderSourceApp,
 		SubtotalAmount:          subtotal,
+		DeliveryFee:             deliveryFee,
 		VATAmount:               vatAmount,
 		TotalAmount:             totalAmount,
 		CustomerPhone:           req.CustomerPhone,
 		SpecialNotes:            req.SpecialNotes,
 		EstimatedCompletionTime: req.EstimatedCompletionTime,
+		ScheduledFor:            req.ScheduledFor,
+		ReleaseAt:               releaseAt,
 		OrderItems:              orderItems,
+		Courses:                 courses,
+		Delivery:                delivery,
 	}
 
 	// Set TableID only if provided (for dine-in orders)
@@ -153,14 +226,79 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*repos
 		order.TableID = *req.TableID
 	}
 
-	if err := s.orderRepo.Create(order); err != nil {
-		return nil, errors.New("failed to create order")
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if order.ScheduledFor != nil {
+			if err := reservePickupSlot(uow.Orders, s.config, *order.ScheduledFor); err != nil {
+				return err
+			}
+		}
+
+		if err := s.assignPickupNumber(uow.Orders, order); err != nil {
+			return err
+		}
+
+		if err := uow.Orders.Create(order); err != nil {
+			return errors.New("failed to create order")
+		}
+
+		return recordOrderEvent(uow, userID, repositories.OrderEvent{
+			OrderID:  order.ID,
+			Type:     repositories.OrderEventCreated,
+			NewValue: fmt.Sprintf("%s (total %s)", describeOrderItems(orderItems, menuItemNames(menuItems)), formatAmount(totalAmount)),
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	s.kitchenService.BroadcastOrderUpdate(order.ID, "new_order")
+
 	// Return order with all relations
 	return s.orderRepo.GetByID(order.ID)
 }
 
+// scheduleOrder validates the pickup time of a pre-order and returns when it
+// should enter the kitchen queue. Orders without a pickup time go straight in.
+// The pickup slot is reserved with reservePickupSlot when the order is stored.
+func (s *OrderService) scheduleOrder(orderType repositories.OrderType, scheduledFor *time.Time) (*time.Time, error) {
+	if scheduledFor == nil {
+		return nil, nil
+	}
+
+	releaseAt, err := schedulePickup(s.config, orderType, *scheduledFor)
+	if err != nil {
+		return nil, err
+	}
+	return &releaseAt, nil
+}
+
+// menuTime is when the items of an order must be on the menu: the pickup
+// time of a pre-order, otherwise now, in the restaurant's time zone.
+func (s *OrderService) menuTime(scheduledFor *time.Time) time.Time {
+	at := time.Now()
+	if scheduledFor != nil {
+		at = *scheduledFor
+	}
+	return at.In(s.config.Location())
+}
+
+// planOrderDelivery works out where a delivery order goes and its fee. Other
+// order types have neither.
+func (s *OrderService) planOrderDelivery(userID uint, orderType repositories.OrderType, req *DeliveryRequest, subtotal float64) (*repositories.OrderDelivery, float64, error) {
+	if orderType != repositories.OrderTypeDelivery {
+		return nil, 0, nil
+	}
+
+	delivery, err := planDelivery(s.deliveryRepo, userID, req, subtotal)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The zone already exists; only its ID is stored with the order
+	fee := delivery.Zone.Fee
+	delivery.Zone = nil
+	return delivery, fee, nil
+}
+
 func (s *OrderService) validateOrderRequest(req *CreateOrderRequest) error {
 	switch req.OrderType {
 	case repositories.OrderTypeDineIn:
@@ -171,13 +309,20 @@ func (s *OrderService) validateOrderRequest(req *CreateOrderRequest) error {
 		if req.CustomerPhone == "" {
 			return errors.New("customer_phone is required for takeaway orders")
 		}
-		// Set estimated completion time if not provided (default 30 minutes)
-		if req.EstimatedCompletionTime == nil {
-			estimatedTime := time.Now().Add(30 * time.Minute)
-			req.EstimatedCompletionTime = &estimatedTime
-		}
+	case repositories.OrderTypeDelivery:
+		return validateDeliveryOrder(req.CustomerPhone, req.Delivery)
 	default:
-		return errors.New("invalid order type. Must be 'dine_in' or 'takeaway'")
+		return errors.New("invalid order type. Must be 'dine_in', 'takeaway' or 'delivery'")
+	}
+	return nil
+}
+
+func validateDeliveryOrder(customerPhone string, delivery *DeliveryRequest) error {
+	if customerPhone == "" {
+		return errors.New("customer_phone is required for delivery orders")
+	}
+	if delivery == nil {
+		return errors.New("delivery address is required for delivery orders")
 	}
 	return nil
 }
@@ -206,19 +351,52 @@ func (s *OrderService) GetAllOrders(page, limit int) ([]repositories.Order, erro
 	return s.orderRepo.GetAll(limit, offset)
 }
 
-func (s *OrderService) UpdateOrderStatus(orderID uint, status repositories.OrderStatus) error {
+// UpdateOrderStatus moves an order along the kitchen workflow. A non-zero
+// expectedVersion must match the order's current version (If-Match). Orders
+// are cancelled through CancellationService instead.
+func (s *OrderService) UpdateOrderStatus(orderID uint, status repositories.OrderStatus, expectedVersion, actorID uint) error {
+	if status == repositories.OrderStatusCancelled {
+		return ErrCancelWithReason
+	}
+
 	// Validate order exists
 	order, err := s.orderRepo.GetByID(orderID)
 	if err != nil {
 		return errors.New("order not found")
 	}
 
+	if err := checkVersion(expectedVersion, order.Version); err != nil {
+		return err
+	}
+
 	// Validate status transition
 	if err := s.validateStatusTransition(order.Status, status); err != nil {
 		return err
 	}
+	status = heldStatus(order, status)
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateStatus(orderID, order.Version, status); err != nil {
+			return err
+		}
+
+		if err := recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(status),
+		}); err != nil {
+			return err
+		}
+
+		return paceCourses(uow, orderID, status, actorID, s.config)
+	})
+	if err != nil {
+		return err
+	}
 
-	return s.orderRepo.UpdateStatus(orderID, status)
+	s.kitchenService.BroadcastStatusChange(orderID)
+	return nil
 }
 
 func (s *OrderService) GetActiveOrders() ([]repositories.Order, error) {
@@ -229,15 +407,25 @@ func (s *OrderService) GetKitchenOrders() ([]repositories.Order, error) {
 	return s.orderRepo.GetKitchenOrders()
 }
 
+// heldStatus is the status a confirmed pending order really moves to: like a
+// paid one, a pre-order waits outside the kitchen until its release time.
+func heldStatus(order *repositories.Order, status repositories.OrderStatus) repositories.OrderStatus {
+	if order.Status == repositories.OrderStatusPending && status == repositories.OrderStatusConfirmed {
+		return paidStatus(order)
+	}
+	return status
+}
+
 func (s *OrderService) validateStatusTransition(currentStatus, newStatus repositories.OrderStatus) error {
 	validTransitions := map[repositories.OrderStatus][]repositories.OrderStatus{
 		repositories.OrderStatusPending: {
 			repositories.OrderStatusConfirmed,
-			repositories.OrderStatusCancelled,
+		},
+		repositories.OrderStatusScheduled: {
+			repositories.OrderStatusConfirmed, // Sent to the kitchen early
 		},
 		repositories.OrderStatusConfirmed: {
 			repositories.OrderStatusPreparing,
-			repositories.OrderStatusCancelled,
 		},
 		repositories.OrderStatusPreparing: {
 			repositories.OrderStatusReady,
@@ -278,12 +466,18 @@ func (s *OrderService) GetOrderByIDAdmin(id uint) (*repositories.Order, error) {
 	return s.orderRepo.GetByIDWithDetails(id)
 }
 
-func (s *OrderService) UpdateOrderStatusAdmin(id uint, status string) (*repositories.Order, error) {
+// UpdateOrderStatusAdmin sets the status of an order. Admins may move an
+// order outside the normal workflow, but such an override needs a reason and
+// is recorded as one in the order history. Cancelling is not a status change
+// here either; it goes through CancellationService.
+func (s *OrderService) UpdateOrderStatusAdmin(id uint, status string, expectedVersion uint, reason string, actorID uint) (*repositories.Order, error) {
 	// Convert string status to OrderStatus enum
 	var orderStatus repositories.OrderStatus
 	switch status {
 	case "pending":
 		orderStatus = repositories.OrderStatusPending
+	case "scheduled":
+		orderStatus = repositories.OrderStatusScheduled
 	case "confirmed":
 		orderStatus = repositories.OrderStatusConfirmed
 	case "preparing":
@@ -293,20 +487,74 @@ func (s *OrderService) UpdateOrderStatusAdmin(id uint, status string) (*reposito
 	case "served":
 		orderStatus = repositories.OrderStatusServed
 	case "cancelled":
-		orderStatus = repositories.OrderStatusCancelled
+		return nil, ErrCancelWithReason
 	default:
 		return nil, errors.New("invalid order status")
 	}
 
-	if err := s.orderRepo.UpdateStatus(id, orderStatus); err != nil {
+	order, err := s.orderRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(expectedVersion, order.Version); err != nil {
 		return nil, err
 	}
 
+	eventType := repositories.OrderEventStatusChanged
+	reason = strings.TrimSpace(reason)
+	if err := s.validateStatusTransition(order.Status, orderStatus); err != nil {
+		if reason == "" {
+			return nil, ErrOverrideReasonRequired
+		}
+		eventType = repositories.OrderEventStatusOverridden
+	} else {
+		orderStatus = heldStatus(order, orderStatus)
+	}
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateStatus(id, order.Version, orderStatus); err != nil {
+			return err
+		}
+
+		if err := recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       id,
+			Type:          eventType,
+			PreviousValue: string(order.Status),
+			NewValue:      string(orderStatus),
+			Reason:        reason,
+		}); err != nil {
+			return err
+		}
+
+		return paceCourses(uow, id, orderStatus, actorID, s.config)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.kitchenService.BroadcastStatusChange(id)
+
 	return s.orderRepo.GetByID(id)
 }
 
-func (s *OrderService) DeleteOrder(id uint) error {
-	return s.orderRepo.SoftDelete(id)
+func (s *OrderService) DeleteOrder(id, actorID uint) error {
+	order, err := s.orderRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.SoftDelete(id); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       id,
+			Type:          repositories.OrderEventDeleted,
+			PreviousValue: string(order.Status),
+		})
+	})
 }
 
 func (s *OrderService) GetOrderStatistics(from, to string) (map[string]interface{}, error) {
@@ -317,41 +565,228 @@ func (s *OrderService) GetDailyRevenue(from, to string) (map[string]interface{},
 	return s.orderRepo.GetDailyRevenue(from, to)
 }
 
-func (s *OrderService) UpdateOrderItems(orderID uint, items []repositories.OrderItem) (*repositories.Order, error) {
+func (s *OrderService) UpdateOrderItems(orderID uint, items []repositories.OrderItem, expectedVersion, actorID uint) (*repositories.Order, error) {
 	// Check if order is in pending status
 	order, err := s.orderRepo.GetByID(orderID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkVersion(expectedVersion, order.Version); err != nil {
+		return nil, err
+	}
+
 	if order.Status != repositories.OrderStatusPending {
 		return nil, fmt.Errorf("can only update items for pending orders")
 	}
 
-	// Calculate new total
-	var total float64
+	// Calculate new subtotal
+	var subtotal float64
+	names := make(map[uint]string, len(items))
+	menuTime := s.menuTime(order.ScheduledFor)
 	for i, item := range items {
 		menuItem, err := s.menuRepo.GetMenuItemByID(item.MenuItemID)
 		if err != nil {
 			return nil, fmt.Errorf("menu item not found: %d", item.MenuItemID)
 		}
-		total += menuItem.Price * float64(item.Quantity)
-		items[i].UnitPrice = menuItem.Price
-		items[i].TotalPrice = menuItem.Price * float64(item.Quantity)
+		if !onMenuAt(menuItem, menuTime) {
+			return nil, fmt.Errorf("menu item '%s' is not served at this time", menuItem.Name)
+		}
+		names[menuItem.ID] = menuItem.Name
+
+		choices := make([]BundleChoiceRequest, len(item.Components))
+		for j, component := range item.Components {
+			choices[j] = BundleChoiceRequest{SlotID: component.SlotID, MenuItemID: component.MenuItemID}
+		}
+		components, unitPrice, err := bundleComponents(menuItem, choices)
+		if err != nil {
+			return nil, err
+		}
+
+		subtotal += unitPrice * float64(item.Quantity)
+		items[i].UnitPrice = unitPrice
+		items[i].TotalPrice = unitPrice * float64(item.Quantity)
+		items[i].Components = components
 		items[i].OrderID = orderID
+		items[i].Round = 1 // Nothing has gone to the kitchen yet
 	}
 
-	// Update order items and total
-	if err := s.orderRepo.UpdateOrderItems(orderID, items); err != nil {
+	used := make([]repositories.CourseType, len(items))
+	for i, item := range items {
+		used[i] = item.Course
+	}
+	courses, err := planCourses(order.OrderType, used)
+	if err != nil {
 		return nil, err
 	}
+	// Courses the order already has keep their row, e.g. a hold placed earlier
+	for i := range courses {
+		for _, existing := range order.Courses {
+			if existing.Course == courses[i].Course {
+				courses[i] = existing
+			}
+		}
+	}
+
+	if err := s.checkDeliveryMinimum(order, subtotal); err != nil {
+		return nil, err
+	}
+	vatAmount, totalAmount := orderTotals(subtotal, order.DeliveryFee)
+
+	// Replace the items and totals together so a failure leaves the old order intact
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateOrderItems(orderID, items); err != nil {
+			return err
+		}
+		if err := uow.Orders.UpdateAmounts(orderID, order.Version, subtotal, vatAmount, totalAmount); err != nil {
+			return err
+		}
+		if err := uow.Courses.Replace(orderID, courses); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventItemsUpdated,
+			PreviousValue: fmt.Sprintf("%s (total %s)", describeOrderItems(order.OrderItems, nil), formatAmount(order.TotalAmount)),
+			NewValue:      fmt.Sprintf("%s (total %s)", describeOrderItems(items, names), formatAmount(totalAmount)),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.GetByIDWithDetails(orderID)
+}
+
+// AddOrderRound appends another round of items to a dine-in order that is
+// already in the kitchen, e.g. a second round of drinks. Items sent earlier
+// are left as they are; the new round gets kitchen tickets of its own and the
+// totals and VAT are recomputed. An order that was ready or served goes back
+// to preparing.
+func (s *OrderService) AddOrderRound(orderID uint, req *AddOrderRoundRequest, expectedVersion, actorID uint) (*repositories.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(expectedVersion, order.Version); err != nil {
+		return nil, err
+	}
+
+	if order.OrderType != repositories.OrderTypeDineIn {
+		return nil, errors.New("add-on rounds are only available for dine-in orders")
+	}
 
-	// Update total amount
-	order.TotalAmount = total
-	if err := s.orderRepo.UpdateOrder(order); err != nil {
+	status := order.Status
+	switch order.Status {
+	case repositories.OrderStatusConfirmed, repositories.OrderStatusPreparing:
+	case repositories.OrderStatusReady, repositories.OrderStatusServed:
+		status = repositories.OrderStatusPreparing
+	case repositories.OrderStatusPending:
+		return nil, errors.New("order has not been sent to the kitchen yet, update its items instead")
+	default:
+		return nil, fmt.Errorf("cannot add items to a %s order", order.Status)
+	}
+
+	menuItemIDs := make([]uint, len(req.Items))
+	for i, item := range req.Items {
+		if item.Course != "" {
+			return nil, errors.New("add-on rounds go to the kitchen straight away and cannot be split into courses")
+		}
+		menuItemIDs[i] = item.MenuItemID
+	}
+
+	menuItems, err := s.menuRepo.GetMenuItemsByIDs(uniqueIDs(menuItemIDs))
+	if err != nil {
+		return nil, errors.New("failed to fetch menu items")
+	}
+
+	menuItemMap := make(map[uint]*repositories.MenuItem)
+	for i := range menuItems {
+		menuItemMap[menuItems[i].ID] = &menuItems[i]
+	}
+	menuTime := s.menuTime(nil)
+
+	round := 1
+	for _, item := range order.OrderItems {
+		if item.Round > round {
+			round = item.Round
+		}
+	}
+	round++
+
+	var roundSubtotal float64
+	items := make([]repositories.OrderItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		menuItem, exists := menuItemMap[item.MenuItemID]
+		if !exists {
+			return nil, fmt.Errorf("menu item with ID %d not found", item.MenuItemID)
+		}
+
+		if !menuItem.IsAvailable {
+			return nil, fmt.Errorf("menu item '%s' is not available", menuItem.Name)
+		}
+
+		if !onMenuAt(menuItem, menuTime) {
+			return nil, fmt.Errorf("menu item '%s' is not served at this time", menuItem.Name)
+		}
+
+		components, unitPrice, err := bundleComponents(menuItem, item.Components)
+		if err != nil {
+			return nil, err
+		}
+
+		totalPrice := unitPrice * float64(item.Quantity)
+		roundSubtotal += totalPrice
+
+		items = append(items, repositories.OrderItem{
+			MenuItemID:     item.MenuItemID,
+			Quantity:       item.Quantity,
+			UnitPrice:      unitPrice,
+			TotalPrice:     totalPrice,
+			SpecialRequest: item.SpecialRequest,
+			Round:          round,
+			Components:     components,
+		})
+	}
+
+	// VAT is recomputed on the whole order rather than added per round
+	subtotal := order.SubtotalAmount + roundSubtotal
+	vatAmount, totalAmount := orderTotals(subtotal, order.DeliveryFee)
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.AppendItems(orderID, order.Version, items, subtotal, vatAmount, totalAmount, status); err != nil {
+			return writeError(err, "failed to add order round")
+		}
+
+		if err := recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventRoundAdded,
+			PreviousValue: fmt.Sprintf("total %s", formatAmount(order.TotalAmount)),
+			NewValue:      fmt.Sprintf("round %d: %s (total %s)", round, describeOrderItems(items, menuItemNames(menuItems)), formatAmount(totalAmount)),
+		}); err != nil {
+			return err
+		}
+
+		if status == order.Status {
+			return nil
+		}
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(status),
+			Reason:        fmt.Sprintf("add-on round %d", round),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	// The print queue picks up the new round's tickets from this event
+	s.kitchenService.BroadcastOrderUpdate(orderID, "round_added")
+
 	return s.orderRepo.GetByIDWithDetails(orderID)
 }
 
@@ -362,47 +797,80 @@ func (s *OrderService) CreateCashierOrder(cashierUserID uint, req *CashierOrderR
 		return nil, err
 	}
 
+	courses, err := orderCourses(req.OrderType, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseAt, err := s.scheduleOrder(req.OrderType, req.ScheduledFor)
+	if err != nil {
+		return nil, err
+	}
+	if req.ScheduledFor != nil {
+		req.EstimatedCompletionTime = req.ScheduledFor
+	}
+
 	// Validate menu items
 	menuItemIDs := make([]uint, len(req.Items))
 	for i, item := range req.Items {
 		menuItemIDs[i] = item.MenuItemID
 	}
 
-	menuItems, err := s.menuRepo.GetMenuItemsByIDs(menuItemIDs)
+	// The same dish may be ordered for more than one course
+	menuItems, err := s.menuRepo.GetMenuItemsByIDs(uniqueIDs(menuItemIDs))
 	if err != nil {
 		return nil, errors.New("failed to fetch menu items")
 	}
 
-	if len(menuItems) != len(req.Items) {
+	if len(menuItems) != len(uniqueIDs(menuItemIDs)) {
 		return nil, errors.New("some menu items are not available")
 	}
 
 	// Create menu items map for easy lookup
 	menuItemMap := make(map[uint]*repositories.MenuItem)
-	for _, item := range menuItems {
-		menuItemMap[item.ID] = &item
+	for i := range menuItems {
+		menuItemMap[menuItems[i].ID] = &menuItems[i]
 	}
+	menuTime := s.menuTime(req.ScheduledFor)
 
 	// Calculate subtotal
 	var subtotal float64
-	for _, orderItem := range req.Items {
+	unitPrices := make([]float64, len(req.Items))
+	components := make([][]repositories.OrderItemComponent, len(req.Items))
+	for i, orderItem := range req.Items {
 		menuItem := menuItemMap[orderItem.MenuItemID]
 		if !menuItem.IsAvailable {
 			return nil, fmt.Errorf("menu item %s is not available", menuItem.Name)
 		}
-		subtotal += menuItem.Price * float64(orderItem.Quantity)
+		if !onMenuAt(menuItem, menuTime) {
+			return nil, fmt.Errorf("menu item '%s' is not served at this time", menuItem.Name)
+		}
+		components[i], unitPrices[i], err = bundleComponents(menuItem, orderItem.Components)
+		if err != nil {
+			return nil, err
+		}
+		subtotal += unitPrices[i] * float64(orderItem.Quantity)
+	}
+
+	delivery, deliveryFee, err := s.planOrderDelivery(cashierUserID, req.OrderType, req.Delivery, subtotal)
+	if err != nil {
+		return nil, err
 	}
 
 	// Calculate VAT (10% for Indonesia)
-	const vatRate = 0.10
-	vatAmount := subtotal * vatRate
-	totalAmount := subtotal + vatAmount
+	vatAmount, totalAmount := orderTotals(subtotal, deliveryFee)
+
+	if req.EstimatedCompletionTime == nil {
+		req.EstimatedCompletionTime = s.estimateReadyTime(menuItemIDs)
+	}
 
 	createdOrder := &repositories.Order{
 		UserID:                  cashierUserID,
 		OrderType:               req.OrderType,
 		Status:                  repositories.OrderStatusPending,
+		Source:                  repositories.OrderSourcePOS,
 		SubtotalAmount:          subtotal,
+		DeliveryFee:             deliveryFee,
 		VATAmount:               vatAmount,
 		TotalAmount:             totalAmount,
 		CustomerName:            req.CustomerName,
@@ -410,38 +878,70 @@ func (s *OrderService) CreateCashierOrder(cashierUserID uint, req *CashierOrderR
 		CashierName:             req.CashierName,
 		SpecialNotes:            req.SpecialNotes,
 		EstimatedCompletionTime: req.EstimatedCompletionTime,
+		ScheduledFor:            req.ScheduledFor,
+		ReleaseAt:               releaseAt,
+		Courses:                 courses,
+		Delivery:                delivery,
 	}
 
 	// Set TableID only if provided (for dine-in orders)
 	if req.TableID != nil {
 		createdOrder.TableID = *req.TableID
 	}
-
-	err = s.orderRepo.Create(createdOrder)
-	if err != nil {
-		return nil, errors.New("failed to create order")
+	if req.Source != "" {
+		createdOrder.Source = req.Source
+	}
+	if req.ExternalID != "" {
+		createdOrder.ExternalID = &req.ExternalID
 	}
 
-	// Create order items
-	for _, item := range req.Items {
-		menuItem := menuItemMap[item.MenuItemID]
-		totalPrice := menuItem.Price * float64(item.Quantity)
+	// The order and its items are committed together or not at all
+	orderItems := make([]repositories.OrderItem, 0, len(req.Items))
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if createdOrder.ScheduledFor != nil {
+			if err := reservePickupSlot(uow.Orders, s.config, *createdOrder.ScheduledFor); err != nil {
+				return err
+			}
+		}
 
-		orderItem := &repositories.OrderItem{
-			OrderID:        createdOrder.ID,
-			MenuItemID:     item.MenuItemID,
-			Quantity:       item.Quantity,
-			UnitPrice:      menuItem.Price,
-			TotalPrice:     totalPrice,
-			SpecialRequest: item.SpecialRequest,
+		if err := s.assignPickupNumber(uow.Orders, createdOrder); err != nil {
+			return err
 		}
 
-		err = s.orderRepo.CreateOrderItem(orderItem)
-		if err != nil {
-			return nil, errors.New("failed to create order items")
+		if err := uow.Orders.Create(createdOrder); err != nil {
+			return errors.New("failed to create order")
 		}
+
+		for i, item := range req.Items {
+			orderItem := &repositories.OrderItem{
+				OrderID:        createdOrder.ID,
+				MenuItemID:     item.MenuItemID,
+				Quantity:       item.Quantity,
+				UnitPrice:      unitPrices[i],
+				TotalPrice:     unitPrices[i] * float64(item.Quantity),
+				SpecialRequest: item.SpecialRequest,
+				Course:         item.Course,
+				Components:     components[i],
+			}
+
+			if err := uow.Orders.CreateOrderItem(orderItem); err != nil {
+				return errors.New("failed to create order items")
+			}
+			orderItems = append(orderItems, *orderItem)
+		}
+
+		return recordOrderEvent(uow, cashierUserID, repositories.OrderEvent{
+			OrderID:  createdOrder.ID,
+			Type:     repositories.OrderEventCreated,
+			NewValue: fmt.Sprintf("%s (total %s)", describeOrderItems(orderItems, menuItemNames(menuItems)), formatAmount(totalAmount)),
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	s.kitchenService.BroadcastOrderUpdate(createdOrder.ID, "new_order")
+
 	// Fetch the complete order with items for response
 	completeOrder, err := s.orderRepo.GetByID(createdOrder.ID)
 	if err != nil {
@@ -459,6 +959,7 @@ func (s *OrderService) CreateCashierOrder(cashierUserID uint, req *CashierOrderR
 			UnitPrice:      item.UnitPrice,
 			TotalPrice:     item.TotalPrice,
 			SpecialRequest: item.SpecialRequest,
+			Course:         item.Course,
 		}
 	}
 
@@ -468,13 +969,16 @@ func (s *OrderService) CreateCashierOrder(cashierUserID uint, req *CashierOrderR
 		TableID:        completeOrder.TableID,
 		OrderType:      completeOrder.OrderType,
 		Status:         completeOrder.Status,
+		Source:         completeOrder.Source,
 		SubtotalAmount: completeOrder.SubtotalAmount,
+		DeliveryFee:    completeOrder.DeliveryFee,
 		VATAmount:      completeOrder.VATAmount,
 		TotalAmount:    completeOrder.TotalAmount,
 		CustomerName:   completeOrder.CustomerName,
 		CustomerPhone:  completeOrder.CustomerPhone,
 		CashierName:    completeOrder.CashierName,
 		SpecialNotes:   completeOrder.SpecialNotes,
+		PickupNumber:   completeOrder.PickupNumber,
 		CreatedAt:      completeOrder.CreatedAt.Format("2006-01-02 15:04:05"),
 		OrderItems:     orderItemResponses,
 	}
@@ -484,6 +988,10 @@ func (s *OrderService) CreateCashierOrder(cashierUserID uint, req *CashierOrderR
 		estimatedTime := completeOrder.EstimatedCompletionTime.Format("2006-01-02 15:04:05")
 		response.EstimatedCompletionTime = &estimatedTime
 	}
+	if completeOrder.ScheduledFor != nil {
+		scheduledFor := completeOrder.ScheduledFor.Format("2006-01-02 15:04:05")
+		response.ScheduledFor = &scheduledFor
+	}
 
 	return response, nil
 }
@@ -498,17 +1006,103 @@ func (s *OrderService) validateCashierOrderRequest(req *CashierOrderRequest) err
 		if req.CustomerPhone == "" {
 			return errors.New("customer_phone is required for takeaway orders")
 		}
-		// Set estimated completion time if not provided (default 30 minutes)
-		if req.EstimatedCompletionTime == nil {
-			estimatedTime := time.Now().Add(30 * time.Minute)
-			req.EstimatedCompletionTime = &estimatedTime
-		}
+	case repositories.OrderTypeDelivery:
+		return validateDeliveryOrder(req.CustomerPhone, req.Delivery)
 	default:
-		return errors.New("invalid order type. Must be 'dine_in' or 'takeaway'")
+		return errors.New("invalid order type. Must be 'dine_in', 'takeaway' or 'delivery'")
+	}
+	return nil
+}
+
+// estimateReadyTime asks the prep-time model for an ETA, falling back to the
+// default prep time when the estimate cannot be computed.
+func (s *OrderService) estimateReadyTime(menuItemIDs []uint) *time.Time {
+	estimated, err := s.prepTimeService.EstimateReadyTime(menuItemIDs)
+	if err != nil {
+		estimated = time.Now().Add(time.Duration(s.config.DefaultPrepMinutes) * time.Minute)
+	}
+	return &estimated
+}
+
+// assignPickupNumber gives takeaway and delivery orders a short number such as
+// A-042 that is called out at the counter or to the driver. The sequence
+// resets every business day; the letter advances every 999 orders so numbers
+// stay three digits long.
+func (s *OrderService) assignPickupNumber(orderRepo *repositories.OrderRepository, order *repositories.Order) error {
+	if order.OrderType != repositories.OrderTypeTakeaway && order.OrderType != repositories.OrderTypeDelivery {
+		return nil
+	}
+
+	businessDate := time.Now().In(s.config.Location()).Format("2006-01-02")
+	sequence, err := orderRepo.NextPickupSequence(businessDate)
+	if err != nil {
+		return errors.New("failed to assign pickup number")
 	}
+
+	order.PickupNumber = formatPickupNumber(sequence)
 	return nil
 }
 
+// checkDeliveryMinimum re-checks the zone's minimum order value when the items
+// of a delivery order change.
+func (s *OrderService) checkDeliveryMinimum(order *repositories.Order, subtotal float64) error {
+	if order.Delivery == nil {
+		return nil
+	}
+
+	zone, err := s.deliveryRepo.GetZoneByID(order.Delivery.ZoneID)
+	if err != nil {
+		return err
+	}
+	if subtotal < zone.MinimumOrder {
+		return fmt.Errorf("minimum order for delivery to %s is %s", zone.Name, formatAmount(zone.MinimumOrder))
+	}
+	return nil
+}
+
+func formatPickupNumber(sequence int) string {
+	letter := 'A' + rune(((sequence-1)/999)%26)
+	return fmt.Sprintf("%c-%03d", letter, (sequence-1)%999+1)
+}
+
+// MarkOrderCollected records that a ready takeaway order was handed to the
+// customer, which completes it and removes it from the pickup board.
+func (s *OrderService) MarkOrderCollected(orderID, actorID uint) (*repositories.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.OrderType != repositories.OrderTypeTakeaway {
+		return nil, errors.New("only takeaway orders can be collected")
+	}
+
+	if order.Status != repositories.OrderStatusReady {
+		return nil, fmt.Errorf("order is %s, not ready for pickup", order.Status)
+	}
+
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.MarkCollected(orderID, order.Version, time.Now()); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(repositories.OrderStatusServed),
+			Reason:        "collected by customer",
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.kitchenService.BroadcastOrderUpdate(orderID, "order_collected")
+
+	return s.orderRepo.GetByID(orderID)
+}
+
 // GetOrdersByType returns orders filtered by order type
 func (s *OrderService) GetOrdersByType(orderType repositories.OrderType, page, limit int) ([]repositories.Order, error) {
 	offset := (page - 1) * limit
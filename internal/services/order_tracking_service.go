@@ -0,0 +1,242 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"recursiveDine/internal/repositories"
+)
+
+// OrderTrackingService narrows the kitchen feed down to the orders of a single
+// customer so guests can follow their order without polling GET /orders/:id.
+type OrderTrackingService struct {
+	orderRepo      *repositories.OrderRepository
+	kitchenService *KitchenService
+}
+
+// OrderTrackingUpdate is the customer-facing view of an order event. It leaves
+// out table, cashier and other guests' data that the kitchen feed carries.
+type OrderTrackingUpdate struct {
+	EventID          uint64                   `json:"event_id"`
+	Type             string                   `json:"type"`
+	OrderID          uint                     `json:"order_id"`
+	OrderType        repositories.OrderType   `json:"order_type"`
+	Status           repositories.OrderStatus `json:"status"`
+	EstimatedReadyAt *time.Time               `json:"estimated_ready_at,omitempty"`
+	UpdatedAt        time.Time                `json:"updated_at"`
+}
+
+// PickupBoard is the public "now preparing / ready for pickup" view for the
+// lobby screen. It only exposes pickup numbers and timing, never customer data.
+type PickupBoard struct {
+	Preparing []PickupBoardEntry `json:"preparing"`
+	Ready     []PickupBoardEntry `json:"ready"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+type PickupBoardEntry struct {
+	PickupNumber     string     `json:"pickup_number"`
+	EstimatedReadyAt *time.Time `json:"estimated_ready_at,omitempty"`
+	ReadySince       *time.Time `json:"ready_since,omitempty"`
+}
+
+func NewOrderTrackingService(orderRepo *repositories.OrderRepository, kitchenService *KitchenService) *OrderTrackingService {
+	return &OrderTrackingService{
+		orderRepo:      orderRepo,
+		kitchenService: kitchenService,
+	}
+}
+
+// Subscribe returns a channel of events for the given user's orders, the
+// backlog to send first, and a cancel function that must be called when the
+// client goes away. New clients (or ones too far behind to resume) receive an
+// initial_orders snapshot of their active orders.
+func (s *OrderTrackingService) Subscribe(userID uint, lastEventID uint64) (<-chan KitchenEvent, []KitchenEvent, func(), error) {
+	source, missed, position, resumed := s.kitchenService.Listen(lastEventID)
+
+	var backlog []KitchenEvent
+	if resumed {
+		for _, event := range missed {
+			if customerEvent, ok := s.toCustomerEvent(userID, event); ok {
+				backlog = append(backlog, customerEvent)
+			}
+		}
+	} else {
+		snapshot, err := s.snapshot(userID, position)
+		if err != nil {
+			s.kitchenService.Unsubscribe(source)
+			return nil, nil, nil, err
+		}
+		backlog = append(backlog, snapshot)
+	}
+
+	out := make(chan KitchenEvent, 16)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		for event := range source {
+			customerEvent, ok := s.toCustomerEvent(userID, event)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- customerEvent:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	cancel := func() {
+		close(done)
+		s.kitchenService.Unsubscribe(source)
+	}
+
+	return out, backlog, cancel, nil
+}
+
+func (s *OrderTrackingService) snapshot(userID uint, eventID uint64) (KitchenEvent, error) {
+	orders, err := s.orderRepo.GetActiveOrdersByUserID(userID)
+	if err != nil {
+		return KitchenEvent{}, err
+	}
+
+	updates := make([]OrderTrackingUpdate, len(orders))
+	for i := range orders {
+		updates[i] = newOrderTrackingUpdate(eventID, statusUpdateType(&orders[i]), &orders[i])
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"event_id": eventID,
+		"type":     "initial_orders",
+		"orders":   updates,
+	})
+	if err != nil {
+		return KitchenEvent{}, err
+	}
+
+	return KitchenEvent{ID: eventID, Type: "initial_orders", Data: data, UserID: userID}, nil
+}
+
+func (s *OrderTrackingService) toCustomerEvent(userID uint, event KitchenEvent) (KitchenEvent, bool) {
+	if event.Order == nil || event.UserID != userID {
+		return KitchenEvent{}, false
+	}
+
+	data, err := json.Marshal(newOrderTrackingUpdate(event.ID, event.Type, event.Order))
+	if err != nil {
+		log.Printf("Error marshaling order tracking update: %v", err)
+		return KitchenEvent{}, false
+	}
+
+	return KitchenEvent{
+		ID:      event.ID,
+		Type:    event.Type,
+		Data:    data,
+		OrderID: event.OrderID,
+		UserID:  event.UserID,
+		Order:   event.Order,
+	}, true
+}
+
+func newOrderTrackingUpdate(eventID uint64, updateType string, order *repositories.Order) OrderTrackingUpdate {
+	return OrderTrackingUpdate{
+		EventID:          eventID,
+		Type:             updateType,
+		OrderID:          order.ID,
+		OrderType:        order.OrderType,
+		Status:           order.Status,
+		EstimatedReadyAt: order.EstimatedCompletionTime,
+		UpdatedAt:        order.UpdatedAt,
+	}
+}
+
+// GetPickupBoard builds the current pickup board from active takeaway orders.
+func (s *OrderTrackingService) GetPickupBoard() (*PickupBoard, error) {
+	orders, err := s.orderRepo.GetPickupBoardOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	board := &PickupBoard{
+		Preparing: []PickupBoardEntry{},
+		Ready:     []PickupBoardEntry{},
+		UpdatedAt: time.Now(),
+	}
+
+	for _, order := range orders {
+		if order.Status == repositories.OrderStatusReady {
+			board.Ready = append(board.Ready, PickupBoardEntry{
+				PickupNumber: order.PickupNumber,
+				ReadySince:   order.ReadyAt,
+			})
+			continue
+		}
+
+		board.Preparing = append(board.Preparing, PickupBoardEntry{
+			PickupNumber:     order.PickupNumber,
+			EstimatedReadyAt: order.EstimatedCompletionTime,
+		})
+	}
+
+	return board, nil
+}
+
+// SubscribeBoard streams a fresh pickup board whenever a takeaway order
+// changes. The board is always sent whole, so there is nothing to resume.
+func (s *OrderTrackingService) SubscribeBoard() (<-chan KitchenEvent, []KitchenEvent, func(), error) {
+	source, _, position, _ := s.kitchenService.Listen(0)
+
+	initial, err := s.boardEvent(position)
+	if err != nil {
+		s.kitchenService.Unsubscribe(source)
+		return nil, nil, nil, err
+	}
+
+	out := make(chan KitchenEvent, 4)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		for event := range source {
+			if event.Order == nil || event.Order.OrderType != repositories.OrderTypeTakeaway {
+				continue
+			}
+
+			boardEvent, err := s.boardEvent(event.ID)
+			if err != nil {
+				log.Printf("Error building pickup board: %v", err)
+				continue
+			}
+
+			select {
+			case out <- boardEvent:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	cancel := func() {
+		close(done)
+		s.kitchenService.Unsubscribe(source)
+	}
+
+	return out, []KitchenEvent{initial}, cancel, nil
+}
+
+func (s *OrderTrackingService) boardEvent(eventID uint64) (KitchenEvent, error) {
+	board, err := s.GetPickupBoard()
+	if err != nil {
+		return KitchenEvent{}, err
+	}
+
+	data, err := json.Marshal(board)
+	if err != nil {
+		return KitchenEvent{}, err
+	}
+
+	return KitchenEvent{ID: eventID, Type: "pickup_board", Data: data}, nil
+}
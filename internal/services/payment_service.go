@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"recursiveDine/internal/config"
@@ -12,9 +14,11 @@ import (
 )
 
 type PaymentService struct {
-	paymentRepo *repositories.PaymentRepository
-	orderRepo   *repositories.OrderRepository
-	config      *config.Config
+	paymentRepo    *repositories.PaymentRepository
+	orderRepo      *repositories.OrderRepository
+	kitchenService *KitchenService
+	transactor     *repositories.Transactor
+	config         *config.Config
 }
 
 type QRISPaymentRequest struct {
@@ -36,15 +40,17 @@ type PaymentVerificationRequest struct {
 	Status        string  `json:"status" binding:"required"`
 }
 
-func NewPaymentService(paymentRepo *repositories.PaymentRepository, orderRepo *repositories.OrderRepository, config *config.Config) *PaymentService {
+func NewPaymentService(paymentRepo *repositories.PaymentRepository, orderRepo *repositories.OrderRepository, kitchenService *KitchenService, transactor *repositories.Transactor, config *config.Config) *PaymentService {
 	return &PaymentService{
-		paymentRepo: paymentRepo,
-		orderRepo:   orderRepo,
-		config:      config,
+		paymentRepo:    paymentRepo,
+		orderRepo:      orderRepo,
+		kitchenService: kitchenService,
+		transactor:     transactor,
+		config:         config,
 	}
 }
 
-func (s *PaymentService) InitiateQRISPayment(req *QRISPaymentRequest) (*QRISPaymentResponse, error) {
+func (s *PaymentService) InitiateQRISPayment(req *QRISPaymentRequest, actorID uint) (*QRISPaymentResponse, error) {
 	// Get order details
 	order, err := s.orderRepo.GetByID(req.OrderID)
 	if err != nil {
@@ -86,8 +92,20 @@ func (s *PaymentService) InitiateQRISPayment(req *QRISPaymentRequest) (*QRISPaym
 		TransactionID: transactionID,
 	}
 
-	if err := s.paymentRepo.Create(payment); err != nil {
-		return nil, errors.New("failed to create payment record")
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Payments.Create(payment); err != nil {
+			return errors.New("failed to create payment record")
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:   payment.OrderID,
+			PaymentID: &payment.ID,
+			Type:      repositories.OrderEventPaymentInitiated,
+			NewValue:  describePayment(payment),
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &QRISPaymentResponse{
@@ -106,6 +124,12 @@ func (s *PaymentService) VerifyPayment(req *PaymentVerificationRequest) error {
 		return errors.New("payment not found")
 	}
 
+	// Only a pending payment is settled by the provider. A late or repeated
+	// callback must not reopen a payment that was refunded or cancelled.
+	if payment.Status != repositories.PaymentStatusPending {
+		return fmt.Errorf("payment is already %s", payment.Status)
+	}
+
 	// Verify amount matches
 	if payment.Amount != req.Amount {
 		return errors.New("amount mismatch")
@@ -122,18 +146,57 @@ func (s *PaymentService) VerifyPayment(req *PaymentVerificationRequest) error {
 		return errors.New("invalid payment status")
 	}
 
-	// Update payment
+	// Update payment and, once it is completed, confirm the order. The
+	// provider's callback is a system action, so no actor is recorded.
+	previousStatus := payment.Status
 	payment.Status = newStatus
 	payment.ExternalID = req.ExternalID
-	if err := s.paymentRepo.Update(payment); err != nil {
-		return errors.New("failed to update payment status")
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Payments.Update(payment); err != nil {
+			return writeError(err, "failed to update payment status")
+		}
+
+		eventType := repositories.OrderEventPaymentStatusChanged
+		if newStatus == repositories.PaymentStatusCompleted {
+			eventType = repositories.OrderEventPaymentReceived
+		}
+		if err := recordOrderEvent(uow, 0, repositories.OrderEvent{
+			OrderID:       payment.OrderID,
+			PaymentID:     &payment.ID,
+			Type:          eventType,
+			PreviousValue: string(previousStatus),
+			NewValue:      string(newStatus),
+			Reason:        "payment provider callback " + req.ExternalID,
+		}); err != nil {
+			return err
+		}
+
+		if newStatus == repositories.PaymentStatusCompleted {
+			orderStatus := paidStatus(&payment.Order)
+			if err := uow.Orders.UpdateStatus(payment.OrderID, payment.Order.Version, orderStatus); err != nil {
+				return writeError(err, "failed to update order status")
+			}
+
+			if err := recordOrderEvent(uow, 0, repositories.OrderEvent{
+				OrderID:       payment.OrderID,
+				Type:          repositories.OrderEventStatusChanged,
+				PreviousValue: string(payment.Order.Status),
+				NewValue:      string(orderStatus),
+				Reason:        "payment completed",
+			}); err != nil {
+				return err
+			}
+
+			return paceCourses(uow, payment.OrderID, orderStatus, 0, s.config)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Update order status if payment is completed
 	if newStatus == repositories.PaymentStatusCompleted {
-		if err := s.orderRepo.UpdateStatus(payment.OrderID, repositories.OrderStatusConfirmed); err != nil {
-			return errors.New("failed to update order status")
-		}
+		s.kitchenService.BroadcastStatusChange(payment.OrderID)
 	}
 
 	return nil
@@ -175,7 +238,12 @@ func (s *PaymentService) generateQRISData(order *repositories.Order, transaction
 	return qrisData, nil
 }
 
-func (s *PaymentService) RefundPayment(paymentID uint, reason string) error {
+// describePayment summarises a payment for the order history.
+func describePayment(payment *repositories.Payment) string {
+	return fmt.Sprintf("%s %s %s", payment.Method, formatAmount(payment.Amount), payment.Status)
+}
+
+func (s *PaymentService) RefundPayment(paymentID uint, reason string, actorID uint) error {
 	payment, err := s.paymentRepo.GetByID(paymentID)
 	if err != nil {
 		return errors.New("payment not found")
@@ -185,27 +253,88 @@ func (s *PaymentService) RefundPayment(paymentID uint, reason string) error {
 		return errors.New("can only refund completed payments")
 	}
 
-	// Update payment status
+	// Refund the payment and cancel the order together
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := s.refundWithin(uow, payment, reason, actorID); err != nil {
+			return err
+		}
+
+		if err := uow.Orders.UpdateStatus(payment.OrderID, payment.Order.Version, repositories.OrderStatusCancelled); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       payment.OrderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(payment.Order.Status),
+			NewValue:      string(repositories.OrderStatusCancelled),
+			Reason:        "payment refunded",
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.kitchenService.BroadcastStatusChange(payment.OrderID)
+	return nil
+}
+
+// refundWithin marks a completed payment as fully refunded inside uow and
+// records the refund in the order history. The caller decides what happens to
+// the order.
+func (s *PaymentService) refundWithin(uow *repositories.UnitOfWork, payment *repositories.Payment, reason string, actorID uint) error {
+	if payment.Status != repositories.PaymentStatusCompleted {
+		return errors.New("can only refund completed payments")
+	}
+
 	payment.Status = repositories.PaymentStatusRefunded
-	if err := s.paymentRepo.Update(payment); err != nil {
-		return errors.New("failed to update payment status")
+	if err := uow.Payments.Update(payment); err != nil {
+		return writeError(err, "failed to update payment status")
 	}
 
-	// Update order status
-	if err := s.orderRepo.UpdateStatus(payment.OrderID, repositories.OrderStatusCancelled); err != nil {
-		return errors.New("failed to update order status")
+	return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+		OrderID:       payment.OrderID,
+		PaymentID:     &payment.ID,
+		Type:          repositories.OrderEventPaymentRefunded,
+		PreviousValue: string(repositories.PaymentStatusCompleted),
+		NewValue:      formatAmount(payment.Amount),
+		Reason:        reason,
+	})
+}
+
+// cancelPendingWithin withdraws a payment that was started but never
+// completed, so a late provider callback cannot confirm a cancelled order.
+func (s *PaymentService) cancelPendingWithin(uow *repositories.UnitOfWork, payment *repositories.Payment, reason string, actorID uint) error {
+	previousStatus := payment.Status
+	payment.Status = repositories.PaymentStatusCancelled
+	if err := uow.Payments.Update(payment); err != nil {
+		return writeError(err, "failed to update payment status")
 	}
 
-	return nil
+	return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+		OrderID:       payment.OrderID,
+		PaymentID:     &payment.ID,
+		Type:          repositories.OrderEventPaymentStatusChanged,
+		PreviousValue: string(previousStatus),
+		NewValue:      string(repositories.PaymentStatusCancelled),
+		Reason:        reason,
+	})
 }
 
-func (s *PaymentService) ProcessCashPayment(orderID uint, amountPaid, changeAmount float64) error {
+func (s *PaymentService) ProcessCashPayment(orderID uint, amountPaid, changeAmount float64, actorID uint) error {
 	// Get order details
 	order, err := s.orderRepo.GetByID(orderID)
 	if err != nil {
 		return errors.New("order not found")
 	}
 
+	// A paid dine-in order can still owe the add-on rounds ordered since
+	if existingPayment, err := s.paymentRepo.GetByOrderID(orderID); err == nil && existingPayment.Status == repositories.PaymentStatusCompleted {
+		if order.Status != repositories.OrderStatusCancelled && order.TotalAmount-existingPayment.Amount > 0.005 {
+			return s.collectBalance(order, existingPayment, amountPaid, changeAmount, actorID)
+		}
+	}
+
 	// Check if order is in valid status for payment
 	if order.Status != repositories.OrderStatusPending {
 		return errors.New("order is not pending payment")
@@ -224,6 +353,12 @@ func (s *PaymentService) ProcessCashPayment(orderID uint, amountPaid, changeAmou
 		return errors.New("insufficient payment amount")
 	}
 
+	// The change printed on the receipt is always derived from the amount paid
+	change := amountPaid - order.TotalAmount
+	if changeAmount != 0 && math.Abs(changeAmount-change) > 0.01 {
+		return errors.New("change amount does not match amount paid")
+	}
+
 	// Generate transaction ID
 	transactionID, err := s.generateTransactionID()
 	if err != nil {
@@ -231,26 +366,158 @@ func (s *PaymentService) ProcessCashPayment(orderID uint, amountPaid, changeAmou
 	}
 
 	// Create payment record
+	payment := &repositories.Payment{
+		OrderID:        orderID,
+		Method:         repositories.PaymentMethodCash,
+		Status:         repositories.PaymentStatusCompleted,
+		Amount:         order.TotalAmount,
+		TransactionID:  transactionID,
+		AmountTendered: amountPaid,
+		ChangeAmount:   change,
+	}
+
+	// Confirm the order and record the payment together. The versioned order
+	// update goes first so a second cashier paying the same order conflicts
+	// instead of creating a duplicate payment.
+	orderStatus := paidStatus(order)
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateStatus(orderID, order.Version, orderStatus); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+
+		if err := uow.Payments.Create(payment); err != nil {
+			return errors.New("failed to create payment record")
+		}
+
+		if err := recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:   orderID,
+			PaymentID: &payment.ID,
+			Type:      repositories.OrderEventPaymentReceived,
+			NewValue:  fmt.Sprintf("%s (tendered %s, change %s)", describePayment(payment), formatAmount(amountPaid), formatAmount(change)),
+		}); err != nil {
+			return err
+		}
+
+		if err := recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(orderStatus),
+			Reason:        "payment completed",
+		}); err != nil {
+			return err
+		}
+
+		return paceCourses(uow, orderID, orderStatus, actorID, s.config)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.kitchenService.BroadcastStatusChange(orderID)
+	return nil
+}
+
+// RecordPlatformPayment records that a delivery platform collected the
+// payment for one of its orders and confirms the order, like a cash payment
+// at the counter.
+func (s *PaymentService) RecordPlatformPayment(orderID uint, platform, externalID string) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return errors.New("order not found")
+	}
+
+	if order.Status != repositories.OrderStatusPending {
+		return errors.New("order is not pending payment")
+	}
+
+	transactionID, err := s.generateTransactionID()
+	if err != nil {
+		return errors.New("failed to generate transaction ID")
+	}
+
 	payment := &repositories.Payment{
 		OrderID:       orderID,
-		Method:        repositories.PaymentMethodCash,
+		Method:        repositories.PaymentMethodPlatform,
 		Status:        repositories.PaymentStatusCompleted,
 		Amount:        order.TotalAmount,
 		TransactionID: transactionID,
+		ExternalID:    externalID,
 	}
 
-	if err := s.paymentRepo.Create(payment); err != nil {
-		return errors.New("failed to create payment record")
-	}
+	orderStatus := paidStatus(order)
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateStatus(orderID, order.Version, orderStatus); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+
+		if err := uow.Payments.Create(payment); err != nil {
+			return errors.New("failed to create payment record")
+		}
+
+		if err := recordOrderEvent(uow, 0, repositories.OrderEvent{
+			OrderID:   orderID,
+			PaymentID: &payment.ID,
+			Type:      repositories.OrderEventPaymentReceived,
+			NewValue:  fmt.Sprintf("%s via %s", describePayment(payment), platform),
+		}); err != nil {
+			return err
+		}
 
-	// Update order status
-	if err := s.orderRepo.UpdateStatus(orderID, repositories.OrderStatusConfirmed); err != nil {
-		return errors.New("failed to update order status")
+		if err := recordOrderEvent(uow, 0, repositories.OrderEvent{
+			OrderID:       orderID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(orderStatus),
+			Reason:        fmt.Sprintf("paid on %s", platform),
+		}); err != nil {
+			return err
+		}
+
+		return paceCourses(uow, orderID, orderStatus, 0, s.config)
+	})
+	if err != nil {
+		return err
 	}
 
+	s.kitchenService.BroadcastStatusChange(orderID)
 	return nil
 }
 
+// collectBalance takes cash for the part of the order total that its completed
+// payment does not cover yet, i.e. add-on rounds ordered after paying. The
+// balance is added to the existing payment so refunds cover the whole order.
+func (s *PaymentService) collectBalance(order *repositories.Order, payment *repositories.Payment, amountPaid, changeAmount float64, actorID uint) error {
+	balance := order.TotalAmount - payment.Amount
+	if amountPaid < balance {
+		return errors.New("insufficient payment amount")
+	}
+
+	change := amountPaid - balance
+	if changeAmount != 0 && math.Abs(changeAmount-change) > 0.01 {
+		return errors.New("change amount does not match amount paid")
+	}
+
+	previous := describePayment(payment)
+	payment.Amount = order.TotalAmount
+	payment.AmountTendered = amountPaid
+	payment.ChangeAmount = change
+
+	return s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Payments.Update(payment); err != nil {
+			return writeError(err, "failed to update payment")
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       order.ID,
+			PaymentID:     &payment.ID,
+			Type:          repositories.OrderEventPaymentReceived,
+			PreviousValue: previous,
+			NewValue:      fmt.Sprintf("%s (balance %s in cash, tendered %s, change %s)", describePayment(payment), formatAmount(balance), formatAmount(amountPaid), formatAmount(change)),
+		})
+	})
+}
+
 // Admin/Cashier payment management functions
 
 func (s *PaymentService) GetAllPayments(page, limit int, status, method string) ([]*repositories.Payment, int64, error) {
@@ -262,12 +529,18 @@ func (s *PaymentService) GetPaymentByIDAdmin(id uint) (*repositories.Payment, er
 	return s.paymentRepo.GetByIDWithDetails(id)
 }
 
-func (s *PaymentService) UpdatePaymentStatus(id uint, status string) (*repositories.Payment, error) {
+// UpdatePaymentStatus overrides the status of a payment. A non-zero
+// expectedVersion must match the payment's current version (If-Match).
+func (s *PaymentService) UpdatePaymentStatus(id uint, status string, expectedVersion uint, reason string, actorID uint) (*repositories.Payment, error) {
 	payment, err := s.paymentRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkVersion(expectedVersion, payment.Version); err != nil {
+		return nil, err
+	}
+
 	// Convert string status to PaymentStatus enum
 	var paymentStatus repositories.PaymentStatus
 	switch status {
@@ -285,15 +558,30 @@ func (s *PaymentService) UpdatePaymentStatus(id uint, status string) (*repositor
 		return nil, fmt.Errorf("invalid payment status")
 	}
 
+	previousStatus := payment.Status
 	payment.Status = paymentStatus
-	if err := s.paymentRepo.Update(payment); err != nil {
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Payments.Update(payment); err != nil {
+			return err
+		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       payment.OrderID,
+			PaymentID:     &payment.ID,
+			Type:          repositories.OrderEventPaymentStatusChanged,
+			PreviousValue: string(previousStatus),
+			NewValue:      string(paymentStatus),
+			Reason:        strings.TrimSpace(reason),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return payment, nil
 }
 
-func (s *PaymentService) ProcessRefund(paymentID uint, amount float64, reason string) (*repositories.Payment, error) {
+func (s *PaymentService) ProcessRefund(paymentID uint, amount float64, reason string, actorID uint) (*repositories.Payment, error) {
 	payment, err := s.paymentRepo.GetByID(paymentID)
 	if err != nil {
 		return nil, err
@@ -316,16 +604,30 @@ func (s *PaymentService) ProcessRefund(paymentID uint, amount float64, reason st
 		TransactionID: fmt.Sprintf("REFUND-%s", payment.TransactionID),
 	}
 
-	if err := s.paymentRepo.Create(refund); err != nil {
-		return nil, err
-	}
+	err = s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Payments.Create(refund); err != nil {
+			return err
+		}
 
-	// Update original payment status if full refund
-	if amount == payment.Amount {
-		payment.Status = repositories.PaymentStatusRefunded
-		if err := s.paymentRepo.Update(payment); err != nil {
-			return nil, err
+		// Update original payment status if full refund
+		if amount == payment.Amount {
+			payment.Status = repositories.PaymentStatusRefunded
+			if err := uow.Payments.Update(payment); err != nil {
+				return err
+			}
 		}
+
+		return recordOrderEvent(uow, actorID, repositories.OrderEvent{
+			OrderID:       payment.OrderID,
+			PaymentID:     &payment.ID,
+			Type:          repositories.OrderEventPaymentRefunded,
+			PreviousValue: formatAmount(payment.Amount),
+			NewValue:      formatAmount(amount),
+			Reason:        reason,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return refund, nil
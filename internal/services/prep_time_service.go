@@ -0,0 +1,386 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+)
+
+const (
+	// prepSampleWindow is how many recent samples make up an item's estimate.
+	prepSampleWindow = 20
+	// minPrepSamples is the history needed before a learned estimate replaces
+	// the configured default.
+	minPrepSamples = 3
+	// overrunTolerance is how far over the estimate still counts as on time.
+	overrunTolerance = 0.1
+	// criticalPathSlack is how much earlier than the slowest item of an order
+	// an item may be expected to finish and still be timed. Items expected
+	// well before it were probably done early and waited for the rest.
+	criticalPathSlack = time.Minute
+)
+
+// PrepTimeService learns per-menu-item preparation times from completed orders
+// and turns them, together with the current kitchen queue, into ready-time
+// estimates.
+type PrepTimeService struct {
+	prepTimeRepo   *repositories.PrepTimeRepository
+	orderRepo      *repositories.OrderRepository
+	eventRepo      *repositories.OrderEventRepository
+	kitchenService *KitchenService
+	events         *OrderEventConsumer
+	config         *config.Config
+}
+
+type PrepTimeOverrun struct {
+	repositories.PrepTimeStat
+	OverrunRate float64 `json:"overrun_rate"`
+}
+
+func NewPrepTimeService(prepTimeRepo *repositories.PrepTimeRepository, orderRepo *repositories.OrderRepository, eventRepo *repositories.OrderEventRepository, kitchenService *KitchenService, config *config.Config) *PrepTimeService {
+	service := &PrepTimeService{
+		prepTimeRepo:   prepTimeRepo,
+		orderRepo:      orderRepo,
+		eventRepo:      eventRepo,
+		kitchenService: kitchenService,
+		config:         config,
+	}
+	service.events = NewOrderEventConsumer("prep_time", eventRepo, kitchenService, service.handleEvent)
+	return service
+}
+
+// Start follows the order history in the background: orders that become
+// ready are recorded as samples and every queue change refreshes the ETAs of
+// the orders still waiting.
+func (s *PrepTimeService) Start() {
+	s.events.Start()
+}
+
+// ProcessOrderEvents handles the order events committed since the last run.
+// Start does this in the background.
+func (s *PrepTimeService) ProcessOrderEvents() error {
+	return s.events.Poll()
+}
+
+func (s *PrepTimeService) handleEvent(event *repositories.OrderEvent) error {
+	switch event.Type {
+	case repositories.OrderEventStatusChanged, repositories.OrderEventStatusOverridden:
+		if repositories.OrderStatus(event.NewValue) == repositories.OrderStatusReady {
+			if err := s.recordCompletion(event); err != nil {
+				return err
+			}
+		}
+	case repositories.OrderEventCreated, repositories.OrderEventItemsUpdated,
+		repositories.OrderEventRoundAdded, repositories.OrderEventCourseFired:
+	default:
+		return nil
+	}
+
+	return s.RefreshQueueEstimates()
+}
+
+// EstimateReadyTime predicts when a new order with the given items would be
+// ready if it joined the back of the current kitchen queue.
+func (s *PrepTimeService) EstimateReadyTime(menuItemIDs []uint) (time.Time, error) {
+	now := time.Now()
+
+	queued, err := s.orderRepo.GetQueuedOrders()
+	if err != nil {
+		return now, err
+	}
+
+	allItemIDs := append(orderMenuItemIDs(queued), menuItemIDs...)
+	estimates, err := s.itemEstimates(allItemIDs)
+	if err != nil {
+		return now, err
+	}
+
+	var workAhead time.Duration
+	for i := range queued {
+		if queued[i].Status != repositories.OrderStatusPending {
+			workAhead += remainingPrep(&queued[i], estimates, now)
+		}
+	}
+
+	return now.Add(s.queueDelay(workAhead) + maxPrep(menuItemIDs, estimates)), nil
+}
+
+// RefreshQueueEstimates walks the queue oldest first and gives every waiting
+// order a new EstimatedCompletionTime based on the work ahead of it. Pending
+// (unpaid) orders get an estimate but do not hold up the orders behind them.
+// Scheduled orders are never estimated earlier than their pickup time.
+// Orders whose estimate moved by a minute or more are re-broadcast as
+// eta_update.
+func (s *PrepTimeService) RefreshQueueEstimates() error {
+	now := time.Now()
+
+	queued, err := s.orderRepo.GetQueuedOrders()
+	if err != nil {
+		return err
+	}
+
+	estimates, err := s.itemEstimates(orderMenuItemIDs(queued))
+	if err != nil {
+		return err
+	}
+
+	var workAhead time.Duration
+	for i := range queued {
+		order := &queued[i]
+		remaining := remainingPrep(order, estimates, now)
+		estimated := now.Add(s.queueDelay(workAhead) + remaining).Truncate(time.Minute)
+		if order.Status != repositories.OrderStatusPending {
+			workAhead += remaining
+		}
+		// Pre-orders are not handed out before their pickup time
+		if order.ScheduledFor != nil && estimated.Before(*order.ScheduledFor) {
+			estimated = *order.ScheduledFor
+		}
+
+		if order.EstimatedCompletionTime != nil {
+			drift := estimated.Sub(*order.EstimatedCompletionTime)
+			if drift < time.Minute && drift > -time.Minute {
+				continue
+			}
+		}
+
+		if err := s.orderRepo.UpdateEstimatedCompletionTime(order.ID, estimated); err != nil {
+			return err
+		}
+		s.kitchenService.BroadcastOrderUpdate(order.ID, "eta_update")
+	}
+
+	return nil
+}
+
+// GetOverrunReport lists menu items whose actual prep time exceeded the
+// estimate in at least minRate of their samples over the last days.
+func (s *PrepTimeService) GetOverrunReport(days, minSamples int, minRate float64) ([]PrepTimeOverrun, error) {
+	since := time.Now().AddDate(0, 0, -days)
+
+	stats, err := s.prepTimeRepo.GetStatsSince(since, overrunTolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	report := []PrepTimeOverrun{}
+	for _, stat := range stats {
+		if stat.SampleCount < minSamples {
+			continue
+		}
+		rate := float64(stat.OverrunCount) / float64(stat.SampleCount)
+		if rate >= minRate {
+			report = append(report, PrepTimeOverrun{PrepTimeStat: stat, OverrunRate: rate})
+		}
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].OverrunRate > report[j].OverrunRate
+	})
+
+	return report, nil
+}
+
+// recordCompletion times the items that went to the kitchen since the order
+// was last ready, from when each was sent until the ready event. Only the
+// items expected to finish last are recorded: the order waits for its
+// slowest item, so the others were done earlier than the order shows.
+func (s *PrepTimeService) recordCompletion(ready *repositories.OrderEvent) error {
+	order, err := s.orderRepo.GetByID(ready.OrderID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOrderNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	history, err := s.eventRepo.GetByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+	var previousReady time.Time
+	for _, event := range history {
+		if event.ID < ready.ID && event.NewValue == string(repositories.OrderStatusReady) &&
+			(event.Type == repositories.OrderEventStatusChanged || event.Type == repositories.OrderEventStatusOverridden) {
+			previousReady = event.CreatedAt
+		}
+	}
+
+	sampled, err := s.prepTimeRepo.GetSampledOrderItems(order.ID)
+	if err != nil {
+		return err
+	}
+
+	type timedItem struct {
+		item  *repositories.OrderItem
+		start time.Time
+	}
+	var items []timedItem
+	var menuItemIDs []uint
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		if !printing.ItemSent(order, item) {
+			continue
+		}
+		start := itemStart(order, item)
+		if start == nil || start.Before(previousReady) || !start.Before(ready.CreatedAt) {
+			continue
+		}
+		// Events can be handled twice. The slowest item is always timed, so
+		// any sample means this ready event has been recorded.
+		if sampled[item.ID] {
+			return nil
+		}
+		items = append(items, timedItem{item: item, start: *start})
+		menuItemIDs = append(menuItemIDs, item.MenuItemID)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	estimates, err := s.itemEstimates(menuItemIDs)
+	if err != nil {
+		return err
+	}
+
+	var lastFinish time.Time
+	for _, timed := range items {
+		if finish := timed.start.Add(estimates[timed.item.MenuItemID]); finish.After(lastFinish) {
+			lastFinish = finish
+		}
+	}
+
+	samples := make([]repositories.PrepTimeSample, 0, len(items))
+	for _, timed := range items {
+		estimate := estimates[timed.item.MenuItemID]
+		if lastFinish.Sub(timed.start.Add(estimate)) > criticalPathSlack {
+			continue
+		}
+		orderItemID := timed.item.ID
+		samples = append(samples, repositories.PrepTimeSample{
+			MenuItemID:       timed.item.MenuItemID,
+			OrderID:          order.ID,
+			OrderItemID:      &orderItemID,
+			ActualSeconds:    int(ready.CreatedAt.Sub(timed.start).Seconds()),
+			EstimatedSeconds: int(estimate.Seconds()),
+		})
+	}
+
+	return s.prepTimeRepo.CreateSamples(samples)
+}
+
+// itemStart is when an item went to the kitchen: when its course was fired,
+// when its add-on round was ordered, or else when the order was confirmed.
+func itemStart(order *repositories.Order, item *repositories.OrderItem) *time.Time {
+	if item.Course != "" {
+		for _, course := range order.Courses {
+			if course.Course == item.Course {
+				return course.FiredAt
+			}
+		}
+		return nil
+	}
+
+	if order.ConfirmedAt == nil {
+		return nil
+	}
+	if item.Round > 1 && item.CreatedAt.After(*order.ConfirmedAt) {
+		start := item.CreatedAt
+		return &start
+	}
+	return order.ConfirmedAt
+}
+
+// itemEstimates averages the recent samples of each item, falling back to the
+// configured default prep time until enough history exists.
+func (s *PrepTimeService) itemEstimates(menuItemIDs []uint) (map[uint]time.Duration, error) {
+	unique := uniqueIDs(menuItemIDs)
+
+	samples, err := s.prepTimeRepo.GetRecentSamples(unique, prepSampleWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultPrep := time.Duration(s.config.DefaultPrepMinutes) * time.Minute
+	estimates := make(map[uint]time.Duration, len(unique))
+	for _, id := range unique {
+		itemSamples := samples[id]
+		if len(itemSamples) < minPrepSamples {
+			estimates[id] = defaultPrep
+			continue
+		}
+
+		var total int
+		for _, sample := range itemSamples {
+			total += sample.ActualSeconds
+		}
+		estimates[id] = time.Duration(total/len(itemSamples)) * time.Second
+	}
+
+	return estimates, nil
+}
+
+// queueDelay spreads the work ahead over the orders the kitchen can work on
+// in parallel.
+func (s *PrepTimeService) queueDelay(workAhead time.Duration) time.Duration {
+	parallel := s.config.KitchenParallelOrders
+	if parallel < 1 {
+		parallel = 1
+	}
+	return workAhead / time.Duration(parallel)
+}
+
+// remainingPrep is the order's prep time minus the time it has already spent
+// in the kitchen. Items in one order are cooked side by side, so the slowest
+// item decides.
+func remainingPrep(order *repositories.Order, estimates map[uint]time.Duration, now time.Time) time.Duration {
+	menuItemIDs := make([]uint, 0, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		menuItemIDs = append(menuItemIDs, item.MenuItemID)
+	}
+
+	remaining := maxPrep(menuItemIDs, estimates)
+	if order.ConfirmedAt != nil {
+		remaining -= now.Sub(*order.ConfirmedAt)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func maxPrep(menuItemIDs []uint, estimates map[uint]time.Duration) time.Duration {
+	var longest time.Duration
+	for _, id := range menuItemIDs {
+		if estimates[id] > longest {
+			longest = estimates[id]
+		}
+	}
+	return longest
+}
+
+func orderMenuItemIDs(orders []repositories.Order) []uint {
+	var ids []uint
+	for _, order := range orders {
+		for _, item := range order.OrderItems {
+			ids = append(ids, item.MenuItemID)
+		}
+	}
+	return ids
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,669 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+)
+
+const (
+	// printPollInterval is how often the worker looks for due print jobs.
+	printPollInterval = 2 * time.Second
+	// printerCheckInterval is how often idle printers are probed so an
+	// offline printer shows up before a ticket is lost on it.
+	printerCheckInterval = 30 * time.Second
+	printerTimeout       = 5 * time.Second
+	printRetryBaseDelay  = 5 * time.Second
+	printRetryMaxDelay   = 5 * time.Minute
+	printBatchSize       = 50
+)
+
+// PrintService routes rendered tickets to registered printers through a
+// persistent queue. Jobs for an unreachable printer stay queued and are
+// retried with backoff until they print or run out of attempts.
+type PrintService struct {
+	printerRepo    *repositories.PrinterRepository
+	orderRepo      *repositories.OrderRepository
+	receiptService *ReceiptService
+	events         *OrderEventConsumer
+	config         *config.Config
+	wake           chan struct{}
+
+	// queueMutex keeps the event consumer, the start-up check and manual
+	// reprints from queueing the same ticket twice
+	queueMutex sync.Mutex
+}
+
+type PrinterRequest struct {
+	Name       string                         `json:"name" binding:"required"`
+	Connection repositories.PrinterConnection `json:"connection" binding:"required"`
+	Address    string                         `json:"address" binding:"required"`
+	PaperWidth int                            `json:"paper_width"`
+	Format     string                         `json:"format"`
+	Stations   []string                       `json:"stations"`
+	Terminal   string                         `json:"terminal"`
+	IsActive   *bool                          `json:"is_active"`
+}
+
+// PrinterStatusReport is a printer together with the state of its queue, as
+// shown on the manager dashboard.
+type PrinterStatusReport struct {
+	repositories.Printer
+	QueuedJobs   int        `json:"queued_jobs"`
+	FailedJobs   int        `json:"failed_jobs"`
+	OldestQueued *time.Time `json:"oldest_queued,omitempty"`
+}
+
+func NewPrintService(printerRepo *repositories.PrinterRepository, orderRepo *repositories.OrderRepository, eventRepo *repositories.OrderEventRepository, receiptService *ReceiptService, kitchenService *KitchenService, config *config.Config) *PrintService {
+	service := &PrintService{
+		printerRepo:    printerRepo,
+		orderRepo:      orderRepo,
+		receiptService: receiptService,
+		config:         config,
+		wake:           make(chan struct{}, 1),
+	}
+	service.events = NewOrderEventConsumer("print", eventRepo, kitchenService, service.handleEvent)
+	return service
+}
+
+// Start runs the print worker and follows the order history in the
+// background: kitchen tickets are queued for every order that reaches the
+// kitchen, add-on round and fired course, and cancelled orders are voided.
+// Orders already in the kitchen get any ticket they are missing.
+func (s *PrintService) Start() {
+	go s.run()
+	s.events.Start()
+
+	go func() {
+		if err := s.QueueMissingKitchenTickets(); err != nil {
+			log.Printf("Error checking kitchen tickets: %v", err)
+		}
+	}()
+}
+
+// ProcessOrderEvents handles the order events committed since the last run.
+// Start does this in the background.
+func (s *PrintService) ProcessOrderEvents() error {
+	return s.events.Poll()
+}
+
+func (s *PrintService) handleEvent(event *repositories.OrderEvent) error {
+	switch event.Type {
+	case repositories.OrderEventRoundAdded, repositories.OrderEventCourseFired:
+		return s.queueNewKitchenTickets(event.OrderID)
+	case repositories.OrderEventStatusChanged, repositories.OrderEventStatusOverridden:
+		switch repositories.OrderStatus(event.NewValue) {
+		case repositories.OrderStatusConfirmed:
+			return s.queueNewKitchenTickets(event.OrderID)
+		case repositories.OrderStatusCancelled:
+			_, err := s.VoidKitchenTickets(event.OrderID)
+			return err
+		}
+	}
+	return nil
+}
+
+// queueNewKitchenTickets queues the tickets of an order that were not queued
+// yet. An order cancelled since the event was recorded prints nothing.
+func (s *PrintService) queueNewKitchenTickets(orderID uint) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOrderNotFound) {
+			return nil
+		}
+		return err
+	}
+	if order.Status == repositories.OrderStatusCancelled {
+		return nil
+	}
+
+	_, err = s.QueueKitchenTickets(orderID, false)
+	return err
+}
+
+// QueueMissingKitchenTickets queues every ticket not queued yet for the
+// orders in the kitchen, e.g. ones that reached it before the print queue
+// first started.
+func (s *PrintService) QueueMissingKitchenTickets() error {
+	orders, err := s.orderRepo.GetKitchenOrders()
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if _, err := s.QueueKitchenTickets(order.ID, false); err != nil {
+			return fmt.Errorf("order %d: %w", order.ID, err)
+		}
+	}
+	return nil
+}
+
+// QueueKitchenTickets queues the tickets of the order on every printer
+// serving their station: one per station, round and course that was sent to
+// the kitchen. Unless reprint is set, tickets that were queued before are
+// skipped, so an add-on round or a fired course only prints its own items.
+// Stations without a printer of their own fall back to the printers of the
+// default kitchen station.
+func (s *PrintService) QueueKitchenTickets(orderID uint, reprint bool) ([]repositories.PrintJob, error) {
+	s.queueMutex.Lock()
+	defer s.queueMutex.Unlock()
+
+	tickets, err := s.receiptService.GetOrderTickets(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	printers, err := s.printerRepo.GetActive()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []repositories.PrintJob
+	for _, ticket := range tickets {
+		if !reprint {
+			queued, err := s.printerRepo.HasOrderJob(orderID, repositories.PrintJobKitchenTicket, ticket.Station, ticket.Round, ticket.Course)
+			if err != nil {
+				return jobs, err
+			}
+			if queued {
+				continue
+			}
+		}
+
+		targets := printersForStation(printers, ticket.Station)
+		if len(targets) == 0 {
+			targets = printersForStation(printers, printing.DefaultStation)
+		}
+		if len(targets) == 0 {
+			log.Printf("No printer configured for station %q, order %d", ticket.Station, orderID)
+			continue
+		}
+
+		for _, printer := range targets {
+			format, width := printerOutput(&printer)
+			doc, err := s.receiptService.RenderKitchenTicket(orderID, ticket, format, width)
+			if err != nil {
+				return jobs, err
+			}
+
+			job, err := s.enqueue(&printer, repositories.PrintJobKitchenTicket, &orderID, ticket, doc.Data)
+			if err != nil {
+				return jobs, err
+			}
+			jobs = append(jobs, *job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// VoidKitchenTickets stops the kitchen from preparing a cancelled order.
+// Tickets that have not printed yet are withdrawn from the queue; every
+// printer that already printed one gets a VOID slip for that station, once.
+func (s *PrintService) VoidKitchenTickets(orderID uint) ([]repositories.PrintJob, error) {
+	s.queueMutex.Lock()
+	defer s.queueMutex.Unlock()
+
+	if _, err := s.printerRepo.CancelUnprintedOrderJobs(orderID, repositories.PrintJobKitchenTicket); err != nil {
+		return nil, err
+	}
+
+	printed, err := s.printerRepo.GetPrintedOrderJobs(orderID, repositories.PrintJobKitchenTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reprinted tickets, and orders voided before, need only one slip per
+	// printer and ticket
+	slips, err := s.printerRepo.GetOrderJobs(orderID, repositories.PrintJobVoidTicket)
+	if err != nil {
+		return nil, err
+	}
+	voided := make(map[string]bool)
+	for _, slip := range slips {
+		voided[printJobKey(&slip)] = true
+	}
+
+	var jobs []repositories.PrintJob
+	for _, ticket := range printed {
+		key := printJobKey(&ticket)
+		if voided[key] {
+			continue
+		}
+		voided[key] = true
+
+		format, width := printerOutput(&ticket.Printer)
+		slip := printing.Ticket{Station: ticket.Station, Round: ticket.Round, Course: ticket.Course}
+		doc, err := s.receiptService.RenderVoidTicket(orderID, slip, format, width)
+		if err != nil {
+			return jobs, err
+		}
+
+		job, err := s.enqueue(&ticket.Printer, repositories.PrintJobVoidTicket, &orderID, slip, doc.Data)
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, nil
+}
+
+// QueueReceipt queues the receipt of an order on the given printer, or on the
+// printer registered for the cashier terminal when printerID is zero.
+func (s *PrintService) QueueReceipt(orderID uint, terminal string, printerID uint) (*repositories.PrintJob, error) {
+	var printer *repositories.Printer
+	var err error
+	switch {
+	case printerID != 0:
+		printer, err = s.printerRepo.GetByID(printerID)
+	case terminal != "":
+		printer, err = s.printerRepo.GetActiveByTerminal(terminal)
+	default:
+		return nil, errors.New("terminal or printer_id is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	format, width := printerOutput(printer)
+	doc, err := s.receiptService.RenderReceipt(orderID, format, width)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.enqueue(printer, repositories.PrintJobReceipt, &orderID, printing.Ticket{}, doc.Data)
+}
+
+// QueueTestPage prints a short test page so staff can check a new printer.
+func (s *PrintService) QueueTestPage(printerID uint) (*repositories.PrintJob, error) {
+	printer, err := s.printerRepo.GetByID(printerID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &printing.Document{Title: "Test page"}
+	doc.Add(printing.Line{Text: s.config.RestaurantName, Align: printing.AlignCenter, Bold: true, Large: true})
+	doc.Center("Printer test page")
+	doc.Rule()
+	doc.Columns("Printer", printer.Name)
+	doc.Columns("Stations", printer.Stations)
+	doc.Columns("Terminal", printer.Terminal)
+	doc.Columns("Printed", time.Now().In(s.config.Location()).Format("02/01/2006 15:04"))
+	doc.Rule()
+
+	format, width := printerOutput(printer)
+	data, err := printing.Render(doc, format, width)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.enqueue(printer, repositories.PrintJobTestPage, nil, printing.Ticket{}, data)
+}
+
+// RetryJob puts a failed job back in the queue with a fresh set of attempts.
+func (s *PrintService) RetryJob(jobID uint) (*repositories.PrintJob, error) {
+	job, err := s.printerRepo.GetJobByID(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status == repositories.PrintJobPrinted {
+		return nil, errors.New("print job has already been printed")
+	}
+
+	if job.Status == repositories.PrintJobCancelled {
+		return nil, errors.New("print job was cancelled with its order")
+	}
+
+	err = s.printerRepo.UpdateJob(job.ID, map[string]interface{}{
+		"status":          repositories.PrintJobQueued,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	return s.printerRepo.GetJobByID(job.ID)
+}
+
+func (s *PrintService) GetJobs(page, limit int, status string, printerID uint) ([]repositories.PrintJob, int64, error) {
+	offset := (page - 1) * limit
+	return s.printerRepo.GetJobsPaginated(offset, limit, status, printerID)
+}
+
+func (s *PrintService) GetPrinters() ([]repositories.Printer, error) {
+	return s.printerRepo.GetAll()
+}
+
+func (s *PrintService) CreatePrinter(req *PrinterRequest) (*repositories.Printer, error) {
+	printer := &repositories.Printer{
+		IsActive: true,
+		Status:   repositories.PrinterStatusUnknown,
+	}
+	if err := applyPrinterRequest(printer, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.printerRepo.Create(printer); err != nil {
+		return nil, err
+	}
+	return printer, nil
+}
+
+func (s *PrintService) UpdatePrinter(id uint, req *PrinterRequest) (*repositories.Printer, error) {
+	printer, err := s.printerRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPrinterRequest(printer, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.printerRepo.Update(printer); err != nil {
+		return nil, err
+	}
+	return printer, nil
+}
+
+func (s *PrintService) DeletePrinter(id uint) error {
+	if _, err := s.printerRepo.GetByID(id); err != nil {
+		return err
+	}
+	return s.printerRepo.Delete(id)
+}
+
+// GetPrinterStatus reports every printer with its connection state and queue.
+func (s *PrintService) GetPrinterStatus() ([]PrinterStatusReport, error) {
+	printers, err := s.printerRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.printerRepo.GetQueueStats()
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]PrinterStatusReport, len(printers))
+	for i, printer := range printers {
+		stat := stats[printer.ID]
+		reports[i] = PrinterStatusReport{
+			Printer:      printer,
+			QueuedJobs:   stat.QueuedJobs,
+			FailedJobs:   stat.FailedJobs,
+			OldestQueued: stat.OldestQueued,
+		}
+	}
+	return reports, nil
+}
+
+// enqueue stores a job for the printer. ticket is left empty for receipts
+// and test pages.
+func (s *PrintService) enqueue(printer *repositories.Printer, kind repositories.PrintJobKind, orderID *uint, ticket printing.Ticket, data []byte) (*repositories.PrintJob, error) {
+	job := &repositories.PrintJob{
+		PrinterID:     printer.ID,
+		OrderID:       orderID,
+		Kind:          kind,
+		Station:       ticket.Station,
+		Round:         ticket.Round,
+		Course:        ticket.Course,
+		Data:          data,
+		Status:        repositories.PrintJobQueued,
+		NextAttemptAt: time.Now(),
+	}
+	if err := s.printerRepo.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	return job, nil
+}
+
+// notify wakes the worker so new jobs print without waiting for the next poll.
+func (s *PrintService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *PrintService) run() {
+	poll := time.NewTicker(printPollInterval)
+	defer poll.Stop()
+	check := time.NewTicker(printerCheckInterval)
+	defer check.Stop()
+
+	for {
+		select {
+		case <-s.wake:
+		case <-poll.C:
+		case <-check.C:
+			s.checkPrinters()
+			continue
+		}
+		s.ProcessDueJobs()
+	}
+}
+
+// ProcessDueJobs sends the queued jobs whose next attempt is due. Start does
+// this in the background.
+func (s *PrintService) ProcessDueJobs() {
+	jobs, err := s.printerRepo.GetDueJobs(time.Now(), printBatchSize)
+	if err != nil {
+		log.Printf("Error loading print jobs: %v", err)
+		return
+	}
+
+	// Once a printer fails, leave its remaining jobs for the next attempt so
+	// tickets still come out in order
+	failed := make(map[uint]bool)
+	for i := range jobs {
+		job := &jobs[i]
+		if failed[job.PrinterID] {
+			continue
+		}
+
+		if err := s.send(job); err != nil {
+			failed[job.PrinterID] = true
+			s.jobFailed(job, err)
+			continue
+		}
+		s.jobPrinted(job)
+	}
+}
+
+func (s *PrintService) send(job *repositories.PrintJob) error {
+	printer := &job.Printer
+	if printer.ID == 0 {
+		return errors.New("printer has been removed")
+	}
+	if !printer.IsActive {
+		return errors.New("printer is disabled")
+	}
+
+	switch printer.Connection {
+	case repositories.PrinterConnectionTCP:
+		return printing.SendTCP(printer.Address, job.Data, printerTimeout)
+	case repositories.PrinterConnectionSpool:
+		format, _ := printerOutput(printer)
+		name := fmt.Sprintf("%s-job-%d.%s", job.CreatedAt.Format("20060102-150405"), job.ID, format.Extension())
+		return printing.SendSpool(printer.Address, name, job.Data)
+	default:
+		return fmt.Errorf("unsupported printer connection %q", printer.Connection)
+	}
+}
+
+func (s *PrintService) jobPrinted(job *repositories.PrintJob) {
+	now := time.Now()
+	err := s.printerRepo.UpdateJob(job.ID, map[string]interface{}{
+		"status":     repositories.PrintJobPrinted,
+		"attempts":   job.Attempts + 1,
+		"last_error": "",
+		"printed_at": now,
+	})
+	if err != nil {
+		log.Printf("Error updating print job %d: %v", job.ID, err)
+	}
+
+	s.setPrinterStatus(&job.Printer, nil)
+}
+
+func (s *PrintService) jobFailed(job *repositories.PrintJob, sendErr error) {
+	attempts := job.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      sendErr.Error(),
+		"next_attempt_at": time.Now().Add(retryDelay(attempts)),
+	}
+	if attempts >= s.config.PrintMaxAttempts {
+		updates["status"] = repositories.PrintJobFailed
+		log.Printf("Print job %d failed after %d attempts: %v", job.ID, attempts, sendErr)
+	}
+
+	if err := s.printerRepo.UpdateJob(job.ID, updates); err != nil {
+		log.Printf("Error updating print job %d: %v", job.ID, err)
+	}
+
+	if job.Printer.ID != 0 {
+		s.setPrinterStatus(&job.Printer, sendErr)
+	}
+}
+
+// checkPrinters probes every active printer so the dashboard reflects printers
+// that went offline while idle.
+func (s *PrintService) checkPrinters() {
+	printers, err := s.printerRepo.GetActive()
+	if err != nil {
+		log.Printf("Error loading printers: %v", err)
+		return
+	}
+
+	for i := range printers {
+		printer := &printers[i]
+		var probeErr error
+		switch printer.Connection {
+		case repositories.PrinterConnectionTCP:
+			probeErr = printing.ProbeTCP(printer.Address, printerTimeout)
+		case repositories.PrinterConnectionSpool:
+			probeErr = printing.ProbeSpool(printer.Address)
+		}
+		s.setPrinterStatus(printer, probeErr)
+	}
+}
+
+func (s *PrintService) setPrinterStatus(printer *repositories.Printer, contactErr error) {
+	var err error
+	if contactErr != nil {
+		if printer.Status != repositories.PrinterStatusOffline {
+			log.Printf("Printer %s is offline: %v", printer.Name, contactErr)
+		}
+		err = s.printerRepo.UpdateStatus(printer.ID, repositories.PrinterStatusOffline, contactErr.Error(), nil)
+		printer.Status = repositories.PrinterStatusOffline
+	} else {
+		now := time.Now()
+		err = s.printerRepo.UpdateStatus(printer.ID, repositories.PrinterStatusOnline, "", &now)
+		printer.Status = repositories.PrinterStatusOnline
+	}
+	if err != nil {
+		log.Printf("Error updating printer %d status: %v", printer.ID, err)
+	}
+}
+
+// printJobKey identifies the printer and kitchen ticket a job was queued for.
+func printJobKey(job *repositories.PrintJob) string {
+	return fmt.Sprintf("%d/%s/%d/%s", job.PrinterID, job.Station, job.Round, job.Course)
+}
+
+// retryDelay doubles the wait after every failed attempt, up to a ceiling.
+func retryDelay(attempts int) time.Duration {
+	delay := printRetryBaseDelay
+	for i := 1; i < attempts && delay < printRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > printRetryMaxDelay {
+		return printRetryMaxDelay
+	}
+	return delay
+}
+
+func printersForStation(printers []repositories.Printer, station string) []repositories.Printer {
+	var matches []repositories.Printer
+	for _, printer := range printers {
+		for _, name := range strings.Split(printer.Stations, ",") {
+			if strings.TrimSpace(name) == station {
+				matches = append(matches, printer)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func printerOutput(printer *repositories.Printer) (printing.Format, printing.PaperWidth) {
+	format, err := printing.ParseFormat(printer.Format)
+	if err != nil {
+		format = printing.FormatESCPOS
+	}
+	width, err := printing.ParsePaperWidth(strconv.Itoa(printer.PaperWidth))
+	if err != nil {
+		width = printing.Paper80mm
+	}
+	return format, width
+}
+
+func applyPrinterRequest(printer *repositories.Printer, req *PrinterRequest) error {
+	switch req.Connection {
+	case repositories.PrinterConnectionTCP:
+		if err := printing.ValidateTCPAddress(req.Address); err != nil {
+			return err
+		}
+	case repositories.PrinterConnectionSpool:
+	default:
+		return errors.New("invalid connection. Must be 'tcp' or 'spool'")
+	}
+
+	width := printing.Paper80mm
+	if req.PaperWidth != 0 {
+		parsed, err := printing.ParsePaperWidth(strconv.Itoa(req.PaperWidth))
+		if err != nil {
+			return err
+		}
+		width = parsed
+	}
+
+	format, err := printing.ParseFormat(req.Format)
+	if err != nil {
+		return err
+	}
+
+	stations := make([]string, 0, len(req.Stations))
+	for _, station := range req.Stations {
+		if station = strings.ToLower(strings.TrimSpace(station)); station != "" {
+			stations = append(stations, station)
+		}
+	}
+
+	printer.Name = strings.TrimSpace(req.Name)
+	printer.Connection = req.Connection
+	printer.Address = strings.TrimSpace(req.Address)
+	printer.PaperWidth = int(width)
+	printer.Format = string(format)
+	printer.Stations = strings.Join(stations, ",")
+	printer.Terminal = strings.TrimSpace(req.Terminal)
+	if req.IsActive != nil {
+		printer.IsActive = *req.IsActive
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+)
+
+// ReceiptService renders customer receipts and kitchen tickets for orders.
+type ReceiptService struct {
+	orderRepo *repositories.OrderRepository
+	config    *config.Config
+}
+
+// RenderedDocument is a receipt or ticket ready to be sent to a printer or
+// downloaded.
+type RenderedDocument struct {
+	Title       string
+	Format      printing.Format
+	ContentType string
+	Data        []byte
+}
+
+func NewReceiptService(orderRepo *repositories.OrderRepository, config *config.Config) *ReceiptService {
+	return &ReceiptService{
+		orderRepo: orderRepo,
+		config:    config,
+	}
+}
+
+func (s *ReceiptService) RenderReceipt(orderID uint, format printing.Format, width printing.PaperWidth) (*RenderedDocument, error) {
+	order, err := s.orderRepo.GetByIDWithDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(printing.BuildReceipt(order, s.storeInfo()), format, width)
+}
+
+// RenderKitchenTicket renders one kitchen ticket of the order. A ticket with
+// a zero round covers every item the station has received.
+func (s *ReceiptService) RenderKitchenTicket(orderID uint, ticket printing.Ticket, format printing.Format, width printing.PaperWidth) (*RenderedDocument, error) {
+	order, err := s.orderRepo.GetByIDWithDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := printing.BuildKitchenTicket(order, ticket, s.storeInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(doc, format, width)
+}
+
+func (s *ReceiptService) RenderVoidTicket(orderID uint, ticket printing.Ticket, format printing.Format, width printing.PaperWidth) (*RenderedDocument, error) {
+	order, err := s.orderRepo.GetByIDWithDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := printing.BuildVoidTicket(order, ticket, s.storeInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(doc, format, width)
+}
+
+// GetOrderStations lists the stations that receive a ticket for the order.
+func (s *ReceiptService) GetOrderStations(orderID uint) ([]string, error) {
+	order, err := s.orderRepo.GetByIDWithDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return printing.Stations(order), nil
+}
+
+// GetOrderRoundStations lists, per round of the order, the stations that
+// receive a ticket for that round.
+func (s *ReceiptService) GetOrderRoundStations(orderID uint) (map[int][]string, error) {
+	order, err := s.orderRepo.GetByIDWithDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	stations := make(map[int][]string)
+	seen := make(map[string]bool)
+	for _, ticket := range printing.Tickets(order) {
+		key := fmt.Sprintf("%d/%s", ticket.Round, ticket.Station)
+		if !seen[key] {
+			seen[key] = true
+			stations[ticket.Round] = append(stations[ticket.Round], ticket.Station)
+		}
+	}
+	for round := range stations {
+		sort.Strings(stations[round])
+	}
+	return stations, nil
+}
+
+// GetOrderTickets lists the kitchen tickets of the items sent to the kitchen.
+func (s *ReceiptService) GetOrderTickets(orderID uint) ([]printing.Ticket, error) {
+	order, err := s.orderRepo.GetByIDWithDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return printing.Tickets(order), nil
+}
+
+func (s *ReceiptService) render(doc *printing.Document, format printing.Format, width printing.PaperWidth) (*RenderedDocument, error) {
+	data, err := printing.Render(doc, format, width)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenderedDocument{
+		Title:       doc.Title,
+		Format:      format,
+		ContentType: format.ContentType(),
+		Data:        data,
+	}, nil
+}
+
+func (s *ReceiptService) storeInfo() printing.StoreInfo {
+	return printing.StoreInfo{
+		Name:     s.config.RestaurantName,
+		Address:  s.config.RestaurantAddress,
+		Phone:    s.config.RestaurantPhone,
+		TaxID:    s.config.RestaurantTaxID,
+		Footer:   s.config.ReceiptFooter,
+		Location: s.config.Location(),
+	}
+}
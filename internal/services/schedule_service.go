@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/repositories"
+)
+
+// scheduleReleasePollInterval is how often the worker looks for scheduled
+// orders that are due in the kitchen.
+const scheduleReleasePollInterval = 30 * time.Second
+
+// ErrPickupSlotFull is returned when the requested pickup slot has no
+// capacity left.
+var ErrPickupSlotFull = errors.New("pickup slot is fully booked")
+
+// ScheduleService handles pre-ordered takeaway. A scheduled order is paid up
+// front but kept out of the kitchen queue until SCHEDULED_LEAD_MINUTES before
+// its pickup time, when it is released as a normal confirmed order.
+type ScheduleService struct {
+	orderRepo      *repositories.OrderRepository
+	kitchenService *KitchenService
+	transactor     *repositories.Transactor
+	config         *config.Config
+}
+
+// PickupSlot is one bookable pickup window.
+type PickupSlot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Booked    int       `json:"booked"`
+	Capacity  int       `json:"capacity,omitempty"` // 0 means unlimited
+	Available bool      `json:"available"`
+}
+
+func NewScheduleService(orderRepo *repositories.OrderRepository, kitchenService *KitchenService, transactor *repositories.Transactor, config *config.Config) *ScheduleService {
+	return &ScheduleService{
+		orderRepo:      orderRepo,
+		kitchenService: kitchenService,
+		transactor:     transactor,
+		config:         config,
+	}
+}
+
+// Start releases scheduled orders into the kitchen queue as they fall due.
+func (s *ScheduleService) Start() {
+	go func() {
+		ticker := time.NewTicker(scheduleReleasePollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.ReleaseDueOrders(); err != nil {
+				log.Printf("Error releasing scheduled orders: %v", err)
+			}
+		}
+	}()
+}
+
+// GetPickupSlots lists the pickup slots of a business date (YYYY-MM-DD in the
+// restaurant's time zone, today when empty) that can still be chosen.
+func (s *ScheduleService) GetPickupSlots(date string) ([]PickupSlot, error) {
+	loc := s.config.Location()
+	day := time.Now().In(loc)
+	if date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return nil, errors.New("invalid date. Use YYYY-MM-DD")
+		}
+		day = parsed
+	}
+
+	opening, closing, err := openingHours(s.config.OpeningHours)
+	if err != nil {
+		return nil, err
+	}
+
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	from, to := midnight.Add(opening), midnight.Add(closing)
+	booked, err := s.orderRepo.GetScheduledPickupTimes(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	length := pickupSlotLength(s.config)
+	earliest, latest := scheduleWindow(s.config)
+	slots := []PickupSlot{}
+	for start := from; start.Before(to); start = start.Add(length) {
+		end := start.Add(length)
+		if !end.After(earliest) || start.After(latest) {
+			continue
+		}
+
+		slot := PickupSlot{Start: start, End: end, Capacity: s.config.PickupSlotCapacity}
+		for _, pickup := range booked {
+			if !pickup.Before(start) && pickup.Before(end) {
+				slot.Booked++
+			}
+		}
+		slot.Available = slot.Capacity == 0 || slot.Booked < slot.Capacity
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// ReleaseDueOrders moves scheduled orders whose release time has come into
+// the kitchen queue.
+func (s *ScheduleService) ReleaseDueOrders() error {
+	due, err := s.orderRepo.GetDueScheduledOrders(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range due {
+		if err := s.release(&due[i]); err != nil && !IsVersionConflict(err) {
+			log.Printf("Error releasing scheduled order %d: %v", due[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *ScheduleService) release(order *repositories.Order) error {
+	reason := "scheduled order released"
+	if order.ScheduledFor != nil {
+		reason = fmt.Sprintf("scheduled for pickup at %s", order.ScheduledFor.In(s.config.Location()).Format("15:04"))
+	}
+
+	err := s.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Orders.UpdateStatus(order.ID, order.Version, repositories.OrderStatusConfirmed); err != nil {
+			return writeError(err, "failed to update order status")
+		}
+
+		if err := recordOrderEvent(uow, 0, repositories.OrderEvent{
+			OrderID:       order.ID,
+			Type:          repositories.OrderEventStatusChanged,
+			PreviousValue: string(order.Status),
+			NewValue:      string(repositories.OrderStatusConfirmed),
+			Reason:        reason,
+		}); err != nil {
+			return err
+		}
+
+		return paceCourses(uow, order.ID, repositories.OrderStatusConfirmed, 0, s.config)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Kitchen tickets are printed from this event
+	s.kitchenService.BroadcastStatusChange(order.ID)
+	return nil
+}
+
+// schedulePickup validates a requested pickup time against the opening hours
+// and the booking window, and returns when the order should enter the kitchen
+// queue. The capacity of the slot is checked by reservePickupSlot when the
+// order is stored.
+func schedulePickup(cfg *config.Config, orderType repositories.OrderType, pickupAt time.Time) (time.Time, error) {
+	if orderType != repositories.OrderTypeTakeaway {
+		return time.Time{}, errors.New("only takeaway orders can be scheduled")
+	}
+
+	earliest, latest := scheduleWindow(cfg)
+	if pickupAt.Before(earliest) {
+		return time.Time{}, fmt.Errorf("pickup time must be at least %d minutes from now", cfg.ScheduledLeadMinutes)
+	}
+	if pickupAt.After(latest) {
+		return time.Time{}, fmt.Errorf("orders can be scheduled at most %d days ahead", cfg.ScheduleMaxDays)
+	}
+
+	if _, err := pickupSlotStart(cfg, pickupAt); err != nil {
+		return time.Time{}, err
+	}
+
+	return pickupAt.Add(-time.Duration(cfg.ScheduledLeadMinutes) * time.Minute), nil
+}
+
+// reservePickupSlot checks that the slot of a pickup time has room for one
+// more order. It must run in the transaction that stores the order: the slot
+// stays locked until that transaction ends, so concurrent bookings cannot
+// overfill it.
+func reservePickupSlot(orderRepo *repositories.OrderRepository, cfg *config.Config, pickupAt time.Time) error {
+	if cfg.PickupSlotCapacity <= 0 {
+		return nil
+	}
+
+	start, err := pickupSlotStart(cfg, pickupAt)
+	if err != nil {
+		return err
+	}
+	if err := orderRepo.LockPickupSlot(start); err != nil {
+		return errors.New("failed to check pickup slot")
+	}
+
+	booked, err := orderRepo.GetScheduledPickupTimes(start, start.Add(pickupSlotLength(cfg)))
+	if err != nil {
+		return errors.New("failed to check pickup slot")
+	}
+	if len(booked) >= cfg.PickupSlotCapacity {
+		return ErrPickupSlotFull
+	}
+	return nil
+}
+
+// pickupSlotStart returns the start of the pickup slot a time falls in, or an
+// error when the kitchen is closed then.
+func pickupSlotStart(cfg *config.Config, pickupAt time.Time) (time.Time, error) {
+	opening, closing, err := openingHours(cfg.OpeningHours)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := pickupAt.In(cfg.Location())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	since := local.Sub(midnight)
+	if since < opening || since >= closing {
+		// After midnight the kitchen may still be open from the previous day
+		if closing <= 24*time.Hour || since+24*time.Hour >= closing {
+			return time.Time{}, fmt.Errorf("pickup time is outside opening hours (%s)", cfg.OpeningHours)
+		}
+		midnight = midnight.Add(-24 * time.Hour)
+		since += 24 * time.Hour
+	}
+
+	length := pickupSlotLength(cfg)
+	return midnight.Add(opening + (since-opening)/length*length), nil
+}
+
+// paidStatus is the status an order moves to once it is paid: a scheduled
+// order waits outside the kitchen queue until its release time.
+func paidStatus(order *repositories.Order) repositories.OrderStatus {
+	if order.ReleaseAt != nil && order.ReleaseAt.After(time.Now()) {
+		return repositories.OrderStatusScheduled
+	}
+	return repositories.OrderStatusConfirmed
+}
+
+// scheduleWindow is the earliest and latest pickup time that can be booked now.
+func scheduleWindow(cfg *config.Config) (time.Time, time.Time) {
+	now := time.Now()
+	return now.Add(time.Duration(cfg.ScheduledLeadMinutes) * time.Minute), now.AddDate(0, 0, cfg.ScheduleMaxDays)
+}
+
+func pickupSlotLength(cfg *config.Config) time.Duration {
+	if cfg.PickupSlotMinutes < 1 {
+		return 15 * time.Minute
+	}
+	return time.Duration(cfg.PickupSlotMinutes) * time.Minute
+}
+
+// openingHours parses a "10:00-22:00" window into offsets from midnight. A
+// closing time at or before the opening time means closing after midnight.
+func openingHours(hours string) (time.Duration, time.Duration, error) {
+	parts := strings.Split(hours, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid opening hours %q", hours)
+	}
+
+	var offsets [2]time.Duration
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid opening hours %q", hours)
+		}
+		offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	opening, closing := offsets[0], offsets[1]
+	if closing <= opening {
+		closing += 24 * time.Hour
+	}
+	return opening, closing, nil
+}
@@ -231,6 +231,7 @@ func (s *SeedService) SeedMenuCategories() (int, error) {
 			Description: "Refreshing drinks and beverages",
 			IsActive:    true,
 			SortOrder:   4,
+			Station:     "bar",
 		},
 		{
 			Name:        "Salads",
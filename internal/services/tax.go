@@ -0,0 +1,12 @@
+package services
+
+// vatRate is the PPN (Indonesian VAT) charged on orders.
+const vatRate = 0.10
+
+// orderTotals works out the VAT and grand total of an order. The delivery fee
+// is part of the sale, so it is taxed together with the items.
+func orderTotals(subtotal, deliveryFee float64) (vat, total float64) {
+	taxable := subtotal + deliveryFee
+	vat = taxable * vatRate
+	return vat, taxable + vat
+}
@@ -63,6 +63,7 @@ func (s *UserService) CreateUser(user *repositories.User) (*repositories.User, e
 		repositories.RoleAdmin, 
 		repositories.RoleCashier, 
 		repositories.RoleStaff, 
+		repositories.RoleDriver, 
 		repositories.RoleCustomer,
 	}
 	if user.Role != "" && !containsRole(validRoles, user.Role) {
@@ -168,6 +169,7 @@ func (s *UserService) UpdateUser(user *repositories.User) (*repositories.User, e
 			repositories.RoleAdmin, 
 			repositories.RoleCashier, 
 			repositories.RoleStaff, 
+			repositories.RoleDriver, 
 			repositories.RoleCustomer,
 		}
 		if !containsRole(validRoles, user.Role) {
@@ -259,6 +261,7 @@ func (s *UserService) UpdateUserByAdmin(userID uint, updates interface{}) (*repo
 			repositories.RoleAdmin, 
 			repositories.RoleCashier, 
 			repositories.RoleStaff, 
+			repositories.RoleDriver, 
 			repositories.RoleCustomer,
 		}
 		userRole := repositories.UserRole(role)
@@ -306,6 +309,7 @@ func (s *UserService) UpdateUserRole(userID uint, role string) (*repositories.Us
 		repositories.RoleAdmin, 
 		repositories.RoleCashier, 
 		repositories.RoleStaff, 
+		repositories.RoleDriver, 
 		repositories.RoleCustomer,
 	}
 	userRole := repositories.UserRole(role)
@@ -383,6 +387,7 @@ func (s *UserService) BulkUpdateUsers(userIDs []uint, updates map[string]interfa
 			repositories.RoleAdmin, 
 			repositories.RoleCashier, 
 			repositories.RoleStaff, 
+			repositories.RoleDriver, 
 			repositories.RoleCustomer,
 		}
 		userRole := repositories.UserRole(role)
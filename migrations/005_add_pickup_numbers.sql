@@ -0,0 +1,14 @@
+-- Migration: add_pickup_numbers
+-- Created: 2026-10-18 09:12:00
+
+-- Short human-readable pickup numbers for takeaway orders (e.g. A-042)
+ALTER TABLE orders ADD COLUMN pickup_number VARCHAR(10);
+ALTER TABLE orders ADD COLUMN collected_at TIMESTAMP;
+
+CREATE INDEX idx_orders_pickup_number ON orders(pickup_number);
+
+-- Daily counter behind the pickup numbers, keyed by restaurant-local date
+CREATE TABLE pickup_counters (
+    business_date VARCHAR(10) PRIMARY KEY,
+    last_number INTEGER NOT NULL DEFAULT 0
+);
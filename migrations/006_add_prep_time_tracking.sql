@@ -0,0 +1,20 @@
+-- Migration: add_prep_time_tracking
+-- Created: 2026-10-18 10:05:00
+
+-- Timestamps used to measure how long the kitchen took for an order
+ALTER TABLE orders ADD COLUMN confirmed_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN ready_at TIMESTAMP;
+
+-- Observed prep times per menu item, used to learn estimates
+CREATE TABLE prep_time_samples (
+    id SERIAL PRIMARY KEY,
+    menu_item_id INTEGER NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    actual_seconds INTEGER NOT NULL,
+    estimated_seconds INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_prep_time_samples_menu_item_id ON prep_time_samples(menu_item_id);
+CREATE INDEX idx_prep_time_samples_order_id ON prep_time_samples(order_id);
+CREATE INDEX idx_prep_time_samples_created_at ON prep_time_samples(created_at);
@@ -0,0 +1,13 @@
+-- Migration: add_print_stations
+-- Created: 2026-10-18 11:20:00
+
+-- Preparation station per category, optionally overridden per menu item,
+-- used to route kitchen tickets (e.g. drinks to the bar)
+ALTER TABLE menu_categories ADD COLUMN station VARCHAR(50) DEFAULT 'kitchen';
+ALTER TABLE menu_items ADD COLUMN station VARCHAR(50);
+
+UPDATE menu_categories SET station = 'bar' WHERE name = 'Beverages';
+
+-- Cash tendered and change given, printed on receipts
+ALTER TABLE payments ADD COLUMN amount_tendered DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE payments ADD COLUMN change_amount DECIMAL(10,2) DEFAULT 0;
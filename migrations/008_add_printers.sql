@@ -0,0 +1,46 @@
+-- Migration: add_printers
+-- Created: 2026-10-18 13:40:00
+
+-- Registered ticket printers and what they print
+CREATE TABLE printers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    connection VARCHAR(20) NOT NULL CHECK (connection IN ('tcp', 'spool')),
+    address TEXT NOT NULL,
+    paper_width INTEGER NOT NULL DEFAULT 80,
+    format VARCHAR(10) DEFAULT 'escpos',
+    stations TEXT,
+    terminal VARCHAR(100),
+    is_active BOOLEAN DEFAULT true,
+    status VARCHAR(20) DEFAULT 'unknown',
+    last_error TEXT,
+    last_seen_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_printers_terminal ON printers(terminal);
+CREATE INDEX idx_printers_deleted_at ON printers(deleted_at);
+
+-- Persistent print queue; jobs keep their rendered bytes for retries
+CREATE TABLE print_jobs (
+    id SERIAL PRIMARY KEY,
+    printer_id INTEGER NOT NULL REFERENCES printers(id),
+    order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+    kind VARCHAR(20) NOT NULL,
+    station VARCHAR(50),
+    data BYTEA NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'printed', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP,
+    printed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_print_jobs_printer_id ON print_jobs(printer_id);
+CREATE INDEX idx_print_jobs_order_id ON print_jobs(order_id);
+CREATE INDEX idx_print_jobs_status ON print_jobs(status);
+CREATE INDEX idx_print_jobs_next_attempt_at ON print_jobs(next_attempt_at);
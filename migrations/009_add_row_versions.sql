@@ -0,0 +1,8 @@
+-- Migration: add_row_versions
+-- Created: 2026-10-18 14:10:00
+
+-- Row versions for optimistic concurrency control. Every staff or payment
+-- write bumps the version and only succeeds if it still matches the one the
+-- client read; the API exposes it as the ETag of the record.
+ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE payments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
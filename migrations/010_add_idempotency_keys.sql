@@ -0,0 +1,23 @@
+-- Migration: add_idempotency_keys
+-- Created: 2026-10-18 14:40:00
+
+-- Responses stored under a client's Idempotency-Key so retried order and
+-- payment requests are replayed instead of executed twice
+CREATE TABLE idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+    response_code INTEGER,
+    response_body BYTEA,
+    content_type VARCHAR(100),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_idempotency_user_key ON idempotency_keys(user_id, key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
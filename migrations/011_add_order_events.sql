@@ -0,0 +1,19 @@
+-- Migration: add_order_events
+-- Created: 2026-10-18 15:10:00
+
+-- Audit trail of every status change, admin override, item edit, payment
+-- and refund on an order
+CREATE TABLE order_events (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id),
+    payment_id INTEGER REFERENCES payments(id),
+    type VARCHAR(30) NOT NULL CHECK (type IN ('order_created', 'status_changed', 'status_override', 'items_updated', 'payment_initiated', 'payment_received', 'payment_status_changed', 'payment_refunded', 'order_deleted')),
+    actor_id INTEGER REFERENCES users(id),
+    previous_value TEXT,
+    new_value TEXT,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_events_order_id ON order_events(order_id);
+CREATE INDEX idx_order_events_created_at ON order_events(created_at);
@@ -0,0 +1,32 @@
+-- Migration: add_order_cancellations
+-- Created: 2026-10-18 15:40:00
+
+-- Cancellations with a reason code and, once the kitchen has started on the
+-- order, the manager who approved or rejected them
+CREATE TABLE order_cancellations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id),
+    reason_code VARCHAR(30) NOT NULL CHECK (reason_code IN ('customer_request', 'customer_no_show', 'out_of_stock', 'kitchen_error', 'payment_issue', 'duplicate_order', 'other')),
+    note TEXT,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending_approval', 'rejected', 'completed')),
+    order_status TEXT,
+    requested_by_id INTEGER NOT NULL REFERENCES users(id),
+    decided_by_id INTEGER REFERENCES users(id),
+    decision_note TEXT,
+    decided_at TIMESTAMP,
+    refund_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_cancellations_order_id ON order_cancellations(order_id);
+CREATE INDEX idx_order_cancellations_status ON order_cancellations(status);
+
+-- New order history entries for the approval workflow
+ALTER TABLE order_events DROP CONSTRAINT order_events_type_check;
+ALTER TABLE order_events ADD CONSTRAINT order_events_type_check CHECK (type IN ('order_created', 'status_changed', 'status_override', 'items_updated', 'payment_initiated', 'payment_received', 'payment_status_changed', 'payment_refunded', 'order_deleted', 'cancellation_requested', 'cancellation_rejected'));
+
+-- Kitchen tickets of cancelled orders are withdrawn from the print queue and
+-- printers that already printed one get a VOID slip
+ALTER TABLE print_jobs DROP CONSTRAINT print_jobs_status_check;
+ALTER TABLE print_jobs ADD CONSTRAINT print_jobs_status_check CHECK (status IN ('queued', 'printed', 'failed', 'cancelled'));
@@ -0,0 +1,15 @@
+-- Migration: add_order_rounds
+-- Created: 2026-10-18 16:10:00
+
+-- Add-on rounds appended to a dine-in order after it went to the kitchen.
+-- Existing items belong to the original order, round 1.
+ALTER TABLE order_items ADD COLUMN round INTEGER NOT NULL DEFAULT 1;
+
+-- Kitchen tickets are printed once per station and round
+ALTER TABLE print_jobs ADD COLUMN round INTEGER NOT NULL DEFAULT 0;
+UPDATE print_jobs SET round = 1 WHERE kind IN ('kitchen_ticket', 'void_ticket');
+
+CREATE INDEX idx_print_jobs_order_round ON print_jobs(order_id, kind, station, round);
+
+ALTER TABLE order_events DROP CONSTRAINT order_events_type_check;
+ALTER TABLE order_events ADD CONSTRAINT order_events_type_check CHECK (type IN ('order_created', 'status_changed', 'status_override', 'items_updated', 'round_added', 'payment_initiated', 'payment_received', 'payment_status_changed', 'payment_refunded', 'order_deleted', 'cancellation_requested', 'cancellation_rejected'));
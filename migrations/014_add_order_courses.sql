@@ -0,0 +1,32 @@
+-- Migration: add_order_courses
+-- Created: 2026-10-18 16:40:00
+
+-- Courses of a dine-in order. Items of a course only go to the kitchen once
+-- the course is fired.
+CREATE TABLE order_courses (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    course VARCHAR(20) NOT NULL CHECK (course IN ('starter', 'main', 'dessert')),
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'held', 'fired', 'served')),
+    fire_at TIMESTAMP,
+    fired_at TIMESTAMP,
+    served_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_order_courses_order_course ON order_courses(order_id, course);
+CREATE INDEX idx_order_courses_status ON order_courses(status);
+CREATE INDEX idx_order_courses_fire_at ON order_courses(fire_at);
+
+-- Items without a course go to the kitchen with the order
+ALTER TABLE order_items ADD COLUMN course VARCHAR(20) NOT NULL DEFAULT '';
+
+-- Kitchen tickets are printed once per station, round and course
+ALTER TABLE print_jobs ADD COLUMN course VARCHAR(20) NOT NULL DEFAULT '';
+
+DROP INDEX idx_print_jobs_order_round;
+CREATE INDEX idx_print_jobs_order_round ON print_jobs(order_id, kind, station, round, course);
+
+ALTER TABLE order_events DROP CONSTRAINT order_events_type_check;
+ALTER TABLE order_events ADD CONSTRAINT order_events_type_check CHECK (type IN ('order_created', 'status_changed', 'status_override', 'items_updated', 'round_added', 'course_held', 'course_fired', 'course_served', 'payment_initiated', 'payment_received', 'payment_status_changed', 'payment_refunded', 'order_deleted', 'cancellation_requested', 'cancellation_rejected'));
@@ -0,0 +1,15 @@
+-- Migration: add_event_cursors
+-- Created: 2026-10-19 09:00:00
+
+-- How far each background consumer (prep times, printing) has worked through
+-- order_events, so it resumes after a restart instead of relying on the
+-- in-memory kitchen feed
+CREATE TABLE event_cursors (
+    consumer VARCHAR(50) PRIMARY KEY,
+    last_event_id INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Prep times are learned per order item rather than per order
+ALTER TABLE prep_time_samples ADD COLUMN order_item_id INTEGER REFERENCES order_items(id) ON DELETE SET NULL;
+CREATE INDEX idx_prep_time_samples_order_item_id ON prep_time_samples(order_item_id);
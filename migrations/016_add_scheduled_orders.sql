@@ -0,0 +1,10 @@
+-- Migration: add_scheduled_orders
+-- Created: 2026-10-18 17:10:00
+
+-- Pre-ordered takeaway: the requested pickup time and when the order leaves
+-- the 'scheduled' status for the kitchen queue
+ALTER TABLE orders ADD COLUMN scheduled_for TIMESTAMP;
+ALTER TABLE orders ADD COLUMN release_at TIMESTAMP;
+
+CREATE INDEX idx_orders_scheduled_for ON orders(scheduled_for);
+CREATE INDEX idx_orders_release_at ON orders(release_at);
@@ -0,0 +1,73 @@
+-- Migration: add_delivery
+-- Created: 2026-10-18 17:40:00
+
+ALTER TABLE orders DROP CONSTRAINT chk_order_type;
+ALTER TABLE orders ADD CONSTRAINT chk_order_type CHECK (order_type IN ('dine_in', 'takeaway', 'delivery'));
+
+-- Delivery fee, taxed together with the items
+ALTER TABLE orders ADD COLUMN delivery_fee DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- Addresses customers saved for reuse
+CREATE TABLE customer_addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label VARCHAR(50),
+    address TEXT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_customer_addresses_user_id ON customer_addresses(user_id);
+CREATE INDEX idx_customer_addresses_deleted_at ON customer_addresses(deleted_at);
+
+-- Areas delivered to: a radius around a center point or a polygon of
+-- [latitude, longitude] points stored as JSON. Overlapping zones are matched
+-- by ascending priority.
+CREATE TABLE delivery_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('radius', 'polygon')),
+    center_lat DOUBLE PRECISION,
+    center_lng DOUBLE PRECISION,
+    radius_km DOUBLE PRECISION,
+    polygon TEXT,
+    fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+    minimum_order DECIMAL(10,2) NOT NULL DEFAULT 0,
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_delivery_zones_deleted_at ON delivery_zones(deleted_at);
+
+-- Where a delivery order goes and how far the driver has got with it
+CREATE TABLE order_deliveries (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    zone_id INTEGER NOT NULL REFERENCES delivery_zones(id),
+    address TEXT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'assigned', 'picked_up', 'delivered')),
+    driver_id INTEGER REFERENCES users(id),
+    assigned_at TIMESTAMP,
+    picked_up_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_order_deliveries_order_id ON order_deliveries(order_id);
+CREATE INDEX idx_order_deliveries_zone_id ON order_deliveries(zone_id);
+CREATE INDEX idx_order_deliveries_status ON order_deliveries(status);
+CREATE INDEX idx_order_deliveries_driver_id ON order_deliveries(driver_id);
+
+ALTER TABLE order_events DROP CONSTRAINT order_events_type_check;
+ALTER TABLE order_events ADD CONSTRAINT order_events_type_check CHECK (type IN ('order_created', 'status_changed', 'status_override', 'items_updated', 'round_added', 'course_held', 'course_fired', 'course_served', 'driver_assigned', 'delivery_picked_up', 'delivered', 'payment_initiated', 'payment_received', 'payment_status_changed', 'payment_refunded', 'order_deleted', 'cancellation_requested', 'cancellation_rejected'));
@@ -0,0 +1,24 @@
+-- Migration: add_platform_orders
+-- Created: 2026-10-18 18:10:00
+
+-- Where an order was placed: app, pos or a delivery platform such as grabfood.
+-- Platform orders keep the platform's order ID so webhook retries are ignored.
+ALTER TABLE orders ADD COLUMN source VARCHAR(30) NOT NULL DEFAULT 'app';
+ALTER TABLE orders ADD COLUMN external_id VARCHAR(100);
+
+UPDATE orders SET source = 'pos' WHERE cashier_name IS NOT NULL AND cashier_name <> '';
+
+CREATE UNIQUE INDEX idx_orders_source_external_id ON orders(source, external_id);
+
+-- Platform SKUs and the menu items they stand for
+CREATE TABLE external_item_mappings (
+    id SERIAL PRIMARY KEY,
+    platform VARCHAR(30) NOT NULL,
+    sku VARCHAR(100) NOT NULL,
+    menu_item_id INTEGER NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_external_item_mappings_platform_sku ON external_item_mappings(platform, sku);
+CREATE INDEX idx_external_item_mappings_menu_item_id ON external_item_mappings(menu_item_id);
@@ -0,0 +1,23 @@
+-- Migration: add_menu_schedules
+-- Created: 2026-10-18 18:40:00
+
+-- Dayparts in which a menu category or item can be ordered. Categories and
+-- items without schedules are served all day. Times and dates are in the
+-- restaurant's time zone; an end_time before start_time runs past midnight.
+CREATE TABLE menu_schedules (
+    id SERIAL PRIMARY KEY,
+    category_id INTEGER REFERENCES menu_categories(id) ON DELETE CASCADE,
+    menu_item_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
+    name VARCHAR(50),
+    days TEXT,
+    start_time VARCHAR(5),
+    end_time VARCHAR(5),
+    start_date VARCHAR(10),
+    end_date VARCHAR(10),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT menu_schedules_owner_check CHECK ((category_id IS NULL) <> (menu_item_id IS NULL))
+);
+
+CREATE INDEX idx_menu_schedules_category_id ON menu_schedules(category_id);
+CREATE INDEX idx_menu_schedules_menu_item_id ON menu_schedules(menu_item_id);
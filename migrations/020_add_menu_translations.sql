@@ -0,0 +1,19 @@
+-- Migration: add_menu_translations
+-- Created: 2026-10-18 19:10:00
+
+-- Names and descriptions of menu categories and items in languages other
+-- than MENU_LOCALE, which the categories and items themselves are written in.
+CREATE TABLE menu_translations (
+    id SERIAL PRIMARY KEY,
+    category_id INTEGER REFERENCES menu_categories(id) ON DELETE CASCADE,
+    menu_item_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
+    locale VARCHAR(20) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT menu_translations_owner_check CHECK ((category_id IS NULL) <> (menu_item_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_menu_translations_category_locale ON menu_translations(category_id, locale);
+CREATE UNIQUE INDEX idx_menu_translations_item_locale ON menu_translations(menu_item_id, locale);
@@ -0,0 +1,8 @@
+-- Migration: add_menu_item_tags
+-- Created: 2026-10-18 19:40:00
+
+-- Allergens a dish contains and diets it suits, as JSON arrays of tags such as
+-- ["peanut","egg"] and ["halal"], plus optional nutrition facts per serving.
+ALTER TABLE menu_items ADD COLUMN allergens TEXT;
+ALTER TABLE menu_items ADD COLUMN dietary_tags TEXT;
+ALTER TABLE menu_items ADD COLUMN nutrition TEXT;
@@ -0,0 +1,8 @@
+-- Migration: add_menu_search
+-- Created: 2026-10-18 20:10:00
+
+-- Trigram word similarity lets menu search match misspelt dish names, e.g.
+-- "nasi gorng". The search builds its documents from names, categories and
+-- translations on the fly, which no index can serve; menus are small enough
+-- for that to stay quick.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
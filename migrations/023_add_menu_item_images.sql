@@ -0,0 +1,7 @@
+-- Migration: add_menu_item_images
+-- Created: 2026-10-18 20:40:00
+
+-- Uploaded menu item images: the URLs of the thumbnail, medium and original
+-- sizes, and where each file is stored, as JSON objects keyed by size.
+ALTER TABLE menu_items ADD COLUMN image_variants TEXT;
+ALTER TABLE menu_items ADD COLUMN image_keys TEXT;
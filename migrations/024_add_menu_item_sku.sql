@@ -0,0 +1,7 @@
+-- Migration: add_menu_item_sku
+-- Created: 2026-10-18 21:10:00
+
+-- Optional stock keeping unit of a menu item, used to match items when a menu
+-- file is imported.
+ALTER TABLE menu_items ADD COLUMN sku VARCHAR(100);
+CREATE UNIQUE INDEX idx_menu_items_sku ON menu_items(sku);
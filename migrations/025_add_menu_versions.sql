@@ -0,0 +1,47 @@
+-- Migration: add_menu_versions
+-- Created: 2026-10-18 22:30:00
+
+-- Price history of menu items. Rows with an empty applied_at are scheduled
+-- changes, copied onto the item once effective_at has passed.
+CREATE TABLE menu_item_prices (
+    id SERIAL PRIMARY KEY,
+    menu_item_id INTEGER NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    price DECIMAL(10,2) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    effective_at TIMESTAMP NOT NULL,
+    applied_at TIMESTAMP,
+    menu_version_id INTEGER,
+    created_by_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_menu_item_prices_menu_item_id ON menu_item_prices(menu_item_id);
+CREATE INDEX idx_menu_item_prices_effective_at ON menu_item_prices(effective_at);
+CREATE INDEX idx_menu_item_prices_applied_at ON menu_item_prices(applied_at);
+
+-- Start the history of existing items with their current price
+INSERT INTO menu_item_prices (menu_item_id, price, source, effective_at, applied_at)
+SELECT id, price, 'initial', created_at, created_at FROM menu_items WHERE deleted_at IS NULL;
+
+-- Draft and published copies of the whole menu, in the JSON export format
+CREATE TABLE menu_versions (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    note TEXT,
+    content TEXT NOT NULL,
+    rolled_back_from_id INTEGER REFERENCES menu_versions(id),
+    created_by_id INTEGER REFERENCES users(id),
+    published_by_id INTEGER REFERENCES users(id),
+    published_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_menu_versions_status ON menu_versions(status);
+CREATE INDEX idx_menu_versions_published_at ON menu_versions(published_at);
+
+-- At most one open draft
+CREATE UNIQUE INDEX idx_menu_versions_one_draft ON menu_versions(status) WHERE status = 'draft';
+
+ALTER TABLE menu_item_prices ADD CONSTRAINT fk_menu_item_prices_menu_version
+    FOREIGN KEY (menu_version_id) REFERENCES menu_versions(id);
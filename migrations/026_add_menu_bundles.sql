@@ -0,0 +1,45 @@
+-- Migration: add_menu_bundles
+-- Created: 2026-10-18 23:30:00
+
+-- Component slots of bundle items such as a Paket Hemat. A slot with a single
+-- option is fixed; otherwise the guest chooses one of its options.
+CREATE TABLE menu_bundle_slots (
+    id SERIAL PRIMARY KEY,
+    bundle_item_id INTEGER NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    sort_order INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_menu_bundle_slots_bundle_item_id ON menu_bundle_slots(bundle_item_id);
+
+CREATE TABLE menu_bundle_options (
+    id SERIAL PRIMARY KEY,
+    slot_id INTEGER NOT NULL REFERENCES menu_bundle_slots(id) ON DELETE CASCADE,
+    menu_item_id INTEGER NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    upcharge DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (upcharge >= 0),
+    is_default BOOLEAN DEFAULT FALSE,
+    sort_order INTEGER DEFAULT 0
+);
+
+CREATE INDEX idx_menu_bundle_options_slot_id ON menu_bundle_options(slot_id);
+CREATE INDEX idx_menu_bundle_options_menu_item_id ON menu_bundle_options(menu_item_id);
+
+-- The components an ordered bundle was made up of, with the share of the
+-- bundle's unit price allocated to each for sales reporting.
+CREATE TABLE order_item_components (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    slot_id INTEGER NOT NULL,
+    slot_name VARCHAR(255),
+    menu_item_id INTEGER NOT NULL REFERENCES menu_items(id),
+    quantity INTEGER NOT NULL DEFAULT 1,
+    upcharge DECIMAL(10,2) NOT NULL DEFAULT 0,
+    allocated_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_item_components_order_item_id ON order_item_components(order_item_id);
+CREATE INDEX idx_order_item_components_menu_item_id ON order_item_components(menu_item_id);
@@ -0,0 +1,9 @@
+-- Migration: add_pickup_slot_locks
+-- Created: 2026-10-19 10:00:00
+
+-- One row per pickup slot, locked by each order booking into the slot so
+-- concurrent bookings cannot overfill it
+CREATE TABLE pickup_slot_locks (
+    slot_start TIMESTAMP PRIMARY KEY,
+    locked_at TIMESTAMP NOT NULL
+);
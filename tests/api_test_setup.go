@@ -8,9 +8,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"recursiveDine/internal/cache"
 	"recursiveDine/internal/config"
 	"recursiveDine/internal/controllers"
+	"recursiveDine/internal/media"
 	"recursiveDine/internal/middleware"
 	"recursiveDine/internal/repositories"
 	"recursiveDine/internal/services"
@@ -66,30 +69,38 @@ func (suite *APITestSuite) SetupSuite() {
 	tableRepo := repositories.NewTableRepository(suite.db)
 	menuRepo := repositories.NewMenuRepository(suite.db)
 	orderRepo := repositories.NewOrderRepository(suite.db)
+	orderEventRepo := repositories.NewOrderEventRepository(suite.db)
 	paymentRepo := repositories.NewPaymentRepository(suite.db)
+	transactor := repositories.NewTransactor(suite.db)
+	menuCache := services.NewMenuCache(cache.NewMemoryStore(), time.Minute)
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, cfg)
 	userService := services.NewUserService(userRepo)
 	tableService := services.NewTableService(tableRepo)
-	menuService := services.NewMenuService(menuRepo)
-	orderService := services.NewOrderService(orderRepo, menuRepo)
-	paymentService := services.NewPaymentService(paymentRepo, orderRepo, cfg)
+	menuService := services.NewMenuService(menuRepo, menuCache, transactor, cfg)
+	cfg.MediaDir = suite.T().TempDir()
+	menuImageService := services.NewMenuImageService(menuRepo, menuCache, media.NewLocalStorage(cfg.MediaDir, cfg.MediaBaseURL), cfg)
 	kitchenService := services.NewKitchenService(orderRepo)
+	prepTimeService := services.NewPrepTimeService(repositories.NewPrepTimeRepository(suite.db), orderRepo, orderEventRepo, kitchenService, cfg)
+	orderService := services.NewOrderService(orderRepo, menuRepo, orderEventRepo, repositories.NewDeliveryRepository(suite.db), kitchenService, prepTimeService, transactor, cfg)
+	paymentService := services.NewPaymentService(paymentRepo, orderRepo, kitchenService, transactor, cfg)
 
 	// Initialize controllers
 	authController := controllers.NewAuthController(authService)
 	tableController := controllers.NewTableController(tableService)
 	menuController := controllers.NewMenuController(menuService)
+	menuImageController := controllers.NewMenuImageController(menuImageService)
+	menuImportController := controllers.NewMenuImportController(services.NewMenuImportService(menuRepo, repositories.NewExternalItemMappingRepository(suite.db), menuCache, transactor))
 	orderController := controllers.NewOrderController(orderService, authService)
 	paymentController := controllers.NewPaymentController(paymentService)
-	kitchenController := controllers.NewKitchenController(kitchenService)
+	kitchenController := controllers.NewKitchenController(kitchenService, prepTimeService)
 	userController := controllers.NewUserController(userService)
 	orderManagementController := controllers.NewOrderManagementController(orderService)
 	paymentManagementController := controllers.NewPaymentManagementController(paymentService)
 
 	// Setup router with all controllers
-	suite.router = setupTestRouter(cfg, authController, tableController, menuController, orderController, paymentController, kitchenController, userController, orderManagementController, paymentManagementController)
+	suite.router = setupTestRouter(cfg, authController, tableController, menuController, menuImageController, menuImportController, orderController, paymentController, kitchenController, userController, orderManagementController, paymentManagementController)
 
 	// Create test users and get tokens
 	suite.createTestUsers(authService)
@@ -127,9 +138,30 @@ func setupTestDatabase(cfg *config.Config) (*gorm.DB, error) {
 		&repositories.Table{},
 		&repositories.MenuCategory{},
 		&repositories.MenuItem{},
+		&repositories.MenuSchedule{},
+		&repositories.MenuTranslation{},
+		&repositories.MenuItemPrice{},
+		&repositories.MenuVersion{},
+		&repositories.MenuBundleSlot{},
+		&repositories.MenuBundleOption{},
 		&repositories.Order{},
 		&repositories.OrderItem{},
+		&repositories.OrderItemComponent{},
 		&repositories.Payment{},
+		&repositories.PickupCounter{},
+		&repositories.PickupSlotLock{},
+		&repositories.PrepTimeSample{},
+		&repositories.Printer{},
+		&repositories.PrintJob{},
+		&repositories.IdempotencyKey{},
+		&repositories.OrderEvent{},
+		&repositories.EventCursor{},
+		&repositories.OrderCancellation{},
+		&repositories.OrderCourse{},
+		&repositories.CustomerAddress{},
+		&repositories.DeliveryZone{},
+		&repositories.OrderDelivery{},
+		&repositories.ExternalItemMapping{},
 	)
 	if err != nil {
 		return nil, err
@@ -206,9 +238,30 @@ func (suite *APITestSuite) createTestUsers(authService *services.AuthService) {
 
 func (suite *APITestSuite) dropTestTables() {
 	suite.db.Migrator().DropTable(
+		&repositories.ExternalItemMapping{},
+		&repositories.OrderDelivery{},
+		&repositories.DeliveryZone{},
+		&repositories.CustomerAddress{},
+		&repositories.OrderCourse{},
+		&repositories.OrderCancellation{},
+		&repositories.EventCursor{},
+		&repositories.OrderEvent{},
+		&repositories.IdempotencyKey{},
+		&repositories.PrintJob{},
+		&repositories.Printer{},
+		&repositories.PrepTimeSample{},
+		&repositories.PickupCounter{},
+		&repositories.PickupSlotLock{},
 		&repositories.Payment{},
+		&repositories.OrderItemComponent{},
 		&repositories.OrderItem{},
 		&repositories.Order{},
+		&repositories.MenuBundleOption{},
+		&repositories.MenuBundleSlot{},
+		&repositories.MenuVersion{},
+		&repositories.MenuItemPrice{},
+		&repositories.MenuTranslation{},
+		&repositories.MenuSchedule{},
 		&repositories.MenuItem{},
 		&repositories.MenuCategory{},
 		&repositories.Table{},
@@ -252,7 +305,7 @@ func TestAPITestSuite(t *testing.T) {
 }
 
 // setupTestRouter creates a test router with all endpoints
-func setupTestRouter(cfg *config.Config, authController *controllers.AuthController, tableController *controllers.TableController, menuController *controllers.MenuController, orderController *controllers.OrderController, paymentController *controllers.PaymentController, kitchenController *controllers.KitchenController, userController *controllers.UserController, orderManagementController *controllers.OrderManagementController, paymentManagementController *controllers.PaymentManagementController) *gin.Engine {
+func setupTestRouter(cfg *config.Config, authController *controllers.AuthController, tableController *controllers.TableController, menuController *controllers.MenuController, menuImageController *controllers.MenuImageController, menuImportController *controllers.MenuImportController, orderController *controllers.OrderController, paymentController *controllers.PaymentController, kitchenController *controllers.KitchenController, userController *controllers.UserController, orderManagementController *controllers.OrderManagementController, paymentManagementController *controllers.PaymentManagementController) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 
 	router := gin.New()
@@ -260,6 +313,8 @@ func setupTestRouter(cfg *config.Config, authController *controllers.AuthControl
 	// Use minimal middleware for testing
 	router.Use(gin.Recovery())
 
+	router.GET("/media/*key", menuImageController.ServeMedia)
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
@@ -284,6 +339,7 @@ func setupTestRouter(cfg *config.Config, authController *controllers.AuthControl
 			menu.GET("", menuController.GetMenu)
 			menu.GET("/categories", menuController.GetCategories)
 			menu.GET("/items/search", menuController.SearchItems)
+			menu.GET("/tags", menuController.GetMenuTags)
 		}
 
 		// Order routes
@@ -337,12 +393,26 @@ func setupTestRouter(cfg *config.Config, authController *controllers.AuthControl
 				menuAdmin.POST("/categories", menuController.CreateCategory)
 				menuAdmin.PUT("/categories/:id", menuController.UpdateCategory)
 				menuAdmin.DELETE("/categories/:id", menuController.DeleteCategory)
+				menuAdmin.PUT("/categories/:id/schedules", menuController.SetCategorySchedules)
+				menuAdmin.GET("/categories/:id/translations", menuController.GetCategoryTranslations)
+				menuAdmin.PUT("/categories/:id/translations/:locale", menuController.SetCategoryTranslation)
+				menuAdmin.DELETE("/categories/:id/translations/:locale", menuController.DeleteCategoryTranslation)
 
 				// Menu item management
 				menuAdmin.POST("/items", menuController.CreateMenuItem)
 				menuAdmin.PUT("/items/:id", menuController.UpdateMenuItem)
 				menuAdmin.DELETE("/items/:id", menuController.DeleteMenuItem)
 				menuAdmin.PATCH("/items/:id/availability", menuController.UpdateMenuItemAvailability)
+				menuAdmin.PUT("/items/:id/schedules", menuController.SetMenuItemSchedules)
+				menuAdmin.PUT("/items/:id/image", menuImageController.UploadMenuItemImage)
+				menuAdmin.DELETE("/items/:id/image", menuImageController.DeleteMenuItemImage)
+
+				// Bulk export and import
+				menuAdmin.GET("/export", menuImportController.ExportMenu)
+				menuAdmin.POST("/import", menuImportController.ImportMenu)
+				menuAdmin.GET("/items/:id/translations", menuController.GetMenuItemTranslations)
+				menuAdmin.PUT("/items/:id/translations/:locale", menuController.SetMenuItemTranslation)
+				menuAdmin.DELETE("/items/:id/translations/:locale", menuController.DeleteMenuItemTranslation)
 			}
 
 			// Order management
@@ -350,8 +420,10 @@ func setupTestRouter(cfg *config.Config, authController *controllers.AuthControl
 			{
 				orders.GET("", orderManagementController.GetAllOrders)
 				orders.GET("/:id", orderManagementController.GetOrderByID)
+				orders.GET("/:id/history", orderManagementController.GetOrderHistory)
 				orders.PATCH("/:id/status", orderManagementController.UpdateOrderStatus)
 				orders.PUT("/:id/items", orderManagementController.UpdateOrderItems)
+				orders.POST("/:id/rounds", orderManagementController.AddOrderRound)
 				orders.DELETE("/:id", orderManagementController.DeleteOrder)
 				orders.GET("/statistics", orderManagementController.GetOrderStatistics)
 				orders.GET("/revenue", orderManagementController.GetDailyRevenue)
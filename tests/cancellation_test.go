@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"testing"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// CancellationTestSuite covers the cancellation workflow: refunds, kitchen
+// voids and the rules on who may cancel at each stage.
+type CancellationTestSuite struct {
+	serviceSuite
+	cancellationService *services.CancellationService
+}
+
+func (suite *CancellationTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.cancellationService = services.NewCancellationService(repositories.NewOrderCancellationRepository(suite.db), suite.orderRepo, suite.paymentService, suite.kitchenService, suite.transactor)
+}
+
+func (suite *CancellationTestSuite) TestCancelPaidOrderRefundsPayment() {
+	created := suite.paidOrder()
+
+	cancellation, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationCustomerRequest,
+		Note:       "changed their mind",
+	}, 0, suite.user.ID, "cashier")
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CancellationCompleted, cancellation.Status)
+	suite.Equal(repositories.OrderStatusConfirmed, cancellation.OrderStatus)
+	suite.Equal(60500.0, cancellation.RefundAmount)
+	suite.Nil(cancellation.DecidedByID, "no approval is needed before the kitchen starts")
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusCancelled, order.Status)
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusRefunded, payment.Status)
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	last := events[len(events)-1]
+	suite.Equal(repositories.OrderEventPaymentRefunded, last.Type)
+	suite.Equal("Customer request: changed their mind", last.Reason)
+}
+
+func (suite *CancellationTestSuite) TestStatusUpdatesCannotCancel() {
+	pending, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	paid := suite.paidOrder()
+
+	for _, order := range []*services.OrderResponse{pending, paid} {
+		err := suite.orderService.UpdateOrderStatus(order.ID, repositories.OrderStatusCancelled, 0, suite.user.ID)
+		suite.ErrorIs(err, services.ErrCancelWithReason)
+
+		_, err = suite.orderService.UpdateOrderStatusAdmin(order.ID, "cancelled", 0, "guest left", suite.user.ID)
+		suite.ErrorIs(err, services.ErrCancelWithReason, "not even as an override")
+		suite.NotEqual(repositories.OrderStatusCancelled, suite.reloadOrder(order.ID).Status)
+	}
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(paid.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusCompleted, payment.Status)
+	suite.Zero(suite.count(&repositories.OrderCancellation{}))
+}
+
+func (suite *CancellationTestSuite) TestCancelAfterPreparingNeedsApproval() {
+	created := suite.paidOrder()
+	suite.moveTo(created.ID, repositories.OrderStatusPreparing)
+
+	cancellation, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationOutOfStock,
+	}, 0, suite.user.ID, "cashier")
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CancellationPendingApproval, cancellation.Status)
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusPreparing, order.Status, "the order carries on until a manager decides")
+
+	_, err = suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationOutOfStock,
+	}, 0, suite.user.ID, "cashier")
+	suite.ErrorIs(err, services.ErrCancellationPending)
+
+	approved, err := suite.cancellationService.ApproveCancellation(cancellation.ID, "no rendang left", suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CancellationCompleted, approved.Status)
+	suite.Equal("no rendang left", approved.DecisionNote)
+	suite.Require().NotNil(approved.DecidedAt)
+	suite.Equal(60500.0, approved.RefundAmount)
+
+	_, err = suite.cancellationService.ApproveCancellation(cancellation.ID, "", suite.user.ID)
+	suite.ErrorIs(err, services.ErrCancellationDecided)
+
+	order, err = suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusCancelled, order.Status)
+}
+
+func (suite *CancellationTestSuite) TestAdminCancelsPreparingOrderDirectly() {
+	created := suite.paidOrder()
+	suite.moveTo(created.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+
+	cancellation, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationKitchenError,
+	}, 0, suite.user.ID, "admin")
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CancellationCompleted, cancellation.Status)
+	suite.Require().NotNil(cancellation.DecidedByID)
+	suite.Equal(suite.user.ID, *cancellation.DecidedByID)
+}
+
+func (suite *CancellationTestSuite) TestRejectedCancellationLeavesOrder() {
+	created := suite.paidOrder()
+	suite.moveTo(created.ID, repositories.OrderStatusPreparing)
+
+	cancellation, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationCustomerRequest,
+	}, 0, suite.user.ID, "staff")
+	suite.Require().NoError(err)
+
+	rejected, err := suite.cancellationService.RejectCancellation(cancellation.ID, "already plated", suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CancellationRejected, rejected.Status)
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusPreparing, order.Status)
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusCompleted, payment.Status)
+
+	types := suite.eventTypes(created.ID)
+	suite.Equal(repositories.OrderEventCancellationRejected, types[len(types)-1])
+}
+
+func (suite *CancellationTestSuite) TestCancellationValidation() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	_, err = suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{ReasonCode: "bored"}, 0, suite.user.ID, "cashier")
+	suite.ErrorIs(err, services.ErrInvalidCancellationReason)
+
+	_, err = suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{ReasonCode: repositories.CancellationOther, Note: " "}, 0, suite.user.ID, "cashier")
+	suite.ErrorIs(err, services.ErrInvalidCancellationReason)
+
+	_, err = suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{ReasonCode: repositories.CancellationDuplicateOrder}, 7, suite.user.ID, "cashier")
+	suite.True(services.IsVersionConflict(err))
+
+	_, err = suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{ReasonCode: repositories.CancellationDuplicateOrder}, 0, suite.user.ID, "cashier")
+	suite.Require().NoError(err)
+
+	_, err = suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{ReasonCode: repositories.CancellationDuplicateOrder}, 0, suite.user.ID, "cashier")
+	suite.ErrorIs(err, services.ErrOrderNotCancellable)
+}
+
+func (suite *CancellationTestSuite) TestCancelWithdrawsPendingQRISPayment() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	qris, err := suite.paymentService.InitiateQRISPayment(&services.QRISPaymentRequest{OrderID: created.ID}, suite.user.ID)
+	suite.Require().NoError(err)
+
+	cancellation, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationPaymentIssue,
+	}, 0, suite.user.ID, "cashier")
+	suite.Require().NoError(err)
+	suite.Zero(cancellation.RefundAmount)
+
+	// A late provider callback must not confirm the cancelled order
+	err = suite.paymentService.VerifyPayment(&services.PaymentVerificationRequest{
+		TransactionID: qris.TransactionID,
+		ExternalID:    "late",
+		Amount:        qris.Amount,
+		Status:        "success",
+	})
+	suite.EqualError(err, "payment is already cancelled")
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusCancelled, order.Status)
+}
+
+func (suite *CancellationTestSuite) TestLateCallbackAfterRefundIsRejected() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	qris, err := suite.paymentService.InitiateQRISPayment(&services.QRISPaymentRequest{OrderID: created.ID}, suite.user.ID)
+	suite.Require().NoError(err)
+	callback := &services.PaymentVerificationRequest{
+		TransactionID: qris.TransactionID,
+		ExternalID:    "QRIS-1",
+		Amount:        qris.Amount,
+		Status:        "success",
+	}
+	suite.Require().NoError(suite.paymentService.VerifyPayment(callback))
+
+	cancellation, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationCustomerRequest,
+	}, 0, suite.user.ID, "cashier")
+	suite.Require().NoError(err)
+	suite.Equal(qris.Amount, cancellation.RefundAmount)
+	historyLength := len(suite.eventTypes(created.ID))
+
+	// The provider sends the success callback again after the refund
+	suite.EqualError(suite.paymentService.VerifyPayment(callback), "payment is already refunded")
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusRefunded, payment.Status)
+	suite.Equal(repositories.OrderStatusCancelled, suite.reloadOrder(created.ID).Status)
+	suite.Len(suite.eventTypes(created.ID), historyLength, "nothing is recorded for the rejected callback")
+}
+
+func (suite *CancellationTestSuite) TestCancellationRollsBackWhenRecordFails() {
+	created := suite.paidOrder()
+
+	suite.failWrites("order_cancellations", 0)
+	_, err := suite.cancellationService.CancelOrder(created.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationCustomerRequest,
+	}, 0, suite.user.ID, "cashier")
+	suite.Error(err)
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status)
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusCompleted, payment.Status, "the refund must roll back with the cancellation")
+}
+
+func TestCancellationTestSuite(t *testing.T) {
+	suite.Run(t, new(CancellationTestSuite))
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// CourseTestSuite covers course pacing for dine-in orders. The suite never
+// starts the course worker: the first course fires and the next one is
+// scheduled by the status changes themselves.
+type CourseTestSuite struct {
+	serviceSuite
+	courseService *services.CourseService
+}
+
+func (suite *CourseTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.courseService = services.NewCourseService(repositories.NewOrderCourseRepository(suite.db), suite.orderRepo, suite.kitchenService, suite.transactor, suite.cfg)
+}
+
+// coursedRequest is a dine-in order with a starter, a main and a drink that
+// goes to the kitchen with the order.
+func (suite *CourseTestSuite) coursedRequest() *services.CashierOrderRequest {
+	table := repositories.Table{Number: 9, QRCode: "table-9", Capacity: 4}
+	suite.Require().NoError(suite.db.Create(&table).Error)
+
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDineIn
+	req.TableID = &table.ID
+	req.Items = []services.CreateOrderItemRequest{
+		{MenuItemID: suite.teh.ID, Quantity: 2},
+		{MenuItemID: suite.teh.ID, Quantity: 1, Course: repositories.CourseStarter},
+		{MenuItemID: suite.nasi.ID, Quantity: 2, Course: repositories.CourseMain},
+	}
+	return req
+}
+
+// coursedDineInOrder is a paid coursedRequest.
+func (suite *CourseTestSuite) coursedDineInOrder() *repositories.Order {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.coursedRequest())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+	return suite.reloadOrder(created.ID)
+}
+
+func (suite *CourseTestSuite) courseStatus(order *repositories.Order, course repositories.CourseType) repositories.CourseStatus {
+	for _, c := range order.Courses {
+		if c.Course == course {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func (suite *CourseTestSuite) sentQuantity(order *repositories.Order) int {
+	var quantity int
+	for i := range order.OrderItems {
+		if printing.ItemSent(order, &order.OrderItems[i]) {
+			quantity += order.OrderItems[i].Quantity
+		}
+	}
+	return quantity
+}
+
+func (suite *CourseTestSuite) TestCoursesWaitUntilFired() {
+	order := suite.coursedDineInOrder()
+	suite.Require().Len(order.Courses, 2)
+	suite.Equal(repositories.CourseFired, suite.courseStatus(order, repositories.CourseStarter), "the first course fires with the payment")
+	suite.Equal(repositories.CourseWaiting, suite.courseStatus(order, repositories.CourseMain))
+	suite.Equal(3, suite.sentQuantity(order))
+
+	events, err := suite.orderService.GetOrderHistory(order.ID)
+	suite.Require().NoError(err)
+	last := events[len(events)-1]
+	suite.Equal(repositories.OrderEventCourseFired, last.Type)
+	suite.Equal(string(repositories.CourseStarter), last.NewValue)
+	suite.Equal("order reached the kitchen", last.Reason)
+
+	// Confirming the order again does not fire the main
+	_, err = suite.orderService.UpdateOrderStatusAdmin(order.ID, "pending", 0, "paid by mistake", suite.user.ID)
+	suite.Require().NoError(err)
+	_, err = suite.orderService.UpdateOrderStatusAdmin(order.ID, "confirmed", 0, "", suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CourseWaiting, suite.courseStatus(suite.reloadOrder(order.ID), repositories.CourseMain))
+}
+
+func (suite *CourseTestSuite) TestHeldFirstCourseIsNotFired() {
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.coursedRequest())
+	suite.Require().NoError(err)
+	_, err = suite.courseService.HoldCourse(order.ID, repositories.CourseStarter, suite.user.ID)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(order.ID, 100000, 0, suite.user.ID))
+	reloaded := suite.reloadOrder(order.ID)
+	suite.Equal(repositories.CourseHeld, suite.courseStatus(reloaded, repositories.CourseStarter))
+	suite.Equal(repositories.CourseWaiting, suite.courseStatus(reloaded, repositories.CourseMain))
+	suite.Equal(2, suite.sentQuantity(reloaded), "only items without a course reach the kitchen with the order")
+}
+
+func (suite *CourseTestSuite) TestServingOrderServesFiredCourses() {
+	order := suite.coursedDineInOrder()
+	suite.moveTo(order.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady, repositories.OrderStatusServed)
+
+	order = suite.reloadOrder(order.ID)
+	suite.Equal(repositories.CourseServed, suite.courseStatus(order, repositories.CourseStarter))
+
+	var main repositories.OrderCourse
+	suite.Require().NoError(suite.db.Where("order_id = ? AND course = ?", order.ID, repositories.CourseMain).First(&main).Error)
+	suite.Equal(repositories.CourseWaiting, main.Status)
+	suite.Require().NotNil(main.FireAt, "the main is scheduled without any worker running")
+	suite.WithinDuration(time.Now().Add(10*time.Minute), *main.FireAt, time.Minute)
+}
+
+func (suite *CourseTestSuite) TestServingCourseSchedulesNextCourse() {
+	order := suite.coursedDineInOrder()
+
+	_, err := suite.courseService.ServeCourse(order.ID, repositories.CourseStarter, suite.user.ID)
+	suite.Require().NoError(err)
+	order = suite.reloadOrder(order.ID)
+	suite.Equal(repositories.CourseServed, suite.courseStatus(order, repositories.CourseStarter))
+
+	// The main is due ten minutes after the starter was served
+	suite.Require().NoError(suite.courseService.FireDueCourses())
+	suite.Equal(repositories.CourseWaiting, suite.courseStatus(suite.reloadOrder(order.ID), repositories.CourseMain))
+
+	suite.Require().NoError(suite.db.Model(&repositories.OrderCourse{}).
+		Where("order_id = ? AND course = ?", order.ID, repositories.CourseMain).
+		Update("fire_at", time.Now().Add(-time.Minute)).Error)
+	suite.Require().NoError(suite.courseService.FireDueCourses())
+	order = suite.reloadOrder(order.ID)
+	suite.Equal(repositories.CourseFired, suite.courseStatus(order, repositories.CourseMain))
+	suite.Equal(5, suite.sentQuantity(order))
+}
+
+func (suite *CourseTestSuite) TestHeldCourseWaitsForWaiter() {
+	order := suite.coursedDineInOrder()
+
+	_, err := suite.courseService.HoldCourse(order.ID, repositories.CourseMain, suite.user.ID)
+	suite.Require().NoError(err)
+	_, err = suite.courseService.ServeCourse(order.ID, repositories.CourseStarter, suite.user.ID)
+	suite.Require().NoError(err)
+
+	var main repositories.OrderCourse
+	suite.Require().NoError(suite.db.Where("order_id = ? AND course = ?", order.ID, repositories.CourseMain).First(&main).Error)
+	suite.Equal(repositories.CourseHeld, main.Status)
+	suite.Nil(main.FireAt, "held courses are not scheduled")
+
+	fired, err := suite.courseService.FireCourse(order.ID, repositories.CourseMain, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.CourseFired, suite.courseStatus(fired, repositories.CourseMain))
+
+	_, err = suite.courseService.HoldCourse(order.ID, repositories.CourseMain, suite.user.ID)
+	suite.EqualError(err, "cannot hold a course that is fired")
+}
+
+func (suite *CourseTestSuite) TestFiringCourseReopensServedOrder() {
+	order := suite.coursedDineInOrder()
+	suite.moveTo(order.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady, repositories.OrderStatusServed)
+
+	fired, err := suite.courseService.FireCourse(order.ID, repositories.CourseMain, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusPreparing, fired.Status, "the kitchen has work again")
+
+	events, err := suite.orderService.GetOrderHistory(order.ID)
+	suite.Require().NoError(err)
+	last := events[len(events)-1]
+	suite.Equal(repositories.OrderEventStatusChanged, last.Type)
+	suite.Equal("main fired", last.Reason)
+}
+
+func (suite *CourseTestSuite) TestCoursesRejectedOutsideDineIn() {
+	req := suite.cashierOrder()
+	req.Items[0].Course = repositories.CourseMain
+	_, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.EqualError(err, "courses are only available for dine-in orders")
+
+	order := suite.coursedDineInOrder()
+	_, err = suite.courseService.FireCourse(order.ID, repositories.CourseDessert, suite.user.ID)
+	suite.EqualError(err, "course not found")
+	_, err = suite.courseService.FireCourse(order.ID, "soup", suite.user.ID)
+	suite.ErrorIs(err, services.ErrInvalidCourse)
+}
+
+func TestCourseTestSuite(t *testing.T) {
+	suite.Run(t, new(CourseTestSuite))
+}
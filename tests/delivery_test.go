@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"testing"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// DeliveryTestSuite covers delivery orders. The central zone is a 3 km
+// circle around Monas; the cashier order is worth 55,000 before VAT.
+type DeliveryTestSuite struct {
+	serviceSuite
+	deliveryService *services.DeliveryService
+}
+
+func (suite *DeliveryTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.deliveryService = services.NewDeliveryService(repositories.NewDeliveryRepository(suite.db), suite.orderRepo, repositories.NewUserRepository(suite.db), suite.kitchenService, suite.transactor)
+}
+
+func (suite *DeliveryTestSuite) centralZone() *repositories.DeliveryZone {
+	zone, err := suite.deliveryService.CreateZone(&services.DeliveryZoneRequest{
+		Name:         "Central",
+		Type:         repositories.DeliveryZoneRadius,
+		CenterLat:    -6.1754,
+		CenterLng:    106.8272,
+		RadiusKm:     3,
+		Fee:          10000,
+		MinimumOrder: 30000,
+	})
+	suite.Require().NoError(err)
+	return zone
+}
+
+func (suite *DeliveryTestSuite) deliveryOrder(latitude, longitude float64) (*services.OrderResponse, error) {
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDelivery
+	req.Delivery = &services.DeliveryRequest{Address: "Jl. Medan Merdeka Barat 1", Latitude: latitude, Longitude: longitude}
+	return suite.orderService.CreateCashierOrder(suite.user.ID, req)
+}
+
+func (suite *DeliveryTestSuite) TestDeliveryFeeIsTaxedWithTheOrder() {
+	zone := suite.centralZone()
+
+	created, err := suite.deliveryOrder(-6.1800, 106.8300)
+	suite.Require().NoError(err)
+	suite.Equal(10000.0, created.DeliveryFee)
+	suite.Equal(6500.0, created.VATAmount, "VAT covers the items and the fee")
+	suite.Equal(71500.0, created.TotalAmount)
+	suite.Equal("A-001", created.PickupNumber)
+
+	order := suite.reloadOrder(created.ID)
+	suite.Require().NotNil(order.Delivery)
+	suite.Equal(zone.ID, order.Delivery.ZoneID)
+	suite.Equal(repositories.DeliveryPending, order.Delivery.Status)
+}
+
+func (suite *DeliveryTestSuite) TestDeliveryOutsideZoneOrBelowMinimumIsRejected() {
+	suite.centralZone()
+
+	_, err := suite.deliveryOrder(-6.3000, 106.9000)
+	suite.ErrorIs(err, services.ErrOutsideDeliveryArea)
+
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDelivery
+	req.Items = []services.CreateOrderItemRequest{{MenuItemID: suite.teh.ID, Quantity: 1}}
+	req.Delivery = &services.DeliveryRequest{Address: "Jl. Medan Merdeka Barat 1", Latitude: -6.1800, Longitude: 106.8300}
+	_, err = suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.EqualError(err, "minimum order for delivery to Central is 30000.00")
+
+	req.Delivery = nil
+	_, err = suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.EqualError(err, "delivery address is required for delivery orders")
+	suite.Zero(suite.count(&repositories.Order{}))
+}
+
+func (suite *DeliveryTestSuite) TestPolygonZoneMatchesByPriority() {
+	suite.centralZone()
+	_, err := suite.deliveryService.CreateZone(&services.DeliveryZoneRequest{
+		Name: "Kemayoran",
+		Type: repositories.DeliveryZonePolygon,
+		Polygon: []repositories.GeoPoint{
+			{-6.1500, 106.8300}, {-6.1500, 106.8700}, {-6.1800, 106.8700}, {-6.1800, 106.8300},
+		},
+		Fee:      5000,
+		Priority: -1,
+	})
+	suite.Require().NoError(err)
+
+	quote, err := suite.deliveryService.QuoteDelivery(-6.1650, 106.8500)
+	suite.Require().NoError(err)
+	suite.Equal("Kemayoran", quote.ZoneName, "the polygon has the higher priority where the zones overlap")
+	suite.Equal(5000.0, quote.Fee)
+
+	quote, err = suite.deliveryService.QuoteDelivery(-6.1900, 106.8200)
+	suite.Require().NoError(err)
+	suite.Equal("Central", quote.ZoneName)
+
+	_, err = suite.deliveryService.QuoteDelivery(-6.1650, 106.9000)
+	suite.ErrorIs(err, services.ErrOutsideDeliveryArea)
+}
+
+func (suite *DeliveryTestSuite) TestDriverDeliversOrder() {
+	suite.centralZone()
+	driver := repositories.User{Name: "Driver", Username: "driver", Email: "driver@test.com", Password: "x", Role: repositories.RoleDriver, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&driver).Error)
+
+	created, err := suite.deliveryOrder(-6.1800, 106.8300)
+	suite.Require().NoError(err)
+
+	_, err = suite.deliveryService.AssignDriver(created.ID, driver.ID, suite.user.ID)
+	suite.EqualError(err, "order has not been sent to the kitchen yet")
+
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+	_, err = suite.deliveryService.AssignDriver(created.ID, suite.user.ID, suite.user.ID)
+	suite.EqualError(err, "driver not found", "only users with the driver role take deliveries")
+
+	delivery, err := suite.deliveryService.AssignDriver(created.ID, driver.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.DeliveryAssigned, delivery.Status)
+
+	_, err = suite.deliveryService.MarkPickedUp(created.ID, driver.ID)
+	suite.EqualError(err, "order is not ready for pickup yet")
+
+	suite.Require().NoError(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusPreparing, 0, suite.user.ID))
+	suite.Require().NoError(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusReady, 0, suite.user.ID))
+
+	_, err = suite.deliveryService.MarkPickedUp(created.ID, suite.user.ID)
+	suite.EqualError(err, "delivery not found", "only the assigned driver can pick it up")
+
+	mine, err := suite.deliveryService.GetDriverDeliveries(driver.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(mine, 1)
+	suite.Equal("A-001", mine[0].Order.PickupNumber)
+
+	_, err = suite.deliveryService.MarkPickedUp(created.ID, driver.ID)
+	suite.Require().NoError(err)
+	delivery, err = suite.deliveryService.MarkDelivered(created.ID, driver.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.DeliveryDelivered, delivery.Status)
+	suite.NotNil(delivery.DeliveredAt)
+
+	suite.Equal(repositories.OrderStatusServed, suite.reloadOrder(created.ID).Status)
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	var types []repositories.OrderEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	suite.Contains(types, repositories.OrderEventDriverAssigned)
+	suite.Contains(types, repositories.OrderEventDeliveryPickedUp)
+	suite.Contains(types, repositories.OrderEventDelivered)
+	suite.Equal(repositories.OrderEventStatusChanged, types[len(types)-1])
+
+	mine, err = suite.deliveryService.GetDriverDeliveries(driver.ID)
+	suite.Require().NoError(err)
+	suite.Empty(mine)
+}
+
+func TestDeliveryTestSuite(t *testing.T) {
+	suite.Run(t, new(DeliveryTestSuite))
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"recursiveDine/internal/controllers"
+	"recursiveDine/internal/middleware"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// IdempotencyTestSuite covers Idempotency-Key handling on cashier order
+// creation and order cancellation.
+type IdempotencyTestSuite struct {
+	serviceSuite
+	router *gin.Engine
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.Require().NoError(suite.db.AutoMigrate(&repositories.IdempotencyKey{}))
+	suite.cfg.IdempotencyKeyTTLHours = 24
+
+	idempotencyService := services.NewIdempotencyService(repositories.NewIdempotencyRepository(suite.db), suite.cfg)
+	cancellationService := services.NewCancellationService(repositories.NewOrderCancellationRepository(suite.db), suite.orderRepo, suite.paymentService, suite.kitchenService, suite.transactor)
+	orderController := controllers.NewOrderController(suite.orderService, nil)
+	cancellationController := controllers.NewCancellationController(cancellationService, suite.orderService)
+
+	gin.SetMode(gin.TestMode)
+	suite.router = gin.New()
+	suite.router.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Set("user_role", string(repositories.RoleCashier))
+	})
+	idempotent := middleware.Idempotency(idempotencyService)
+	suite.router.POST("/cashier/orders", idempotent, orderController.CreateCashierOrder)
+	suite.router.POST("/staff/orders/:id/cancel", idempotent, cancellationController.CancelOrder)
+}
+
+func (suite *IdempotencyTestSuite) post(path, key string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	suite.Require().NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *IdempotencyTestSuite) TestRetryReplaysTheFirstResponse() {
+	first := suite.post("/cashier/orders", "order-1", suite.cashierOrder())
+	suite.Require().Equal(http.StatusCreated, first.Code, first.Body.String())
+	suite.Empty(first.Header().Get("Idempotent-Replayed"))
+
+	retry := suite.post("/cashier/orders", "order-1", suite.cashierOrder())
+	suite.Equal(http.StatusCreated, retry.Code)
+	suite.Equal("true", retry.Header().Get("Idempotent-Replayed"))
+	suite.Equal(first.Body.String(), retry.Body.String())
+	suite.Equal("application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	suite.Equal(int64(1), suite.count(&repositories.Order{}), "the order is only created once")
+
+	other := suite.post("/cashier/orders", "order-2", suite.cashierOrder())
+	suite.Equal(http.StatusCreated, other.Code)
+	suite.Empty(other.Header().Get("Idempotent-Replayed"), "a new key is a new request")
+	suite.Equal(int64(2), suite.count(&repositories.Order{}))
+}
+
+func (suite *IdempotencyTestSuite) TestKeyReusedForDifferentRequestIsRejected() {
+	suite.Require().Equal(http.StatusCreated, suite.post("/cashier/orders", "order-1", suite.cashierOrder()).Code)
+
+	changed := suite.cashierOrder()
+	changed.Items[0].Quantity = 5
+	w := suite.post("/cashier/orders", "order-1", changed)
+	suite.Equal(http.StatusUnprocessableEntity, w.Code)
+	suite.Contains(w.Body.String(), "idempotency key was already used for a different request")
+
+	order := suite.reloadOrder(1)
+	w = suite.post(fmt.Sprintf("/staff/orders/%d/cancel", order.ID), "order-1", suite.cashierOrder())
+	suite.Equal(http.StatusUnprocessableEntity, w.Code, "nor for another endpoint")
+	suite.Equal(int64(1), suite.count(&repositories.Order{}))
+	suite.Equal(repositories.OrderStatusPending, suite.reloadOrder(order.ID).Status)
+}
+
+func (suite *IdempotencyTestSuite) TestVersionConflictIsNotReplayed() {
+	order := suite.paidOrder()
+	path := fmt.Sprintf("/staff/orders/%d/cancel", order.ID)
+	body := services.CancelOrderRequest{ReasonCode: repositories.CancellationCustomerRequest}
+
+	stale := suite.reloadOrder(order.ID).Version - 1
+	w := suite.post(path, "cancel-1", body, "If-Match", fmt.Sprintf(`"%d"`, stale))
+	suite.Require().Equal(http.StatusConflict, w.Code, w.Body.String())
+	etag := w.Header().Get("ETag")
+	suite.NotEmpty(etag)
+
+	// The client reloads the order and retries with the same key
+	w = suite.post(path, "cancel-1", body, "If-Match", etag)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.Empty(w.Header().Get("Idempotent-Replayed"))
+	suite.Equal(repositories.OrderStatusCancelled, suite.reloadOrder(order.ID).Status)
+
+	// From now on the successful cancellation is what gets replayed
+	w = suite.post(path, "cancel-1", body, "If-Match", etag)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal("true", w.Header().Get("Idempotent-Replayed"))
+}
+
+func (suite *IdempotencyTestSuite) TestRejectedRequestCanBeRetried() {
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Update("is_available", false).Error)
+	w := suite.post("/cashier/orders", "order-1", suite.cashierOrder())
+	suite.Require().GreaterOrEqual(w.Code, http.StatusBadRequest)
+
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Update("is_available", true).Error)
+	w = suite.post("/cashier/orders", "order-1", suite.cashierOrder())
+	suite.Equal(http.StatusCreated, w.Code, w.Body.String())
+	suite.Empty(w.Header().Get("Idempotent-Replayed"))
+
+	invalid := suite.post("/cashier/orders", "order-2", map[string]string{"order_type": "takeaway"})
+	suite.Equal(http.StatusBadRequest, invalid.Code)
+	w = suite.post("/cashier/orders", "order-2", suite.cashierOrder())
+	suite.Equal(http.StatusCreated, w.Code, "a corrected request may reuse the key of a rejected one")
+	suite.Equal(int64(2), suite.count(&repositories.Order{}))
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
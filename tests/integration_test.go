@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"recursiveDine/internal/aggregators"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// IntegrationTestSuite covers delivery platform webhooks. The config signs
+// GrabFood webhooks with "grab-secret" and GoFood webhooks with "gojek-secret".
+type IntegrationTestSuite struct {
+	serviceSuite
+	integrationService *services.IntegrationService
+}
+
+func (suite *IntegrationTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.integrationService = services.NewIntegrationService(repositories.NewExternalItemMappingRepository(suite.db), suite.orderRepo, suite.menuRepo, repositories.NewUserRepository(suite.db), suite.orderService, suite.paymentService, suite.cfg)
+}
+
+func (suite *IntegrationTestSuite) mapSKU(platform, sku string, menuItemID uint) {
+	_, err := suite.integrationService.SetMapping(&services.SKUMappingRequest{Platform: platform, SKU: sku, MenuItemID: menuItemID})
+	suite.Require().NoError(err)
+}
+
+func (suite *IntegrationTestSuite) grabFoodOrder(orderID string, skus ...string) []byte {
+	payload := aggregators.GrabFoodOrder{
+		OrderID:          orderID,
+		ShortOrderNumber: "GF-042",
+		Eater:            aggregators.GrabFoodEater{Name: "Siti", MobileNumber: "+6281398765432", Comment: "Tidak pedas"},
+	}
+	for _, sku := range skus {
+		payload.Items = append(payload.Items, aggregators.GrabFoodItem{ID: sku, Name: sku, Quantity: 2})
+	}
+	body, err := json.Marshal(payload)
+	suite.Require().NoError(err)
+	return body
+}
+
+func (suite *IntegrationTestSuite) TestPlatformOrderIsCreatedAndConfirmed() {
+	suite.mapSKU("grabfood", "NG-01", suite.nasi.ID)
+	suite.mapSKU("grabfood", "ET-01", suite.teh.ID)
+
+	body := suite.grabFoodOrder("GF-1001", "NG-01", "ET-01")
+	order, created, err := suite.integrationService.ReceiveOrder("grabfood", body, "sha256="+aggregators.Sign("grab-secret", body))
+	suite.Require().NoError(err)
+	suite.True(created)
+
+	suite.Equal(repositories.OrderSource("grabfood"), order.Source)
+	suite.Require().NotNil(order.ExternalID)
+	suite.Equal("GF-1001", *order.ExternalID)
+	suite.Equal(repositories.OrderTypeTakeaway, order.OrderType)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status, "the platform collected the payment")
+	suite.Equal(60000.0, order.SubtotalAmount, "priced from our menu")
+	suite.Equal("GrabFood #GF-042. Tidak pedas", order.SpecialNotes)
+	suite.Equal("platform-grabfood", order.User.Username)
+	suite.Require().NotNil(order.Payment)
+	suite.Equal(repositories.PaymentMethodPlatform, order.Payment.Method)
+	suite.Equal("GF-1001", order.Payment.ExternalID)
+
+	// The platform retries the webhook
+	again, created, err := suite.integrationService.ReceiveOrder("grabfood", body, aggregators.Sign("grab-secret", body))
+	suite.Require().NoError(err)
+	suite.False(created)
+	suite.Equal(order.ID, again.ID)
+	suite.Equal(int64(1), suite.count(&repositories.Order{}))
+}
+
+func (suite *IntegrationTestSuite) TestPlatformOrderSignatureIsVerified() {
+	suite.mapSKU("grabfood", "NG-01", suite.nasi.ID)
+	body := suite.grabFoodOrder("GF-1002", "NG-01")
+
+	_, _, err := suite.integrationService.ReceiveOrder("grabfood", body, aggregators.Sign("gojek-secret", body))
+	suite.ErrorIs(err, services.ErrInvalidSignature)
+	_, _, err = suite.integrationService.ReceiveOrder("grabfood", body, "")
+	suite.ErrorIs(err, services.ErrInvalidSignature)
+	_, _, err = suite.integrationService.ReceiveOrder("shopeefood", body, aggregators.Sign("grab-secret", body))
+	suite.ErrorIs(err, services.ErrUnknownPlatform)
+
+	suite.Zero(suite.count(&repositories.Order{}))
+}
+
+func (suite *IntegrationTestSuite) TestPlatformOrderWithUnmappedSKUIsRejected() {
+	suite.mapSKU("grabfood", "NG-01", suite.nasi.ID)
+	suite.mapSKU("gofood", "ET-01", suite.teh.ID)
+
+	body := suite.grabFoodOrder("GF-1003", "NG-01", "ET-01", "XX-99")
+	_, _, err := suite.integrationService.ReceiveOrder("grabfood", body, aggregators.Sign("grab-secret", body))
+	suite.ErrorIs(err, services.ErrUnmappedSKU)
+	suite.EqualError(err, "unmapped SKU: ET-01, XX-99", "mappings are per platform")
+	suite.Zero(suite.count(&repositories.Order{}))
+}
+
+func (suite *IntegrationTestSuite) TestGoFoodPayloadIsNormalized() {
+	suite.mapSKU("gofood", "ES-TEH", suite.teh.ID)
+
+	event := aggregators.GoFoodEvent{
+		Header: aggregators.GoFoodHeader{EventName: "gofood.order.merchant_accepted"},
+		Body: aggregators.GoFoodBody{
+			Order: aggregators.GoFoodOrder{
+				OrderNumber: "F-778899",
+				PIN:         "4321",
+				OrderItems:  []aggregators.GoFoodItem{{ExternalID: "ES-TEH", Quantity: 3, Notes: "less ice"}},
+			},
+			Customer: aggregators.GoFoodCustomer{Name: "Andi", Phone: "+6285712345678"},
+		},
+	}
+	body, err := json.Marshal(event)
+	suite.Require().NoError(err)
+
+	order, created, err := suite.integrationService.ReceiveOrder("gofood", body, aggregators.Sign("gojek-secret", body))
+	suite.Require().NoError(err)
+	suite.True(created)
+	suite.Equal("F-778899", *order.ExternalID)
+	suite.Equal("GoFood #4321", order.SpecialNotes)
+	suite.Equal("Andi", order.CustomerName)
+	suite.Require().Len(order.OrderItems, 1)
+	suite.Equal(3, order.OrderItems[0].Quantity)
+	suite.Equal("less ice", order.OrderItems[0].SpecialRequest)
+}
+
+func TestIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, new(IntegrationTestSuite))
+}
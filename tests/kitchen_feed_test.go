@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"recursiveDine/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// KitchenFeedTestSuite covers the kitchen event stream: resuming with
+// Last-Event-ID, snapshots for new clients and event ordering.
+type KitchenFeedTestSuite struct {
+	serviceSuite
+	router *gin.Engine
+}
+
+func (suite *KitchenFeedTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+
+	gin.SetMode(gin.TestMode)
+	ctrl := controllers.NewKitchenController(suite.kitchenService, suite.prepTimeService)
+	suite.router = gin.New()
+	suite.router.GET("/kitchen/events", ctrl.HandleEventStream)
+}
+
+var sseEvent = regexp.MustCompile(`id: (\d+)\nevent: (\S+)\n`)
+
+type sseFrame struct {
+	ID   uint64
+	Type string
+}
+
+// stream opens the SSE endpoint, runs publish once the client is subscribed,
+// then disconnects and returns the events written.
+func (suite *KitchenFeedTestSuite) stream(lastEventID string, publish func()) []sseFrame {
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/kitchen/events", nil).WithContext(ctx)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	w := httptest.NewRecorder()
+
+	clients := suite.kitchenService.GetClientCount()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		suite.router.ServeHTTP(w, req)
+	}()
+	suite.Eventually(func() bool { return suite.kitchenService.GetClientCount() > clients }, time.Second, time.Millisecond)
+
+	publish()
+	// Give the handler a moment to write the live events before disconnecting
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	var frames []sseFrame
+	for _, match := range sseEvent.FindAllStringSubmatch(w.Body.String(), -1) {
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		suite.Require().NoError(err)
+		frames = append(frames, sseFrame{ID: id, Type: match[2]})
+	}
+	return frames
+}
+
+func (suite *KitchenFeedTestSuite) TestNewClientGetsSnapshotThenLiveEvents() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	frames := suite.stream("", func() {
+		suite.kitchenService.BroadcastOrderUpdate(created.ID, "status_update")
+	})
+
+	suite.Require().Len(frames, 2)
+	suite.Equal("initial_orders", frames[0].Type)
+	suite.Equal("status_update", frames[1].Type)
+	suite.Equal(frames[0].ID+1, frames[1].ID, "the snapshot carries the ID the live events continue from")
+}
+
+func (suite *KitchenFeedTestSuite) TestReconnectResumesAfterLastEventID() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	first := suite.stream("", func() {
+		suite.kitchenService.BroadcastOrderUpdate(created.ID, "new_order")
+	})
+	suite.Require().Len(first, 2)
+	seen := first[1].ID
+
+	// Events published while the client was away
+	suite.kitchenService.BroadcastOrderUpdate(created.ID, "status_update")
+	suite.kitchenService.BroadcastOrderUpdate(created.ID, "order_ready")
+
+	resumed := suite.stream(strconv.FormatUint(seen, 10), func() {
+		suite.kitchenService.BroadcastOrderUpdate(created.ID, "order_cancelled")
+	})
+	suite.Equal([]sseFrame{
+		{ID: seen + 1, Type: "status_update"},
+		{ID: seen + 2, Type: "order_ready"},
+		{ID: seen + 3, Type: "order_cancelled"},
+	}, resumed, "only the missed events are replayed, without a snapshot")
+}
+
+func (suite *KitchenFeedTestSuite) TestUnknownLastEventIDFallsBackToSnapshot() {
+	frames := suite.stream("42", func() {})
+	suite.Require().Len(frames, 1)
+	suite.Equal("initial_orders", frames[0].Type)
+}
+
+func (suite *KitchenFeedTestSuite) TestConcurrentPublishersKeepEventsInOrder() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	events, _, err := suite.kitchenService.Subscribe(0)
+	suite.Require().NoError(err)
+	defer suite.kitchenService.Unsubscribe(events)
+
+	// Stay within the subscriber buffer so nothing is dropped as slow
+	const publishers = 4
+	const perPublisher = 6
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perPublisher; j++ {
+				suite.kitchenService.BroadcastOrderUpdate(created.ID, "status_update")
+			}
+		}()
+	}
+	wg.Wait()
+
+	var last uint64
+	for i := 0; i < publishers*perPublisher; i++ {
+		event := <-events
+		if last != 0 {
+			suite.Equal(last+1, event.ID, "events arrive in ID order without gaps")
+		}
+		last = event.ID
+	}
+}
+
+func TestKitchenFeedTestSuite(t *testing.T) {
+	suite.Run(t, new(KitchenFeedTestSuite))
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuBundleTestSuite covers bundle menu items and their slots.
+type MenuBundleTestSuite struct {
+	serviceSuite
+}
+
+// bundleMenu sets up a Paket Hemat of Nasi Goreng and a choice of drink from
+// the bar, Es Teh by default or Es Jeruk for an upcharge.
+func (suite *MenuBundleTestSuite) bundleMenu() (paket, jeruk repositories.MenuItem) {
+	drinks := repositories.MenuCategory{Name: "Drinks", IsActive: true, Station: "bar"}
+	suite.Require().NoError(suite.db.Create(&drinks).Error)
+	suite.Require().NoError(suite.db.Model(&suite.teh).Update("category_id", drinks.ID).Error)
+	jeruk = repositories.MenuItem{CategoryID: drinks.ID, Name: "Es Jeruk", Price: 8000, IsAvailable: true}
+	suite.Require().NoError(suite.db.Create(&jeruk).Error)
+	paket = repositories.MenuItem{CategoryID: suite.nasi.CategoryID, Name: "Paket Hemat", Price: 28000, IsAvailable: true}
+	suite.Require().NoError(suite.db.Create(&paket).Error)
+
+	bundle, err := suite.menuService.SetMenuItemBundle(paket.ID, []services.MenuBundleSlotRequest{
+		{Name: "Main", Options: []services.MenuBundleOptionRequest{{MenuItemID: suite.nasi.ID}}},
+		{Name: "Drink", Options: []services.MenuBundleOptionRequest{
+			{MenuItemID: suite.teh.ID, IsDefault: true},
+			{MenuItemID: jeruk.ID, Upcharge: 2000},
+		}},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(bundle.BundleSlots, 2)
+	suite.True(bundle.BundleSlots[0].Options[0].IsDefault, "fixed slots serve their only option")
+	return *bundle, jeruk
+}
+
+func (suite *MenuBundleTestSuite) TestBundleSetupRules() {
+	paket, jeruk := suite.bundleMenu()
+
+	_, err := suite.menuService.SetMenuItemBundle(suite.nasi.ID, []services.MenuBundleSlotRequest{
+		{Name: "Side", Options: []services.MenuBundleOptionRequest{{MenuItemID: jeruk.ID}}},
+	})
+	suite.Error(err, "components cannot become bundles")
+
+	_, err = suite.menuService.SetMenuItemBundle(jeruk.ID, []services.MenuBundleSlotRequest{
+		{Name: "Bundle", Options: []services.MenuBundleOptionRequest{{MenuItemID: paket.ID}}},
+	})
+	suite.Error(err, "bundles cannot be nested")
+
+	_, err = suite.menuService.SetMenuItemBundle(paket.ID, []services.MenuBundleSlotRequest{
+		{Name: "Drink", Options: []services.MenuBundleOptionRequest{
+			{MenuItemID: suite.teh.ID, IsDefault: true},
+			{MenuItemID: jeruk.ID, IsDefault: true},
+		}},
+	})
+	suite.Error(err)
+	item, err := suite.menuService.GetMenuItemByID(paket.ID)
+	suite.Require().NoError(err)
+	suite.Len(item.BundleSlots, 2, "invalid slots leave the bundle as it was")
+
+	item, err = suite.menuService.SetMenuItemBundle(paket.ID, nil)
+	suite.Require().NoError(err)
+	suite.Empty(item.BundleSlots)
+	suite.Zero(suite.count(&repositories.MenuBundleOption{}))
+}
+
+func (suite *MenuBundleTestSuite) TestBundleOrderPricing() {
+	paket, jeruk := suite.bundleMenu()
+
+	req := suite.cashierOrder()
+	req.Items = []services.CreateOrderItemRequest{
+		{MenuItemID: paket.ID, Quantity: 2, Components: []services.BundleChoiceRequest{{SlotID: paket.BundleSlots[1].ID, MenuItemID: jeruk.ID}}},
+		{MenuItemID: paket.ID, Quantity: 1},
+	}
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+	suite.Equal(2*30000.0+28000.0, order.SubtotalAmount, "upcharges are added to the bundle price")
+
+	stored := suite.reloadOrder(order.ID)
+	suite.Require().Len(stored.OrderItems, 2)
+	for _, item := range stored.OrderItems {
+		suite.Require().Len(item.Components, 2)
+		var allocated float64
+		for _, component := range item.Components {
+			allocated += component.AllocatedPrice
+		}
+		suite.InDelta(item.UnitPrice, allocated, 0.001, "the allocation adds up to the bundle price")
+	}
+
+	upgraded := stored.OrderItems[0]
+	suite.Equal(suite.nasi.ID, upgraded.Components[0].MenuItemID)
+	suite.Equal(jeruk.ID, upgraded.Components[1].MenuItemID)
+	suite.Equal(21212.12, upgraded.Components[0].AllocatedPrice, "shares follow the list prices")
+	suite.Equal(6787.88+2000, upgraded.Components[1].AllocatedPrice, "the upcharge goes to its component")
+	suite.Equal(suite.teh.ID, stored.OrderItems[1].Components[1].MenuItemID, "open slots get their default")
+
+	req.Items = []services.CreateOrderItemRequest{
+		{MenuItemID: paket.ID, Quantity: 1, Components: []services.BundleChoiceRequest{{SlotID: paket.BundleSlots[1].ID, MenuItemID: suite.nasi.ID}}},
+	}
+	_, err = suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Error(err, "only the slot's options can be chosen")
+
+	suite.Require().NoError(suite.db.Model(&repositories.MenuItem{}).Where("id = ?", suite.teh.ID).Update("is_available", false).Error)
+	req.Items = []services.CreateOrderItemRequest{{MenuItemID: paket.ID, Quantity: 1}}
+	_, err = suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Error(err, "unavailable components cannot be served")
+}
+
+func (suite *MenuBundleTestSuite) TestBundleKitchenTicketsExplodeComponents() {
+	paket, _ := suite.bundleMenu()
+
+	req := suite.cashierOrder()
+	req.Items = []services.CreateOrderItemRequest{{MenuItemID: paket.ID, Quantity: 2}}
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	order := suite.reloadOrder(created.ID)
+	tickets := printing.Tickets(order)
+	suite.Require().Len(tickets, 2, "each component goes to its own station")
+	suite.Equal("bar", tickets[0].Station)
+	suite.Equal("kitchen", tickets[1].Station)
+
+	doc, err := printing.BuildKitchenTicket(order, tickets[0], printing.StoreInfo{Location: time.UTC})
+	suite.Require().NoError(err)
+	text := string(printing.RenderText(doc, printing.Paper80mm))
+	suite.Contains(text, "2 x Es Teh")
+	suite.Contains(text, "[Paket Hemat]")
+	suite.NotContains(text, "Nasi Goreng")
+
+	receipt := string(printing.RenderText(printing.BuildReceipt(order, printing.StoreInfo{Location: time.UTC}), printing.Paper80mm))
+	suite.Contains(receipt, "2x Paket Hemat")
+	suite.Contains(receipt, "- Nasi Goreng")
+}
+
+func TestMenuBundleTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuBundleTestSuite))
+}
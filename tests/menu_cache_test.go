@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"recursiveDine/internal/controllers"
+	"recursiveDine/internal/media"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuCacheTestSuite covers the menu cache, which these tests keep in
+// memory. Writes made straight to the database bypass the services, so they
+// show what the cache serves.
+type MenuCacheTestSuite struct {
+	serviceSuite
+	menuImageService *services.MenuImageService
+}
+
+func (suite *MenuCacheTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.menuImageService = services.NewMenuImageService(suite.menuRepo, suite.menuCache, media.NewLocalStorage(suite.T().TempDir(), suite.cfg.MediaBaseURL), suite.cfg)
+}
+
+func (suite *MenuCacheTestSuite) renameNasiBehindTheCache() {
+	suite.Require().NoError(suite.db.Model(&repositories.MenuItem{}).Where("id = ?", suite.nasi.ID).Update("name", "Nasi Goreng Spesial").Error)
+}
+
+func (suite *MenuCacheTestSuite) TestMenuCacheServesRepeatedReads() {
+	opts := services.MenuOptions{At: time.Now()}
+	menu, err := suite.menuService.GetCompleteMenu(opts)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, menuNames(menu))
+
+	suite.renameNasiBehindTheCache()
+	menu, err = suite.menuService.GetCompleteMenu(opts)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, menuNames(menu), "the menu is served from the cache")
+
+	suite.Require().NoError(suite.menuService.UpdateMenuItemAvailability(suite.teh.ID, true))
+	menu, err = suite.menuService.GetCompleteMenu(opts)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"Mains/Nasi Goreng Spesial", "Mains/Es Teh"}, menuNames(menu), "menu writes invalidate the cache")
+}
+
+func (suite *MenuCacheTestSuite) TestMenuCacheIsSharedByAllGuests() {
+	suite.translateMenu()
+	suite.Equal([]string{"Main Courses/Fried Rice", "Main Courses/Es Teh"}, suite.menuIn("en"))
+
+	suite.renameNasiBehindTheCache()
+	suite.Equal([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, suite.menuIn("id"), "one entry serves every language")
+
+	_, err := suite.menuService.SetMenuItemTranslation(suite.nasi.ID, "en", &services.TranslationRequest{Name: "Special Fried Rice"})
+	suite.Require().NoError(err)
+	suite.Equal([]string{"Mains/Nasi Goreng Spesial", "Mains/Es Teh"}, suite.menuIn("id"))
+	suite.Equal([]string{"Main Courses/Special Fried Rice", "Main Courses/Es Teh"}, suite.menuIn("en"))
+}
+
+func (suite *MenuCacheTestSuite) TestMenuCacheIsInvalidatedByImageUploads() {
+	_, err := suite.menuService.GetCompleteMenu(services.MenuOptions{At: time.Now()})
+	suite.Require().NoError(err)
+
+	_, err = suite.menuImageService.SetMenuItemImage(suite.nasi.ID, testJPEG(400, 300))
+	suite.Require().NoError(err)
+	menu, err := suite.menuService.GetCompleteMenu(services.MenuOptions{At: time.Now()})
+	suite.Require().NoError(err)
+	suite.NotEmpty(menu[0].MenuItems[0].ImageURL)
+}
+
+func (suite *MenuCacheTestSuite) TestMenuETag() {
+	gin.SetMode(gin.TestMode)
+	controller := controllers.NewMenuController(suite.menuService)
+	router := gin.New()
+	router.GET("/menu", controller.GetMenu)
+	router.GET("/menu/categories", controller.GetCategories)
+
+	get := func(url, etag, language string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if language != "" {
+			req.Header.Set("Accept-Language", language)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/menu", "", "")
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.Equal("no-cache", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	suite.Regexp(`^"[^"]+"$`, etag, "the ETag is strong")
+
+	w = get("/menu", etag, "")
+	suite.Equal(http.StatusNotModified, w.Code)
+	suite.Empty(w.Body.String())
+	suite.Equal(etag, w.Header().Get("ETag"))
+	suite.Equal(http.StatusNotModified, get("/menu", `"other", W/`+etag, "").Code)
+	suite.NotEmpty(get("/menu/categories", "", "").Header().Get("ETag"))
+
+	suite.translateMenu()
+	suite.Equal(http.StatusNotModified, get("/menu", etag, "").Code, "the ETag follows the content guests see")
+	w = get("/menu", etag, "en")
+	suite.Equal(http.StatusOK, w.Code, "each language has its own ETag")
+	suite.Contains(w.Body.String(), "Fried Rice")
+
+	suite.renameNasiBehindTheCache()
+	suite.Require().NoError(suite.menuService.UpdateMenuItemAvailability(suite.teh.ID, true))
+	w = get("/menu", etag, "")
+	suite.Require().Equal(http.StatusOK, w.Code, "a changed menu has a new ETag")
+	suite.NotEqual(etag, w.Header().Get("ETag"))
+	suite.Contains(w.Body.String(), "Nasi Goreng Spesial")
+}
+
+func TestMenuCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuCacheTestSuite))
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"recursiveDine/internal/controllers"
+	"recursiveDine/internal/media"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuImageTestSuite covers menu item images, stored in a temporary
+// directory with a 1 MB upload limit.
+type MenuImageTestSuite struct {
+	serviceSuite
+	mediaDir         string
+	menuImageService *services.MenuImageService
+}
+
+func (suite *MenuImageTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.mediaDir = suite.T().TempDir()
+	suite.menuImageService = services.NewMenuImageService(suite.menuRepo, suite.menuCache, media.NewLocalStorage(suite.mediaDir, suite.cfg.MediaBaseURL), suite.cfg)
+}
+
+func testJPEG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func testPNG(width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// mediaFiles lists the files stored for a menu item.
+func (suite *MenuImageTestSuite) mediaFiles(menuItemID uint) []string {
+	entries, err := os.ReadDir(filepath.Join(suite.mediaDir, "menu-items", fmt.Sprint(menuItemID)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	suite.Require().NoError(err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// storedImageSize decodes the size of a stored image from its URL.
+func (suite *MenuImageTestSuite) storedImageSize(url string) (int, int, string) {
+	data, err := os.ReadFile(filepath.Join(suite.mediaDir, filepath.FromSlash(strings.TrimPrefix(url, "/media/"))))
+	suite.Require().NoError(err)
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	suite.Require().NoError(err)
+	return config.Width, config.Height, format
+}
+
+func (suite *MenuImageTestSuite) TestMenuItemImageUpload() {
+	photo := testJPEG(1600, 900)
+	item, err := suite.menuImageService.SetMenuItemImage(suite.nasi.ID, photo)
+	suite.Require().NoError(err)
+
+	prefix := fmt.Sprintf("/media/menu-items/%d/", suite.nasi.ID)
+	suite.Require().Len(item.ImageVariants, 3)
+	suite.Equal(item.ImageVariants["medium"], item.ImageURL)
+	for _, url := range item.ImageVariants {
+		suite.True(strings.HasPrefix(url, prefix), url)
+	}
+
+	width, height, format := suite.storedImageSize(item.ImageVariants["medium"])
+	suite.Equal([]interface{}{800, 450, "jpeg"}, []interface{}{width, height, format})
+	width, height, _ = suite.storedImageSize(item.ImageVariants["thumbnail"])
+	suite.Equal([]int{200, 113}, []int{width, height})
+	original, err := os.ReadFile(filepath.Join(suite.mediaDir, filepath.FromSlash(strings.TrimPrefix(item.ImageVariants["original"], "/media/"))))
+	suite.Require().NoError(err)
+	suite.Equal(photo, original, "the original is stored as uploaded")
+
+	item.ImageURL = "https://example.com/other.jpg"
+	item.ImageVariants = nil
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(item))
+	saved, err := suite.menuService.GetMenuItemByID(item.ID)
+	suite.Require().NoError(err)
+	suite.Equal(prefix, saved.ImageURL[:len(prefix)], "an uploaded image is only replaced by uploading another")
+	suite.Len(saved.ImageVariants, 3)
+
+	logo, err := suite.menuImageService.SetMenuItemImage(suite.nasi.ID, testPNG(120, 60))
+	suite.Require().NoError(err)
+	suite.NotEqual(item.ImageURL, logo.ImageURL)
+	width, height, format = suite.storedImageSize(logo.ImageVariants["medium"])
+	suite.Equal([]interface{}{120, 60, "png"}, []interface{}{width, height, format}, "small images are not scaled up")
+	suite.Len(suite.mediaFiles(suite.nasi.ID), 3, "the replaced image is removed")
+
+	cleared, err := suite.menuImageService.DeleteMenuItemImage(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Empty(cleared.ImageURL)
+	suite.Empty(cleared.ImageVariants)
+	suite.Empty(suite.mediaFiles(suite.nasi.ID))
+}
+
+func (suite *MenuImageTestSuite) TestMenuItemImageValidation() {
+	_, err := suite.menuImageService.SetMenuItemImage(suite.nasi.ID, []byte("GIF89a"))
+	suite.ErrorIs(err, media.ErrUnsupportedImage)
+	_, err = suite.menuImageService.SetMenuItemImage(suite.nasi.ID, []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	suite.ErrorIs(err, media.ErrUnsupportedImage)
+	_, err = suite.menuImageService.SetMenuItemImage(suite.nasi.ID, append(testJPEG(10, 10), make([]byte, 1<<20)...))
+	suite.ErrorIs(err, media.ErrImageTooLarge)
+
+	wide := new(bytes.Buffer)
+	suite.Require().NoError(png.Encode(wide, image.NewGray(image.Rect(0, 0, 9000, 1))))
+	_, err = suite.menuImageService.SetMenuItemImage(suite.nasi.ID, wide.Bytes())
+	suite.ErrorIs(err, media.ErrImageTooLarge, "images are measured before they are decoded")
+
+	_, err = suite.menuImageService.SetMenuItemImage(999, testJPEG(10, 10))
+	suite.EqualError(err, "menu item not found")
+	suite.Empty(suite.mediaFiles(suite.nasi.ID))
+}
+
+func (suite *MenuImageTestSuite) TestMenuItemImageEndpoints() {
+	gin.SetMode(gin.TestMode)
+	controller := controllers.NewMenuImageController(suite.menuImageService)
+	router := gin.New()
+	router.PUT("/admin/menu/items/:id/image", controller.UploadMenuItemImage)
+	router.GET("/media/*key", controller.ServeMedia)
+
+	upload := func(data []byte) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		form := multipart.NewWriter(body)
+		part, err := form.CreateFormFile("image", "photo.jpg")
+		suite.Require().NoError(err)
+		_, err = part.Write(data)
+		suite.Require().NoError(err)
+		suite.Require().NoError(form.Close())
+
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/admin/menu/items/%d/image", suite.nasi.ID), body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	suite.Equal(http.StatusUnsupportedMediaType, upload([]byte("plain text")).Code)
+	suite.Equal(http.StatusRequestEntityTooLarge, upload(make([]byte, 3<<20)).Code)
+
+	w := upload(testJPEG(400, 300))
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var item repositories.MenuItem
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &item))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, item.ImageVariants["thumbnail"], nil))
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Equal("image/jpeg", w.Header().Get("Content-Type"))
+	suite.Equal("public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	suite.NotEmpty(etag)
+
+	req := httptest.NewRequest(http.MethodGet, item.ImageVariants["thumbnail"], nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	suite.Equal(http.StatusNotModified, w.Code)
+
+	for _, url := range []string{"/media/menu-items/1/missing.jpg", "/media/menu-items/%2e%2e/%2e%2e/secret", "/media/"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		suite.Equal(http.StatusNotFound, w.Code, url)
+	}
+}
+
+func TestMenuImageTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuImageTestSuite))
+}
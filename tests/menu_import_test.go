@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuImportTestSuite covers menu import and export.
+type MenuImportTestSuite struct {
+	serviceSuite
+	menuImportService *services.MenuImportService
+}
+
+func (suite *MenuImportTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.menuImportService = services.NewMenuImportService(suite.menuRepo, repositories.NewExternalItemMappingRepository(suite.db), suite.menuCache, suite.transactor)
+}
+
+func (suite *MenuImportTestSuite) importCSV(csv string, dryRun bool) *services.MenuImportResult {
+	file, rowErrors, err := services.ParseMenuCSV([]byte(csv))
+	suite.Require().NoError(err)
+	result, err := suite.menuImportService.ImportMenu(file, rowErrors, dryRun)
+	suite.Require().NoError(err)
+	return result
+}
+
+func (suite *MenuImportTestSuite) TestMenuExportRoundTrip() {
+	sku := "NG-1"
+	suite.nasi.SKU = &sku
+	suite.nasi.Description = "Fried rice, \"kampung\" style"
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.nasi))
+	suite.Require().NoError(repositories.NewExternalItemMappingRepository(suite.db).Upsert(&repositories.ExternalItemMapping{Platform: "grabfood", SKU: "GF-7", MenuItemID: suite.teh.ID}))
+	suite.Require().NoError(suite.menuService.UpdateMenuItemAvailability(suite.teh.ID, false))
+
+	file, err := suite.menuImportService.ExportMenu()
+	suite.Require().NoError(err)
+	suite.Require().Len(file.Categories, 1)
+	suite.Equal("Mains", file.Categories[0].Name)
+	suite.Require().Len(file.Items, 2, "unavailable items are exported too")
+	suite.Equal("NG-1", file.Items[0].SKU)
+	suite.Equal([]string{"grabfood:GF-7"}, file.Items[1].ExternalCodes)
+	suite.False(*file.Items[1].IsAvailable)
+
+	var csv bytes.Buffer
+	suite.Require().NoError(services.WriteMenuCSV(&csv, file))
+	suite.Equal(`sku,external_codes,category,name,description,price,is_available,sort_order,station
+NG-1,,Mains,Nasi Goreng,"Fried rice, ""kampung"" style",25000,true,0,
+,grabfood:GF-7,Mains,Es Teh,,5000,false,0,
+`, csv.String())
+
+	result := suite.importCSV(csv.String(), true)
+	suite.Empty(result.Errors)
+	suite.Equal(services.ImportSummary{Unchanged: 3}, result.Summary, "importing an export changes nothing")
+}
+
+func (suite *MenuImportTestSuite) TestMenuImportPreviewAndApply() {
+	csv := `sku,external_codes,category,name,price,is_available,sort_order
+NG-1,,Mains,Nasi Goreng,27000,,1
+,grabfood:GF-9;gofood:GO-9,Drinks,Es Jeruk,8000,true,
+,,mains,Es Teh,5000,false,
+`
+	preview := suite.importCSV(csv, true)
+	suite.Require().Empty(preview.Errors)
+	suite.True(preview.DryRun)
+	suite.False(preview.Applied)
+	suite.Equal(services.ImportSummary{Created: 2, Updated: 2, Unchanged: 1}, preview.Summary)
+
+	suite.Require().Len(preview.Items, 3)
+	nasi := preview.Items[0]
+	suite.Equal(services.ImportUpdate, nasi.Action)
+	suite.Equal(suite.nasi.ID, nasi.ID, "items without a SKU yet are matched by name")
+	suite.Equal(services.FieldChange{From: 25000.0, To: 27000.0}, nasi.Changes["price"])
+	suite.Equal(services.FieldChange{From: 0, To: 1}, nasi.Changes["sort_order"])
+	suite.Contains(nasi.Changes, "sku")
+	jeruk := preview.Items[1]
+	suite.Equal(services.ImportCreate, jeruk.Action)
+	suite.Equal([]string{"grabfood:GF-9", "gofood:GO-9"}, jeruk.Changes["external_codes"].To)
+	suite.Equal(services.FieldChange{From: true, To: false}, preview.Items[2].Changes["is_available"], "categories are matched regardless of case")
+	suite.Equal(services.ImportChange{Row: 3, Action: services.ImportCreate, Name: "Drinks"}, preview.Categories[1])
+	suite.Equal(int64(2), suite.count(&repositories.MenuItem{}), "a dry run writes nothing")
+
+	result := suite.importCSV(csv, false)
+	suite.Require().Empty(result.Errors)
+	suite.True(result.Applied)
+	suite.Equal(preview.Summary, result.Summary)
+	suite.NotZero(result.Items[1].ID)
+
+	saved, err := suite.menuService.GetMenuItemByID(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal(27000.0, saved.Price)
+	suite.Equal("NG-1", *saved.SKU)
+	mappings, err := repositories.NewExternalItemMappingRepository(suite.db).GetBySKUs("gofood", []string{"GO-9"})
+	suite.Require().NoError(err)
+	suite.Require().Len(mappings, 1)
+	suite.Equal(result.Items[1].ID, mappings[0].MenuItemID)
+
+	again := suite.importCSV(csv, true)
+	suite.Equal(services.ImportSummary{Unchanged: 5}, again.Summary, "items are now matched by SKU and platform SKU")
+
+	_, err = suite.menuService.SetMenuItemSchedules(suite.nasi.ID, []services.MenuScheduleRequest{{StartTime: "10:00", EndTime: "14:00"}})
+	suite.Require().NoError(err)
+	suite.importCSV("sku,category,name,price\nNG-1,Mains,Nasi Goreng Spesial,30000\n", false)
+	saved, err = suite.menuService.GetMenuItemByID(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal("Nasi Goreng Spesial", saved.Name, "a SKU lets an import rename an item")
+	suite.Len(saved.Schedules, 1, "imports leave schedules alone")
+}
+
+func (suite *MenuImportTestSuite) TestMenuImportReportsRowErrors() {
+	result := suite.importCSV(`sku,external_codes,category,name,price
+X-1,,Mains,Sate Ayam,abc
+X-1,,Mains,Sate Kambing,30000
+,,Mains,,10000
+,shopee:SH-1,Mains,Soto,15000
+,,Mains,Bakso,-5
+,,Mains,Bakso Urat,
+,,Mains,Mie Ayam,18000
+`, false)
+
+	suite.False(result.Applied)
+	messages := make([]string, 0, len(result.Errors))
+	for _, rowError := range result.Errors {
+		messages = append(messages, fmt.Sprintf("%s/%d/%s", rowError.Section, rowError.Row, rowError.Field))
+	}
+	suite.Equal([]string{
+		"items/2/price",
+		"items/3/sku",
+		"items/4/name",
+		"items/5/external_codes",
+		"items/6/price",
+		"items/7/price",
+	}, messages)
+	suite.Contains(result.Errors[1].Message, "also used on row 2")
+	suite.Equal(int64(2), suite.count(&repositories.MenuItem{}), "nothing is applied while any row is invalid")
+
+	sku := "NG-1"
+	suite.nasi.SKU = &sku
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.nasi))
+	suite.Require().NoError(repositories.NewExternalItemMappingRepository(suite.db).Upsert(&repositories.ExternalItemMapping{Platform: "grabfood", SKU: "GF-7", MenuItemID: suite.teh.ID}))
+	file, err := services.ParseMenuJSON([]byte(`{"categories": [{"name": "Mains"}, {"name": " mains "}], "items": [{"category": "Mains", "name": "Nasi Goreng", "sku": "NG-1", "external_codes": ["GrabFood:GF-7"]}]}`))
+	suite.Require().NoError(err)
+	result, err = suite.menuImportService.ImportMenu(file, nil, true)
+	suite.Require().NoError(err)
+	suite.Require().Len(result.Errors, 2)
+	suite.Equal(services.ImportRowError{Section: "categories", Row: 2, Field: "name", Message: `category "mains" is listed more than once`}, result.Errors[0])
+	suite.Equal(fmt.Sprintf("the platform SKUs belong to item #%d, not to item #%d with SKU NG-1", suite.teh.ID, suite.nasi.ID), result.Errors[1].Message)
+
+	_, _, err = services.ParseMenuCSV([]byte("category,name\nMains,Soto\n"))
+	suite.ErrorIs(err, services.ErrInvalidMenuFile)
+	_, err = services.ParseMenuJSON([]byte(`{"items": [{"nama": "Soto"}]}`))
+	suite.ErrorIs(err, services.ErrInvalidMenuFile)
+}
+
+func (suite *MenuImportTestSuite) TestMenuImportIsTransactional() {
+	file, rowErrors, err := services.ParseMenuCSV([]byte("category,name,price\nDrinks,Es Jeruk,8000\nDrinks,Jus Alpukat,15000\n"))
+	suite.Require().NoError(err)
+
+	suite.failWrites("menu_items", 1)
+	_, err = suite.menuImportService.ImportMenu(file, rowErrors, false)
+	suite.ErrorIs(err, errInjectedFailure)
+	suite.failOn = ""
+
+	suite.Equal(int64(1), suite.count(&repositories.MenuCategory{}), "the new category is rolled back")
+	suite.Equal(int64(2), suite.count(&repositories.MenuItem{}))
+}
+
+func TestMenuImportTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuImportTestSuite))
+}
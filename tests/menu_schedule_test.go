@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuScheduleTestSuite covers menu availability windows. The restaurant is
+// in Jakarta; 2026-10-16 is a Friday.
+type MenuScheduleTestSuite struct {
+	serviceSuite
+}
+
+func jakartaAt(date string, hour, minute int) time.Time {
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	day, _ := time.ParseInLocation("2006-01-02", date, loc)
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func menuNames(categories []repositories.MenuCategory) []string {
+	var names []string
+	for _, category := range categories {
+		for _, item := range category.MenuItems {
+			names = append(names, category.Name+"/"+item.Name)
+		}
+	}
+	return names
+}
+
+func (suite *MenuScheduleTestSuite) TestMenuIsFilteredByDaypart() {
+	breakfast := repositories.MenuCategory{Name: "Breakfast", IsActive: true}
+	suite.Require().NoError(suite.menuService.CreateCategory(&breakfast))
+	bubur := repositories.MenuItem{CategoryID: breakfast.ID, Name: "Bubur Ayam", Price: 18000, IsAvailable: true}
+	suite.Require().NoError(suite.menuService.CreateMenuItem(&bubur))
+
+	_, err := suite.menuService.SetCategorySchedules(breakfast.ID, []services.MenuScheduleRequest{
+		{Name: "Breakfast", Days: []string{"Mon", "tue", "wed", "thu", "fri"}, StartTime: "06:00", EndTime: "10:30"},
+		{Name: "Weekend brunch", Days: []string{"sat", "sun"}, StartTime: "07:00", EndTime: "12:00"},
+	})
+	suite.Require().NoError(err)
+	teh, err := suite.menuService.SetMenuItemSchedules(suite.teh.ID, []services.MenuScheduleRequest{{StartTime: "10:00"}})
+	suite.Require().NoError(err)
+	suite.Require().Len(teh.Schedules, 1)
+	suite.Equal("10:00", teh.Schedules[0].StartTime)
+
+	menu, err := suite.menuService.GetCompleteMenu(services.MenuOptions{At: jakartaAt("2026-10-16", 8, 0)})
+	suite.Require().NoError(err)
+	suite.ElementsMatch([]string{"Mains/Nasi Goreng", "Breakfast/Bubur Ayam"}, menuNames(menu))
+
+	menu, err = suite.menuService.GetCompleteMenu(services.MenuOptions{At: jakartaAt("2026-10-16", 11, 0)})
+	suite.Require().NoError(err)
+	suite.ElementsMatch([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, menuNames(menu), "categories without items in the daypart are left out")
+
+	// 01:00 UTC on Saturday is 08:00 in Jakarta, inside the weekend window
+	menu, err = suite.menuService.GetCompleteMenu(services.MenuOptions{At: time.Date(2026, 10, 17, 1, 0, 0, 0, time.UTC)})
+	suite.Require().NoError(err)
+	suite.Contains(menuNames(menu), "Breakfast/Bubur Ayam")
+
+	items, err := suite.menuService.SearchMenuItems("bubur", services.MenuOptions{At: jakartaAt("2026-10-16", 19, 0)})
+	suite.Require().NoError(err)
+	suite.Empty(items)
+}
+
+func (suite *MenuScheduleTestSuite) TestOvernightAndSeasonalSchedules() {
+	_, err := suite.menuService.SetMenuItemSchedules(suite.nasi.ID, []services.MenuScheduleRequest{
+		{Name: "Late night", Days: []string{"fri"}, StartTime: "22:00", EndTime: "02:00"},
+	})
+	suite.Require().NoError(err)
+	_, err = suite.menuService.SetMenuItemSchedules(suite.teh.ID, []services.MenuScheduleRequest{
+		{Name: "Promo week", StartDate: "2026-10-10", EndDate: "2026-10-16"},
+	})
+	suite.Require().NoError(err)
+
+	cases := []struct {
+		at   time.Time
+		want []string
+	}{
+		{jakartaAt("2026-10-16", 21, 59), []string{"Mains/Es Teh"}},
+		{jakartaAt("2026-10-16", 23, 0), []string{"Mains/Nasi Goreng", "Mains/Es Teh"}},
+		{jakartaAt("2026-10-17", 1, 30), []string{"Mains/Nasi Goreng"}}, // Still Friday night
+		{jakartaAt("2026-10-17", 2, 0), nil},
+		{jakartaAt("2026-10-18", 1, 30), nil}, // Saturday night is not scheduled
+	}
+	for _, tc := range cases {
+		menu, err := suite.menuService.GetCompleteMenu(services.MenuOptions{At: tc.at})
+		suite.Require().NoError(err)
+		suite.Equal(tc.want, menuNames(menu), tc.at.String())
+	}
+
+	_, err = suite.menuService.SetMenuItemSchedules(suite.teh.ID, []services.MenuScheduleRequest{{Days: []string{"funday"}}})
+	suite.EqualError(err, `invalid day "funday". Use mon, tue, wed, thu, fri, sat or sun`)
+	_, err = suite.menuService.SetMenuItemSchedules(suite.teh.ID, []services.MenuScheduleRequest{{StartTime: "25:00"}})
+	suite.EqualError(err, `invalid schedule time "25:00". Use HH:MM`)
+	_, err = suite.menuService.SetMenuItemSchedules(suite.teh.ID, []services.MenuScheduleRequest{{StartDate: "2026-12-31", EndDate: "2026-12-01"}})
+	suite.EqualError(err, "schedule end date is before its start date")
+	_, err = suite.menuService.SetCategorySchedules(999, nil)
+	suite.EqualError(err, "category not found")
+}
+
+func (suite *MenuScheduleTestSuite) TestOffScheduleItemIsRejectedAtOrderTime() {
+	_, err := suite.menuService.SetMenuItemSchedules(suite.nasi.ID, []services.MenuScheduleRequest{
+		{Name: "Lunch", StartTime: "10:00", EndTime: "15:00"},
+	})
+	suite.Require().NoError(err)
+
+	// Pre-orders are checked against the pickup time rather than now
+	_, err = suite.scheduledOrder(tomorrowAt(12, 0))
+	suite.Require().NoError(err)
+	_, err = suite.scheduledOrder(tomorrowAt(18, 0))
+	suite.EqualError(err, "menu item 'Nasi Goreng' is not served at this time")
+
+	_, err = suite.menuService.SetMenuItemSchedules(suite.nasi.ID, []services.MenuScheduleRequest{
+		{Name: "Last season", StartDate: "2025-06-01", EndDate: "2025-08-31"},
+	})
+	suite.Require().NoError(err)
+	_, err = suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.EqualError(err, "menu item 'Nasi Goreng' is not served at this time")
+
+	_, err = suite.orderService.CreateOrder(suite.user.ID, &services.CreateOrderRequest{
+		OrderType:     repositories.OrderTypeTakeaway,
+		CustomerPhone: "+6281234567890",
+		Items:         []services.CreateOrderItemRequest{{MenuItemID: suite.nasi.ID, Quantity: 1}},
+	})
+	suite.EqualError(err, "menu item 'Nasi Goreng' is not served at this time")
+	suite.Equal(int64(1), suite.count(&repositories.Order{}))
+}
+
+func TestMenuScheduleTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuScheduleTestSuite))
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"recursiveDine/internal/repositories"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// menuSearchSchema keeps the suite's tables apart from anything else in the
+// test database.
+const menuSearchSchema = "menu_search_test"
+
+// MenuSearchPostgresTestSuite covers menu search on Postgres, which ranks with
+// full-text search and trigram similarity. It uses the database given by the
+// DB_* variables, as CI does, and is skipped when there is none.
+type MenuSearchPostgresTestSuite struct {
+	suite.Suite
+	db       *gorm.DB
+	menuRepo *repositories.MenuRepository
+}
+
+func (suite *MenuSearchPostgresTestSuite) SetupSuite() {
+	if os.Getenv("DB_HOST") == "" {
+		suite.T().Skip("DB_HOST is not set; skipping Postgres menu search")
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable search_path=%s,public",
+		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), menuSearchSchema)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		suite.T().Skipf("Postgres is not reachable: %v", err)
+	}
+	suite.db = db
+
+	suite.Require().NoError(db.Exec("CREATE SCHEMA IF NOT EXISTS " + menuSearchSchema).Error)
+	migration, err := os.ReadFile("../migrations/022_add_menu_search.sql")
+	suite.Require().NoError(err)
+	suite.Require().NoError(db.Exec(string(migration)).Error)
+	suite.menuRepo = repositories.NewMenuRepository(db)
+}
+
+func (suite *MenuSearchPostgresTestSuite) TearDownSuite() {
+	if suite.db == nil {
+		return
+	}
+	suite.NoError(suite.db.Exec("DROP SCHEMA IF EXISTS " + menuSearchSchema + " CASCADE").Error)
+	if sqlDB, err := suite.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// SetupTest creates a fresh menu: Nasi Goreng with an English name, Sate Ayam
+// among the snacks, an unavailable Es Teh and a dish in an inactive category.
+func (suite *MenuSearchPostgresTestSuite) SetupTest() {
+	tables := []interface{}{
+		&repositories.MenuCategory{},
+		&repositories.MenuItem{},
+		&repositories.MenuSchedule{},
+		&repositories.MenuTranslation{},
+	}
+	suite.Require().NoError(suite.db.Migrator().DropTable(tables...))
+	suite.Require().NoError(suite.db.AutoMigrate(tables...))
+
+	mains := repositories.MenuCategory{Name: "Mains", IsActive: true}
+	snacks := repositories.MenuCategory{Name: "Snacks", IsActive: true}
+	closed := repositories.MenuCategory{Name: "Seasonal", IsActive: true}
+	for _, category := range []*repositories.MenuCategory{&mains, &snacks, &closed} {
+		suite.Require().NoError(suite.db.Create(category).Error)
+	}
+	suite.Require().NoError(suite.db.Model(&closed).Update("is_active", false).Error)
+
+	nasi := repositories.MenuItem{CategoryID: mains.ID, Name: "Nasi Goreng", Description: "Wok-tossed rice with egg and shredded ayam", Price: 25000, IsAvailable: true}
+	sate := repositories.MenuItem{CategoryID: snacks.ID, Name: "Sate Ayam", Description: "Chicken satay with peanut sauce", Price: 30000, IsAvailable: true}
+	teh := repositories.MenuItem{CategoryID: mains.ID, Name: "Es Teh", Price: 5000, IsAvailable: true}
+	kambing := repositories.MenuItem{CategoryID: closed.ID, Name: "Sate Kambing", Price: 35000, IsAvailable: true}
+	for _, item := range []*repositories.MenuItem{&nasi, &sate, &teh, &kambing} {
+		suite.Require().NoError(suite.db.Create(item).Error)
+	}
+	suite.Require().NoError(suite.db.Model(&teh).Update("is_available", false).Error)
+
+	suite.Require().NoError(suite.db.Create(&repositories.MenuTranslation{MenuItemID: &nasi.ID, Locale: "en", Name: "Fried Rice"}).Error)
+}
+
+func (suite *MenuSearchPostgresTestSuite) searchNames(query string) []string {
+	hits, err := suite.menuRepo.SearchMenuItems(query)
+	suite.Require().NoError(err)
+	names := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		names = append(names, hit.Item.Name)
+	}
+	return names
+}
+
+func (suite *MenuSearchPostgresTestSuite) TestSearchRanksNamesAboveDescriptions() {
+	suite.Equal([]string{"Sate Ayam", "Nasi Goreng"}, suite.searchNames("ayam"), "a name match ranks above a description match")
+	suite.Equal([]string{"Sate Ayam"}, suite.searchNames("snacks"), "items are found by their category")
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("fried"), "and by their translations")
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("nasi gor"), "the last word may be unfinished")
+	suite.Empty(suite.searchNames("kambing"), "items in inactive categories are not found")
+	suite.Empty(suite.searchNames("teh"), "unavailable items are not found")
+	suite.Empty(suite.searchNames("  ,  "))
+}
+
+func (suite *MenuSearchPostgresTestSuite) TestSearchToleratesTypos() {
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("nasi gorng"))
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("fried rcie"))
+	suite.Empty(suite.searchNames("rendang"))
+}
+
+func TestMenuSearchPostgresTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuSearchPostgresTestSuite))
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuSearchTestSuite covers menu search on SQLite, which uses the
+// in-process ranking rather than Postgres full-text search.
+type MenuSearchTestSuite struct {
+	serviceSuite
+}
+
+func (suite *MenuSearchTestSuite) searchMenu() {
+	suite.nasi.Description = "Fried rice with egg, chicken satay and prawn crackers"
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.nasi))
+
+	snacks := repositories.MenuCategory{Name: "Snacks", IsActive: true}
+	suite.Require().NoError(suite.menuService.CreateCategory(&snacks))
+	sate := repositories.MenuItem{CategoryID: snacks.ID, Name: "Sate Ayam", Description: "Chicken satay with peanut sauce", Price: 30000, IsAvailable: true}
+	suite.Require().NoError(suite.menuService.CreateMenuItem(&sate))
+
+	closed := repositories.MenuCategory{Name: "Seasonal", IsActive: false}
+	suite.Require().NoError(suite.menuService.CreateCategory(&closed))
+	suite.Require().NoError(suite.db.Model(&closed).Update("is_active", false).Error)
+	hidden := repositories.MenuItem{CategoryID: closed.ID, Name: "Sate Kambing", Price: 35000, IsAvailable: true}
+	suite.Require().NoError(suite.menuService.CreateMenuItem(&hidden))
+}
+
+func (suite *MenuSearchTestSuite) searchNames(query string) []string {
+	results, err := suite.menuService.SearchMenuItems(query, services.MenuOptions{At: time.Now()})
+	suite.Require().NoError(err)
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
+
+func (suite *MenuSearchTestSuite) TestSearchRanksNamesAboveDescriptions() {
+	suite.searchMenu()
+
+	suite.ElementsMatch([]string{"Sate Ayam", "Nasi Goreng"}, suite.searchNames("satay chicken"), "both only match in their description")
+	suite.Equal([]string{"Sate Ayam", "Nasi Goreng"}, suite.searchNames("sate"), "a name match ranks above a description match")
+	suite.Equal([]string{"Sate Ayam"}, suite.searchNames("snacks"), "items are found by their category")
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("nasi gor"), "the last word may be unfinished")
+	suite.Empty(suite.searchNames("kambing"), "items in inactive categories are not found")
+	suite.Empty(suite.searchNames("  ,  "))
+}
+
+func (suite *MenuSearchTestSuite) TestSearchToleratesTypos() {
+	suite.searchMenu()
+
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("nasi gorng"))
+	suite.Equal([]string{"Nasi Goreng"}, suite.searchNames("nais goreng"), "swapped letters count as one typo")
+	suite.ElementsMatch([]string{"Sate Ayam", "Nasi Goreng"}, suite.searchNames("chiken"))
+	suite.Empty(suite.searchNames("teh goreng ayam"), "every word must match")
+	suite.Empty(suite.searchNames("tea"), "short words must match exactly")
+}
+
+func (suite *MenuSearchTestSuite) TestSearchHighlightsMatches() {
+	suite.searchMenu()
+
+	results, err := suite.menuService.SearchMenuItems("goreng egg", services.MenuOptions{At: time.Now()})
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 1)
+	result := results[0]
+	suite.Greater(result.Score, 0.0)
+	suite.Equal("Nasi <mark>Goreng</mark>", result.Highlight.Name)
+	suite.Equal("Mains", result.Highlight.Category)
+	suite.Equal("Fried rice with <mark>egg</mark>, chicken satay and prawn crackers", result.Highlight.Description)
+
+	_, err = suite.menuService.SetMenuItemTranslation(suite.nasi.ID, "en", &services.TranslationRequest{
+		Name:        "Fried Rice <Special>",
+		Description: "Our house fried rice, wok-tossed with sweet soy sauce, shallots and garlic, topped with a fried egg and served with prawn crackers",
+	})
+	suite.Require().NoError(err)
+
+	results, err = suite.menuService.SearchMenuItems("frid egg", services.MenuOptions{At: time.Now(), Locales: []string{"en"}})
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 1)
+	result = results[0]
+	suite.Equal("<mark>Fried</mark> Rice &lt;Special&gt;", result.Highlight.Name, "text is HTML-escaped")
+	suite.Equal("Our house <mark>fried</mark> rice, wok-tossed with sweet soy sauce, shallots and…", result.Highlight.Description, "the snippet starts near the first match")
+
+	results, err = suite.menuService.SearchMenuItems("egg", services.MenuOptions{At: time.Now(), Locales: []string{"en"}})
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 1)
+	suite.Equal("…and garlic, topped with a fried <mark>egg</mark> and served with prawn crackers", results[0].Highlight.Description)
+}
+
+func TestMenuSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuSearchTestSuite))
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuTagsTestSuite covers allergen and dietary tags.
+type MenuTagsTestSuite struct {
+	serviceSuite
+}
+
+func (suite *MenuTagsTestSuite) tagMenu() {
+	suite.nasi.Allergens = []string{"egg", "soy"}
+	suite.nasi.DietaryTags = []string{"halal"}
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.nasi))
+	suite.teh.DietaryTags = []string{"halal", "vegetarian", "vegan"}
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.teh))
+
+	snacks := repositories.MenuCategory{Name: "Snacks", IsActive: true}
+	suite.Require().NoError(suite.menuService.CreateCategory(&snacks))
+	sate := repositories.MenuItem{CategoryID: snacks.ID, Name: "Sate Ayam", Price: 30000, IsAvailable: true, Allergens: []string{"peanut"}, DietaryTags: []string{"halal"}}
+	suite.Require().NoError(suite.menuService.CreateMenuItem(&sate))
+}
+
+func (suite *MenuTagsTestSuite) filteredMenu(excludeAllergens, diets string) []string {
+	opts := services.MenuOptions{At: time.Now()}
+	suite.Require().NoError(opts.SetFilters(excludeAllergens, diets))
+	menu, err := suite.menuService.GetCompleteMenu(opts)
+	suite.Require().NoError(err)
+	return menuNames(menu)
+}
+
+func (suite *MenuTagsTestSuite) TestMenuItemTagsAreValidated() {
+	calories := 450.0
+	item := repositories.MenuItem{
+		CategoryID:  suite.nasi.CategoryID,
+		Name:        "Gado-Gado",
+		Price:       22000,
+		IsAvailable: true,
+		Allergens:   []string{" Peanut", "tree-nut", "egg", "peanut"},
+		DietaryTags: []string{"Vegetarian"},
+		Nutrition:   &repositories.NutritionFacts{Calories: &calories},
+	}
+	suite.Require().NoError(suite.menuService.CreateMenuItem(&item))
+
+	saved, err := suite.menuService.GetMenuItemByID(item.ID)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"peanut", "tree_nut", "egg"}, saved.Allergens)
+	suite.Equal([]string{"vegetarian"}, saved.DietaryTags)
+	suite.Require().NotNil(saved.Nutrition)
+	suite.Equal(450.0, *saved.Nutrition.Calories)
+	suite.Nil(saved.Nutrition.Protein)
+
+	saved.Allergens = []string{"peanuts"}
+	err = suite.menuService.UpdateMenuItem(saved)
+	suite.ErrorIs(err, services.ErrInvalidMenuTag)
+	suite.Contains(err.Error(), `unknown allergen "peanuts"`)
+
+	saved.Allergens = []string{"dairy"}
+	saved.DietaryTags = []string{"vegan"}
+	suite.EqualError(suite.menuService.UpdateMenuItem(saved), "invalid menu tag: a vegan dish cannot contain dairy")
+
+	saved.DietaryTags = nil
+	sugar := -1.0
+	saved.Nutrition.Sugar = &sugar
+	suite.EqualError(suite.menuService.UpdateMenuItem(saved), "nutrition values cannot be negative")
+}
+
+func (suite *MenuTagsTestSuite) TestMenuFiltersByAllergenAndDiet() {
+	suite.tagMenu()
+
+	suite.ElementsMatch([]string{"Mains/Nasi Goreng", "Mains/Es Teh", "Snacks/Sate Ayam"}, suite.filteredMenu("", ""))
+	suite.ElementsMatch([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, suite.filteredMenu("peanut", ""), "categories left empty are dropped")
+	suite.ElementsMatch([]string{"Mains/Es Teh", "Snacks/Sate Ayam"}, suite.filteredMenu("Egg,gluten", ""))
+	suite.ElementsMatch([]string{"Mains/Es Teh"}, suite.filteredMenu("", "vegetarian,halal"), "dishes must suit every diet")
+	suite.ElementsMatch([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, suite.filteredMenu("tree nut,peanut", "halal"))
+
+	opts := services.MenuOptions{At: time.Now()}
+	suite.ErrorIs(opts.SetFilters("peanuts", ""), services.ErrInvalidMenuTag, "a misspelt allergen must not match every dish")
+	suite.ErrorIs(opts.SetFilters("", "keto"), services.ErrInvalidMenuTag)
+
+	opts = services.MenuOptions{At: time.Now()}
+	suite.Require().NoError(opts.SetFilters("soy", ""))
+	items, err := suite.menuService.SearchMenuItems("mains", opts)
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 1)
+	suite.Equal("Es Teh", items[0].Name)
+}
+
+func (suite *MenuTagsTestSuite) TestKitchenTicketShowsTags() {
+	suite.tagMenu()
+
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	order := suite.reloadOrder(created.ID)
+	tickets := printing.Tickets(order)
+	suite.Require().Len(tickets, 1)
+	doc, err := printing.BuildKitchenTicket(order, tickets[0], printing.StoreInfo{Location: time.UTC})
+	suite.Require().NoError(err)
+
+	text := string(printing.RenderText(doc, printing.Paper80mm))
+	suite.Contains(text, "! CONTAINS EGG, SOY")
+	suite.Contains(text, "(halal)")
+	suite.Contains(text, "(halal, vegetarian, vegan)")
+}
+
+func TestMenuTagsTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuTagsTestSuite))
+}
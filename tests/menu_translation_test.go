@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuTranslationTestSuite covers menu translations. The menu is written in
+// Indonesian (MENU_LOCALE=id).
+type MenuTranslationTestSuite struct {
+	serviceSuite
+}
+
+func (suite *MenuTranslationTestSuite) TestMenuIsShownInGuestLanguage() {
+	suite.translateMenu()
+
+	suite.Equal([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, suite.menuIn())
+	suite.Equal([]string{"Main Courses/Fried Rice", "Main Courses/Es Teh"}, suite.menuIn("en-AU"), "en-AU falls back to en, then to the menu itself")
+	suite.Equal([]string{"Main Courses/Fried Rice", "Main Courses/冰茶"}, suite.menuIn("zh-tw", "en"), "each item uses the first language it is translated into")
+	suite.Equal([]string{"Mains/Nasi Goreng", "Mains/Es Teh"}, suite.menuIn("id-ID", "en"), "Indonesian is the menu's own language")
+	suite.Equal([]string{"Main Courses/Fried Rice", "Main Courses/Es Teh"}, suite.menuIn("not a locale", "EN"))
+}
+
+func (suite *MenuTranslationTestSuite) TestSearchMatchesTranslations() {
+	suite.translateMenu()
+
+	items, err := suite.menuService.SearchMenuItems("fried", services.MenuOptions{At: time.Now()})
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 1)
+	suite.Equal("Nasi Goreng", items[0].Name, "English search terms find the Indonesian menu")
+
+	items, err = suite.menuService.SearchMenuItems("goreng", services.MenuOptions{At: time.Now(), Locales: []string{"en"}})
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 1)
+	suite.Equal("Fried Rice", items[0].Name)
+	suite.Equal("With egg and prawn crackers", items[0].Description)
+	suite.Equal("Main Courses", items[0].Category.Name)
+}
+
+func (suite *MenuTranslationTestSuite) TestTranslationManagement() {
+	suite.translateMenu()
+
+	translation, err := suite.menuService.SetMenuItemTranslation(suite.nasi.ID, " EN ", &services.TranslationRequest{Name: "Nasi Goreng (fried rice)"})
+	suite.Require().NoError(err)
+	suite.Equal("en", translation.Locale)
+	suite.Equal("Nasi Goreng (fried rice)", translation.Name)
+
+	translations, err := suite.menuService.GetMenuItemTranslations(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(translations, 1, "saving a locale again replaces it")
+	suite.Equal("Nasi Goreng (fried rice)", translations[0].Name)
+
+	_, err = suite.menuService.SetMenuItemTranslation(suite.nasi.ID, "id", &services.TranslationRequest{Name: "Nasi Goreng"})
+	suite.EqualError(err, "the menu is written in id; update the category or item itself instead")
+	_, err = suite.menuService.SetMenuItemTranslation(suite.nasi.ID, "english", &services.TranslationRequest{Name: "Fried Rice"})
+	suite.EqualError(err, "invalid locale. Use a language tag such as en or en-AU")
+	_, err = suite.menuService.SetCategoryTranslation(999, "en", &services.TranslationRequest{Name: "Drinks"})
+	suite.EqualError(err, "category not found")
+
+	suite.Require().NoError(suite.menuService.DeleteMenuItemTranslation(suite.nasi.ID, "en"))
+	suite.EqualError(suite.menuService.DeleteMenuItemTranslation(suite.nasi.ID, "en"), "translation not found")
+	suite.Equal(int64(2), suite.count(&repositories.MenuTranslation{}))
+}
+
+func TestMenuTranslationTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuTranslationTestSuite))
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MenuVersionTestSuite covers menu price history and published versions.
+type MenuVersionTestSuite struct {
+	serviceSuite
+	menuVersionService *services.MenuVersionService
+}
+
+func (suite *MenuVersionTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.menuVersionService = services.NewMenuVersionService(repositories.NewMenuVersionRepository(suite.db), suite.menuRepo, repositories.NewExternalItemMappingRepository(suite.db), suite.menuCache, suite.transactor)
+}
+
+func (suite *MenuVersionTestSuite) TestMenuItemPriceHistory() {
+	suite.nasi.Price = 27000
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.nasi))
+	suite.nasi.Description = "Spicy"
+	suite.Require().NoError(suite.menuService.UpdateMenuItem(&suite.nasi))
+
+	prices, err := suite.menuVersionService.GetPrices(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal(27000.0, prices.Price)
+	suite.Require().Len(prices.History, 1, "edits that keep the price add nothing")
+	suite.Equal(repositories.PriceSourceEdit, prices.History[0].Source)
+
+	later := 30000.0
+	_, err = suite.menuVersionService.SchedulePrice(suite.nasi.ID, &services.SchedulePriceRequest{Price: &later, EffectiveAt: time.Now().Add(-time.Minute)}, suite.user.ID)
+	suite.Error(err, "prices that change now are set on the item")
+	scheduled, err := suite.menuVersionService.SchedulePrice(suite.nasi.ID, &services.SchedulePriceRequest{Price: &later, EffectiveAt: time.Now().Add(time.Hour)}, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(suite.user.ID, *scheduled.CreatedByID)
+	cancelled, err := suite.menuVersionService.SchedulePrice(suite.nasi.ID, &services.SchedulePriceRequest{Price: &later, EffectiveAt: time.Now().Add(2 * time.Hour)}, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.menuVersionService.CancelScheduledPrice(suite.nasi.ID, cancelled.ID))
+
+	suite.Require().NoError(suite.menuVersionService.ApplyDuePrices())
+	prices, err = suite.menuVersionService.GetPrices(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal(27000.0, prices.Price, "changes wait for their time")
+	suite.Require().Len(prices.Scheduled, 1)
+
+	suite.Require().NoError(suite.db.Model(&repositories.MenuItemPrice{}).Where("id = ?", scheduled.ID).Update("effective_at", time.Now()).Error)
+	suite.Require().NoError(suite.menuVersionService.ApplyDuePrices())
+	prices, err = suite.menuVersionService.GetPrices(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal(30000.0, prices.Price)
+	suite.Empty(prices.Scheduled)
+	suite.Require().Len(prices.History, 2)
+	suite.Equal(scheduled.ID, prices.History[0].ID)
+	suite.NotNil(prices.History[0].AppliedAt)
+	suite.Error(suite.menuVersionService.CancelScheduledPrice(suite.nasi.ID, scheduled.ID), "applied changes cannot be cancelled")
+
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Equal(65000.0, order.SubtotalAmount, "orders are charged the price in effect")
+}
+
+func (suite *MenuVersionTestSuite) TestFailedScheduledPriceKeepsTheMenuCached() {
+	later := 30000.0
+	scheduled, err := suite.menuVersionService.SchedulePrice(suite.nasi.ID, &services.SchedulePriceRequest{Price: &later, EffectiveAt: time.Now().Add(time.Hour)}, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Model(&repositories.MenuItemPrice{}).Where("id = ?", scheduled.ID).Update("effective_at", time.Now()).Error)
+
+	// Warm the cache, then change the item behind its back
+	suite.Contains(suite.menuIn(), "Mains/Nasi Goreng")
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Update("name", "Nasi Goreng Kampung").Error)
+
+	suite.failWrites("menu_items", 0)
+	suite.Require().NoError(suite.menuVersionService.ApplyDuePrices())
+	suite.failOn = ""
+
+	suite.Equal(25000.0, suite.itemPrice(suite.nasi.ID))
+	suite.Contains(suite.menuIn(), "Mains/Nasi Goreng", "nothing changed, so the cached menu is kept")
+	prices, err := suite.menuVersionService.GetPrices(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Len(prices.Scheduled, 1, "the change is tried again")
+
+	suite.Require().NoError(suite.menuVersionService.ApplyDuePrices())
+	suite.Equal(30000.0, suite.itemPrice(suite.nasi.ID))
+	suite.Contains(suite.menuIn(), "Mains/Nasi Goreng Kampung")
+}
+
+func (suite *MenuVersionTestSuite) TestMenuDraftPublishAndRollback() {
+	draft, err := suite.menuVersionService.CreateDraft("Price rise", suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.MenuVersionDraft, draft.Status)
+	suite.Require().Len(draft.Content.Items, 2, "drafts start from the live menu")
+	_, err = suite.menuVersionService.CreateDraft("", suite.user.ID)
+	suite.ErrorIs(err, services.ErrDraftExists)
+
+	// Raise the price of Nasi Goreng, drop Es Teh and add a drink
+	price, jeruk := 27000.0, 8000.0
+	file := draft.Content
+	file.Items[0].Price = &price
+	file.Items = append(file.Items[:1], services.MenuItemRow{Category: "Drinks", Name: "Es Jeruk", Price: &jeruk})
+	_, err = suite.menuVersionService.UpdateDraft(draft.ID, nil, file)
+	suite.Require().NoError(err)
+
+	preview, err := suite.menuVersionService.PreviewVersion(draft.ID)
+	suite.Require().NoError(err)
+	suite.Equal(services.ImportSummary{Created: 2, Updated: 2, Unchanged: 1}, preview.Summary)
+	suite.Equal(25000.0, suite.itemPrice(suite.nasi.ID), "previews write nothing")
+
+	published, err := suite.menuVersionService.PublishDraft(draft.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(published.Version)
+	suite.Equal(repositories.MenuVersionPublished, published.Version.Status)
+	suite.Equal(27000.0, suite.itemPrice(suite.nasi.ID))
+	teh, err := suite.menuService.GetMenuItemByID(suite.teh.ID)
+	suite.Require().NoError(err)
+	suite.False(teh.IsAvailable, "items the version leaves out are taken off the menu")
+	prices, err := suite.menuVersionService.GetPrices(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PriceSourcePublish, prices.History[0].Source)
+	suite.Equal(published.Version.ID, *prices.History[0].MenuVersionID)
+	_, err = suite.menuVersionService.PublishDraft(draft.ID, suite.user.ID)
+	suite.ErrorIs(err, services.ErrNotDraft)
+
+	second, err := suite.menuVersionService.CreateDraft("", suite.user.ID)
+	suite.Require().NoError(err)
+	price = 32000
+	second.Content.Items[0].Price = &price
+	_, err = suite.menuVersionService.UpdateDraft(second.ID, nil, second.Content)
+	suite.Require().NoError(err)
+	_, err = suite.menuVersionService.PublishDraft(second.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(32000.0, suite.itemPrice(suite.nasi.ID))
+
+	_, err = suite.menuVersionService.RollbackToVersion(second.ID, suite.user.ID)
+	suite.ErrorIs(err, services.ErrCurrentVersion)
+	rolledBack, err := suite.menuVersionService.RollbackToVersion(draft.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(draft.ID, *rolledBack.Version.RolledBackFromID)
+	suite.Equal(27000.0, suite.itemPrice(suite.nasi.ID))
+	prices, err = suite.menuVersionService.GetPrices(suite.nasi.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PriceSourceRollback, prices.History[0].Source)
+
+	versions, err := suite.menuVersionService.GetVersions()
+	suite.Require().NoError(err)
+	suite.Len(versions, 3)
+	suite.Equal(rolledBack.Version.ID, versions[0].ID)
+
+	third, err := suite.menuVersionService.CreateDraft("", suite.user.ID)
+	suite.Require().NoError(err)
+	_, err = suite.menuVersionService.RollbackToVersion(third.ID, suite.user.ID)
+	suite.ErrorIs(err, services.ErrNotPublished)
+	suite.Require().NoError(suite.menuVersionService.DiscardDraft(third.ID))
+	suite.ErrorIs(suite.menuVersionService.DiscardDraft(second.ID), services.ErrNotDraft)
+}
+
+func (suite *MenuVersionTestSuite) TestMenuPublishIsTransactional() {
+	draft, err := suite.menuVersionService.CreateDraft("", suite.user.ID)
+	suite.Require().NoError(err)
+	price := 27000.0
+	draft.Content.Items[0].Price = &price
+	_, err = suite.menuVersionService.UpdateDraft(draft.ID, nil, draft.Content)
+	suite.Require().NoError(err)
+
+	suite.failWrites("menu_item_prices", 0)
+	_, err = suite.menuVersionService.PublishDraft(draft.ID, suite.user.ID)
+	suite.Error(err)
+	suite.failOn = ""
+
+	suite.Equal(25000.0, suite.itemPrice(suite.nasi.ID), "the price change is rolled back")
+	version, err := suite.menuVersionService.GetVersion(draft.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.MenuVersionDraft, version.Status)
+}
+
+func (suite *MenuVersionTestSuite) itemPrice(id uint) float64 {
+	item, err := suite.menuService.GetMenuItemByID(id)
+	suite.Require().NoError(err)
+	return item.Price
+}
+
+func TestMenuVersionTestSuite(t *testing.T) {
+	suite.Run(t, new(MenuVersionTestSuite))
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"testing"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// OrderHistoryTestSuite covers the order history: which changes are recorded,
+// by whom and why, and that a change never commits without its entry.
+type OrderHistoryTestSuite struct {
+	serviceSuite
+}
+
+func (suite *OrderHistoryTestSuite) TestHistoryRecordsOrderLifecycle() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.RefundPayment(payment.ID, "customer left", suite.user.ID))
+
+	suite.Equal([]repositories.OrderEventType{
+		repositories.OrderEventCreated,
+		repositories.OrderEventPaymentReceived,
+		repositories.OrderEventStatusChanged,
+		repositories.OrderEventPaymentRefunded,
+		repositories.OrderEventStatusChanged,
+	}, suite.eventTypes(created.ID))
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal("2x Nasi Goreng, 1x Es Teh (total 60500.00)", events[0].NewValue)
+	suite.Require().NotNil(events[3].Actor)
+	suite.Equal("cashier", events[3].Actor.Username)
+	suite.Equal("customer left", events[3].Reason)
+	suite.Equal(string(repositories.OrderStatusConfirmed), events[4].PreviousValue)
+	suite.Equal(string(repositories.OrderStatusCancelled), events[4].NewValue)
+}
+
+func (suite *OrderHistoryTestSuite) TestHistoryRecordsItemEdits() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	_, err = suite.orderService.UpdateOrderItems(created.ID, []repositories.OrderItem{
+		{MenuItemID: suite.teh.ID, Quantity: 4},
+	}, 0, suite.user.ID)
+	suite.Require().NoError(err)
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(events, 2)
+	suite.Equal(repositories.OrderEventItemsUpdated, events[1].Type)
+	suite.Equal("2x Nasi Goreng, 1x Es Teh (total 60500.00)", events[1].PreviousValue)
+	suite.Equal("4x Es Teh (total 22000.00)", events[1].NewValue)
+}
+
+func (suite *OrderHistoryTestSuite) TestAdminOverrideRequiresReason() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	// pending -> ready skips the kitchen workflow
+	_, err = suite.orderService.UpdateOrderStatusAdmin(created.ID, "ready", 0, "  ", suite.user.ID)
+	suite.ErrorIs(err, services.ErrOverrideReasonRequired)
+
+	order, err := suite.orderService.UpdateOrderStatusAdmin(created.ID, "ready", 0, "cooked before the order was keyed in", suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusReady, order.Status)
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	override := events[len(events)-1]
+	suite.Equal(repositories.OrderEventStatusOverridden, override.Type)
+	suite.Equal(string(repositories.OrderStatusPending), override.PreviousValue)
+	suite.Equal(string(repositories.OrderStatusReady), override.NewValue)
+	suite.Equal("cooked before the order was keyed in", override.Reason)
+	suite.Require().NotNil(override.ActorID)
+	suite.Equal(suite.user.ID, *override.ActorID)
+
+	// A normal transition needs no reason
+	_, err = suite.orderService.UpdateOrderStatusAdmin(created.ID, "served", 0, "", suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderEventStatusChanged, suite.eventTypes(created.ID)[2])
+}
+
+func (suite *OrderHistoryTestSuite) TestStatusChangeRollsBackWhenHistoryFails() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	suite.failWrites("order_events", 0)
+	suite.Error(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusConfirmed, 0, suite.user.ID))
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusPending, order.Status, "a change must not be committed without its history entry")
+	suite.Equal(uint(1), order.Version)
+}
+
+func (suite *OrderHistoryTestSuite) TestHistoryOfUnknownOrder() {
+	_, err := suite.orderService.GetOrderHistory(9999)
+	suite.EqualError(err, "order not found")
+}
+
+func TestOrderHistoryTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderHistoryTestSuite))
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"testing"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// OrderRoundTestSuite covers add-on rounds on dine-in orders.
+type OrderRoundTestSuite struct {
+	serviceSuite
+}
+
+func (suite *OrderRoundTestSuite) paidDineInOrder() *services.OrderResponse {
+	table := repositories.Table{Number: 7, QRCode: "table-7", Capacity: 4}
+	suite.Require().NoError(suite.db.Create(&table).Error)
+
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDineIn
+	req.TableID = &table.ID
+
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+	return created
+}
+
+func (suite *OrderRoundTestSuite) drinksRound() *services.AddOrderRoundRequest {
+	return &services.AddOrderRoundRequest{
+		Items: []services.CreateOrderItemRequest{
+			{MenuItemID: suite.teh.ID, Quantity: 2, SpecialRequest: "less ice"},
+		},
+	}
+}
+
+func (suite *OrderRoundTestSuite) TestAddRoundAppendsItemsAndRecomputesVAT() {
+	created := suite.paidDineInOrder()
+
+	order, err := suite.orderService.AddOrderRound(created.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status)
+	suite.Equal(65000.0, order.SubtotalAmount)
+	suite.Equal(6500.0, order.VATAmount)
+	suite.Equal(71500.0, order.TotalAmount)
+
+	suite.Require().Len(order.OrderItems, 3)
+	for _, item := range order.OrderItems {
+		if item.MenuItemID == suite.teh.ID && item.Quantity == 2 {
+			suite.Equal(2, item.Round)
+			suite.Equal("less ice", item.SpecialRequest)
+			continue
+		}
+		suite.Equal(1, item.Round, "items sent earlier keep their round")
+	}
+	suite.Equal(created.OrderItems[0].ID, order.OrderItems[0].ID, "items sent earlier are not replaced")
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	last := events[len(events)-1]
+	suite.Equal(repositories.OrderEventRoundAdded, last.Type)
+	suite.Equal("round 2: 2x Es Teh (total 71500.00)", last.NewValue)
+
+	third, err := suite.orderService.AddOrderRound(created.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(3, third.OrderItems[len(third.OrderItems)-1].Round)
+}
+
+func (suite *OrderRoundTestSuite) TestAddRoundReopensServedOrder() {
+	created := suite.paidDineInOrder()
+	suite.moveTo(created.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady, repositories.OrderStatusServed)
+
+	order, err := suite.orderService.AddOrderRound(created.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusPreparing, order.Status, "the kitchen has work again")
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	last := events[len(events)-1]
+	suite.Equal(repositories.OrderEventStatusChanged, last.Type)
+	suite.Equal(string(repositories.OrderStatusServed), last.PreviousValue)
+	suite.Equal("add-on round 2", last.Reason)
+}
+
+func (suite *OrderRoundTestSuite) TestAddRoundRejectsOrdersOutsideTheKitchen() {
+	takeaway := suite.paidOrder()
+	_, err := suite.orderService.AddOrderRound(takeaway.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.EqualError(err, "add-on rounds are only available for dine-in orders")
+
+	table := repositories.Table{Number: 8, QRCode: "table-8", Capacity: 2}
+	suite.Require().NoError(suite.db.Create(&table).Error)
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDineIn
+	req.TableID = &table.ID
+	pending, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+
+	_, err = suite.orderService.AddOrderRound(pending.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.EqualError(err, "order has not been sent to the kitchen yet, update its items instead")
+}
+
+func (suite *OrderRoundTestSuite) TestAddRoundRollsBackWhenItemsFail() {
+	created := suite.paidDineInOrder()
+	before, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	events := suite.count(&repositories.OrderEvent{})
+
+	suite.failWrites("order_items", 0)
+	_, err = suite.orderService.AddOrderRound(created.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.Require().Error(err)
+	suite.failOn = ""
+
+	after, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(before.TotalAmount, after.TotalAmount)
+	suite.Equal(before.Version, after.Version)
+	suite.Len(after.OrderItems, 2)
+	suite.Equal(events, suite.count(&repositories.OrderEvent{}))
+}
+
+func (suite *OrderRoundTestSuite) TestCashPaymentCollectsRoundBalance() {
+	created := suite.paidDineInOrder()
+	_, err := suite.orderService.AddOrderRound(created.ID, suite.drinksRound(), 0, suite.user.ID)
+	suite.Require().NoError(err)
+
+	suite.EqualError(suite.paymentService.ProcessCashPayment(created.ID, 10000, 0, suite.user.ID), "insufficient payment amount")
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 20000, 0, suite.user.ID))
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(71500.0, payment.Amount, "the payment covers the whole order")
+	suite.Equal(20000.0, payment.AmountTendered)
+	suite.Equal(9000.0, payment.ChangeAmount)
+
+	suite.EqualError(suite.paymentService.ProcessCashPayment(created.ID, 20000, 0, suite.user.ID), "order is not pending payment")
+}
+
+func TestOrderRoundTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderRoundTestSuite))
+}
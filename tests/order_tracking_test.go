@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// OrderTrackingTestSuite covers the customer order tracking stream, pickup
+// numbers and the pickup board. Two customers order takeaway; each may only
+// follow their own.
+type OrderTrackingTestSuite struct {
+	serviceSuite
+	trackingService *services.OrderTrackingService
+	budi            repositories.User
+	sari            repositories.User
+}
+
+func (suite *OrderTrackingTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.trackingService = services.NewOrderTrackingService(suite.orderRepo, suite.kitchenService)
+
+	suite.budi = repositories.User{Name: "Budi", Username: "budi", Email: "budi@test.com", Password: "x", Role: repositories.RoleCustomer}
+	suite.Require().NoError(suite.db.Create(&suite.budi).Error)
+	suite.sari = repositories.User{Name: "Sari", Username: "sari", Email: "sari@test.com", Password: "x", Role: repositories.RoleCustomer}
+	suite.Require().NoError(suite.db.Create(&suite.sari).Error)
+}
+
+func (suite *OrderTrackingTestSuite) customerOrder(customer repositories.User) *repositories.Order {
+	order, err := suite.orderService.CreateOrder(customer.ID, &services.CreateOrderRequest{
+		OrderType:     repositories.OrderTypeTakeaway,
+		CustomerPhone: "+6281234567890",
+		Items:         []services.CreateOrderItemRequest{{MenuItemID: suite.nasi.ID, Quantity: 1}},
+	})
+	suite.Require().NoError(err)
+	return order
+}
+
+// next waits for the next event on a tracking stream.
+func (suite *OrderTrackingTestSuite) next(events <-chan services.KitchenEvent) services.KitchenEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		suite.FailNow("no tracking event received")
+		return services.KitchenEvent{}
+	}
+}
+
+func (suite *OrderTrackingTestSuite) TestSnapshotOnlyHoldsOwnOrders() {
+	mine := suite.customerOrder(suite.budi)
+	suite.customerOrder(suite.sari)
+
+	_, backlog, cancel, err := suite.trackingService.Subscribe(suite.budi.ID, 0)
+	suite.Require().NoError(err)
+	defer cancel()
+
+	suite.Require().Len(backlog, 1)
+	suite.Equal("initial_orders", backlog[0].Type)
+
+	var snapshot struct {
+		Orders []services.OrderTrackingUpdate `json:"orders"`
+	}
+	suite.Require().NoError(json.Unmarshal(backlog[0].Data, &snapshot))
+	suite.Require().Len(snapshot.Orders, 1)
+	suite.Equal(mine.ID, snapshot.Orders[0].OrderID)
+	suite.Equal(repositories.OrderStatusPending, snapshot.Orders[0].Status)
+}
+
+func (suite *OrderTrackingTestSuite) TestStreamSkipsOtherCustomersAndStaffFields() {
+	mine := suite.customerOrder(suite.budi)
+	theirs := suite.customerOrder(suite.sari)
+
+	events, _, cancel, err := suite.trackingService.Subscribe(suite.budi.ID, 0)
+	suite.Require().NoError(err)
+	defer cancel()
+
+	suite.moveTo(theirs.ID, repositories.OrderStatusConfirmed)
+	suite.moveTo(mine.ID, repositories.OrderStatusConfirmed)
+
+	event := suite.next(events)
+	suite.Equal(mine.ID, event.OrderID, "other customers' orders never reach the stream")
+
+	var fields map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(event.Data, &fields))
+	suite.Equal(string(repositories.OrderStatusConfirmed), fields["status"])
+	suite.NotContains(fields, "order", "the kitchen view of the order is not forwarded")
+	suite.NotContains(fields, "customer_phone")
+	suite.NotContains(fields, "table_id")
+}
+
+func (suite *OrderTrackingTestSuite) TestReconnectReplaysOnlyOwnMissedEvents() {
+	mine := suite.customerOrder(suite.budi)
+	theirs := suite.customerOrder(suite.sari)
+
+	events, _, cancel, err := suite.trackingService.Subscribe(suite.budi.ID, 0)
+	suite.Require().NoError(err)
+	suite.moveTo(mine.ID, repositories.OrderStatusConfirmed)
+	seen := suite.next(events).ID
+	cancel()
+
+	// Events published while the customer was away
+	suite.moveTo(theirs.ID, repositories.OrderStatusConfirmed)
+	suite.moveTo(mine.ID, repositories.OrderStatusPreparing)
+
+	_, backlog, cancel, err := suite.trackingService.Subscribe(suite.budi.ID, seen)
+	suite.Require().NoError(err)
+	defer cancel()
+
+	suite.Require().Len(backlog, 1, "no snapshot, and nothing from other customers")
+	suite.Equal(mine.ID, backlog[0].OrderID)
+	suite.Greater(backlog[0].ID, seen)
+
+	var update services.OrderTrackingUpdate
+	suite.Require().NoError(json.Unmarshal(backlog[0].Data, &update))
+	suite.Equal(repositories.OrderStatusPreparing, update.Status)
+}
+
+func (suite *OrderTrackingTestSuite) TestPickupNumbersCountUpPerDay() {
+	first := suite.paidOrder()
+	second := suite.paidOrder()
+	suite.Equal("A-001", first.PickupNumber)
+	suite.Equal("A-002", second.PickupNumber)
+
+	table := repositories.Table{Number: 3, QRCode: "table-3", Capacity: 2}
+	suite.Require().NoError(suite.db.Create(&table).Error)
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDineIn
+	req.TableID = &table.ID
+	dineIn, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+	suite.Empty(dineIn.PickupNumber, "dine-in orders are served at the table")
+
+	// The letter moves on after 999 orders in a day
+	today := time.Now().In(suite.cfg.Location()).Format("2006-01-02")
+	suite.Require().NoError(suite.db.Model(&repositories.PickupCounter{}).
+		Where("business_date = ?", today).Update("last_number", 999).Error)
+	suite.Equal("B-001", suite.paidOrder().PickupNumber)
+
+	// A new business day starts again at A-001
+	suite.Require().NoError(suite.db.Model(&repositories.PickupCounter{}).
+		Where("business_date = ?", today).Update("business_date", "2000-01-01").Error)
+	suite.Equal("A-001", suite.paidOrder().PickupNumber)
+}
+
+func (suite *OrderTrackingTestSuite) TestPickupBoardListsKitchenAndReadyOrders() {
+	preparing := suite.paidOrder()
+	ready := suite.paidOrder()
+	suite.moveTo(ready.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+	suite.customerOrder(suite.budi) // unpaid orders are not in the kitchen yet
+
+	// Later writes to the order do not move the time it became ready
+	readyAt := time.Now().Add(-4 * time.Minute).Truncate(time.Second)
+	suite.Require().NoError(suite.db.Model(&repositories.Order{}).Where("id = ?", ready.ID).
+		Updates(map[string]interface{}{"ready_at": readyAt, "updated_at": time.Now()}).Error)
+
+	board, err := suite.trackingService.GetPickupBoard()
+	suite.Require().NoError(err)
+	suite.Require().Len(board.Preparing, 1)
+	suite.Equal(preparing.PickupNumber, board.Preparing[0].PickupNumber)
+	suite.NotNil(board.Preparing[0].EstimatedReadyAt)
+	suite.Require().Len(board.Ready, 1)
+	suite.Equal(ready.PickupNumber, board.Ready[0].PickupNumber)
+	suite.Require().NotNil(board.Ready[0].ReadySince)
+	suite.True(readyAt.Equal(*board.Ready[0].ReadySince), "ready since %v, want %v", *board.Ready[0].ReadySince, readyAt)
+
+	_, err = suite.orderService.MarkOrderCollected(ready.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	board, err = suite.trackingService.GetPickupBoard()
+	suite.Require().NoError(err)
+	suite.Empty(board.Ready, "collected orders leave the board")
+}
+
+func (suite *OrderTrackingTestSuite) TestBoardStreamRefreshesOnTakeawayChanges() {
+	order := suite.paidOrder()
+
+	events, backlog, cancel, err := suite.trackingService.SubscribeBoard()
+	suite.Require().NoError(err)
+	defer cancel()
+	suite.Require().Len(backlog, 1)
+	suite.Equal("pickup_board", backlog[0].Type)
+
+	suite.moveTo(order.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+	suite.next(events)
+	event := suite.next(events)
+
+	var board services.PickupBoard
+	suite.Require().NoError(json.Unmarshal(event.Data, &board))
+	suite.Empty(board.Preparing)
+	suite.Require().Len(board.Ready, 1)
+	suite.Equal(order.PickupNumber, board.Ready[0].PickupNumber)
+	suite.NotContains(string(event.Data), "Budi", "the board never shows customer details")
+}
+
+func TestOrderTrackingTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderTrackingTestSuite))
+}
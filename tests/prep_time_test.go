@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// PrepTimeTestSuite covers learned prep times, order ETAs and the overrun
+// report. The config defaults to 15 minutes per item until an item has three
+// samples, with three orders cooked side by side.
+type PrepTimeTestSuite struct {
+	serviceSuite
+}
+
+// learn gives a menu item enough history for its estimate to be learned.
+func (suite *PrepTimeTestSuite) learn(item repositories.MenuItem, prep time.Duration) {
+	for i := 0; i < 3; i++ {
+		suite.Require().NoError(suite.db.Create(&repositories.PrepTimeSample{
+			MenuItemID:       item.ID,
+			OrderID:          9999,
+			ActualSeconds:    int(prep.Seconds()),
+			EstimatedSeconds: int(prep.Seconds()),
+		}).Error)
+	}
+}
+
+// confirmedAgo backdates when an order reached the kitchen.
+func (suite *PrepTimeTestSuite) confirmedAgo(orderID uint, ago time.Duration) {
+	suite.Require().NoError(suite.db.Model(&repositories.Order{}).Where("id = ?", orderID).
+		Update("confirmed_at", time.Now().Add(-ago)).Error)
+}
+
+func (suite *PrepTimeTestSuite) samplesOf(orderID uint) []repositories.PrepTimeSample {
+	var samples []repositories.PrepTimeSample
+	suite.Require().NoError(suite.db.Where("order_id = ?", orderID).Find(&samples).Error)
+	return samples
+}
+
+func (suite *PrepTimeTestSuite) TestNewOrderEstimateUsesDefaultThenHistory() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	eta := suite.reloadOrder(created.ID).EstimatedCompletionTime
+	suite.Require().NotNil(eta)
+	suite.WithinDuration(time.Now().Add(15*time.Minute), *eta, time.Minute, "no history yet, so the default applies")
+
+	suite.learn(suite.nasi, 8*time.Minute)
+	suite.learn(suite.teh, 2*time.Minute)
+	estimate, err := suite.prepTimeService.EstimateReadyTime([]uint{suite.nasi.ID, suite.teh.ID})
+	suite.Require().NoError(err)
+	suite.WithinDuration(time.Now().Add(8*time.Minute), estimate, 5*time.Second, "the slowest item decides; unpaid orders hold nobody up")
+}
+
+func (suite *PrepTimeTestSuite) TestQueueEstimatesAddWorkAhead() {
+	first := suite.paidOrder()
+	second := suite.paidOrder()
+	suite.Require().NoError(suite.prepTimeService.RefreshQueueEstimates())
+
+	// Three orders cook side by side, so the first order's 15 minutes hold
+	// the second up by 5
+	suite.WithinDuration(time.Now().Add(15*time.Minute), *suite.reloadOrder(first.ID).EstimatedCompletionTime, time.Minute)
+	suite.WithinDuration(time.Now().Add(20*time.Minute), *suite.reloadOrder(second.ID).EstimatedCompletionTime, time.Minute)
+
+	// Time already spent in the kitchen comes off
+	suite.confirmedAgo(first.ID, 10*time.Minute)
+	suite.confirmedAgo(second.ID, 10*time.Minute)
+	suite.Require().NoError(suite.prepTimeService.RefreshQueueEstimates())
+	suite.WithinDuration(time.Now().Add(5*time.Minute), *suite.reloadOrder(first.ID).EstimatedCompletionTime, time.Minute)
+	suite.WithinDuration(time.Now().Add(6*time.Minute), *suite.reloadOrder(second.ID).EstimatedCompletionTime, time.Minute)
+}
+
+func (suite *PrepTimeTestSuite) TestReadyOrderOnlyTimesItsSlowestItems() {
+	suite.learn(suite.nasi, 20*time.Minute)
+	suite.learn(suite.teh, 2*time.Minute)
+
+	order := suite.paidOrder()
+	suite.confirmedAgo(order.ID, 18*time.Minute)
+	suite.moveTo(order.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+	suite.Require().NoError(suite.prepTimeService.ProcessOrderEvents())
+
+	samples := suite.samplesOf(order.ID)
+	suite.Require().Len(samples, 1, "the tea was done long before and waited for the rice")
+	suite.Equal(suite.nasi.ID, samples[0].MenuItemID)
+	suite.Require().NotNil(samples[0].OrderItemID)
+	suite.InDelta(18*60, samples[0].ActualSeconds, 5)
+	suite.Equal(20*60, samples[0].EstimatedSeconds)
+
+	// Handling the history again does not add samples
+	suite.Require().NoError(suite.eventRepo.SaveCursor("prep_time", 0))
+	suite.Require().NoError(suite.prepTimeService.ProcessOrderEvents())
+	suite.Len(suite.samplesOf(order.ID), 1)
+}
+
+func (suite *PrepTimeTestSuite) TestEventsAreHandledAfterRestart() {
+	first := suite.paidOrder()
+	suite.moveTo(first.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+	suite.Require().NoError(suite.prepTimeService.ProcessOrderEvents())
+	suite.Len(suite.samplesOf(first.ID), 2, "items with the same estimate are both timed")
+
+	// An order becomes ready while the server is down
+	second := suite.paidOrder()
+	suite.moveTo(second.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+
+	restarted := services.NewPrepTimeService(repositories.NewPrepTimeRepository(suite.db), suite.orderRepo, suite.eventRepo, suite.kitchenService, suite.cfg)
+	suite.Require().NoError(restarted.ProcessOrderEvents())
+	suite.Len(suite.samplesOf(first.ID), 2)
+	suite.Len(suite.samplesOf(second.ID), 2)
+
+	events, err := suite.orderService.GetOrderHistory(second.ID)
+	suite.Require().NoError(err)
+	cursor, err := suite.eventRepo.GetCursor("prep_time")
+	suite.Require().NoError(err)
+	suite.Equal(events[len(events)-1].ID, cursor)
+}
+
+func (suite *PrepTimeTestSuite) TestDeletedOrderIsSkipped() {
+	order := suite.paidOrder()
+	suite.moveTo(order.ID, repositories.OrderStatusPreparing, repositories.OrderStatusReady)
+	suite.Require().NoError(suite.db.Delete(&repositories.Order{}, order.ID).Error)
+
+	suite.Require().NoError(suite.prepTimeService.ProcessOrderEvents(), "the events of a deleted order do not hold up the others")
+	suite.Empty(suite.samplesOf(order.ID))
+}
+
+func (suite *PrepTimeTestSuite) TestOverrunReportListsSlowItems() {
+	for i, actual := range []int{1100, 1200, 1300, 800} {
+		suite.Require().NoError(suite.db.Create(&repositories.PrepTimeSample{
+			MenuItemID: suite.nasi.ID, OrderID: uint(i + 1), ActualSeconds: actual, EstimatedSeconds: 900,
+		}).Error)
+		suite.Require().NoError(suite.db.Create(&repositories.PrepTimeSample{
+			MenuItemID: suite.teh.ID, OrderID: uint(i + 1), ActualSeconds: 120, EstimatedSeconds: 120,
+		}).Error)
+	}
+
+	report, err := suite.prepTimeService.GetOverrunReport(30, 4, 0.6)
+	suite.Require().NoError(err)
+	suite.Require().Len(report, 1)
+	suite.Equal(suite.nasi.ID, report[0].MenuItemID)
+	suite.Equal("Nasi Goreng", report[0].MenuItemName)
+	suite.Equal(4, report[0].SampleCount)
+	suite.Equal(3, report[0].OverrunCount)
+	suite.InDelta(0.75, report[0].OverrunRate, 0.001)
+
+	report, err = suite.prepTimeService.GetOverrunReport(30, 5, 0.6)
+	suite.Require().NoError(err)
+	suite.Empty(report, "too few samples to judge")
+}
+
+func TestPrepTimeTestSuite(t *testing.T) {
+	suite.Run(t, new(PrepTimeTestSuite))
+}
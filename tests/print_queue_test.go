@@ -0,0 +1,283 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// PrintQueueTestSuite covers the kitchen print queue. The kitchen and the bar
+// each have a spool printer; drinks are made at the bar.
+type PrintQueueTestSuite struct {
+	serviceSuite
+	cancellationService *services.CancellationService
+	printService        *services.PrintService
+	kitchenDir          string
+	barDir              string
+	kitchen             *repositories.Printer
+	bar                 *repositories.Printer
+}
+
+func (suite *PrintQueueTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.Require().NoError(suite.db.AutoMigrate(&repositories.Printer{}, &repositories.PrintJob{}))
+	suite.cfg.PrintMaxAttempts = 3
+
+	suite.cancellationService = services.NewCancellationService(repositories.NewOrderCancellationRepository(suite.db), suite.orderRepo, suite.paymentService, suite.kitchenService, suite.transactor)
+	suite.printService = suite.newPrintService()
+
+	suite.Require().NoError(suite.db.Model(&suite.teh).Update("station", "bar").Error)
+
+	suite.kitchenDir = filepath.Join(suite.T().TempDir(), "kitchen")
+	suite.barDir = filepath.Join(suite.T().TempDir(), "bar")
+	suite.Require().NoError(os.Mkdir(suite.kitchenDir, 0o755))
+	suite.Require().NoError(os.Mkdir(suite.barDir, 0o755))
+	suite.kitchen = suite.printer("Kitchen", suite.kitchenDir, "kitchen")
+	suite.bar = suite.printer("Bar", suite.barDir, "bar")
+}
+
+// newPrintService builds a print service as the server does on start-up.
+func (suite *PrintQueueTestSuite) newPrintService() *services.PrintService {
+	receiptService := services.NewReceiptService(suite.orderRepo, suite.cfg)
+	return services.NewPrintService(repositories.NewPrinterRepository(suite.db), suite.orderRepo, suite.eventRepo, receiptService, suite.kitchenService, suite.cfg)
+}
+
+func (suite *PrintQueueTestSuite) printer(name, dir, station string) *repositories.Printer {
+	printer, err := suite.printService.CreatePrinter(&services.PrinterRequest{
+		Name:       name,
+		Connection: repositories.PrinterConnectionSpool,
+		Address:    dir,
+		Format:     "text",
+		Stations:   []string{station},
+	})
+	suite.Require().NoError(err)
+	return printer
+}
+
+func (suite *PrintQueueTestSuite) jobs(kind repositories.PrintJobKind) []repositories.PrintJob {
+	var jobs []repositories.PrintJob
+	suite.Require().NoError(suite.db.Where("kind = ?", kind).Order("id ASC").Find(&jobs).Error)
+	return jobs
+}
+
+func (suite *PrintQueueTestSuite) job(id uint) repositories.PrintJob {
+	var job repositories.PrintJob
+	suite.Require().NoError(suite.db.First(&job, id).Error)
+	return job
+}
+
+// makeDue lets a job waiting for its retry be attempted now.
+func (suite *PrintQueueTestSuite) makeDue(id uint) {
+	suite.Require().NoError(suite.db.Model(&repositories.PrintJob{}).Where("id = ?", id).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+}
+
+func (suite *PrintQueueTestSuite) spooled(dir string) int {
+	files, err := os.ReadDir(dir)
+	suite.Require().NoError(err)
+	return len(files)
+}
+
+func (suite *PrintQueueTestSuite) TestConfirmedOrderPrintsOneTicketPerStation() {
+	order := suite.paidOrder()
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+
+	tickets := suite.jobs(repositories.PrintJobKitchenTicket)
+	suite.Require().Len(tickets, 2)
+	suite.Equal(suite.bar.ID, tickets[0].PrinterID)
+	suite.Equal(suite.kitchen.ID, tickets[1].PrinterID)
+	suite.Contains(string(tickets[1].Data), "2 x Nasi Goreng")
+
+	// Handling the history again does not print twice
+	suite.Require().NoError(suite.eventRepo.SaveCursor("print", 0))
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+	suite.Require().NoError(suite.printService.QueueMissingKitchenTickets())
+	suite.Len(suite.jobs(repositories.PrintJobKitchenTicket), 2)
+
+	suite.printService.ProcessDueJobs()
+	suite.Equal(1, suite.spooled(suite.kitchenDir))
+	suite.Equal(1, suite.spooled(suite.barDir))
+	for _, ticket := range suite.jobs(repositories.PrintJobKitchenTicket) {
+		suite.Equal(repositories.PrintJobPrinted, ticket.Status)
+		suite.Equal(1, ticket.Attempts)
+		suite.NotNil(ticket.PrintedAt)
+		suite.Equal(order.ID, *ticket.OrderID)
+	}
+}
+
+func (suite *PrintQueueTestSuite) TestOrdersConfirmedWhileDownArePrintedOnStart() {
+	suite.paidOrder()
+
+	// A fresh consumer starts at the newest event, so the start-up check
+	// has to find the order
+	suite.Require().NoError(suite.eventRepo.InitCursor("print"))
+	restarted := suite.newPrintService()
+	suite.Require().NoError(restarted.ProcessOrderEvents())
+	suite.Empty(suite.jobs(repositories.PrintJobKitchenTicket))
+
+	suite.Require().NoError(restarted.QueueMissingKitchenTickets())
+	suite.Len(suite.jobs(repositories.PrintJobKitchenTicket), 2)
+
+	// Once the cursor is stored, events missed while down are handled
+	second := suite.paidOrder()
+	suite.Require().NoError(suite.newPrintService().ProcessOrderEvents())
+	tickets := suite.jobs(repositories.PrintJobKitchenTicket)
+	suite.Require().Len(tickets, 4)
+	suite.Equal(second.ID, *tickets[3].OrderID)
+}
+
+func (suite *PrintQueueTestSuite) TestDeletedOrderPrintsNothing() {
+	deleted := suite.paidOrder()
+	suite.Require().NoError(suite.db.Delete(&repositories.Order{}, deleted.ID).Error)
+	kept := suite.paidOrder()
+
+	suite.Require().NoError(suite.printService.ProcessOrderEvents(), "the events of a deleted order do not hold up the others")
+	tickets := suite.jobs(repositories.PrintJobKitchenTicket)
+	suite.Require().Len(tickets, 2)
+	for _, ticket := range tickets {
+		suite.Equal(kept.ID, *ticket.OrderID)
+	}
+}
+
+func (suite *PrintQueueTestSuite) TestOfflinePrinterIsRetriedWithBackoff() {
+	suite.Require().NoError(os.Remove(suite.kitchenDir))
+	first := suite.paidOrder()
+	suite.paidOrder()
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+
+	var kitchenJobs []repositories.PrintJob
+	for _, job := range suite.jobs(repositories.PrintJobKitchenTicket) {
+		if job.PrinterID == suite.kitchen.ID {
+			kitchenJobs = append(kitchenJobs, job)
+		}
+	}
+	suite.Require().Len(kitchenJobs, 2)
+	head, next := kitchenJobs[0].ID, kitchenJobs[1].ID
+
+	suite.printService.ProcessDueJobs()
+	job := suite.job(head)
+	suite.Equal(repositories.PrintJobQueued, job.Status)
+	suite.Equal(1, job.Attempts)
+	suite.NotEmpty(job.LastError)
+	suite.WithinDuration(time.Now().Add(5*time.Second), job.NextAttemptAt, time.Second)
+	suite.Equal(0, suite.job(next).Attempts, "later tickets wait so the kitchen gets them in order")
+	suite.Equal(2, suite.spooled(suite.barDir), "other printers are not held up")
+
+	printer, err := repositories.NewPrinterRepository(suite.db).GetByID(suite.kitchen.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PrinterStatusOffline, printer.Status)
+
+	// Nothing is attempted before the retry is due, then the wait doubles
+	suite.printService.ProcessDueJobs()
+	suite.Equal(1, suite.job(head).Attempts)
+	suite.makeDue(head)
+	suite.printService.ProcessDueJobs()
+	job = suite.job(head)
+	suite.Equal(2, job.Attempts)
+	suite.WithinDuration(time.Now().Add(10*time.Second), job.NextAttemptAt, time.Second)
+
+	suite.makeDue(head)
+	suite.printService.ProcessDueJobs()
+	suite.Equal(repositories.PrintJobFailed, suite.job(head).Status, "out of attempts")
+
+	// Staff fix the printer and retry the ticket
+	suite.Require().NoError(os.Mkdir(suite.kitchenDir, 0o755))
+	retried, err := suite.printService.RetryJob(head)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PrintJobQueued, retried.Status)
+	suite.Equal(0, retried.Attempts)
+	suite.makeDue(next)
+	suite.printService.ProcessDueJobs()
+
+	suite.Equal(repositories.PrintJobPrinted, suite.job(head).Status)
+	suite.Equal(repositories.PrintJobPrinted, suite.job(next).Status)
+	suite.Equal(2, suite.spooled(suite.kitchenDir))
+
+	_, err = suite.printService.RetryJob(head)
+	suite.EqualError(err, "print job has already been printed")
+	suite.Equal(first.ID, *suite.job(head).OrderID)
+}
+
+func (suite *PrintQueueTestSuite) TestLaterTicketsWaitForFailedTicket() {
+	suite.Require().NoError(os.Remove(suite.kitchenDir))
+	suite.paidOrder()
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+	suite.printService.ProcessDueJobs()
+
+	// A second order reaches the kitchen while its printer is backing off
+	suite.paidOrder()
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+	var kitchenJobs []repositories.PrintJob
+	for _, job := range suite.jobs(repositories.PrintJobKitchenTicket) {
+		if job.PrinterID == suite.kitchen.ID {
+			kitchenJobs = append(kitchenJobs, job)
+		}
+	}
+	suite.Require().Len(kitchenJobs, 2)
+	head, next := kitchenJobs[0].ID, kitchenJobs[1].ID
+
+	suite.printService.ProcessDueJobs()
+	suite.Equal(1, suite.job(head).Attempts)
+	suite.Equal(0, suite.job(next).Attempts, "the printer is not tried again before its retry is due")
+	suite.Equal(2, suite.spooled(suite.barDir), "other printers are not held up")
+
+	// Once the printer is back, the tickets come out in order
+	suite.Require().NoError(os.Mkdir(suite.kitchenDir, 0o755))
+	suite.makeDue(head)
+	suite.printService.ProcessDueJobs()
+	first, second := suite.job(head), suite.job(next)
+	suite.Equal(repositories.PrintJobPrinted, first.Status)
+	suite.Equal(repositories.PrintJobPrinted, second.Status)
+	suite.False(second.PrintedAt.Before(*first.PrintedAt))
+	suite.Equal(1, second.Attempts)
+}
+
+func (suite *PrintQueueTestSuite) TestCancelledOrderIsVoidedOnce() {
+	printed := suite.paidOrder()
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+	suite.printService.ProcessDueJobs()
+	unprinted := suite.paidOrder()
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+	unseen := suite.paidOrder()
+
+	for _, order := range []*services.OrderResponse{printed, unprinted, unseen} {
+		_, err := suite.cancellationService.CancelOrder(order.ID, &services.CancelOrderRequest{
+			ReasonCode: repositories.CancellationCustomerRequest,
+		}, 0, suite.user.ID, "cashier")
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+
+	voids := suite.jobs(repositories.PrintJobVoidTicket)
+	suite.Require().Len(voids, 2, "one slip per printer that printed a ticket")
+	for _, void := range voids {
+		suite.Equal(printed.ID, *void.OrderID)
+	}
+	suite.Contains(string(voids[1].Data), "ORDER CANCELLED - DO NOT PREPARE")
+
+	for _, ticket := range suite.jobs(repositories.PrintJobKitchenTicket) {
+		suite.NotEqual(unseen.ID, *ticket.OrderID, "cancelled before its tickets were queued")
+		if *ticket.OrderID == unprinted.ID {
+			suite.Equal(repositories.PrintJobCancelled, ticket.Status, "never printed, so nothing to void")
+		}
+	}
+
+	// Handling the cancellation again does not print more slips
+	suite.Require().NoError(suite.eventRepo.SaveCursor("print", 0))
+	suite.Require().NoError(suite.printService.ProcessOrderEvents())
+	jobs, err := suite.printService.VoidKitchenTickets(printed.ID)
+	suite.Require().NoError(err)
+	suite.Empty(jobs)
+	suite.Len(suite.jobs(repositories.PrintJobVoidTicket), 2)
+	suite.Len(suite.jobs(repositories.PrintJobKitchenTicket), 4, "cancelled orders are not queued again")
+}
+
+func TestPrintQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(PrintQueueTestSuite))
+}
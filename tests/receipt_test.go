@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"recursiveDine/internal/printing"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ReceiptTestSuite covers receipts and kitchen tickets in each output format.
+// Drinks are made at the bar, everything else in the kitchen.
+type ReceiptTestSuite struct {
+	serviceSuite
+	receiptService *services.ReceiptService
+}
+
+func (suite *ReceiptTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.cfg.RestaurantName = "Warung Rekursif"
+	suite.cfg.ReceiptFooter = "Terima kasih"
+	suite.receiptService = services.NewReceiptService(suite.orderRepo, suite.cfg)
+
+	suite.Require().NoError(suite.db.Model(&suite.teh).Update("station", "bar").Error)
+}
+
+func (suite *ReceiptTestSuite) render(orderID uint, format printing.Format, width printing.PaperWidth) *services.RenderedDocument {
+	doc, err := suite.receiptService.RenderReceipt(orderID, format, width)
+	suite.Require().NoError(err)
+	return doc
+}
+
+func (suite *ReceiptTestSuite) ticket(orderID uint, station string, format printing.Format) *services.RenderedDocument {
+	doc, err := suite.receiptService.RenderKitchenTicket(orderID, printing.Ticket{Station: station, Round: 1}, format, printing.Paper80mm)
+	suite.Require().NoError(err)
+	return doc
+}
+
+// assertFits checks that no line of a text document is wider than the roll.
+func (suite *ReceiptTestSuite) assertFits(data []byte, width printing.PaperWidth) []string {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for _, line := range lines {
+		suite.LessOrEqual(utf8.RuneCountInString(line), width.Columns(), "line %q overflows the roll", line)
+	}
+	return lines
+}
+
+func (suite *ReceiptTestSuite) TestReceiptListsItemsTotalsAndChange() {
+	order := suite.paidOrder()
+
+	doc := suite.render(order.ID, printing.FormatText, printing.Paper58mm)
+	suite.Equal(fmt.Sprintf("Receipt #%d", order.ID), doc.Title)
+	suite.Equal("text/plain; charset=utf-8", doc.ContentType)
+
+	lines := suite.assertFits(doc.Data, printing.Paper58mm)
+	suite.Equal("Warung Rekursif", strings.TrimSpace(lines[0]))
+	suite.Contains(lines, "2x Nasi Goreng            50.000")
+	suite.Contains(lines, "   @ 25.000")
+	suite.Contains(lines, "1x Es Teh                  5.000")
+	suite.Contains(lines, "PPN 10%                    5.500")
+	suite.Contains(lines, "TOTAL                  Rp 60.500")
+	suite.Contains(lines, "Cash                     100.000")
+	suite.Contains(lines, "Change                    39.500")
+	suite.Contains(lines, "Pickup "+order.PickupNumber)
+	suite.Contains(lines, "          Terima kasih")
+}
+
+func (suite *ReceiptTestSuite) TestUnpaidOrderPrintsAsBill() {
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	doc := suite.render(order.ID, printing.FormatText, printing.Paper80mm)
+	suite.Equal(fmt.Sprintf("Bill #%d", order.ID), doc.Title)
+	suite.Contains(string(doc.Data), "UNPAID")
+	suite.NotContains(string(doc.Data), "Change")
+}
+
+func (suite *ReceiptTestSuite) TestLongLinesWrapWithinTheRoll() {
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Update("name", "Nasi Goreng Kampung Spesial dengan Telur Mata Sapi").Error)
+	req := suite.cashierOrder()
+	req.Items[0].SpecialRequest = "tidak pedas, tanpa bawang goreng, kerupuk dipisah ya"
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+
+	lines := suite.assertFits(suite.render(order.ID, printing.FormatText, printing.Paper58mm).Data, printing.Paper58mm)
+	suite.Contains(lines, "2x Nasi Goreng Kampung    50.000", "the amount stays on the item's first row")
+	suite.Contains(lines, "Spesial dengan Telur Mata", "the name wraps inside its column")
+	suite.Contains(lines, "   * tidak pedas, tanpa bawang", "wrapped requests keep their indent")
+	suite.Contains(lines, "   goreng, kerupuk dipisah ya")
+
+	ticket := suite.ticket(order.ID, "kitchen", printing.FormatText)
+	suite.assertFits(ticket.Data, printing.Paper80mm)
+}
+
+func (suite *ReceiptTestSuite) TestKitchenTicketsSplitByStation() {
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Updates(&repositories.MenuItem{Allergens: []string{"peanut", "tree_nut"}}).Error)
+	req := suite.cashierOrder()
+	req.Items[0].SpecialRequest = "no chili"
+	req.SpecialNotes = "Birthday"
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.Require().NoError(err)
+
+	tickets, err := suite.receiptService.GetOrderTickets(order.ID)
+	suite.Require().NoError(err)
+	suite.Equal([]printing.Ticket{{Station: "bar", Round: 1}, {Station: "kitchen", Round: 1}}, tickets)
+
+	kitchen := string(suite.ticket(order.ID, "Kitchen", printing.FormatText).Data)
+	suite.Contains(kitchen, "2 x Nasi Goreng")
+	suite.Contains(kitchen, "  ! CONTAINS PEANUT, TREE NUT")
+	suite.Contains(kitchen, "  >> no chili")
+	suite.Contains(kitchen, "NOTE: Birthday")
+	suite.NotContains(kitchen, "Es Teh", "bar items go on the bar ticket")
+	suite.NotContains(kitchen, "25.000", "tickets carry no prices")
+
+	bar := string(suite.ticket(order.ID, "bar", printing.FormatText).Data)
+	suite.Contains(bar, "1 x Es Teh")
+	suite.NotContains(bar, "Nasi Goreng")
+
+	_, err = suite.receiptService.RenderKitchenTicket(order.ID, printing.Ticket{Station: "grill", Round: 1}, printing.FormatText, printing.Paper80mm)
+	suite.EqualError(err, "order has no items for this station")
+}
+
+func (suite *ReceiptTestSuite) TestVoidTicketRepeatsTheItems() {
+	order := suite.paidOrder()
+
+	doc, err := suite.receiptService.RenderVoidTicket(order.ID, printing.Ticket{Station: "kitchen", Round: 1}, printing.FormatText, printing.Paper80mm)
+	suite.Require().NoError(err)
+	suite.Equal(fmt.Sprintf("VOID Order #%d - kitchen", order.ID), doc.Title)
+
+	text := string(doc.Data)
+	suite.Contains(text, "*** VOID ***")
+	suite.Contains(text, "2 x Nasi Goreng")
+	suite.Contains(text, "ORDER CANCELLED - DO NOT PREPARE")
+}
+
+func (suite *ReceiptTestSuite) TestESCPOSWrapsLinesInPrinterCommands() {
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Update("name", "Nasi Goreng Spésial").Error)
+	order := suite.paidOrder()
+
+	doc := suite.ticket(order.ID, "kitchen", printing.FormatESCPOS)
+	suite.Equal("application/octet-stream", doc.ContentType)
+
+	data := doc.Data
+	suite.True(bytes.HasPrefix(data, []byte{0x1B, 0x40}), "the printer is reset first")
+	suite.True(bytes.HasSuffix(data, []byte{0x1D, 0x56, 0x42, 0x03}), "the paper is fed and cut last")
+
+	// Item lines are bold and double size, and fit 24 characters a row
+	item := append([]byte{0x1B, 0x45, 0x01, 0x1D, 0x21, 0x11}, "2 x Nasi Goreng Sp?sial\n"...)
+	item = append(item, 0x1D, 0x21, 0x00, 0x1B, 0x45, 0x00)
+	suite.Contains(string(data), string(item), "characters outside ASCII are replaced")
+}
+
+func (suite *ReceiptTestSuite) TestPDFIsSizedToThePaperRoll() {
+	suite.Require().NoError(suite.db.Model(&suite.nasi).Update("name", "Nasi Goreng (Pedas)").Error)
+	order := suite.paidOrder()
+
+	doc := suite.render(order.ID, printing.FormatPDF, printing.Paper58mm)
+	suite.Equal("application/pdf", doc.ContentType)
+
+	pdf := string(doc.Data)
+	suite.True(strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	suite.True(strings.HasSuffix(pdf, "%%EOF\n"))
+	suite.Contains(pdf, "/MediaBox [0 0 164.41 ", "58mm wide")
+	suite.Contains(pdf, `(2x Nasi Goreng \(Pedas\)    50.000) Tj`, "brackets are escaped")
+	suite.Contains(pdf, fmt.Sprintf("/Title (Receipt #%d)", order.ID))
+
+	// The cross-reference table must point at the objects
+	start := strings.LastIndex(pdf, "startxref\n") + len("startxref\n")
+	offset, err := strconv.Atoi(strings.SplitN(pdf[start:], "\n", 2)[0])
+	suite.Require().NoError(err)
+	suite.True(strings.HasPrefix(pdf[offset:], "xref\n"))
+	for i, entry := range strings.Split(pdf[offset:], "\n")[3:10] {
+		at, err := strconv.Atoi(entry[:10])
+		suite.Require().NoError(err)
+		suite.True(strings.HasPrefix(pdf[at:], fmt.Sprintf("%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+}
+
+func TestReceiptTestSuite(t *testing.T) {
+	suite.Run(t, new(ReceiptTestSuite))
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ScheduledOrderTestSuite covers scheduled pickup orders. The config opens
+// from 10:00 to 22:00 Jakarta time with two orders per 15-minute pickup slot.
+type ScheduledOrderTestSuite struct {
+	serviceSuite
+	cancellationService *services.CancellationService
+	scheduleService     *services.ScheduleService
+}
+
+func (suite *ScheduledOrderTestSuite) SetupTest() {
+	suite.serviceSuite.SetupTest()
+	suite.cancellationService = services.NewCancellationService(repositories.NewOrderCancellationRepository(suite.db), suite.orderRepo, suite.paymentService, suite.kitchenService, suite.transactor)
+	suite.scheduleService = services.NewScheduleService(suite.orderRepo, suite.kitchenService, suite.transactor, suite.cfg)
+}
+
+// tomorrowAt returns a time tomorrow in the restaurant's time zone.
+func tomorrowAt(hour, minute int) time.Time {
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, loc)
+}
+
+func (suite *ScheduledOrderTestSuite) TestScheduledOrderWaitsOutsideKitchen() {
+	pickupAt := tomorrowAt(12, 0)
+	created, err := suite.scheduledOrder(pickupAt)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	order := suite.reloadOrder(created.ID)
+	suite.Equal(repositories.OrderStatusScheduled, order.Status, "paid pre-orders are held out of the kitchen")
+	suite.Require().NotNil(order.ReleaseAt)
+	suite.WithinDuration(pickupAt.Add(-30*time.Minute), *order.ReleaseAt, time.Second)
+	suite.WithinDuration(pickupAt, *order.EstimatedCompletionTime, time.Second)
+
+	kitchen, err := suite.orderService.GetKitchenOrders()
+	suite.Require().NoError(err)
+	suite.Empty(kitchen)
+
+	// Not due yet
+	suite.Require().NoError(suite.scheduleService.ReleaseDueOrders())
+	suite.Equal(repositories.OrderStatusScheduled, suite.reloadOrder(created.ID).Status)
+}
+
+func (suite *ScheduledOrderTestSuite) TestDueScheduledOrderIsReleased() {
+	created, err := suite.scheduledOrder(tomorrowAt(12, 0))
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	suite.Require().NoError(suite.db.Model(&repositories.Order{}).Where("id = ?", created.ID).
+		Update("release_at", time.Now().Add(-time.Minute)).Error)
+	suite.Require().NoError(suite.scheduleService.ReleaseDueOrders())
+
+	order := suite.reloadOrder(created.ID)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status)
+	suite.NotNil(order.ConfirmedAt)
+
+	events, err := suite.orderService.GetOrderHistory(created.ID)
+	suite.Require().NoError(err)
+	last := events[len(events)-1]
+	suite.Equal(repositories.OrderEventStatusChanged, last.Type)
+	suite.Equal(string(repositories.OrderStatusScheduled), last.PreviousValue)
+	suite.Equal("scheduled for pickup at 12:00", last.Reason)
+	suite.Nil(last.ActorID, "released by the system")
+}
+
+func (suite *ScheduledOrderTestSuite) TestScheduledPickupTimeIsValidated() {
+	_, err := suite.scheduledOrder(tomorrowAt(23, 0))
+	suite.EqualError(err, "pickup time is outside opening hours (10:00-22:00)")
+
+	_, err = suite.scheduledOrder(time.Now().Add(10 * time.Minute))
+	suite.EqualError(err, "pickup time must be at least 30 minutes from now")
+
+	_, err = suite.scheduledOrder(time.Now().AddDate(0, 0, 10))
+	suite.EqualError(err, "orders can be scheduled at most 7 days ahead")
+
+	table := repositories.Table{Number: 11, QRCode: "table-11", Capacity: 2}
+	suite.Require().NoError(suite.db.Create(&table).Error)
+	req := suite.cashierOrder()
+	req.OrderType = repositories.OrderTypeDineIn
+	req.TableID = &table.ID
+	pickupAt := tomorrowAt(12, 0)
+	req.ScheduledFor = &pickupAt
+	_, err = suite.orderService.CreateCashierOrder(suite.user.ID, req)
+	suite.EqualError(err, "only takeaway orders can be scheduled")
+}
+
+func (suite *ScheduledOrderTestSuite) TestPickupSlotCapacity() {
+	first, err := suite.scheduledOrder(tomorrowAt(12, 0))
+	suite.Require().NoError(err)
+	_, err = suite.scheduledOrder(tomorrowAt(12, 10))
+	suite.Require().NoError(err)
+
+	_, err = suite.scheduledOrder(tomorrowAt(12, 5))
+	suite.ErrorIs(err, services.ErrPickupSlotFull)
+	_, err = suite.scheduledOrder(tomorrowAt(12, 15))
+	suite.NoError(err, "the next slot is still open")
+
+	slots, err := suite.scheduleService.GetPickupSlots(tomorrowAt(0, 0).Format("2006-01-02"))
+	suite.Require().NoError(err)
+	suite.Len(slots, 48)
+	suite.Equal(2, slots[8].Booked)
+	suite.False(slots[8].Available)
+
+	// A cancelled order gives its place back
+	_, err = suite.cancellationService.CancelOrder(first.ID, &services.CancelOrderRequest{
+		ReasonCode: repositories.CancellationCustomerRequest,
+	}, 0, suite.user.ID, "cashier")
+	suite.Require().NoError(err)
+	_, err = suite.scheduledOrder(tomorrowAt(12, 5))
+	suite.NoError(err)
+}
+
+func (suite *ScheduledOrderTestSuite) TestConcurrentBookingsCannotOverfillSlot() {
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = suite.scheduledOrder(tomorrowAt(12, i))
+		}(i)
+	}
+	wg.Wait()
+
+	var booked int
+	for _, err := range errs {
+		if err == nil {
+			booked++
+			continue
+		}
+		suite.ErrorIs(err, services.ErrPickupSlotFull)
+	}
+	suite.Equal(2, booked)
+	suite.Equal(int64(2), suite.count(&repositories.Order{}))
+}
+
+func (suite *ScheduledOrderTestSuite) TestConfirmingUnpaidPreOrderKeepsItScheduled() {
+	created, err := suite.scheduledOrder(tomorrowAt(12, 0))
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusConfirmed, 0, suite.user.ID))
+	suite.Equal(repositories.OrderStatusScheduled, suite.reloadOrder(created.ID).Status, "held like a paid pre-order")
+
+	kitchen, err := suite.orderService.GetKitchenOrders()
+	suite.Require().NoError(err)
+	suite.Empty(kitchen)
+
+	// Sending it to the kitchen early is still possible
+	suite.Require().NoError(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusConfirmed, 0, suite.user.ID))
+	suite.Equal(repositories.OrderStatusConfirmed, suite.reloadOrder(created.ID).Status)
+}
+
+func TestScheduledOrderTestSuite(t *testing.T) {
+	suite.Run(t, new(ScheduledOrderTestSuite))
+}
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"errors"
+	"time"
+
+	"recursiveDine/internal/cache"
+	"recursiveDine/internal/config"
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errInjectedFailure = errors.New("injected write failure")
+
+// serviceSuite is embedded by the service test suites. Every test gets a
+// fresh in-memory SQLite database with a cashier and a small menu, and the
+// services every feature builds on: menu, orders and payments. Suites build
+// the services of their own feature in their SetupTest. Writes to a chosen
+// table can be made to fail part-way through a unit of work to check that it
+// rolls back.
+type serviceSuite struct {
+	suite.Suite
+	db              *gorm.DB
+	cfg             *config.Config
+	transactor      *repositories.Transactor
+	orderRepo       *repositories.OrderRepository
+	menuRepo        *repositories.MenuRepository
+	menuCache       *services.MenuCache
+	eventRepo       *repositories.OrderEventRepository
+	kitchenService  *services.KitchenService
+	prepTimeService *services.PrepTimeService
+	menuService     *services.MenuService
+	orderService    *services.OrderService
+	paymentService  *services.PaymentService
+
+	user     repositories.User
+	nasi     repositories.MenuItem
+	teh      repositories.MenuItem
+	failOn   string
+	failSkip int
+}
+
+// SetupTest gives every test a fresh database
+func (suite *serviceSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	suite.Require().NoError(err)
+
+	// One connection keeps the in-memory database alive and makes any write
+	// that escapes its transaction block instead of silently succeeding
+	sqlDB, err := db.DB()
+	suite.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(
+		&repositories.User{},
+		&repositories.Table{},
+		&repositories.MenuCategory{},
+		&repositories.MenuItem{},
+		&repositories.MenuSchedule{},
+		&repositories.MenuTranslation{},
+		&repositories.MenuItemPrice{},
+		&repositories.MenuVersion{},
+		&repositories.MenuBundleSlot{},
+		&repositories.MenuBundleOption{},
+		&repositories.Order{},
+		&repositories.OrderItem{},
+		&repositories.OrderItemComponent{},
+		&repositories.Payment{},
+		&repositories.PickupCounter{},
+		&repositories.PickupSlotLock{},
+		&repositories.PrepTimeSample{},
+		&repositories.OrderEvent{},
+		&repositories.EventCursor{},
+		&repositories.OrderCancellation{},
+		&repositories.OrderCourse{},
+		&repositories.CustomerAddress{},
+		&repositories.DeliveryZone{},
+		&repositories.OrderDelivery{},
+		&repositories.ExternalItemMapping{},
+	)
+	suite.Require().NoError(err)
+
+	suite.failOn = ""
+	suite.Require().NoError(db.Callback().Create().Before("gorm:create").Register("tests:inject_failure", suite.injectFailure))
+	suite.Require().NoError(db.Callback().Update().Before("gorm:update").Register("tests:inject_failure", suite.injectFailure))
+	suite.Require().NoError(db.Callback().Delete().Before("gorm:delete").Register("tests:inject_failure", suite.injectFailure))
+	suite.db = db
+
+	suite.cfg = &config.Config{
+		Timezone:              "Asia/Jakarta",
+		MenuLocale:            "id",
+		DefaultPrepMinutes:    15,
+		KitchenParallelOrders: 3,
+		CourseAutoFireMinutes: 10,
+		OpeningHours:          "10:00-22:00",
+		PickupSlotMinutes:     15,
+		PickupSlotCapacity:    2,
+		ScheduledLeadMinutes:  30,
+		ScheduleMaxDays:       7,
+
+		PlatformWebhookSecrets: "grabfood=grab-secret,gofood=gojek-secret",
+
+		MediaBaseURL:     "/media",
+		ImageMaxUploadMB: 1,
+	}
+	suite.orderRepo = repositories.NewOrderRepository(db)
+	suite.menuRepo = repositories.NewMenuRepository(db)
+	suite.menuCache = services.NewMenuCache(cache.NewMemoryStore(), time.Minute)
+	suite.kitchenService = services.NewKitchenService(suite.orderRepo)
+	suite.eventRepo = repositories.NewOrderEventRepository(db)
+	suite.prepTimeService = services.NewPrepTimeService(repositories.NewPrepTimeRepository(db), suite.orderRepo, suite.eventRepo, suite.kitchenService, suite.cfg)
+
+	suite.transactor = repositories.NewTransactor(db)
+	suite.menuService = services.NewMenuService(suite.menuRepo, suite.menuCache, suite.transactor, suite.cfg)
+	suite.orderService = services.NewOrderService(suite.orderRepo, suite.menuRepo, suite.eventRepo, repositories.NewDeliveryRepository(db), suite.kitchenService, suite.prepTimeService, suite.transactor, suite.cfg)
+	suite.paymentService = services.NewPaymentService(repositories.NewPaymentRepository(db), suite.orderRepo, suite.kitchenService, suite.transactor, suite.cfg)
+
+	suite.seedMenu()
+}
+
+// TearDownTest closes the in-memory database
+func (suite *serviceSuite) TearDownTest() {
+	sqlDB, _ := suite.db.DB()
+	sqlDB.Close()
+}
+
+func (suite *serviceSuite) seedMenu() {
+	suite.user = repositories.User{Name: "Cashier", Username: "cashier", Email: "cashier@test.com", Password: "x", Role: repositories.RoleCashier}
+	suite.Require().NoError(suite.db.Create(&suite.user).Error)
+
+	mains := repositories.MenuCategory{Name: "Mains", IsActive: true}
+	suite.Require().NoError(suite.db.Create(&mains).Error)
+
+	suite.nasi = repositories.MenuItem{CategoryID: mains.ID, Name: "Nasi Goreng", Price: 25000, IsAvailable: true}
+	suite.Require().NoError(suite.db.Create(&suite.nasi).Error)
+	suite.teh = repositories.MenuItem{CategoryID: mains.ID, Name: "Es Teh", Price: 5000, IsAvailable: true}
+	suite.Require().NoError(suite.db.Create(&suite.teh).Error)
+}
+
+// failWrites makes the next writes to table fail after skip successful ones
+func (suite *serviceSuite) failWrites(table string, skip int) {
+	suite.failOn = table
+	suite.failSkip = skip
+}
+
+func (suite *serviceSuite) injectFailure(db *gorm.DB) {
+	if suite.failOn == "" || db.Statement.Table != suite.failOn {
+		return
+	}
+	if suite.failSkip > 0 {
+		suite.failSkip--
+		return
+	}
+	db.AddError(errInjectedFailure)
+}
+
+func (suite *serviceSuite) count(model interface{}) int64 {
+	var total int64
+	suite.Require().NoError(suite.db.Model(model).Count(&total).Error)
+	return total
+}
+
+func (suite *serviceSuite) cashierOrder() *services.CashierOrderRequest {
+	return &services.CashierOrderRequest{
+		OrderType:     repositories.OrderTypeTakeaway,
+		CustomerName:  "Budi",
+		CustomerPhone: "+6281234567890",
+		CashierName:   "Cashier",
+		Items: []services.CreateOrderItemRequest{
+			{MenuItemID: suite.nasi.ID, Quantity: 2},
+			{MenuItemID: suite.teh.ID, Quantity: 1},
+		},
+	}
+}
+
+func (suite *serviceSuite) reloadOrder(orderID uint) *repositories.Order {
+	order, err := suite.orderService.GetOrderByIDAdmin(orderID)
+	suite.Require().NoError(err)
+	return order
+}
+
+func (suite *serviceSuite) moveTo(orderID uint, statuses ...repositories.OrderStatus) {
+	for _, status := range statuses {
+		suite.Require().NoError(suite.orderService.UpdateOrderStatus(orderID, status, 0, suite.user.ID))
+	}
+}
+
+func (suite *serviceSuite) paidOrder() *services.OrderResponse {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+	return created
+}
+
+func (suite *serviceSuite) scheduledOrder(pickupAt time.Time) (*services.OrderResponse, error) {
+	req := suite.cashierOrder()
+	req.ScheduledFor = &pickupAt
+	return suite.orderService.CreateCashierOrder(suite.user.ID, req)
+}
+
+func (suite *serviceSuite) translateMenu() {
+	mains := suite.nasi.CategoryID
+	_, err := suite.menuService.SetCategoryTranslation(mains, "en", &services.TranslationRequest{Name: "Main Courses"})
+	suite.Require().NoError(err)
+	_, err = suite.menuService.SetMenuItemTranslation(suite.nasi.ID, "en", &services.TranslationRequest{Name: "Fried Rice", Description: "With egg and prawn crackers"})
+	suite.Require().NoError(err)
+	_, err = suite.menuService.SetMenuItemTranslation(suite.teh.ID, "zh", &services.TranslationRequest{Name: "冰茶"})
+	suite.Require().NoError(err)
+}
+
+func (suite *serviceSuite) menuIn(locales ...string) []string {
+	menu, err := suite.menuService.GetCompleteMenu(services.MenuOptions{At: time.Now(), Locales: locales})
+	suite.Require().NoError(err)
+	for _, category := range menu {
+		suite.Nil(category.Translations, "guests only see their own language")
+	}
+	return menuNames(menu)
+}
+
+func (suite *serviceSuite) eventTypes(orderID uint) []repositories.OrderEventType {
+	events, err := suite.orderService.GetOrderHistory(orderID)
+	suite.Require().NoError(err)
+
+	types := make([]repositories.OrderEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
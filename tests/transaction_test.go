@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"testing"
+
+	"recursiveDine/internal/repositories"
+	"recursiveDine/internal/services"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TransactionTestSuite checks that multi-step writes roll back as a whole
+// and that versioned writes reject stale data. It injects a failure into a
+// chosen table part-way through a unit of work.
+type TransactionTestSuite struct {
+	serviceSuite
+}
+
+func (suite *TransactionTestSuite) TestCashierOrderCommitsOrderAndItems() {
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	suite.Equal(int64(1), suite.count(&repositories.Order{}))
+	suite.Len(order.OrderItems, 2)
+	suite.Equal(55000.0, order.SubtotalAmount)
+	suite.Equal("A-001", order.PickupNumber)
+
+	// Each item keeps the price of its own menu item
+	prices := map[uint]float64{}
+	for _, item := range order.OrderItems {
+		prices[item.MenuItemID] = item.UnitPrice
+	}
+	suite.Equal(25000.0, prices[suite.nasi.ID])
+	suite.Equal(5000.0, prices[suite.teh.ID])
+}
+
+func (suite *TransactionTestSuite) TestCashierOrderRollsBackWhenItemInsertFails() {
+	suite.failWrites("order_items", 1)
+
+	_, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Error(err)
+
+	suite.Equal(int64(0), suite.count(&repositories.Order{}))
+	suite.Equal(int64(0), suite.count(&repositories.OrderItem{}))
+	suite.Equal(int64(0), suite.count(&repositories.PickupCounter{}), "pickup number must not be consumed")
+
+	// The next order gets the first pickup number of the day
+	suite.failWrites("", 0)
+	order, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Equal("A-001", order.PickupNumber)
+}
+
+func (suite *TransactionTestSuite) TestCustomerOrderRollsBackWhenItemInsertFails() {
+	suite.failWrites("order_items", 0)
+
+	_, err := suite.orderService.CreateOrder(suite.user.ID, &services.CreateOrderRequest{
+		OrderType:     repositories.OrderTypeTakeaway,
+		CustomerPhone: "+6281234567890",
+		Items:         []services.CreateOrderItemRequest{{MenuItemID: suite.nasi.ID, Quantity: 1}},
+	})
+	suite.Error(err)
+
+	suite.Equal(int64(0), suite.count(&repositories.Order{}))
+	suite.Equal(int64(0), suite.count(&repositories.PickupCounter{}))
+}
+
+func (suite *TransactionTestSuite) TestUpdateOrderItemsRollsBackWhenInsertFails() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	suite.failWrites("order_items", 0)
+	_, err = suite.orderService.UpdateOrderItems(created.ID, []repositories.OrderItem{
+		{MenuItemID: suite.teh.ID, Quantity: 4},
+	}, 0, suite.user.ID)
+	suite.Error(err)
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Len(order.OrderItems, 2, "old items must survive a failed replacement")
+	suite.Equal(created.TotalAmount, order.TotalAmount)
+}
+
+func (suite *TransactionTestSuite) TestUpdateOrderItemsRollsBackWhenTotalsFail() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	suite.failWrites("orders", 0)
+	_, err = suite.orderService.UpdateOrderItems(created.ID, []repositories.OrderItem{
+		{MenuItemID: suite.teh.ID, Quantity: 4},
+	}, 0, suite.user.ID)
+	suite.Error(err)
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Len(order.OrderItems, 2)
+	suite.Equal(created.TotalAmount, order.TotalAmount)
+}
+
+func (suite *TransactionTestSuite) TestUpdateOrderItemsReplacesItemsAndTotals() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	order, err := suite.orderService.UpdateOrderItems(created.ID, []repositories.OrderItem{
+		{MenuItemID: suite.teh.ID, Quantity: 4},
+	}, 0, suite.user.ID)
+	suite.Require().NoError(err)
+
+	suite.Len(order.OrderItems, 1)
+	suite.Equal(20000.0, order.SubtotalAmount)
+	suite.Equal(22000.0, order.TotalAmount)
+	suite.Equal(int64(1), suite.count(&repositories.OrderItem{}))
+}
+
+func (suite *TransactionTestSuite) TestCashPaymentRollsBackWhenOrderUpdateFails() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	suite.failWrites("orders", 0)
+	err = suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID)
+	suite.Error(err)
+
+	suite.Equal(int64(0), suite.count(&repositories.Payment{}), "payment must not be recorded for an unconfirmed order")
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusPending, order.Status)
+}
+
+func (suite *TransactionTestSuite) TestRefundRollsBackWhenOrderUpdateFails() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	payment, err := suite.paymentService.GetPaymentByOrderID(created.ID)
+	suite.Require().NoError(err)
+
+	suite.failWrites("orders", 0)
+	suite.Error(suite.paymentService.RefundPayment(payment.ID, "customer left", suite.user.ID))
+
+	payment, err = suite.paymentService.GetPaymentStatus(payment.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusCompleted, payment.Status)
+}
+
+func (suite *TransactionTestSuite) TestTransactorRollsBackOnPanic() {
+	suite.Panics(func() {
+		suite.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+			uow.Orders.Create(&repositories.Order{UserID: suite.user.ID, OrderType: repositories.OrderTypeTakeaway, Status: repositories.OrderStatusPending})
+			panic("boom")
+		})
+	})
+
+	suite.Equal(int64(0), suite.count(&repositories.Order{}))
+}
+
+func (suite *TransactionTestSuite) TestStatusUpdateBumpsVersion() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(uint(1), order.Version)
+
+	suite.Require().NoError(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusConfirmed, 1, suite.user.ID))
+
+	order, err = suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(uint(2), order.Version)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status)
+}
+
+func (suite *TransactionTestSuite) TestIfMatchMismatchConflicts() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.orderService.UpdateOrderStatus(created.ID, repositories.OrderStatusConfirmed, 0, suite.user.ID))
+
+	// The client still holds version 1
+	_, err = suite.orderService.UpdateOrderStatusAdmin(created.ID, "preparing", 1, "", suite.user.ID)
+	suite.True(services.IsVersionConflict(err))
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status)
+}
+
+func (suite *TransactionTestSuite) TestStaleOrderWriteConflictsAndRollsBack() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+
+	// Cashier A confirms the order while cashier B still has version 1
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	err = suite.transactor.WithinTransaction(func(uow *repositories.UnitOfWork) error {
+		if err := uow.Payments.Create(&repositories.Payment{OrderID: created.ID + 1, Method: repositories.PaymentMethodCash, Amount: 1}); err != nil {
+			return err
+		}
+		return uow.Orders.UpdateStatus(created.ID, 1, repositories.OrderStatusCancelled)
+	})
+	suite.ErrorIs(err, repositories.ErrVersionConflict)
+	suite.Equal(int64(1), suite.count(&repositories.Payment{}), "conflicting unit of work must roll back")
+
+	order, err := suite.orderService.GetOrderByID(created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.OrderStatusConfirmed, order.Status)
+}
+
+func (suite *TransactionTestSuite) TestStalePaymentUpdateConflicts() {
+	created, err := suite.orderService.CreateCashierOrder(suite.user.ID, suite.cashierOrder())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.paymentService.ProcessCashPayment(created.ID, 100000, 0, suite.user.ID))
+
+	paymentRepo := repositories.NewPaymentRepository(suite.db)
+	first, err := paymentRepo.GetByOrderID(created.ID)
+	suite.Require().NoError(err)
+	second, err := paymentRepo.GetByOrderID(created.ID)
+	suite.Require().NoError(err)
+
+	first.Status = repositories.PaymentStatusRefunded
+	suite.Require().NoError(paymentRepo.Update(first))
+	suite.Equal(uint(2), first.Version)
+
+	second.ExternalID = "late-webhook"
+	suite.ErrorIs(paymentRepo.Update(second), repositories.ErrVersionConflict)
+
+	stored, err := paymentRepo.GetByID(first.ID)
+	suite.Require().NoError(err)
+	suite.Equal(repositories.PaymentStatusRefunded, stored.Status)
+	suite.Empty(stored.ExternalID)
+}
+
+func (suite *TransactionTestSuite) TestVersionedWriteOnMissingOrder() {
+	err := repositories.NewOrderRepository(suite.db).UpdateStatus(9999, 1, repositories.OrderStatusConfirmed)
+	suite.EqualError(err, "order not found")
+}
+
+func TestTransactionTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactionTestSuite))
+}